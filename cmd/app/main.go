@@ -2,6 +2,7 @@ package main
 
 import (
 	"log"
+	"os"
 
 	_ "github.com/skndash96/lastnight-backend/docs"
 	api "github.com/skndash96/lastnight-backend/internal"
@@ -21,6 +22,34 @@ import (
 // @license.name MIT
 // @license.url https://opensource.org/licenses/MIT
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "gc" {
+		if err := api.GC(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		if err := api.Import(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "team-export" {
+		if err := api.TeamExport(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "team-import" {
+		if err := api.TeamImport(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	if err := api.Server(); err != nil {
 		log.Fatal(err)
 	}
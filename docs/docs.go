@@ -140,6 +140,143 @@ const docTemplate = `{
                 }
             }
         },
+        "/api/storage/objects/{key}": {
+            "get": {
+                "description": "Serves an object at a presigned URL as an attachment, supporting range requests. Only served with the local storage driver.",
+                "tags": [
+                    "Storage"
+                ],
+                "summary": "Download an object",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Object key",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Expiry as a unix timestamp",
+                        "name": "expires",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Signature",
+                        "name": "signature",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/storage/parts/{uploadID}/{partNumber}": {
+            "put": {
+                "description": "Stores one part PUT to a presigned part URL, which must have exactly the presigned size, and returns its ETag header. Only served with the local storage driver.",
+                "tags": [
+                    "Storage"
+                ],
+                "summary": "Upload a part of a multipart upload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Upload ID",
+                        "name": "uploadID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Part number",
+                        "name": "partNumber",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Object key",
+                        "name": "key",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of parts of the upload",
+                        "name": "parts",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Size of the part",
+                        "name": "size",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Expiry as a unix timestamp",
+                        "name": "expires",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Signature",
+                        "name": "signature",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/storage/uploads": {
+            "post": {
+                "description": "Stores a file uploaded with the fields of a presigned upload, like an S3 POST policy. Only served with the local storage driver. The fields must come before the file.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "tags": [
+                    "Storage"
+                ],
+                "summary": "Upload a file to a presigned POST",
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/api/teams": {
             "get": {
                 "description": "Get the user's teams list",
@@ -266,16 +403,16 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/teams/{teamID}/tags": {
-            "post": {
-                "description": "Create a new tag key",
+        "/api/teams/{teamID}/jobs": {
+            "get": {
+                "description": "List the team's latest exports and imports, newest first. Only mods can list them.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Tag"
+                    "Team"
                 ],
-                "summary": "New Tag Key",
+                "summary": "List team jobs",
                 "parameters": [
                     {
                         "type": "string",
@@ -283,22 +420,13 @@ const docTemplate = `{
                         "name": "teamID",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Tag",
-                        "name": "tag",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/dto.CreateTagKeyBody"
-                        }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/dto.CreateTagKeyResponse"
+                            "$ref": "#/definitions/dto.ListTeamJobsResponse"
                         }
                     },
                     "default": {
@@ -310,16 +438,19 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/teams/{teamID}/tags/{tagID}": {
-            "put": {
-                "description": "Update a tag key",
+        "/api/teams/{teamID}/jobs/export": {
+            "post": {
+                "description": "Start exporting the team's tag keys and values, members with their filters, and uploads with their tags to a versioned ZIP archive. With include_objects, the files are included as well. Poll the job for the download URL of the archive. Only mods can export the team.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Tag"
+                    "Team"
                 ],
-                "summary": "Update Tag Key",
+                "summary": "Start team export",
                 "parameters": [
                     {
                         "type": "string",
@@ -329,27 +460,20 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "Tag ID",
-                        "name": "tagID",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Tag",
-                        "name": "tag",
+                        "description": "Export request",
+                        "name": "export_request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dto.UpdateTagKeyBody"
+                            "$ref": "#/definitions/dto.StartTeamExportBody"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "202": {
+                        "description": "Accepted",
                         "schema": {
-                            "$ref": "#/definitions/dto.UpdateTagKeyResponse"
+                            "$ref": "#/definitions/dto.TeamJobDataResponse"
                         }
                     },
                     "default": {
@@ -359,16 +483,21 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Delete a tag key",
+            }
+        },
+        "/api/teams/{teamID}/jobs/import": {
+            "post": {
+                "description": "Start importing an archive of a team export, sent as the request body. Tag keys are matched by name and values by value, and IDs are remapped. Members are matched by email to members of the team, no users are added to it. Uploads are added when their file is included in the archive, or matched when the team has it already. Poll the job for the report. Only mods can import into the team.",
+                "consumes": [
+                    "application/zip"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Tag"
+                    "Team"
                 ],
-                "summary": "Delete Tag Key",
+                "summary": "Start team import",
                 "parameters": [
                     {
                         "type": "string",
@@ -376,20 +505,13 @@ const docTemplate = `{
                         "name": "teamID",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Tag ID",
-                        "name": "tagID",
-                        "in": "path",
-                        "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "202": {
+                        "description": "Accepted",
                         "schema": {
-                            "$ref": "#/definitions/dto.DeleteTagKeyResponse"
+                            "$ref": "#/definitions/dto.TeamJobDataResponse"
                         }
                     },
                     "default": {
@@ -401,16 +523,16 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/teams/{teamID}/tags/{tagID}/values": {
-            "post": {
-                "description": "Create a new tag value",
+        "/api/teams/{teamID}/jobs/{jobID}": {
+            "get": {
+                "description": "Get an export or import with its report once finished, and for exports the presigned download URL of the archive until it is deleted. Only mods can get them.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Tag"
+                    "Team"
                 ],
-                "summary": "Create Tag Value",
+                "summary": "Get team job",
                 "parameters": [
                     {
                         "type": "string",
@@ -421,26 +543,17 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Tag ID",
-                        "name": "tagID",
+                        "description": "Job ID",
+                        "name": "jobID",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Value",
-                        "name": "value",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/dto.CreateTagValueBody"
-                        }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/dto.CreateTagValueResponse"
+                            "$ref": "#/definitions/dto.TeamJobDataResponse"
                         }
                     },
                     "default": {
@@ -452,16 +565,16 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/teams/{teamID}/tags/{tagID}/values/{tagValueID}": {
-            "delete": {
-                "description": "Delete a tag value",
+        "/api/teams/{teamID}/notifications": {
+            "get": {
+                "description": "List the user's latest notifications in the team, newest first. Mods are notified of uploads rejected by the malware scan.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Tag"
+                    "Team"
                 ],
-                "summary": "Delete Tag Value",
+                "summary": "List notifications",
                 "parameters": [
                     {
                         "type": "string",
@@ -469,28 +582,50 @@ const docTemplate = `{
                         "name": "teamID",
                         "in": "path",
                         "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ListTeamNotificationsResponse"
+                        }
                     },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/teams/{teamID}/notifications/{notificationID}/read": {
+            "post": {
+                "description": "Mark one of the user's notifications in the team as read",
+                "tags": [
+                    "Team"
+                ],
+                "summary": "Mark notification read",
+                "parameters": [
                     {
                         "type": "string",
-                        "description": "Tag ID",
-                        "name": "tagID",
+                        "description": "Team ID",
+                        "name": "teamID",
                         "in": "path",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "Value ID",
-                        "name": "valueID",
+                        "description": "Notification ID",
+                        "name": "notificationID",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/dto.DeleteTagValueResponse"
-                        }
+                        "description": "OK"
                     },
                     "default": {
                         "description": "",
@@ -501,19 +636,16 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/teams/{teamID}/uploads/complete": {
+        "/api/teams/{teamID}/tags": {
             "post": {
-                "description": "Call this route after client-side uploading to the bucket via POST policy. Processes uploaded files.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Create a new tag key",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Upload"
+                    "Tag"
                 ],
-                "summary": "Complete upload",
+                "summary": "New Tag Key",
                 "parameters": [
                     {
                         "type": "string",
@@ -523,18 +655,21 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "description": "Complete upload request",
-                        "name": "upload_request",
+                        "description": "Tag",
+                        "name": "tag",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dto.CompleteUploadsBody"
+                            "$ref": "#/definitions/dto.CreateTagKeyBody"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK"
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/dto.CreateTagKeyResponse"
+                        }
                     },
                     "default": {
                         "description": "",
@@ -545,9 +680,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/teams/{teamID}/uploads/presign": {
+        "/api/teams/{teamID}/tags/suggestions": {
             "post": {
-                "description": "Create a pre-signed request for uploading files to S3 via POST policy",
+                "description": "Suggest values of each tag key for a file, scored from 0 to 1 by how well they match its name and, given an upload, the text extracted from it, and by which values were tagged together on the team's uploads",
                 "consumes": [
                     "application/json"
                 ],
@@ -555,9 +690,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Upload"
+                    "Tag"
                 ],
-                "summary": "Create pre-signed request",
+                "summary": "Suggest Tags",
                 "parameters": [
                     {
                         "type": "string",
@@ -567,20 +702,20 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "description": "Presign request",
-                        "name": "upload_request",
+                        "description": "File to suggest tags for",
+                        "name": "suggest_request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dto.PresignUploadsBody"
+                            "$ref": "#/definitions/dto.SuggestTagsBody"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/dto.PresignUploadsResponse"
+                            "$ref": "#/definitions/dto.SuggestTagsResponse"
                         }
                     },
                     "default": {
@@ -591,200 +726,2857 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "db.GetTeamsByUserIDRow": {
-            "type": "object",
-            "properties": {
-                "membership_id": {
-                    "type": "integer"
+        },
+        "/api/teams/{teamID}/tags/values/pending": {
+            "get": {
+                "description": "List free-form tag values awaiting mod approval",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tag"
+                ],
+                "summary": "List Pending Tag Values",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ListPendingTagValuesResponse"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/teams/{teamID}/tags/{tagID}": {
+            "put": {
+                "description": "Rename a tag key or change its mode. Only mods can update tag keys",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tag"
+                ],
+                "summary": "Update Tag Key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tag ID",
+                        "name": "tagID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Tag",
+                        "name": "tag",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.UpdateTagKeyBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.UpdateTagKeyResponse"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Archive a tag key, or permanently delete it with force. Only mods can delete tag keys",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tag"
+                ],
+                "summary": "Delete Tag Key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tag ID",
+                        "name": "tagID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Permanently delete",
+                        "name": "force",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.DeleteTagKeyResponse"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/teams/{teamID}/tags/{tagID}/impact": {
+            "get": {
+                "description": "Preview how many uploads, filters and values deleting a tag key would affect",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tag"
+                ],
+                "summary": "Tag Key Impact",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tag ID",
+                        "name": "tagID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.GetTagKeyImpactResponse"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/teams/{teamID}/tags/{tagID}/options": {
+            "get": {
+                "description": "List values of a tag key that are valid under the already chosen values",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tag"
+                ],
+                "summary": "List Tag Options",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tag ID",
+                        "name": "tagID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Chosen value IDs",
+                        "name": "value",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ListTagOptionsResponse"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/teams/{teamID}/tags/{tagID}/restore": {
+            "post": {
+                "description": "Restore an archived tag key",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tag"
+                ],
+                "summary": "Restore Tag Key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tag ID",
+                        "name": "tagID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.RestoreTagKeyResponse"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/teams/{teamID}/tags/{tagID}/values": {
+            "post": {
+                "description": "Create a new tag value",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tag"
+                ],
+                "summary": "Create Tag Value",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tag ID",
+                        "name": "tagID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Value",
+                        "name": "value",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.CreateTagValueBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/dto.CreateTagValueResponse"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/teams/{teamID}/tags/{tagID}/values/order": {
+            "put": {
+                "description": "Set the display order of a tag key's values",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tag"
+                ],
+                "summary": "Reorder Tag Values",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tag ID",
+                        "name": "tagID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Order",
+                        "name": "order",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ReorderTagValuesBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/teams/{teamID}/tags/{tagID}/values/{tagValueID}": {
+            "delete": {
+                "description": "Delete a tag value",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tag"
+                ],
+                "summary": "Delete Tag Value",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tag ID",
+                        "name": "tagID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Value ID",
+                        "name": "valueID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.DeleteTagValueResponse"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Rename a tag value",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tag"
+                ],
+                "summary": "Rename Tag Value",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tag ID",
+                        "name": "tagID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Value ID",
+                        "name": "tagValueID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Value",
+                        "name": "value",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.UpdateTagValueBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.UpdateTagValueResponse"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/teams/{teamID}/tags/{tagID}/values/{tagValueID}/approve": {
+            "post": {
+                "description": "Approve a pending free-form tag value",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tag"
+                ],
+                "summary": "Approve Tag Value",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tag ID",
+                        "name": "tagID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Value ID",
+                        "name": "tagValueID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ApproveTagValueResponse"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/teams/{teamID}/tags/{tagID}/values/{tagValueID}/merge": {
+            "post": {
+                "description": "Merge a tag value into another value of the same key, moving its uploads and filters",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tag"
+                ],
+                "summary": "Merge Tag Value",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tag ID",
+                        "name": "tagID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Value ID",
+                        "name": "tagValueID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Target",
+                        "name": "target",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.MergeTagValueBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.MergeTagValueResponse"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/teams/{teamID}/tags/{tagID}/values/{tagValueID}/parents": {
+            "put": {
+                "description": "Set the values of other keys a tag value is valid under",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tag"
+                ],
+                "summary": "Set Tag Value Parents",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tag ID",
+                        "name": "tagID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Value ID",
+                        "name": "tagValueID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Parents",
+                        "name": "parents",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.SetTagValueParentsBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/teams/{teamID}/tags/{tagID}/values/{tagValueID}/reject": {
+            "post": {
+                "description": "Reject a pending free-form tag value. It is deleted and removed from the uploads tagged with it",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tag"
+                ],
+                "summary": "Reject Tag Value",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tag ID",
+                        "name": "tagID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Value ID",
+                        "name": "tagValueID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.RejectTagValueResponse"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/teams/{teamID}/uploads": {
+            "get": {
+                "description": "List uploads of a team, optionally filtered by tag values and number ranges. With q, uploads are searched by content and ranked by relevance.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Upload"
+                ],
+                "summary": "List uploads",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Full-text search query",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Tag value IDs",
+                        "name": "value",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Number ranges as keyID:min:max",
+                        "name": "range",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Limit",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ListUploadsResponse"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Upload a file through the server, as an alternative to the pre-signed POST flow. Send either multipart/form-data, with the name and tags fields before the file part, or the raw file as the request body with name and tags in the query. The file is streamed to storage and then processed like a completed upload.",
+                "consumes": [
+                    "multipart/form-data",
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "Upload"
+                ],
+                "summary": "Upload file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "File, for multipart requests",
+                        "name": "file",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "File name, defaults to the name of the file part",
+                        "name": "name",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "JSON array of tags",
+                        "name": "tags",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "File name, for raw requests",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "JSON array of tags, for raw requests",
+                        "name": "tags",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Upload ID to add the file to as a new version",
+                        "name": "version_of",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Upload ID to add the file to as a new version, for raw requests",
+                        "name": "version_of",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created"
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/teams/{teamID}/uploads/check": {
+            "post": {
+                "description": "Check by hash and size whether the team already has a file before uploading it. in_team tells whether it is one of the team's uploads. exists also covers files the team can download as a version of one of its uploads, and with a name given those are added without uploading them again. Files of other teams are never matched.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Upload"
+                ],
+                "summary": "Check upload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Check upload request",
+                        "name": "check_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.CheckUploadBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.CheckUploadResponse"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/teams/{teamID}/uploads/complete": {
+            "post": {
+                "description": "Call this route after client-side uploading to the bucket via POST policy. Processes uploaded file.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Upload"
+                ],
+                "summary": "Complete upload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Complete upload request",
+                        "name": "upload_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.CompleteUploadBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/teams/{teamID}/uploads/export": {
+            "get": {
+                "description": "Download every upload matching the listing filters as one ZIP, named by file name, with a manifest.json of their tags. The ZIP is streamed, so a failure midway leaves it truncated. Exports are capped in total size and rate limited per user, exports refused for their filters do not count.",
+                "produces": [
+                    "application/zip"
+                ],
+                "tags": [
+                    "Upload"
+                ],
+                "summary": "Export uploads",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Full-text search query",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Tag value IDs",
+                        "name": "value",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Number ranges as keyID:min:max",
+                        "name": "range",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/teams/{teamID}/uploads/images/complete": {
+            "post": {
+                "description": "Call this route after uploading all images of a set. Combines the images, in the given order, into a single PDF upload.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Upload"
+                ],
+                "summary": "Complete image set upload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Complete image set upload request",
+                        "name": "upload_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.CompleteImageUploadBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created"
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/teams/{teamID}/uploads/images/presign": {
+            "post": {
+                "description": "Create one pre-signed request per image. The images are combined into a single PDF on completion.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Upload"
+                ],
+                "summary": "Create pre-signed requests for an image set",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Presign image set request",
+                        "name": "upload_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.PresignImageUploadBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.PresignImageUploadResponse"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/teams/{teamID}/uploads/multipart": {
+            "get": {
+                "description": "List the unfinished multipart uploads of the current user, to resume them.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Upload"
+                ],
+                "summary": "List multipart uploads",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ListMultipartUploadsResponse"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Start a resumable upload of a large file. Upload part_count parts of part_size bytes (the last one may be smaller) to URLs from the parts route, then complete it. Unfinished uploads expire.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Upload"
+                ],
+                "summary": "Create multipart upload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Multipart upload request",
+                        "name": "upload_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.CreateMultipartUploadBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/dto.MultipartUploadResponse"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/teams/{teamID}/uploads/multipart/{multipartID}": {
+            "get": {
+                "description": "Get a multipart upload with the parts uploaded so far. Resume by uploading the missing parts.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Upload"
+                ],
+                "summary": "Get multipart upload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Multipart upload ID",
+                        "name": "multipartID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.MultipartUploadStatusResponse"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Cancel a multipart upload and discard its uploaded parts.",
+                "tags": [
+                    "Upload"
+                ],
+                "summary": "Abort multipart upload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Multipart upload ID",
+                        "name": "multipartID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/teams/{teamID}/uploads/multipart/{multipartID}/complete": {
+            "post": {
+                "description": "Call this route once every part is uploaded. Assembles the parts and processes the file like a completed upload. If that fails with a server error, the multipart upload is kept and completing it can be retried.",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Upload"
+                ],
+                "summary": "Complete multipart upload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Multipart upload ID",
+                        "name": "multipartID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Complete multipart upload request",
+                        "name": "upload_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.CompleteMultipartUploadBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created"
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/teams/{teamID}/uploads/multipart/{multipartID}/parts": {
+            "post": {
+                "description": "Create pre-signed URLs to PUT parts of a multipart upload to. URLs expire quickly, request them in batches as the upload progresses.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Upload"
+                ],
+                "summary": "Create pre-signed part requests",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Multipart upload ID",
+                        "name": "multipartID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Part numbers",
+                        "name": "parts_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.PresignUploadPartsBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.PresignUploadPartsResponse"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/teams/{teamID}/uploads/presign": {
+            "post": {
+                "description": "Create a pre-signed request for uploading files to S3 via POST policy",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Upload"
+                ],
+                "summary": "Create pre-signed request",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Presign request",
+                        "name": "upload_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.PresignUploadBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/dto.PresignUploadResponse"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/teams/{teamID}/uploads/rejected": {
+            "get": {
+                "description": "List uploads of a team that failed the malware scan. Only mods can list rejected uploads.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Upload"
+                ],
+                "summary": "List rejected uploads",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ListRejectedUploadsResponse"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/teams/{teamID}/uploads/settings": {
+            "get": {
+                "description": "Get the content types, file name extensions and file size a team accepts on upload",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Upload"
+                ],
+                "summary": "Get upload settings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.UploadSettingsResponse"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Set the content types, file name extensions and file size a team accepts on upload. Only mods can update settings.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Upload"
+                ],
+                "summary": "Update upload settings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Upload settings",
+                        "name": "settings",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.UpdateUploadSettingsBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.UploadSettingsResponse"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/teams/{teamID}/uploads/{uploadID}": {
+            "delete": {
+                "description": "Remove an upload from the team. Uploaders can delete their own uploads, mods any upload of the team. Uploads rejected by the malware scan can only be deleted by mods.",
+                "tags": [
+                    "Upload"
+                ],
+                "summary": "Delete upload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Upload ID",
+                        "name": "uploadID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Rename an upload and/or replace its tags. Uploaders can edit their own uploads, mods any upload of the team. Uploads rejected by the malware scan can only be edited by mods.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Upload"
+                ],
+                "summary": "Update upload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Upload ID",
+                        "name": "uploadID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Upload changes",
+                        "name": "upload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.UpdateUploadBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.UpdateUploadResponse"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/teams/{teamID}/uploads/{uploadID}/derivatives": {
+            "get": {
+                "description": "List the rendered thumbnail and page previews of an upload with presigned GET URLs",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Upload"
+                ],
+                "summary": "List upload previews",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Upload ID",
+                        "name": "uploadID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ListUploadDerivativesResponse"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Render the thumbnail and page previews of a PDF upload again, and extract its text for search. Only mods can regenerate previews.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Upload"
+                ],
+                "summary": "Regenerate upload previews",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Upload ID",
+                        "name": "uploadID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ListUploadDerivativesResponse"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/teams/{teamID}/uploads/{uploadID}/versions": {
+            "get": {
+                "description": "List every version of an upload, newest first, with presigned GET URLs. current marks the version the upload is listed and searched with.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Upload"
+                ],
+                "summary": "List upload versions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Upload ID",
+                        "name": "uploadID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ListUploadVersionsResponse"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/teams/{teamID}/uploads/{uploadID}/versions/current": {
+            "put": {
+                "description": "Make a version the current one of an upload, e.g. to roll back a bad revision. Only mods can set the current version.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Upload"
+                ],
+                "summary": "Set current upload version",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Upload ID",
+                        "name": "uploadID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Set current version request",
+                        "name": "version_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.SetCurrentUploadVersionBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.UpdateUploadResponse"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/teams/{teamID}/usage": {
+            "get": {
+                "description": "Get the bytes stored by the team and each uploader, and the team's quotas. Only mods can view usage.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Upload"
+                ],
+                "summary": "Get storage usage",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.UsageResponse"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/teams/{teamID}/usage/quotas": {
+            "put": {
+                "description": "Set the bytes the team, and each uploader within it, may store. Null is unlimited. Only mods can update quotas.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Upload"
+                ],
+                "summary": "Update storage quotas",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Team ID",
+                        "name": "teamID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Quotas",
+                        "name": "quotas",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.UpdateQuotasBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.UsageResponse"
+                        }
+                    },
+                    "default": {
+                        "description": "",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "db.GetTagKeyImpactRow": {
+            "type": "object",
+            "properties": {
+                "filters": {
+                    "type": "integer"
+                },
+                "tag_values": {
+                    "type": "integer"
+                },
+                "uploads": {
+                    "type": "integer"
+                }
+            }
+        },
+        "db.GetTeamsByUserIDRow": {
+            "type": "object",
+            "properties": {
+                "membership_id": {
+                    "type": "integer"
+                },
+                "team_domain": {
+                    "type": "string"
+                },
+                "team_id": {
+                    "type": "integer"
+                },
+                "team_name": {
+                    "type": "string"
+                },
+                "user_joined_at": {
+                    "type": "string"
+                },
+                "user_role": {
+                    "$ref": "#/definitions/db.TeamUserRole"
+                }
+            }
+        },
+        "db.ListRejectedUploadsRow": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "file_name": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "scan_signature": {
+                    "type": "string"
+                },
+                "scanned_at": {
+                    "type": "string"
+                },
+                "uploader_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "db.ListUploaderUsageRow": {
+            "type": "object",
+            "properties": {
+                "bytes": {
+                    "type": "integer"
+                },
+                "uploader_id": {
+                    "type": "integer"
+                },
+                "uploader_name": {
+                    "type": "string"
+                },
+                "uploads": {
+                    "type": "integer"
+                }
+            }
+        },
+        "db.MultipartUpload": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "file_mime_type": {
+                    "type": "string"
+                },
+                "file_name": {
+                    "type": "string"
+                },
+                "file_size": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "part_count": {
+                    "type": "integer"
+                },
+                "part_size": {
+                    "type": "integer"
+                },
+                "s3_upload_id": {
+                    "type": "string"
+                },
+                "storage_key": {
+                    "type": "string"
+                },
+                "team_id": {
+                    "type": "integer"
+                },
+                "uploader_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "db.Tag": {
+            "type": "object",
+            "properties": {
+                "key": {
+                    "type": "string"
+                },
+                "key_id": {
+                    "type": "integer"
+                },
+                "options": {
+                    "type": "array",
+                    "items": {
+                        "type": "object",
+                        "properties": {
+                            "id": {
+                                "type": "integer"
+                            },
+                            "parent_ids": {
+                                "description": "values of other keys this option is valid under",
+                                "type": "array",
+                                "items": {
+                                    "type": "integer"
+                                }
+                            },
+                            "value": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "value": {
+                    "type": "string",
+                    "x-nullable": true
+                },
+                "value_id": {
+                    "description": "pointers because they can be null",
+                    "type": "integer",
+                    "x-nullable": true
+                }
+            }
+        },
+        "db.TagDataType": {
+            "type": "string",
+            "enum": [
+                "string",
+                "number",
+                "boolean"
+            ],
+            "x-enum-varnames": [
+                "TagDataTypeString",
+                "TagDataTypeNumber",
+                "TagDataTypeBoolean"
+            ]
+        },
+        "db.TagKey": {
+            "type": "object",
+            "properties": {
+                "archived_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "data_type": {
+                    "$ref": "#/definitions/db.TagDataType"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "mode": {
+                    "$ref": "#/definitions/db.TagKeyMode"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "requires_approval": {
+                    "type": "boolean"
+                },
+                "team_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "db.TagKeyMode": {
+            "type": "string",
+            "enum": [
+                "closed",
+                "open"
+            ],
+            "x-enum-varnames": [
+                "TagKeyModeClosed",
+                "TagKeyModeOpen"
+            ]
+        },
+        "db.TagValue": {
+            "type": "object",
+            "properties": {
+                "approved": {
+                    "type": "boolean"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "key_id": {
+                    "type": "integer"
+                },
+                "num_value": {
+                    "type": "number"
+                },
+                "sort_order": {
+                    "type": "integer"
+                },
+                "value": {
+                    "type": "string"
+                }
+            }
+        },
+        "db.Team": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "domain": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "db.TeamJobKind": {
+            "type": "string",
+            "enum": [
+                "export",
+                "import"
+            ],
+            "x-enum-varnames": [
+                "TeamJobKindExport",
+                "TeamJobKindImport"
+            ]
+        },
+        "db.TeamJobStatus": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "running",
+                "done",
+                "failed"
+            ],
+            "x-enum-varnames": [
+                "TeamJobStatusPending",
+                "TeamJobStatusRunning",
+                "TeamJobStatusDone",
+                "TeamJobStatusFailed"
+            ]
+        },
+        "db.TeamNotification": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "membership_id": {
+                    "type": "integer"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "read_at": {
+                    "type": "string"
+                },
+                "upload_ref_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "db.TeamUserRole": {
+            "type": "string",
+            "enum": [
+                "member",
+                "mod"
+            ],
+            "x-enum-varnames": [
+                "TeamUserRoleMember",
+                "TeamUserRoleMod"
+            ]
+        },
+        "db.UploadItem": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "file_mime_type": {
+                    "type": "string"
+                },
+                "file_name": {
+                    "type": "string"
+                },
+                "file_size": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "rank": {
+                    "description": "only set when searching by text, the snippet is HTML escaped with matches in \u003cb\u003e",
+                    "type": "number"
+                },
+                "snippet": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.UploadTag"
+                    }
+                },
+                "uploader_id": {
+                    "type": "integer"
+                },
+                "version": {
+                    "type": "integer"
+                },
+                "version_count": {
+                    "type": "integer"
+                }
+            }
+        },
+        "db.UploadRef": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "file_name": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "status": {
+                    "$ref": "#/definitions/db.UploadRefStatus"
                 },
-                "team_domain": {
+                "team_id": {
+                    "type": "integer"
+                },
+                "upload_id": {
+                    "type": "integer"
+                },
+                "uploader_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "db.UploadRefStatus": {
+            "type": "string",
+            "enum": [
+                "active",
+                "rejected"
+            ],
+            "x-enum-varnames": [
+                "UploadRefStatusActive",
+                "UploadRefStatusRejected"
+            ]
+        },
+        "db.UploadTag": {
+            "type": "object",
+            "properties": {
+                "key": {
                     "type": "string"
                 },
-                "team_id": {
+                "key_id": {
                     "type": "integer"
                 },
-                "team_name": {
+                "value": {
+                    "type": "string"
+                },
+                "value_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.ApproveTagValueResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/db.TagValue"
+                }
+            }
+        },
+        "dto.CheckUploadBody": {
+            "type": "object",
+            "required": [
+                "sha256",
+                "size"
+            ],
+            "properties": {
+                "name": {
+                    "description": "when set and the team can download the file already, it is added under this name",
+                    "type": "string"
+                },
+                "sha256": {
+                    "description": "SHA-256 of the file, hex or base64 encoded",
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.UploadTagBody"
+                    }
+                },
+                "version_of": {
+                    "description": "upload ID to add the file to as its next version, instead of a new upload",
+                    "type": "integer",
+                    "minimum": 1
+                }
+            }
+        },
+        "dto.CheckUploadResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "description": "the team's existing upload, or the upload added by this check",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/db.UploadRef"
+                        }
+                    ]
+                },
+                "exists": {
+                    "description": "the team can download the file already, as a version of one of its\nuploads, and it need not be uploaded",
+                    "type": "boolean"
+                },
+                "in_team": {
+                    "description": "the team already has the file",
+                    "type": "boolean"
+                }
+            }
+        },
+        "dto.CompleteImageUploadBody": {
+            "type": "object",
+            "required": [
+                "keys",
+                "name",
+                "set_id"
+            ],
+            "properties": {
+                "keys": {
+                    "description": "image keys in page order",
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "set_id": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.UploadTagBody"
+                    }
+                },
+                "version_of": {
+                    "description": "upload ID to add the file to as its next version, instead of a new upload",
+                    "type": "integer",
+                    "minimum": 1
+                }
+            }
+        },
+        "dto.CompleteMultipartUploadBody": {
+            "type": "object",
+            "properties": {
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.UploadTagBody"
+                    }
+                },
+                "version_of": {
+                    "description": "upload ID to add the file to as its next version, instead of a new upload",
+                    "type": "integer",
+                    "minimum": 1
+                }
+            }
+        },
+        "dto.CompleteUploadBody": {
+            "type": "object",
+            "properties": {
+                "key": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.UploadTagBody"
+                    }
+                },
+                "version_of": {
+                    "description": "upload ID to add the file to as its next version, instead of a new upload",
+                    "type": "integer",
+                    "minimum": 1
+                }
+            }
+        },
+        "dto.CreateMultipartUploadBody": {
+            "type": "object",
+            "required": [
+                "mime_type",
+                "name",
+                "size"
+            ],
+            "properties": {
+                "mime_type": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer",
+                    "minimum": 1
+                }
+            }
+        },
+        "dto.CreateTagKeyBody": {
+            "type": "object",
+            "required": [
+                "data_type",
+                "name"
+            ],
+            "properties": {
+                "data_type": {
+                    "enum": [
+                        "string",
+                        "number",
+                        "boolean"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/db.TagDataType"
+                        }
+                    ]
+                },
+                "mode": {
+                    "description": "closed (default) keys accept predefined values only",
+                    "enum": [
+                        "closed",
+                        "open"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/db.TagKeyMode"
+                        }
+                    ]
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 100,
+                    "minLength": 2
+                },
+                "requires_approval": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "dto.CreateTagKeyResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/db.TagKey"
+                }
+            }
+        },
+        "dto.CreateTagValueBody": {
+            "type": "object",
+            "required": [
+                "value"
+            ],
+            "properties": {
+                "value": {
+                    "description": "validated against the key's data type by the service",
+                    "type": "string",
+                    "maxLength": 100,
+                    "minLength": 1
+                }
+            }
+        },
+        "dto.CreateTagValueResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/db.TagValue"
+                }
+            }
+        },
+        "dto.DeleteTagKeyResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/db.TagKey"
+                }
+            }
+        },
+        "dto.DeleteTagValueResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/db.TagValue"
+                }
+            }
+        },
+        "dto.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "Something went wrong"
+                }
+            }
+        },
+        "dto.GetTagKeyImpactResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/db.GetTagKeyImpactRow"
+                }
+            }
+        },
+        "dto.GetTeamsResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.GetTeamsByUserIDRow"
+                    }
+                }
+            }
+        },
+        "dto.HealthResponse": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "string",
+                    "example": "ok"
+                }
+            }
+        },
+        "dto.JoinTeamResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/db.Team"
+                }
+            }
+        },
+        "dto.ListFiltersResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.Tag"
+                    }
+                }
+            }
+        },
+        "dto.ListMultipartUploadsResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.MultipartUpload"
+                    }
+                }
+            }
+        },
+        "dto.ListPendingTagValuesResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.TagValue"
+                    }
+                }
+            }
+        },
+        "dto.ListRejectedUploadsResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.ListRejectedUploadsRow"
+                    }
+                }
+            }
+        },
+        "dto.ListTagOptionsResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.TagValue"
+                    }
+                }
+            }
+        },
+        "dto.ListTeamJobsResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.TeamJobResponse"
+                    }
+                }
+            }
+        },
+        "dto.ListTeamNotificationsResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.TeamNotification"
+                    }
+                }
+            }
+        },
+        "dto.ListUploadDerivativesResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.UploadDerivativeResponse"
+                    }
+                }
+            }
+        },
+        "dto.ListUploadVersionsResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.UploadVersionResponse"
+                    }
+                }
+            }
+        },
+        "dto.ListUploadsResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.UploadItem"
+                    }
+                }
+            }
+        },
+        "dto.LoginRequest": {
+            "type": "object",
+            "required": [
+                "email",
+                "password"
+            ],
+            "properties": {
+                "email": {
                     "type": "string"
                 },
-                "user_joined_at": {
+                "password": {
                     "type": "string"
+                }
+            }
+        },
+        "dto.MergeTagValueBody": {
+            "type": "object",
+            "required": [
+                "target_id"
+            ],
+            "properties": {
+                "target_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.MergeTagValueResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/db.TagValue"
+                }
+            }
+        },
+        "dto.MultipartUploadResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/db.MultipartUpload"
+                }
+            }
+        },
+        "dto.MultipartUploadStatusResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/db.MultipartUpload"
                 },
-                "user_role": {
-                    "$ref": "#/definitions/db.TeamUserRole"
+                "parts": {
+                    "description": "parts uploaded so far",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.UploadPartResponse"
+                    }
                 }
             }
         },
-        "db.Tag": {
+        "dto.PresignImagePartResponse": {
             "type": "object",
             "properties": {
+                "fields": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
                 "key": {
                     "type": "string"
                 },
-                "key_id": {
-                    "type": "integer"
-                },
-                "options": {
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.PresignImageUploadBody": {
+            "type": "object",
+            "required": [
+                "images"
+            ],
+            "properties": {
+                "images": {
                     "type": "array",
+                    "minItems": 1,
                     "items": {
                         "type": "object",
                         "properties": {
-                            "id": {
-                                "type": "integer"
-                            },
-                            "value": {
+                            "mime_type": {
                                 "type": "string"
+                            },
+                            "size": {
+                                "type": "integer"
                             }
                         }
                     }
-                },
-                "value": {
-                    "type": "string",
-                    "x-nullable": true
-                },
-                "value_id": {
-                    "description": "pointers because they can be null",
-                    "type": "integer",
-                    "x-nullable": true
                 }
             }
         },
-        "db.TagDataType": {
-            "type": "string",
-            "enum": [
-                "string",
-                "number",
-                "boolean"
-            ],
-            "x-enum-varnames": [
-                "TagDataTypeString",
-                "TagDataTypeNumber",
-                "TagDataTypeBoolean"
-            ]
-        },
-        "db.TagKey": {
+        "dto.PresignImageUploadResponse": {
             "type": "object",
             "properties": {
-                "created_at": {
-                    "type": "string"
-                },
-                "data_type": {
-                    "$ref": "#/definitions/db.TagDataType"
-                },
-                "id": {
-                    "type": "integer"
+                "parts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.PresignImagePartResponse"
+                    }
                 },
-                "name": {
+                "set_id": {
                     "type": "string"
-                },
-                "team_id": {
-                    "type": "integer"
                 }
             }
         },
-        "db.TagValue": {
+        "dto.PresignUploadBody": {
             "type": "object",
             "properties": {
-                "created_at": {
+                "mime_type": {
                     "type": "string"
                 },
-                "id": {
-                    "type": "integer"
+                "name": {
+                    "type": "string"
                 },
-                "key_id": {
+                "size": {
                     "type": "integer"
-                },
-                "value": {
-                    "type": "string"
                 }
             }
         },
-        "db.Team": {
+        "dto.PresignUploadPartsBody": {
             "type": "object",
+            "required": [
+                "part_numbers"
+            ],
             "properties": {
-                "created_at": {
-                    "type": "string"
-                },
-                "domain": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "integer"
-                },
-                "name": {
-                    "type": "string"
+                "part_numbers": {
+                    "type": "array",
+                    "maxItems": 1000,
+                    "minItems": 1,
+                    "items": {
+                        "type": "integer"
+                    }
                 }
             }
         },
-        "db.TeamUserRole": {
-            "type": "string",
-            "enum": [
-                "member",
-                "mod"
-            ],
-            "x-enum-varnames": [
-                "TeamUserRoleMember",
-                "TeamUserRoleMod"
-            ]
-        },
-        "dto.CompleteUploadsBody": {
+        "dto.PresignUploadPartsResponse": {
             "type": "object",
             "properties": {
-                "files": {
+                "parts": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/service.CompleteUploadItem"
+                        "$ref": "#/definitions/dto.PresignedUploadPartResponse"
                     }
                 }
             }
         },
-        "dto.CreateTagKeyBody": {
+        "dto.PresignUploadResponse": {
             "type": "object",
-            "required": [
-                "data_type",
-                "name"
-            ],
             "properties": {
-                "data_type": {
-                    "maxLength": 100,
-                    "minLength": 2,
-                    "allOf": [
-                        {
-                            "$ref": "#/definitions/db.TagDataType"
-                        }
-                    ]
+                "fields": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
                 },
-                "name": {
-                    "type": "string",
-                    "maxLength": 100,
-                    "minLength": 2
+                "url": {
+                    "type": "string"
                 }
             }
         },
-        "dto.CreateTagKeyResponse": {
+        "dto.PresignedUploadPartResponse": {
             "type": "object",
             "properties": {
-                "data": {
-                    "$ref": "#/definitions/db.TagKey"
+                "part_number": {
+                    "type": "integer"
+                },
+                "url": {
+                    "type": "string"
                 }
             }
         },
-        "dto.CreateTagValueBody": {
+        "dto.RegisterRequest": {
             "type": "object",
             "required": [
-                "value"
+                "email",
+                "name",
+                "password"
             ],
             "properties": {
-                "value": {
+                "email": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "password": {
                     "type": "string",
-                    "maxLength": 100,
-                    "minLength": 2
+                    "minLength": 8
                 }
             }
         },
-        "dto.CreateTagValueResponse": {
+        "dto.RejectTagValueResponse": {
             "type": "object",
             "properties": {
                 "data": {
@@ -792,138 +3584,164 @@ const docTemplate = `{
                 }
             }
         },
-        "dto.DeleteTagKeyResponse": {
+        "dto.ReorderTagValuesBody": {
             "type": "object",
+            "required": [
+                "value_ids"
+            ],
             "properties": {
-                "data": {
-                    "$ref": "#/definitions/db.TagKey"
+                "value_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
                 }
             }
         },
-        "dto.DeleteTagValueResponse": {
+        "dto.RestoreTagKeyResponse": {
             "type": "object",
             "properties": {
                 "data": {
-                    "$ref": "#/definitions/db.TagValue"
+                    "$ref": "#/definitions/db.TagKey"
                 }
             }
         },
-        "dto.ErrorResponse": {
+        "dto.SetCurrentUploadVersionBody": {
             "type": "object",
+            "required": [
+                "version"
+            ],
             "properties": {
-                "message": {
-                    "type": "string",
-                    "example": "Something went wrong"
+                "version": {
+                    "type": "integer",
+                    "minimum": 1
                 }
             }
         },
-        "dto.GetTeamsResponse": {
+        "dto.SetTagValueParentsBody": {
             "type": "object",
             "properties": {
-                "data": {
+                "parent_ids": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/db.GetTeamsByUserIDRow"
+                        "type": "integer"
                     }
                 }
             }
         },
-        "dto.HealthResponse": {
+        "dto.StartTeamExportBody": {
             "type": "object",
             "properties": {
-                "status": {
-                    "type": "string",
-                    "example": "ok"
+                "include_objects": {
+                    "description": "also export the files of uploads, not only their metadata",
+                    "type": "boolean"
                 }
             }
         },
-        "dto.JoinTeamResponse": {
+        "dto.SuggestTagsBody": {
             "type": "object",
             "properties": {
-                "data": {
-                    "$ref": "#/definitions/db.Team"
+                "name": {
+                    "description": "name of the file to tag, defaults to the upload's name",
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "upload_id": {
+                    "description": "an upload of the team whose extracted text is matched as well",
+                    "type": "integer",
+                    "minimum": 1
                 }
             }
         },
-        "dto.ListFiltersResponse": {
+        "dto.SuggestTagsResponse": {
             "type": "object",
             "properties": {
                 "data": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/db.Tag"
+                        "$ref": "#/definitions/dto.TagKeySuggestionsResponse"
                     }
                 }
             }
         },
-        "dto.LoginRequest": {
-            "type": "object",
-            "required": [
-                "email",
-                "password"
-            ],
-            "properties": {
-                "email": {
-                    "type": "string"
-                },
-                "password": {
-                    "type": "string"
-                }
-            }
-        },
-        "dto.PresignUploadResult": {
+        "dto.TagKeySuggestionsResponse": {
             "type": "object",
             "properties": {
-                "fields": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "type": "string"
+                "candidates": {
+                    "description": "best first, empty when no value fits",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.TagSuggestionResponse"
                     }
                 },
-                "url": {
+                "key_id": {
+                    "type": "integer"
+                },
+                "key_name": {
                     "type": "string"
                 }
             }
         },
-        "dto.PresignUploadsBody": {
+        "dto.TagSuggestionResponse": {
             "type": "object",
             "properties": {
-                "files": {
+                "reasons": {
+                    "description": "\"name\", \"text\" and \"related\" when often tagged along with another suggestion",
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/service.PresignUploadItem"
+                        "type": "string"
                     }
+                },
+                "score": {
+                    "type": "number"
+                },
+                "value": {
+                    "type": "string"
+                },
+                "value_id": {
+                    "type": "integer"
                 }
             }
         },
-        "dto.PresignUploadsResponse": {
+        "dto.TeamJobDataResponse": {
             "type": "object",
             "properties": {
-                "results": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/dto.PresignUploadResult"
-                    }
+                "data": {
+                    "$ref": "#/definitions/dto.TeamJobResponse"
                 }
             }
         },
-        "dto.RegisterRequest": {
+        "dto.TeamJobResponse": {
             "type": "object",
-            "required": [
-                "email",
-                "name",
-                "password"
-            ],
             "properties": {
-                "email": {
+                "created_at": {
                     "type": "string"
                 },
-                "name": {
+                "error": {
                     "type": "string"
                 },
-                "password": {
-                    "type": "string",
-                    "minLength": 8
+                "finished_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "include_objects": {
+                    "type": "boolean"
+                },
+                "kind": {
+                    "$ref": "#/definitions/db.TeamJobKind"
+                },
+                "report": {
+                    "description": "counts of what was exported or imported, once the job finished",
+                    "type": "object"
+                },
+                "status": {
+                    "$ref": "#/definitions/db.TeamJobStatus"
+                },
+                "url": {
+                    "description": "download URL of an export's archive, until it is deleted",
+                    "type": "string"
                 }
             }
         },
@@ -953,16 +3771,46 @@ const docTemplate = `{
                 }
             }
         },
+        "dto.UpdateQuotasBody": {
+            "type": "object",
+            "properties": {
+                "team_quota": {
+                    "description": "bytes the team may store, null is unlimited",
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "uploader_quota": {
+                    "description": "bytes each uploader may store within the team, null is unlimited",
+                    "type": "integer",
+                    "minimum": 1
+                }
+            }
+        },
         "dto.UpdateTagKeyBody": {
             "type": "object",
             "required": [
                 "name"
             ],
             "properties": {
+                "mode": {
+                    "description": "data type NOT allowed\nleft unchanged when omitted",
+                    "enum": [
+                        "closed",
+                        "open"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/db.TagKeyMode"
+                        }
+                    ]
+                },
                 "name": {
                     "type": "string",
                     "maxLength": 100,
                     "minLength": 2
+                },
+                "requires_approval": {
+                    "type": "boolean"
                 }
             }
         },
@@ -974,36 +3822,200 @@ const docTemplate = `{
                 }
             }
         },
-        "service.CompleteUploadItem": {
+        "dto.UpdateTagValueBody": {
             "type": "object",
+            "required": [
+                "value"
+            ],
             "properties": {
-                "key": {
+                "value": {
+                    "type": "string",
+                    "maxLength": 100,
+                    "minLength": 1
+                }
+            }
+        },
+        "dto.UpdateTagValueResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/db.TagValue"
+                }
+            }
+        },
+        "dto.UpdateUploadBody": {
+            "type": "object",
+            "properties": {
+                "file_name": {
+                    "type": "string",
+                    "minLength": 1
+                },
+                "tags": {
+                    "description": "replaces every tag of the upload when set",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.UploadTagBody"
+                    }
+                }
+            }
+        },
+        "dto.UpdateUploadResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/db.UploadRef"
+                }
+            }
+        },
+        "dto.UpdateUploadSettingsBody": {
+            "type": "object",
+            "properties": {
+                "allowed_extensions": {
+                    "description": "file name extensions such as \".pdf\", null accepts any",
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "allowed_mime_types": {
+                    "description": "sniffed content types accepted on upload, null restores the server default.\nHTML and XML, and types uploads are never detected as, are refused",
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "max_file_size": {
+                    "description": "bytes per file, null falls back to the server limit which also caps this one",
+                    "type": "integer",
+                    "minimum": 1
+                }
+            }
+        },
+        "dto.UploadDerivativeResponse": {
+            "type": "object",
+            "properties": {
+                "height": {
+                    "type": "integer"
+                },
+                "kind": {
+                    "description": "thumbnail or preview",
                     "type": "string"
                 },
-                "mime": {
+                "page": {
+                    "type": "integer"
+                },
+                "url": {
                     "type": "string"
                 },
-                "name": {
+                "width": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.UploadPartResponse": {
+            "type": "object",
+            "properties": {
+                "etag": {
                     "type": "string"
                 },
+                "part_number": {
+                    "type": "integer"
+                },
                 "size": {
-                    "type": "integer",
-                    "format": "int64"
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.UploadSettingsResponse": {
+            "type": "object",
+            "properties": {
+                "allowed_extensions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "allowed_mime_types": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "is_default": {
+                    "type": "boolean"
+                },
+                "max_file_size": {
+                    "type": "integer"
                 }
             }
         },
-        "service.PresignUploadItem": {
+        "dto.UploadTagBody": {
             "type": "object",
             "properties": {
-                "mime": {
+                "keyID": {
+                    "type": "integer"
+                },
+                "value": {
                     "type": "string"
                 },
-                "name": {
+                "valueID": {
+                    "description": "either an existing value, or a free-form value for open keys",
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.UploadVersionResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
                     "type": "string"
                 },
-                "size": {
-                    "type": "integer",
-                    "format": "int64"
+                "current": {
+                    "description": "the version the upload is listed and searched with",
+                    "type": "boolean"
+                },
+                "file_mime_type": {
+                    "type": "string"
+                },
+                "file_name": {
+                    "type": "string"
+                },
+                "file_size": {
+                    "type": "integer"
+                },
+                "uploader_id": {
+                    "type": "integer"
+                },
+                "url": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.UsageResponse": {
+            "type": "object",
+            "properties": {
+                "bytes": {
+                    "type": "integer"
+                },
+                "team_quota": {
+                    "type": "integer"
+                },
+                "uploader_quota": {
+                    "type": "integer"
+                },
+                "uploaders": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/db.ListUploaderUsageRow"
+                    }
+                },
+                "uploads": {
+                    "type": "integer"
                 }
             }
         }
@@ -6,6 +6,7 @@ import (
 
 	"github.com/jackc/pgx/v5"
 	"github.com/labstack/echo/v4"
+	"github.com/skndash96/lastnight-backend/internal/db"
 	"github.com/skndash96/lastnight-backend/internal/repository"
 )
 
@@ -40,3 +41,21 @@ func TeamMW(teamRepo *repository.TeamRepository) echo.MiddlewareFunc {
 		}
 	}
 }
+
+// ModMW allows only team mods through. Must be used after TeamMW.
+func ModMW() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			identity, ok := GetSession(c)
+			if !ok {
+				return echo.ErrUnauthorized
+			}
+
+			if identity.Role != db.TeamUserRoleMod {
+				return echo.ErrForbidden
+			}
+
+			return next(c)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"net/http"
 	"os"
 	"strconv"
@@ -8,11 +9,13 @@ import (
 )
 
 type AppConfig struct {
-	IsProd bool
-	Port   int
-	DbURL  string
-	Auth   AuthConfig
-	Minio  MinioConfig
+	IsProd  bool
+	Port    int
+	DbURL   string
+	Auth    AuthConfig
+	Storage StorageConfig
+	Upload  UploadConfig
+	GC      GCConfig
 }
 
 type AuthConfig struct {
@@ -30,14 +33,89 @@ type CookieConfig struct {
 	SameSite http.SameSite
 }
 
+const (
+	StorageDriverMinio = "minio"
+	StorageDriverLocal = "local"
+)
+
+type StorageConfig struct {
+	// "minio", or "local" to keep objects on disk and serve their signed URLs from the API
+	Driver string
+	// how long presigned URLs stay valid
+	Expiration time.Duration
+	MaxSize    int64
+
+	Minio MinioConfig
+	Local LocalStorageConfig
+}
+
 type MinioConfig struct {
 	Endpoint   string
 	Username   string
 	Password   string
 	UseSSL     bool
 	BucketName string
-	Expiration time.Duration
-	MaxSize    int64
+}
+
+type LocalStorageConfig struct {
+	Dir string
+	// base URL of this server, signed URLs point at it
+	PublicURL string
+	// signs URLs, a random key is generated on startup when empty
+	SigningKey string
+}
+
+type UploadConfig struct {
+	// image sets are combined into a single PDF
+	MaxImages          int
+	MaxImageSize       int64
+	ImageMaxDimension  int
+	KeepImageOriginals bool
+
+	// sniffed content types accepted for teams without their own allowlist
+	AllowedMimeTypes    []string
+	RejectPolyglots     bool
+	RejectEncryptedPDFs bool
+
+	// previews are rendered and text is extracted from PDFs with poppler's pdftoppm and pdftotext
+	PdftoppmPath    string
+	PdftotextPath   string
+	ThumbnailWidth  int
+	PreviewAllPages bool
+	PreviewWidth    int
+	MaxPreviewPages int
+	// at most ProcessingWorkers PDFs are processed at once, each for up to ProcessingTimeout
+	ProcessingWorkers int
+	ProcessingTimeout time.Duration
+
+	// uploads are scanned by clamd when an address is set, e.g. localhost:3310 or /var/run/clamav/clamd.ctl.
+	// clamd's StreamMaxLength (25M by default) must be at least MaxSize, larger uploads are refused
+	ClamdNetwork string
+	ClamdAddress string
+	ScanTimeout  time.Duration
+
+	// large files are uploaded in parts with S3 multipart uploads, resumable until they expire
+	MultipartPartSize   int64
+	MultipartExpiration time.Duration
+
+	// filtered uploads are exported as a single streamed ZIP, each user may start
+	// ExportRateLimit exports per ExportRateWindow
+	ExportMaxSize    int64
+	ExportRateLimit  int
+	ExportRateWindow time.Duration
+
+	// team archives uploaded for import are kept on disk until imported
+	ArchiveMaxSize int64
+}
+
+type GCConfig struct {
+	// how often orphaned uploads are collected, 0 disables the periodic task
+	Interval time.Duration
+	// uploads stay this long after their last ref is deleted
+	GracePeriod time.Duration
+	BatchSize   int32
+	// archives of team exports are deleted this long after they finish
+	ArchiveRetention time.Duration
 }
 
 func New() *AppConfig {
@@ -51,6 +129,31 @@ func New() *AppConfig {
 		isProd = false
 	}
 
+	keepImageOriginals, err := strconv.ParseBool(GetEnv("UPLOAD_KEEP_IMAGE_ORIGINALS", "false"))
+	if err != nil {
+		keepImageOriginals = false
+	}
+
+	rejectPolyglots, err := strconv.ParseBool(GetEnv("UPLOAD_REJECT_POLYGLOTS", "true"))
+	if err != nil {
+		rejectPolyglots = true
+	}
+
+	rejectEncryptedPDFs, err := strconv.ParseBool(GetEnv("UPLOAD_REJECT_ENCRYPTED_PDFS", "true"))
+	if err != nil {
+		rejectEncryptedPDFs = true
+	}
+
+	previewAllPages, err := strconv.ParseBool(GetEnv("UPLOAD_PREVIEW_ALL_PAGES", "false"))
+	if err != nil {
+		previewAllPages = false
+	}
+
+	gcInterval, err := time.ParseDuration(GetEnv("UPLOAD_GC_INTERVAL", "1h"))
+	if err != nil {
+		gcInterval = time.Hour
+	}
+
 	appCfg := &AppConfig{
 		IsProd: isProd,
 		Port:   port,
@@ -67,14 +170,72 @@ func New() *AppConfig {
 			},
 		},
 
-		Minio: MinioConfig{
-			Endpoint:   GetEnv("MINIO_ENDPOINT", ""),
-			Username:  GetEnv("MINIO_USERNAME", ""),
-			Password:  GetEnv("MINIO_PASSWORD", ""),
-			BucketName: GetEnv("MINIO_BUCKET_NAME", ""),
+		Storage: StorageConfig{
+			Driver:     GetEnv("STORAGE_DRIVER", StorageDriverMinio),
 			Expiration: time.Duration(15 * time.Minute),
-			UseSSL:     isProd,
 			MaxSize:    200 * 1024 * 1024, // 200MB
+
+			Minio: MinioConfig{
+				Endpoint:   GetEnv("MINIO_ENDPOINT", ""),
+				Username:   GetEnv("MINIO_USERNAME", ""),
+				Password:   GetEnv("MINIO_PASSWORD", ""),
+				BucketName: GetEnv("MINIO_BUCKET_NAME", ""),
+				UseSSL:     isProd,
+			},
+
+			Local: LocalStorageConfig{
+				Dir:        GetEnv("STORAGE_LOCAL_DIR", "./data/storage"),
+				PublicURL:  GetEnv("STORAGE_PUBLIC_URL", fmt.Sprintf("http://localhost:%d", port)),
+				SigningKey: GetEnv("STORAGE_SIGNING_KEY", ""),
+			},
+		},
+
+		Upload: UploadConfig{
+			MaxImages:          50,
+			MaxImageSize:       20 * 1024 * 1024, // 20MB
+			ImageMaxDimension:  2000,
+			KeepImageOriginals: keepImageOriginals,
+
+			AllowedMimeTypes: []string{
+				"application/pdf",
+				"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+				"application/vnd.openxmlformats-officedocument.presentationml.presentation",
+				"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+				"application/x-ole-storage",
+			},
+			RejectPolyglots:     rejectPolyglots,
+			RejectEncryptedPDFs: rejectEncryptedPDFs,
+
+			PdftoppmPath:    GetEnv("UPLOAD_PDFTOPPM_PATH", "pdftoppm"),
+			PdftotextPath:   GetEnv("UPLOAD_PDFTOTEXT_PATH", "pdftotext"),
+			ThumbnailWidth:  320,
+			PreviewAllPages: previewAllPages,
+			PreviewWidth:    800,
+			MaxPreviewPages: 20,
+
+			ProcessingWorkers: 2,
+			ProcessingTimeout: time.Duration(5 * time.Minute),
+
+			ClamdNetwork: GetEnv("UPLOAD_CLAMD_NETWORK", "tcp"),
+			ClamdAddress: GetEnv("UPLOAD_CLAMD_ADDRESS", ""),
+			ScanTimeout:  time.Duration(2 * time.Minute),
+
+			MultipartPartSize:   16 * 1024 * 1024, // 16MB
+			MultipartExpiration: time.Duration(24 * time.Hour),
+
+			ExportMaxSize:    2 * 1024 * 1024 * 1024, // 2GB
+			ExportRateLimit:  5,
+			ExportRateWindow: time.Duration(time.Hour),
+
+			ArchiveMaxSize: 5 * 1024 * 1024 * 1024, // 5GB
+		},
+
+		GC: GCConfig{
+			Interval:    gcInterval,
+			GracePeriod: time.Duration(24 * time.Hour),
+			BatchSize:   100,
+
+			ArchiveRetention: time.Duration(7 * 24 * time.Hour),
 		},
 	}
 
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+-- numeric representation of values of number-typed keys, used for range filtering
+ALTER TABLE tag_values ADD COLUMN num_value DOUBLE PRECISION;
+
+UPDATE tag_values v SET num_value = v.value::DOUBLE PRECISION
+  FROM tag_keys k
+  WHERE k.id = v.key_id
+    AND k.data_type = 'number'
+    AND v.value ~ '^-?[0-9]+(\.[0-9]+)?$';
+
+CREATE INDEX idx_tag_values_num_value ON tag_values(key_id, num_value);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_tag_values_num_value;
+ALTER TABLE tag_values DROP COLUMN IF EXISTS num_value;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+-- closed keys accept predefined values only, open keys let uploaders add values
+CREATE TYPE TAG_KEY_MODE AS ENUM ('closed', 'open');
+
+ALTER TABLE tag_keys
+  ADD COLUMN mode TAG_KEY_MODE NOT NULL DEFAULT 'closed',
+  ADD COLUMN requires_approval BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- values submitted by uploaders on keys requiring approval start unapproved
+ALTER TABLE tag_values ADD COLUMN approved BOOLEAN NOT NULL DEFAULT TRUE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE tag_values DROP COLUMN IF EXISTS approved;
+
+ALTER TABLE tag_keys
+  DROP COLUMN IF EXISTS requires_approval,
+  DROP COLUMN IF EXISTS mode;
+
+DROP TYPE IF EXISTS TAG_KEY_MODE;
+-- +goose StatementEnd
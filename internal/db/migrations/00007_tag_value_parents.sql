@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- a value with parents on some key is only valid alongside one of those parents,
+-- e.g. course "circuit theory" is only valid under semester "2"
+CREATE TABLE IF NOT EXISTS tag_value_parents (
+    value_id INTEGER NOT NULL REFERENCES tag_values(id) ON DELETE CASCADE,
+    parent_value_id INTEGER NOT NULL REFERENCES tag_values(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (value_id, parent_value_id),
+    CONSTRAINT no_self_parent CHECK (value_id <> parent_value_id)
+);
+
+CREATE INDEX idx_tag_value_parents_parent ON tag_value_parents(parent_value_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_tag_value_parents_parent;
+DROP TABLE IF EXISTS tag_value_parents;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- explicit option order set by mods, ties fall back to numeric then alphabetical order
+ALTER TABLE tag_values ADD COLUMN sort_order INTEGER NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE tag_values DROP COLUMN IF EXISTS sort_order;
+-- +goose StatementEnd
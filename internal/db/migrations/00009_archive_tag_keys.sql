@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- archived keys are hidden from filters and uploads but keep their data until force-deleted
+ALTER TABLE tag_keys ADD COLUMN archived_at TIMESTAMP;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE tag_keys DROP COLUMN IF EXISTS archived_at;
+-- +goose StatementEnd
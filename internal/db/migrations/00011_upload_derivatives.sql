@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- rendered previews of an upload, stored next to its storage_key
+CREATE TABLE IF NOT EXISTS upload_derivatives (
+  id SERIAL PRIMARY KEY,
+  upload_id INTEGER NOT NULL REFERENCES uploads(id) ON DELETE CASCADE,
+  kind TEXT NOT NULL CHECK (kind IN ('thumbnail', 'preview')),
+  page INTEGER NOT NULL,
+  storage_key TEXT UNIQUE NOT NULL,
+  mime_type TEXT NOT NULL,
+  width INTEGER NOT NULL,
+  height INTEGER NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  UNIQUE (upload_id, kind, page)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS upload_derivatives;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- text extracted from an upload, shared by every team referencing it
+CREATE TABLE IF NOT EXISTS upload_texts (
+  upload_id INTEGER PRIMARY KEY REFERENCES uploads(id) ON DELETE CASCADE,
+  content TEXT NOT NULL,
+  search_vector TSVECTOR GENERATED ALWAYS AS (to_tsvector('english', content)) STORED,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_upload_texts_search_vector ON upload_texts USING GIN (search_vector);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS upload_texts;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TYPE UPLOAD_SCAN_STATUS AS ENUM ('unscanned', 'clean', 'infected');
+
+-- scan results live on the upload so deduplicated uploads are not rescanned
+ALTER TABLE uploads ADD COLUMN scan_status UPLOAD_SCAN_STATUS NOT NULL DEFAULT 'unscanned';
+ALTER TABLE uploads ADD COLUMN scan_signature TEXT NOT NULL DEFAULT '';
+ALTER TABLE uploads ADD COLUMN scanned_at TIMESTAMP;
+
+CREATE TYPE UPLOAD_REF_STATUS AS ENUM ('active', 'rejected');
+
+-- rejected refs are hidden from listings and kept for mods to review
+ALTER TABLE upload_refs ADD COLUMN status UPLOAD_REF_STATUS NOT NULL DEFAULT 'active';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE upload_refs DROP COLUMN IF EXISTS status;
+DROP TYPE IF EXISTS UPLOAD_REF_STATUS;
+
+ALTER TABLE uploads DROP COLUMN IF EXISTS scanned_at;
+ALTER TABLE uploads DROP COLUMN IF EXISTS scan_signature;
+ALTER TABLE uploads DROP COLUMN IF EXISTS scan_status;
+DROP TYPE IF EXISTS UPLOAD_SCAN_STATUS;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- set when the last ref to an upload is deleted, the object is garbage collected after a grace period
+ALTER TABLE uploads ADD COLUMN orphaned_at TIMESTAMP;
+
+CREATE INDEX idx_uploads_orphaned_at ON uploads(orphaned_at) WHERE orphaned_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE uploads DROP COLUMN IF EXISTS orphaned_at;
+-- +goose StatementEnd
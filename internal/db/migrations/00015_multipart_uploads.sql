@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+-- in-progress S3 multipart uploads, removed on completion or abort
+CREATE TABLE IF NOT EXISTS multipart_uploads (
+  id SERIAL PRIMARY KEY,
+  team_id INTEGER NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+  uploader_id INTEGER NOT NULL REFERENCES users(id),
+  storage_key TEXT UNIQUE NOT NULL,
+  s3_upload_id TEXT NOT NULL,
+  file_name TEXT NOT NULL,
+  file_mime_type TEXT NOT NULL,
+  file_size BIGINT NOT NULL,
+  part_size BIGINT NOT NULL,
+  part_count INTEGER NOT NULL,
+  expires_at TIMESTAMP NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_multipart_uploads_uploader ON multipart_uploads(team_id, uploader_id);
+CREATE INDEX idx_multipart_uploads_expires_at ON multipart_uploads(expires_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS multipart_uploads;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- bytes of uploads a team, and each uploader within it, may hold; NULL is unlimited
+ALTER TABLE team_settings ADD COLUMN team_quota BIGINT;
+ALTER TABLE team_settings ADD COLUMN uploader_quota BIGINT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE team_settings DROP COLUMN IF EXISTS uploader_quota;
+ALTER TABLE team_settings DROP COLUMN IF EXISTS team_quota;
+-- +goose StatementEnd
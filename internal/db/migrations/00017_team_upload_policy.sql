@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- file name extensions accepted on upload, NULL accepts any
+ALTER TABLE team_settings ADD COLUMN allowed_extensions TEXT[];
+-- bytes per file, NULL falls back to the server limit
+ALTER TABLE team_settings ADD COLUMN max_file_size BIGINT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE team_settings DROP COLUMN IF EXISTS max_file_size;
+ALTER TABLE team_settings DROP COLUMN IF EXISTS allowed_extensions;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- presigned uploads not completed yet, an upload can only be completed by whoever it was issued to
+CREATE TABLE IF NOT EXISTS upload_presigns (
+  id SERIAL PRIMARY KEY,
+  storage_key TEXT UNIQUE NOT NULL,
+  team_id INTEGER NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+  uploader_id INTEGER NOT NULL REFERENCES users(id),
+  file_name TEXT NOT NULL,
+  file_mime_type TEXT NOT NULL,
+  file_size BIGINT NOT NULL,
+  expires_at TIMESTAMP NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_upload_presigns_expires_at ON upload_presigns(expires_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS upload_presigns;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- uploads are deduplicated by (file_sha256, file_size), an empty hash would match unrelated files.
+-- NOT VALID leaves rows recorded before hashes were computed alone
+ALTER TABLE uploads ADD CONSTRAINT uploads_file_sha256_not_empty CHECK (file_sha256 <> '') NOT VALID;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE uploads DROP CONSTRAINT IF EXISTS uploads_file_sha256_not_empty;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+-- every version of an upload ref, upload_refs.upload_id points at the current one
+CREATE TABLE IF NOT EXISTS upload_versions (
+  id SERIAL PRIMARY KEY,
+  upload_ref_id INTEGER NOT NULL REFERENCES upload_refs(id),
+  upload_id INTEGER NOT NULL REFERENCES uploads(id),
+  version INTEGER NOT NULL,
+  uploader_id INTEGER NOT NULL REFERENCES users(id),
+  file_name TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  UNIQUE (upload_ref_id, version),
+  UNIQUE (upload_ref_id, upload_id)
+);
+
+CREATE INDEX idx_upload_versions_upload_id ON upload_versions(upload_id);
+
+INSERT INTO upload_versions (upload_ref_id, upload_id, version, uploader_id, file_name, created_at)
+SELECT id, upload_id, 1, uploader_id, file_name, created_at FROM upload_refs;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS upload_versions;
+-- +goose StatementEnd
@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TYPE TEAM_JOB_KIND AS ENUM ('export', 'import');
+CREATE TYPE TEAM_JOB_STATUS AS ENUM ('pending', 'running', 'done', 'failed');
+
+-- team exports and imports run in the background, an export's archive is kept
+-- at storage_key until the garbage collector removes it
+CREATE TABLE IF NOT EXISTS team_jobs (
+  id SERIAL PRIMARY KEY,
+  team_id INTEGER NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+  user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  kind TEAM_JOB_KIND NOT NULL,
+  status TEAM_JOB_STATUS NOT NULL DEFAULT 'pending',
+  include_objects BOOLEAN NOT NULL DEFAULT FALSE,
+  storage_key TEXT NOT NULL DEFAULT '',
+  report JSONB,
+  error TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  finished_at TIMESTAMP
+);
+
+CREATE INDEX idx_team_jobs_team_id ON team_jobs(team_id);
+-- a team runs one job at a time
+CREATE UNIQUE INDEX idx_team_jobs_active ON team_jobs(team_id) WHERE status IN ('pending', 'running');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS team_jobs;
+DROP TYPE IF EXISTS TEAM_JOB_STATUS;
+DROP TYPE IF EXISTS TEAM_JOB_KIND;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- running jobs touch heartbeat_at, jobs it stopped moving on are those of an
+-- instance that went away
+ALTER TABLE team_jobs ADD COLUMN heartbeat_at TIMESTAMP NOT NULL DEFAULT NOW();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE team_jobs DROP COLUMN IF EXISTS heartbeat_at;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- notifications are delivered to members of a team, e.g. mods about uploads
+-- rejected by the malware scan
+CREATE TABLE IF NOT EXISTS team_notifications (
+  id SERIAL PRIMARY KEY,
+  membership_id INTEGER NOT NULL REFERENCES team_memberships(id) ON DELETE CASCADE,
+  kind TEXT NOT NULL,
+  upload_ref_id INTEGER REFERENCES upload_refs(id) ON DELETE CASCADE,
+  message TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  read_at TIMESTAMP
+);
+
+CREATE INDEX idx_team_notifications_membership_id ON team_notifications(membership_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS team_notifications;
+-- +goose StatementEnd
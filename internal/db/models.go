@@ -55,6 +55,134 @@ func (ns NullTagDataType) Value() (driver.Value, error) {
 	return string(ns.TagDataType), nil
 }
 
+type TagKeyMode string
+
+const (
+	TagKeyModeClosed TagKeyMode = "closed"
+	TagKeyModeOpen   TagKeyMode = "open"
+)
+
+func (e *TagKeyMode) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = TagKeyMode(s)
+	case string:
+		*e = TagKeyMode(s)
+	default:
+		return fmt.Errorf("unsupported scan type for TagKeyMode: %T", src)
+	}
+	return nil
+}
+
+type NullTagKeyMode struct {
+	TagKeyMode TagKeyMode `json:"tag_key_mode"`
+	Valid      bool       `json:"valid"` // Valid is true if TagKeyMode is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullTagKeyMode) Scan(value interface{}) error {
+	if value == nil {
+		ns.TagKeyMode, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.TagKeyMode.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullTagKeyMode) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.TagKeyMode), nil
+}
+
+type TeamJobKind string
+
+const (
+	TeamJobKindExport TeamJobKind = "export"
+	TeamJobKindImport TeamJobKind = "import"
+)
+
+func (e *TeamJobKind) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = TeamJobKind(s)
+	case string:
+		*e = TeamJobKind(s)
+	default:
+		return fmt.Errorf("unsupported scan type for TeamJobKind: %T", src)
+	}
+	return nil
+}
+
+type NullTeamJobKind struct {
+	TeamJobKind TeamJobKind `json:"team_job_kind"`
+	Valid       bool        `json:"valid"` // Valid is true if TeamJobKind is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullTeamJobKind) Scan(value interface{}) error {
+	if value == nil {
+		ns.TeamJobKind, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.TeamJobKind.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullTeamJobKind) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.TeamJobKind), nil
+}
+
+type TeamJobStatus string
+
+const (
+	TeamJobStatusPending TeamJobStatus = "pending"
+	TeamJobStatusRunning TeamJobStatus = "running"
+	TeamJobStatusDone    TeamJobStatus = "done"
+	TeamJobStatusFailed  TeamJobStatus = "failed"
+)
+
+func (e *TeamJobStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = TeamJobStatus(s)
+	case string:
+		*e = TeamJobStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for TeamJobStatus: %T", src)
+	}
+	return nil
+}
+
+type NullTeamJobStatus struct {
+	TeamJobStatus TeamJobStatus `json:"team_job_status"`
+	Valid         bool          `json:"valid"` // Valid is true if TeamJobStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullTeamJobStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.TeamJobStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.TeamJobStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullTeamJobStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.TeamJobStatus), nil
+}
+
 type TeamUserRole string
 
 const (
@@ -97,6 +225,91 @@ func (ns NullTeamUserRole) Value() (driver.Value, error) {
 	return string(ns.TeamUserRole), nil
 }
 
+type UploadRefStatus string
+
+const (
+	UploadRefStatusActive   UploadRefStatus = "active"
+	UploadRefStatusRejected UploadRefStatus = "rejected"
+)
+
+func (e *UploadRefStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = UploadRefStatus(s)
+	case string:
+		*e = UploadRefStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for UploadRefStatus: %T", src)
+	}
+	return nil
+}
+
+type NullUploadRefStatus struct {
+	UploadRefStatus UploadRefStatus `json:"upload_ref_status"`
+	Valid           bool            `json:"valid"` // Valid is true if UploadRefStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullUploadRefStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.UploadRefStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.UploadRefStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullUploadRefStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.UploadRefStatus), nil
+}
+
+type UploadScanStatus string
+
+const (
+	UploadScanStatusUnscanned UploadScanStatus = "unscanned"
+	UploadScanStatusClean     UploadScanStatus = "clean"
+	UploadScanStatusInfected  UploadScanStatus = "infected"
+)
+
+func (e *UploadScanStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = UploadScanStatus(s)
+	case string:
+		*e = UploadScanStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for UploadScanStatus: %T", src)
+	}
+	return nil
+}
+
+type NullUploadScanStatus struct {
+	UploadScanStatus UploadScanStatus `json:"upload_scan_status"`
+	Valid            bool             `json:"valid"` // Valid is true if UploadScanStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullUploadScanStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.UploadScanStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.UploadScanStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullUploadScanStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.UploadScanStatus), nil
+}
+
 type Account struct {
 	ID                int32     `json:"id"`
 	UserID            int32     `json:"user_id"`
@@ -115,6 +328,21 @@ type MemberFilter struct {
 	CreatedAt    time.Time `json:"created_at"`
 }
 
+type MultipartUpload struct {
+	ID           int32     `json:"id"`
+	TeamID       int32     `json:"team_id"`
+	UploaderID   int32     `json:"uploader_id"`
+	StorageKey   string    `json:"storage_key"`
+	S3UploadID   string    `json:"s3_upload_id"`
+	FileName     string    `json:"file_name"`
+	FileMimeType string    `json:"file_mime_type"`
+	FileSize     int64     `json:"file_size"`
+	PartSize     int64     `json:"part_size"`
+	PartCount    int32     `json:"part_count"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
 type Session struct {
 	ID     pgtype.UUID `json:"id"`
 	UserID int32       `json:"user_id"`
@@ -123,11 +351,14 @@ type Session struct {
 }
 
 type TagKey struct {
-	ID        int32       `json:"id"`
-	TeamID    int32       `json:"team_id"`
-	Name      string      `json:"name"`
-	DataType  TagDataType `json:"data_type"`
-	CreatedAt time.Time   `json:"created_at"`
+	ID               int32       `json:"id"`
+	TeamID           int32       `json:"team_id"`
+	Name             string      `json:"name"`
+	DataType         TagDataType `json:"data_type"`
+	CreatedAt        time.Time   `json:"created_at"`
+	Mode             TagKeyMode  `json:"mode"`
+	RequiresApproval bool        `json:"requires_approval"`
+	ArchivedAt       *time.Time  `json:"archived_at"`
 }
 
 type TagValue struct {
@@ -135,6 +366,9 @@ type TagValue struct {
 	KeyID     int32     `json:"key_id"`
 	Value     string    `json:"value"`
 	CreatedAt time.Time `json:"created_at"`
+	NumValue  *float64  `json:"num_value"`
+	Approved  bool      `json:"approved"`
+	SortOrder int32     `json:"sort_order"`
 }
 
 type Team struct {
@@ -144,6 +378,21 @@ type Team struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+type TeamJob struct {
+	ID             int32         `json:"id"`
+	TeamID         int32         `json:"team_id"`
+	UserID         int32         `json:"user_id"`
+	Kind           TeamJobKind   `json:"kind"`
+	Status         TeamJobStatus `json:"status"`
+	IncludeObjects bool          `json:"include_objects"`
+	StorageKey     string        `json:"storage_key"`
+	Report         []byte        `json:"report"`
+	Error          string        `json:"error"`
+	CreatedAt      time.Time     `json:"created_at"`
+	FinishedAt     *time.Time    `json:"finished_at"`
+	HeartbeatAt    time.Time     `json:"heartbeat_at"`
+}
+
 type TeamMembership struct {
 	ID       int32        `json:"id"`
 	TeamID   int32        `json:"team_id"`
@@ -152,22 +401,71 @@ type TeamMembership struct {
 	JoinedAt time.Time    `json:"joined_at"`
 }
 
+type TeamNotification struct {
+	ID           int32      `json:"id"`
+	MembershipID int32      `json:"membership_id"`
+	Kind         string     `json:"kind"`
+	UploadRefID  *int32     `json:"upload_ref_id"`
+	Message      string     `json:"message"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ReadAt       *time.Time `json:"read_at"`
+}
+
+type TeamSetting struct {
+	TeamID            int32     `json:"team_id"`
+	AllowedMimeTypes  []string  `json:"allowed_mime_types"`
+	UpdatedAt         time.Time `json:"updated_at"`
+	TeamQuota         *int64    `json:"team_quota"`
+	UploaderQuota     *int64    `json:"uploader_quota"`
+	AllowedExtensions []string  `json:"allowed_extensions"`
+	MaxFileSize       *int64    `json:"max_file_size"`
+}
+
 type Upload struct {
+	ID            int32            `json:"id"`
+	StorageKey    string           `json:"storage_key"`
+	FileSha256    string           `json:"file_sha256"`
+	FileSize      int64            `json:"file_size"`
+	FileMimeType  string           `json:"file_mime_type"`
+	CreatedAt     time.Time        `json:"created_at"`
+	ScanStatus    UploadScanStatus `json:"scan_status"`
+	ScanSignature string           `json:"scan_signature"`
+	ScannedAt     *time.Time       `json:"scanned_at"`
+	OrphanedAt    *time.Time       `json:"orphaned_at"`
+}
+
+type UploadDerivative struct {
+	ID         int32     `json:"id"`
+	UploadID   int32     `json:"upload_id"`
+	Kind       string    `json:"kind"`
+	Page       int32     `json:"page"`
+	StorageKey string    `json:"storage_key"`
+	MimeType   string    `json:"mime_type"`
+	Width      int32     `json:"width"`
+	Height     int32     `json:"height"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type UploadPresign struct {
 	ID           int32     `json:"id"`
 	StorageKey   string    `json:"storage_key"`
-	FileSha256   string    `json:"file_sha256"`
-	FileSize     int64     `json:"file_size"`
+	TeamID       int32     `json:"team_id"`
+	UploaderID   int32     `json:"uploader_id"`
+	FileName     string    `json:"file_name"`
 	FileMimeType string    `json:"file_mime_type"`
+	FileSize     int64     `json:"file_size"`
+	ExpiresAt    time.Time `json:"expires_at"`
 	CreatedAt    time.Time `json:"created_at"`
 }
 
 type UploadRef struct {
-	ID         int32     `json:"id"`
-	UploadID   int32     `json:"upload_id"`
-	UploaderID int32     `json:"uploader_id"`
-	TeamID     int32     `json:"team_id"`
-	FileName   string    `json:"file_name"`
-	CreatedAt  time.Time `json:"created_at"`
+	ID         int32           `json:"id"`
+	UploadID   int32           `json:"upload_id"`
+	UploaderID int32           `json:"uploader_id"`
+	TeamID     int32           `json:"team_id"`
+	FileName   string          `json:"file_name"`
+	CreatedAt  time.Time       `json:"created_at"`
+	Status     UploadRefStatus `json:"status"`
 }
 
 type UploadRefTag struct {
@@ -178,6 +476,23 @@ type UploadRefTag struct {
 	CreatedAt   time.Time `json:"created_at"`
 }
 
+type UploadText struct {
+	UploadID     int32       `json:"upload_id"`
+	Content      string      `json:"content"`
+	SearchVector interface{} `json:"search_vector"`
+	CreatedAt    time.Time   `json:"created_at"`
+}
+
+type UploadVersion struct {
+	ID          int32     `json:"id"`
+	UploadRefID int32     `json:"upload_ref_id"`
+	UploadID    int32     `json:"upload_id"`
+	Version     int32     `json:"version"`
+	UploaderID  int32     `json:"uploader_id"`
+	FileName    string    `json:"file_name"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
 type User struct {
 	ID        int32     `json:"id"`
 	Email     string    `json:"email"`
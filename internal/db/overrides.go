@@ -1,8 +1,12 @@
 package db
 
+import "time"
+
 type TagOptions []struct {
 	ID    int32  `json:"id"`
 	Value string `json:"value"`
+	// values of other keys this option is valid under
+	ParentIDs []int32 `json:"parent_ids"`
 }
 type Tag struct {
 	KeyID   int32      `json:"key_id"`
@@ -12,3 +16,25 @@ type Tag struct {
 	Value   *string    `json:"value" extensions:"x-nullable"`
 	Options TagOptions `json:"options"`
 }
+
+type UploadTag struct {
+	KeyID   int32  `json:"key_id"`
+	Key     string `json:"key"`
+	ValueID int32  `json:"value_id"`
+	Value   string `json:"value"`
+}
+type UploadItem struct {
+	ID           int32       `json:"id"`
+	FileName     string      `json:"file_name"`
+	UploaderID   int32       `json:"uploader_id"`
+	CreatedAt    time.Time   `json:"created_at"`
+	StorageKey   string      `json:"-"`
+	FileMimeType string      `json:"file_mime_type"`
+	FileSize     int64       `json:"file_size"`
+	Version      int32       `json:"version"`
+	VersionCount int64       `json:"version_count"`
+	// only set when searching by text, the snippet is HTML escaped with matches in <b>
+	Rank    float32     `json:"rank,omitempty"`
+	Snippet string      `json:"snippet,omitempty"`
+	Tags    []UploadTag `json:"tags"`
+}
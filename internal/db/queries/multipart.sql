@@ -0,0 +1,31 @@
+-- name: CreateMultipartUpload :one
+INSERT INTO multipart_uploads (team_id, uploader_id, storage_key, s3_upload_id, file_name, file_mime_type, file_size, part_size, part_count, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING *;
+
+-- name: ListMultipartUploads :many
+-- unexpired sessions of an uploader, to resume after e.g. a page reload
+SELECT * FROM multipart_uploads
+WHERE team_id = $1 AND uploader_id = $2 AND expires_at > NOW()
+ORDER BY created_at DESC;
+
+-- name: GetMultipartUpload :one
+SELECT * FROM multipart_uploads WHERE id = $1 AND team_id = $2;
+
+-- name: LockMultipartUpload :one
+-- held while the upload is completed or aborted, so that happens once
+SELECT * FROM multipart_uploads WHERE id = $1 AND team_id = $2 FOR UPDATE;
+
+-- name: DeleteMultipartUpload :exec
+DELETE FROM multipart_uploads WHERE id = $1;
+
+-- name: ListExpiredMultipartUploads :many
+SELECT * FROM multipart_uploads
+WHERE expires_at < NOW()
+ORDER BY id
+LIMIT @lim;
+
+-- name: LockExpiredMultipartUpload :one
+SELECT * FROM multipart_uploads
+WHERE id = $1 AND expires_at < NOW()
+FOR UPDATE SKIP LOCKED;
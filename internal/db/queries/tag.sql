@@ -7,14 +7,19 @@ SELECT
   JSONB_AGG(
     JSONB_BUILD_OBJECT(
       'id', v.id,
-      'value', v.value
+      'value', v.value,
+      'parent_ids', COALESCE(
+        (SELECT JSONB_AGG(p.parent_value_id) FROM tag_value_parents p WHERE p.value_id = v.id),
+        '[]'::JSONB
+      )
     )
-    ORDER BY v.value
+    ORDER BY v.sort_order, v.num_value, v.value
   ) FILTER (WHERE v.id IS NOT NULL) AS options
   FROM tag_keys k
   LEFT JOIN member_filters f ON f.key_id = k.id AND f.membership_id = $1
   LEFT JOIN tag_values sv ON sv.id = f.value_id
-  LEFT JOIN tag_values v ON v.key_id = k.id
+  LEFT JOIN tag_values v ON v.key_id = k.id AND v.approved
+  WHERE k.archived_at IS NULL
   GROUP BY k.id, k.name, sv.id, sv.value;
 
 -- name: CreateFilter :exec
@@ -26,16 +31,179 @@ ON CONFLICT (membership_id, key_id) DO UPDATE SET value_id = $3;
 DELETE FROM member_filters WHERE membership_id = $1;
 
 -- name: CreateTagKey :one
-INSERT INTO tag_keys (team_id, name, data_type) VALUES ($1, $2, $3) RETURNING *;
+INSERT INTO tag_keys (team_id, name, data_type, mode, requires_approval) VALUES ($1, $2, $3, $4, $5) RETURNING *;
+
+-- name: GetTagKeyByID :one
+SELECT * FROM tag_keys WHERE id = $1 AND team_id = $2;
+
+-- name: GetTagKeyByName :one
+SELECT * FROM tag_keys WHERE team_id = $1 AND name = $2;
 
 -- name: CreateTagValue :one
-INSERT INTO tag_values (key_id, value) VALUES ($1, $2) RETURNING *;
+-- new values go after the ordered ones of a key a mod reordered, see ReorderTagValues,
+-- and stay at 0 to be sorted by value otherwise
+INSERT INTO tag_values (key_id, value, num_value, sort_order)
+VALUES ($1, $2, $3, (SELECT COALESCE(NULLIF(MAX(sort_order), 0) + 1, 0) FROM tag_values WHERE key_id = $1))
+RETURNING *;
+
+-- name: GetOrCreateTagValue :one
+-- placed like CreateTagValue
+INSERT INTO tag_values (key_id, value, num_value, approved, sort_order)
+VALUES ($1, $2, $3, $4, (SELECT COALESCE(NULLIF(MAX(sort_order), 0) + 1, 0) FROM tag_values WHERE key_id = $1))
+ON CONFLICT (key_id, value) DO UPDATE SET value = EXCLUDED.value
+RETURNING *;
+
+-- name: ApproveTagValue :one
+UPDATE tag_values SET approved = TRUE WHERE id = $1 AND key_id = $2 RETURNING *;
+
+-- name: ListPendingTagValues :many
+SELECT v.* FROM tag_values v
+  INNER JOIN tag_keys k ON k.id = v.key_id
+  WHERE k.team_id = $1 AND NOT v.approved
+  ORDER BY v.created_at;
 
 -- name: UpdateTagKey :one
-UPDATE tag_keys SET name = $2, data_type = $3 WHERE id = $1 RETURNING *;
+UPDATE tag_keys SET
+  name = @name,
+  mode = COALESCE(sqlc.narg(mode), mode),
+  requires_approval = COALESCE(sqlc.narg(requires_approval), requires_approval)
+WHERE id = @id AND team_id = @team_id RETURNING *;
 
 -- name: DeleteTagKey :one
-DELETE FROM tag_keys WHERE id = $1 RETURNING *;
+DELETE FROM tag_keys WHERE id = $1 AND team_id = $2 RETURNING *;
+
+-- name: ArchiveTagKey :one
+UPDATE tag_keys SET archived_at = NOW() WHERE id = $1 AND team_id = $2 RETURNING *;
+
+-- name: RestoreTagKey :one
+UPDATE tag_keys SET archived_at = NULL WHERE id = $1 AND team_id = $2 RETURNING *;
+
+-- name: GetTagKeyImpact :one
+SELECT
+  (SELECT COUNT(DISTINCT t.upload_ref_id) FROM upload_ref_tags t WHERE t.key_id = @key_id) AS uploads,
+  (SELECT COUNT(*) FROM member_filters f WHERE f.key_id = @key_id) AS filters,
+  (SELECT COUNT(*) FROM tag_values v WHERE v.key_id = @key_id) AS tag_values;
+
+-- name: DeleteUploadRefTagsByKey :exec
+DELETE FROM upload_ref_tags WHERE key_id = $1;
+
+-- name: DeleteUploadRefTagsByValue :exec
+DELETE FROM upload_ref_tags WHERE value_id = $1;
 
 -- name: DeleteTagValue :one
 DELETE FROM tag_values WHERE id = $1 RETURNING *;
+
+-- name: GetTagValueByID :one
+SELECT v.* FROM tag_values v
+  INNER JOIN tag_keys k ON k.id = v.key_id
+  WHERE v.id = $1 AND k.team_id = $2;
+
+-- name: GetTagValueByValue :one
+SELECT * FROM tag_values WHERE key_id = $1 AND value = $2;
+
+-- name: DeleteTagValueParents :exec
+DELETE FROM tag_value_parents WHERE value_id = $1;
+
+-- name: CreateTagValueParent :exec
+INSERT INTO tag_value_parents (value_id, parent_value_id) VALUES ($1, $2);
+
+-- name: ListTagValueParents :many
+SELECT p.* FROM tag_values p
+  INNER JOIN tag_value_parents tp ON tp.parent_value_id = p.id
+  WHERE tp.value_id = $1
+  ORDER BY p.key_id, p.id;
+
+-- name: ListDependentOptions :many
+-- approved values of a key that are valid alongside the chosen values
+SELECT v.* FROM tag_values v
+  WHERE v.key_id = @key_id AND v.approved
+    AND NOT EXISTS (
+      SELECT 1 FROM tag_value_parents p
+      INNER JOIN tag_values pv ON pv.id = p.parent_value_id
+      WHERE p.value_id = v.id
+        -- constraints only apply to keys that have a chosen value
+        AND EXISTS (
+          SELECT 1 FROM tag_values cv
+          WHERE cv.id = ANY(@value_ids::INTEGER[]) AND cv.key_id = pv.key_id
+        )
+        AND NOT EXISTS (
+          SELECT 1 FROM tag_value_parents p2
+          INNER JOIN tag_values pv2 ON pv2.id = p2.parent_value_id
+          WHERE p2.value_id = v.id
+            AND pv2.key_id = pv.key_id
+            AND p2.parent_value_id = ANY(@value_ids::INTEGER[])
+        )
+    )
+  ORDER BY v.sort_order, v.num_value, v.value;
+
+-- name: ListTagValueConflicts :many
+-- chosen values whose parents on a chosen key are not among the chosen values
+SELECT DISTINCT v.id, v.value FROM tag_values v
+  INNER JOIN tag_value_parents p ON p.value_id = v.id
+  INNER JOIN tag_values pv ON pv.id = p.parent_value_id
+  WHERE v.id = ANY(@value_ids::INTEGER[])
+    AND EXISTS (
+      SELECT 1 FROM tag_values cv
+      WHERE cv.id = ANY(@value_ids::INTEGER[]) AND cv.key_id = pv.key_id
+    )
+    AND NOT EXISTS (
+      SELECT 1 FROM tag_value_parents p2
+      INNER JOIN tag_values pv2 ON pv2.id = p2.parent_value_id
+      WHERE p2.value_id = v.id
+        AND pv2.key_id = pv.key_id
+        AND p2.parent_value_id = ANY(@value_ids::INTEGER[])
+    );
+
+-- name: UpdateTagValue :one
+UPDATE tag_values SET value = $2, num_value = $3 WHERE id = $1 RETURNING *;
+
+-- name: ReorderTagValues :exec
+-- values not listed are placed after the listed ones
+UPDATE tag_values v SET sort_order = COALESCE(o.ord, CARDINALITY(@value_ids::INTEGER[]) + 1)::INTEGER
+  FROM tag_values t
+  LEFT JOIN UNNEST(@value_ids::INTEGER[]) WITH ORDINALITY AS o(id, ord) ON o.id = t.id
+  WHERE v.id = t.id AND v.key_id = @key_id;
+
+-- name: RepointUploadRefTags :exec
+UPDATE upload_ref_tags SET value_id = @target_id WHERE value_id = @source_id;
+
+-- name: RepointMemberFilters :exec
+UPDATE member_filters SET value_id = @target_id WHERE value_id = @source_id;
+
+-- name: CopyTagValueChildren :exec
+-- children of the source value become children of the target value
+INSERT INTO tag_value_parents (value_id, parent_value_id)
+SELECT value_id, @target_id::INTEGER FROM tag_value_parents WHERE parent_value_id = @source_id
+ON CONFLICT DO NOTHING;
+
+-- name: ListTeamTagKeys :many
+SELECT * FROM tag_keys WHERE team_id = $1 ORDER BY id;
+
+-- name: ListTeamTagValues :many
+SELECT v.* FROM tag_values v
+  INNER JOIN tag_keys k ON k.id = v.key_id
+  WHERE k.team_id = $1
+  ORDER BY v.key_id, v.sort_order, v.id;
+
+-- name: ListTeamTagValueParents :many
+SELECT p.value_id, p.parent_value_id FROM tag_value_parents p
+  INNER JOIN tag_values v ON v.id = p.value_id
+  INNER JOIN tag_keys k ON k.id = v.key_id
+  WHERE k.team_id = $1
+  ORDER BY p.value_id, p.parent_value_id;
+
+-- name: CountTeamTagValueUploads :many
+-- number of active uploads each value is tagged on
+SELECT t.value_id, COUNT(*)::INTEGER AS uploads FROM upload_ref_tags t
+  INNER JOIN upload_refs r ON r.id = t.upload_ref_id
+  WHERE r.team_id = $1 AND r.status = 'active'
+  GROUP BY t.value_id;
+
+-- name: ListTagValueCooccurrences :many
+-- how many active uploads are tagged with each given value and another value
+SELECT a.value_id, b.value_id AS other_value_id, COUNT(*)::INTEGER AS uploads
+  FROM upload_ref_tags a
+  INNER JOIN upload_ref_tags b ON b.upload_ref_id = a.upload_ref_id AND b.value_id <> a.value_id
+  INNER JOIN upload_refs r ON r.id = a.upload_ref_id
+  WHERE r.team_id = @team_id AND r.status = 'active' AND a.value_id = ANY(@value_ids::INTEGER[])
+  GROUP BY a.value_id, b.value_id;
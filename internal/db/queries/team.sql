@@ -20,3 +20,45 @@ SELECT * FROM team_memberships WHERE user_id = $1 AND team_id = $2;
 INSERT INTO team_memberships (user_id, team_id, role)
 VALUES ($1, $2, $3)
 RETURNING *;
+
+-- name: GetTeamSettings :one
+SELECT * FROM team_settings WHERE team_id = $1;
+
+-- name: LockTeamSettings :one
+-- uploads of the team lock its settings while checking its quotas
+SELECT * FROM team_settings WHERE team_id = $1 FOR UPDATE;
+
+-- name: UpsertTeamSettings :one
+INSERT INTO team_settings (team_id, allowed_mime_types, allowed_extensions, max_file_size)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (team_id) DO UPDATE
+SET allowed_mime_types = EXCLUDED.allowed_mime_types,
+  allowed_extensions = EXCLUDED.allowed_extensions,
+  max_file_size = EXCLUDED.max_file_size,
+  updated_at = NOW()
+RETURNING *;
+
+-- name: UpsertTeamQuotas :one
+INSERT INTO team_settings (team_id, team_quota, uploader_quota)
+VALUES ($1, $2, $3)
+ON CONFLICT (team_id) DO UPDATE
+SET team_quota = EXCLUDED.team_quota, uploader_quota = EXCLUDED.uploader_quota, updated_at = NOW()
+RETURNING *;
+
+-- name: GetUserMembership :one
+-- users belong to a single team
+SELECT * FROM team_memberships WHERE user_id = $1;
+
+-- name: ListTeamMembers :many
+SELECT m.id, m.role, m.joined_at, u.email, u.name
+  FROM team_memberships m
+  INNER JOIN users u ON u.id = m.user_id
+  WHERE m.team_id = $1
+  ORDER BY m.id;
+
+-- name: ListTeamMemberFilters :many
+SELECT f.membership_id, f.key_id, f.value_id
+  FROM member_filters f
+  INNER JOIN team_memberships m ON m.id = f.membership_id
+  WHERE m.team_id = $1
+  ORDER BY f.id;
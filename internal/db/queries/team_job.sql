@@ -0,0 +1,41 @@
+-- name: CreateTeamJob :one
+INSERT INTO team_jobs (team_id, user_id, kind, include_objects)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetTeamJob :one
+SELECT * FROM team_jobs WHERE id = $1 AND team_id = $2;
+
+-- name: ListTeamJobs :many
+SELECT * FROM team_jobs WHERE team_id = $1
+ORDER BY created_at DESC, id DESC
+LIMIT $2;
+
+-- name: StartTeamJob :exec
+UPDATE team_jobs SET status = 'running', heartbeat_at = NOW() WHERE id = $1;
+
+-- name: TouchTeamJob :exec
+UPDATE team_jobs SET heartbeat_at = NOW() WHERE id = $1;
+
+-- name: FinishTeamJob :one
+UPDATE team_jobs
+SET status = $2, storage_key = $3, report = $4, error = $5, finished_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: FailStaleTeamJobs :execrows
+-- jobs run within a server process, those without a heartbeat for stale_after
+-- were interrupted when it stopped. Heartbeats are set with the database clock,
+-- so they are compared with it too
+UPDATE team_jobs
+SET status = 'failed', error = 'interrupted, the server running it stopped', finished_at = NOW()
+WHERE status IN ('pending', 'running') AND heartbeat_at < NOW() - @stale_after::INTERVAL;
+
+-- name: ListExpiredTeamJobArchives :many
+SELECT * FROM team_jobs
+WHERE storage_key <> '' AND finished_at < @cutoff::TIMESTAMP
+ORDER BY id
+LIMIT @lim;
+
+-- name: ClearTeamJobArchive :exec
+UPDATE team_jobs SET storage_key = '' WHERE id = $1;
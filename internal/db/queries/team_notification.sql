@@ -0,0 +1,15 @@
+-- name: CreateModNotifications :execrows
+-- notifies every mod of the team
+INSERT INTO team_notifications (membership_id, kind, upload_ref_id, message)
+SELECT m.id, @kind::TEXT, @upload_ref_id::INTEGER, @message::TEXT
+FROM team_memberships m
+WHERE m.team_id = @team_id AND m.role = 'mod';
+
+-- name: ListTeamNotifications :many
+SELECT * FROM team_notifications WHERE membership_id = $1
+ORDER BY created_at DESC, id DESC
+LIMIT $2;
+
+-- name: MarkTeamNotificationRead :execrows
+UPDATE team_notifications SET read_at = COALESCE(read_at, NOW())
+WHERE id = $1 AND membership_id = $2;
@@ -1,14 +1,299 @@
+-- name: GetUploadByHash :one
+SELECT * FROM uploads WHERE file_sha256 = $1 AND file_size = $2;
+
 -- name: GetOrCreateUpload :one
-INSERT INTO uploads (storage_key, file_sha256, file_size, file_mime_type)
-VALUES ($1, $2, $3, $4)
-ON CONFLICT (file_sha256, file_size) DO NOTHING
+-- the update makes RETURNING yield the existing row on conflict and revives orphaned uploads;
+-- a scan result is only recorded if the existing row was never scanned
+INSERT INTO uploads (storage_key, file_sha256, file_size, file_mime_type, scan_status, scan_signature, scanned_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (file_sha256, file_size) DO UPDATE
+SET scan_status = CASE WHEN uploads.scan_status = 'unscanned' THEN EXCLUDED.scan_status ELSE uploads.scan_status END,
+  scan_signature = CASE WHEN uploads.scan_status = 'unscanned' THEN EXCLUDED.scan_signature ELSE uploads.scan_signature END,
+  scanned_at = CASE WHEN uploads.scan_status = 'unscanned' THEN EXCLUDED.scanned_at ELSE uploads.scanned_at END,
+  orphaned_at = NULL
 RETURNING *, (xmax = 0) as created;
 
+-- name: ClaimUpload :one
+-- locks an existing upload for a new ref, reviving it if it was orphaned
+UPDATE uploads SET orphaned_at = NULL
+WHERE file_sha256 = $1 AND file_size = $2
+RETURNING *;
+
+-- name: QuarantineUpload :exec
+-- points an upload found infected after it was stored at its quarantined object
+UPDATE uploads SET storage_key = $2 WHERE id = $1;
+
+-- name: RejectUploadRefs :many
+-- rejects the active refs of an infected upload in every team
+UPDATE upload_refs SET status = 'rejected'
+WHERE upload_id = $1 AND status = 'active'
+RETURNING *;
+
+-- name: GetTeamUploadRef :one
+SELECT * FROM upload_refs WHERE upload_id = $1 AND team_id = $2;
+
 -- name: CreateUploadRef :one
-INSERT INTO upload_refs (upload_id, team_id, uploader_id, file_name)
-VALUES ($1, $2, $3, $4)
+INSERT INTO upload_refs (upload_id, team_id, uploader_id, file_name, status)
+VALUES ($1, $2, $3, $4, $5)
 RETURNING *;
 
 -- name: CreateUploadRefTags :exec
 INSERT INTO upload_ref_tags (upload_ref_id, key_id, value_id)
 VALUES ($1, $2, $3);
+
+-- name: ListUploads :many
+SELECT
+  r.id,
+  r.file_name,
+  r.uploader_id,
+  r.created_at,
+  u.storage_key,
+  u.file_mime_type,
+  u.file_size,
+  COALESCE((SELECT v.version FROM upload_versions v WHERE v.upload_ref_id = r.id AND v.upload_id = r.upload_id), 1)::INTEGER AS version,
+  (SELECT COUNT(*) FROM upload_versions v WHERE v.upload_ref_id = r.id) AS version_count,
+  (CASE WHEN @q::TEXT = '' THEN 0 ELSE ts_rank(x.search_vector, query) END)::REAL AS rank,
+  (CASE WHEN @q::TEXT = '' OR x.content IS NULL THEN ''
+    -- the text comes from untrusted files, only the highlighting is markup
+    ELSE ts_headline('english', REPLACE(REPLACE(REPLACE(x.content, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), query, 'MaxFragments=2, MaxWords=20, MinWords=5, StartSel=<b>, StopSel=</b>')
+  END)::TEXT AS snippet,
+  COALESCE(
+    (
+      SELECT JSONB_AGG(
+        JSONB_BUILD_OBJECT(
+          'key_id', t.key_id,
+          'key', k.name,
+          'value_id', t.value_id,
+          'value', v.value
+        )
+        ORDER BY k.name
+      )
+      FROM upload_ref_tags t
+      INNER JOIN tag_keys k ON k.id = t.key_id
+      INNER JOIN tag_values v ON v.id = t.value_id
+      WHERE t.upload_ref_id = r.id AND k.archived_at IS NULL
+    ),
+    '[]'::JSONB
+  ) AS tags
+  FROM upload_refs r
+  INNER JOIN uploads u ON u.id = r.upload_id
+  LEFT JOIN upload_texts x ON x.upload_id = u.id
+  CROSS JOIN websearch_to_tsquery('english', @q::TEXT) AS query
+  WHERE r.team_id = @team_id
+    AND r.status = 'active'
+    -- full-text search, skipped when q is empty
+    AND (@q::TEXT = '' OR x.search_vector @@ query)
+    -- every requested value must be tagged on the upload
+    AND (
+      SELECT COUNT(*) FROM upload_ref_tags t
+      WHERE t.upload_ref_id = r.id AND t.value_id = ANY(@value_ids::INTEGER[])
+    ) = CARDINALITY(@value_ids::INTEGER[])
+    -- every requested range must be satisfied by a number-typed tag of the upload
+    AND NOT EXISTS (
+      SELECT 1
+      FROM UNNEST(
+        @range_key_ids::INTEGER[],
+        @range_mins::DOUBLE PRECISION[],
+        @range_maxs::DOUBLE PRECISION[]
+      ) AS rg(key_id, min_value, max_value)
+      WHERE NOT EXISTS (
+        SELECT 1 FROM upload_ref_tags t
+        INNER JOIN tag_values v ON v.id = t.value_id
+        WHERE t.upload_ref_id = r.id
+          AND t.key_id = rg.key_id
+          AND v.num_value BETWEEN rg.min_value AND rg.max_value
+      )
+    )
+  ORDER BY rank DESC, r.created_at DESC, r.id DESC
+  LIMIT @lim OFFSET @off;
+
+-- name: GetUploadByRefID :one
+SELECT u.* FROM uploads u
+INNER JOIN upload_refs r ON r.upload_id = u.id
+WHERE r.id = $1 AND r.team_id = $2;
+
+-- name: UpsertUploadDerivative :one
+INSERT INTO upload_derivatives (upload_id, kind, page, storage_key, mime_type, width, height)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (upload_id, kind, page) DO UPDATE
+SET storage_key = EXCLUDED.storage_key,
+  mime_type = EXCLUDED.mime_type,
+  width = EXCLUDED.width,
+  height = EXCLUDED.height,
+  created_at = NOW()
+RETURNING *;
+
+-- name: ListUploadDerivatives :many
+SELECT d.* FROM upload_derivatives d
+INNER JOIN upload_refs r ON r.upload_id = d.upload_id
+WHERE r.id = $1 AND r.team_id = $2
+ORDER BY d.kind, d.page;
+
+-- name: UpsertUploadText :exec
+INSERT INTO upload_texts (upload_id, content)
+VALUES ($1, $2)
+ON CONFLICT (upload_id) DO UPDATE
+SET content = EXCLUDED.content, created_at = NOW();
+
+-- name: ListRejectedUploads :many
+SELECT
+  r.id,
+  r.file_name,
+  r.uploader_id,
+  r.created_at,
+  u.scan_signature,
+  u.scanned_at
+  FROM upload_refs r
+  INNER JOIN uploads u ON u.id = r.upload_id
+  WHERE r.team_id = $1 AND r.status = 'rejected'
+  ORDER BY r.created_at DESC, r.id DESC;
+
+-- name: GetUploadRef :one
+SELECT * FROM upload_refs WHERE id = $1 AND team_id = $2 FOR UPDATE;
+
+-- name: RenameUploadRef :one
+UPDATE upload_refs SET file_name = $2 WHERE id = $1
+RETURNING *;
+
+-- name: DeleteUploadRefTags :exec
+DELETE FROM upload_ref_tags WHERE upload_ref_id = $1;
+
+-- name: DeleteUploadRef :exec
+DELETE FROM upload_refs WHERE id = $1;
+
+-- name: MarkUploadOrphaned :execrows
+-- only marks the upload if no refs or versions are left
+UPDATE uploads SET orphaned_at = NOW()
+WHERE id = $1
+  AND NOT EXISTS (SELECT 1 FROM upload_refs r WHERE r.upload_id = $1)
+  AND NOT EXISTS (SELECT 1 FROM upload_versions v WHERE v.upload_id = $1);
+
+-- name: ListOrphanedUploads :many
+-- uploads without refs, orphaned (or created, if they never had a ref) before the cutoff
+SELECT u.* FROM uploads u
+WHERE COALESCE(u.orphaned_at, u.created_at) < @cutoff::TIMESTAMP
+  AND NOT EXISTS (SELECT 1 FROM upload_refs r WHERE r.upload_id = u.id)
+  AND NOT EXISTS (SELECT 1 FROM upload_versions v WHERE v.upload_id = u.id)
+ORDER BY u.id
+LIMIT @lim;
+
+-- name: LockOrphanedUpload :one
+-- skips uploads locked by a concurrent GetOrCreateUpload, which is about to add a ref
+SELECT u.* FROM uploads u
+WHERE u.id = @id
+  AND COALESCE(u.orphaned_at, u.created_at) < @cutoff::TIMESTAMP
+  AND NOT EXISTS (SELECT 1 FROM upload_refs r WHERE r.upload_id = u.id)
+  AND NOT EXISTS (SELECT 1 FROM upload_versions v WHERE v.upload_id = u.id)
+FOR UPDATE SKIP LOCKED;
+
+-- name: ListUploadDerivativeKeys :many
+SELECT storage_key FROM upload_derivatives WHERE upload_id = $1;
+
+-- name: DeleteUpload :exec
+DELETE FROM uploads WHERE id = $1;
+
+-- name: GetTeamUsage :one
+-- every stored version counts, an upload kept by several refs of the team counts once
+SELECT COALESCE(SUM(u.file_size), 0)::BIGINT AS bytes, COUNT(*) AS uploads
+  FROM uploads u
+  WHERE u.id IN (
+    SELECT v.upload_id FROM upload_versions v
+    INNER JOIN upload_refs r ON r.id = v.upload_ref_id
+    WHERE r.team_id = $1 AND r.status = 'active'
+  );
+
+-- name: GetUploaderUsage :one
+SELECT COALESCE(SUM(u.file_size), 0)::BIGINT AS bytes, COUNT(*) AS uploads
+  FROM uploads u
+  WHERE u.id IN (
+    SELECT v.upload_id FROM upload_versions v
+    INNER JOIN upload_refs r ON r.id = v.upload_ref_id
+    WHERE r.team_id = $1 AND v.uploader_id = $2 AND r.status = 'active'
+  );
+
+-- name: IsUploadCounted :one
+-- whether an upload already counts towards the team's and the uploader's usage
+SELECT
+  EXISTS (
+    SELECT 1 FROM upload_versions v
+    INNER JOIN upload_refs r ON r.id = v.upload_ref_id
+    WHERE r.team_id = @team_id AND v.upload_id = @upload_id AND r.status = 'active'
+  ) AS by_team,
+  EXISTS (
+    SELECT 1 FROM upload_versions v
+    INNER JOIN upload_refs r ON r.id = v.upload_ref_id
+    WHERE r.team_id = @team_id AND v.upload_id = @upload_id AND v.uploader_id = @uploader_id AND r.status = 'active'
+  ) AS by_uploader;
+
+-- name: ListUploaderUsage :many
+SELECT
+  s.uploader_id,
+  usr.name AS uploader_name,
+  SUM(u.file_size)::BIGINT AS bytes,
+  COUNT(*) AS uploads
+  FROM (
+    SELECT DISTINCT v.uploader_id, v.upload_id FROM upload_versions v
+    INNER JOIN upload_refs r ON r.id = v.upload_ref_id
+    WHERE r.team_id = $1 AND r.status = 'active'
+  ) s
+  INNER JOIN uploads u ON u.id = s.upload_id
+  INNER JOIN users usr ON usr.id = s.uploader_id
+  GROUP BY s.uploader_id, usr.name
+  ORDER BY bytes DESC, s.uploader_id;
+
+-- name: CreateUploadPresign :exec
+INSERT INTO upload_presigns (storage_key, team_id, uploader_id, file_name, file_mime_type, file_size, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: GetUploadPresign :one
+SELECT * FROM upload_presigns
+WHERE storage_key = $1 AND team_id = $2 AND uploader_id = $3;
+
+-- name: ConsumeUploadPresign :one
+-- removes the presign of a key issued to the uploader, so it is completed once
+DELETE FROM upload_presigns
+WHERE storage_key = $1 AND team_id = $2 AND uploader_id = $3
+RETURNING *;
+
+-- name: ListExpiredUploadPresigns :many
+SELECT * FROM upload_presigns
+WHERE expires_at < @cutoff::TIMESTAMP
+ORDER BY id
+LIMIT @lim;
+
+-- name: DeleteExpiredUploadPresign :one
+-- skips presigns locked by a concurrent completion
+DELETE FROM upload_presigns
+WHERE id = (
+  SELECT p.id FROM upload_presigns p
+  WHERE p.id = @id AND p.expires_at < @cutoff::TIMESTAMP
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: ListTeamUploadRefs :many
+SELECT
+  r.id,
+  r.file_name,
+  r.created_at,
+  u.storage_key,
+  u.file_sha256,
+  u.file_size,
+  u.file_mime_type,
+  us.email AS uploader_email
+  FROM upload_refs r
+  INNER JOIN uploads u ON u.id = r.upload_id
+  INNER JOIN users us ON us.id = r.uploader_id
+  WHERE r.team_id = $1 AND r.status = 'active'
+  ORDER BY r.id;
+
+-- name: ListTeamUploadRefTags :many
+SELECT t.upload_ref_id, t.key_id, t.value_id FROM upload_ref_tags t
+  INNER JOIN upload_refs r ON r.id = t.upload_ref_id
+  WHERE r.team_id = $1 AND r.status = 'active'
+  ORDER BY t.upload_ref_id, t.key_id;
+
+-- name: GetUploadRefText :one
+SELECT x.content FROM upload_refs r
+  INNER JOIN upload_texts x ON x.upload_id = r.upload_id
+  WHERE r.team_id = $1 AND r.id = $2;
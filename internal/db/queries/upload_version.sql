@@ -0,0 +1,50 @@
+-- name: CreateUploadVersion :one
+-- numbers versions per ref, callers hold the ref's lock
+INSERT INTO upload_versions (upload_ref_id, upload_id, version, uploader_id, file_name)
+VALUES (
+  @upload_ref_id,
+  @upload_id,
+  (SELECT COALESCE(MAX(v.version), 0) + 1 FROM upload_versions v WHERE v.upload_ref_id = @upload_ref_id)::INTEGER,
+  @uploader_id,
+  @file_name
+)
+RETURNING *;
+
+-- name: GetUploadVersion :one
+SELECT * FROM upload_versions WHERE upload_ref_id = $1 AND version = $2;
+
+-- name: GetUploadVersionByUpload :one
+SELECT * FROM upload_versions WHERE upload_ref_id = $1 AND upload_id = $2;
+
+-- name: HasTeamUploadVersion :one
+-- whether the upload is a version of an active ref of the team, which its members can download
+SELECT EXISTS (
+  SELECT 1 FROM upload_versions v
+  INNER JOIN upload_refs r ON r.id = v.upload_ref_id
+  WHERE v.upload_id = $1 AND r.team_id = $2 AND r.status = 'active'
+);
+
+-- name: ListUploadVersions :many
+SELECT
+  v.id,
+  v.version,
+  v.uploader_id,
+  v.file_name,
+  v.created_at,
+  u.storage_key,
+  u.file_mime_type,
+  u.file_size,
+  (v.upload_id = r.upload_id) AS current
+  FROM upload_versions v
+  INNER JOIN upload_refs r ON r.id = v.upload_ref_id
+  INNER JOIN uploads u ON u.id = v.upload_id
+  WHERE r.id = $1 AND r.team_id = $2 AND r.status = 'active'
+  ORDER BY v.version DESC;
+
+-- name: DeleteUploadVersions :many
+DELETE FROM upload_versions WHERE upload_ref_id = $1
+RETURNING upload_id;
+
+-- name: SetCurrentUploadVersion :one
+UPDATE upload_refs SET upload_id = $2 WHERE id = $1
+RETURNING *;
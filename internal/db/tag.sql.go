@@ -11,6 +11,105 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const approveTagValue = `-- name: ApproveTagValue :one
+UPDATE tag_values SET approved = TRUE WHERE id = $1 AND key_id = $2 RETURNING id, key_id, value, created_at, num_value, approved, sort_order
+`
+
+type ApproveTagValueParams struct {
+	ID    int32 `json:"id"`
+	KeyID int32 `json:"key_id"`
+}
+
+func (q *Queries) ApproveTagValue(ctx context.Context, arg ApproveTagValueParams) (TagValue, error) {
+	row := q.db.QueryRow(ctx, approveTagValue, arg.ID, arg.KeyID)
+	var i TagValue
+	err := row.Scan(
+		&i.ID,
+		&i.KeyID,
+		&i.Value,
+		&i.CreatedAt,
+		&i.NumValue,
+		&i.Approved,
+		&i.SortOrder,
+	)
+	return i, err
+}
+
+const archiveTagKey = `-- name: ArchiveTagKey :one
+UPDATE tag_keys SET archived_at = NOW() WHERE id = $1 AND team_id = $2 RETURNING id, team_id, name, data_type, created_at, mode, requires_approval, archived_at
+`
+
+type ArchiveTagKeyParams struct {
+	ID     int32 `json:"id"`
+	TeamID int32 `json:"team_id"`
+}
+
+func (q *Queries) ArchiveTagKey(ctx context.Context, arg ArchiveTagKeyParams) (TagKey, error) {
+	row := q.db.QueryRow(ctx, archiveTagKey, arg.ID, arg.TeamID)
+	var i TagKey
+	err := row.Scan(
+		&i.ID,
+		&i.TeamID,
+		&i.Name,
+		&i.DataType,
+		&i.CreatedAt,
+		&i.Mode,
+		&i.RequiresApproval,
+		&i.ArchivedAt,
+	)
+	return i, err
+}
+
+const copyTagValueChildren = `-- name: CopyTagValueChildren :exec
+INSERT INTO tag_value_parents (value_id, parent_value_id)
+SELECT value_id, $1::INTEGER FROM tag_value_parents WHERE parent_value_id = $2
+ON CONFLICT DO NOTHING
+`
+
+type CopyTagValueChildrenParams struct {
+	TargetID int32 `json:"target_id"`
+	SourceID int32 `json:"source_id"`
+}
+
+// children of the source value become children of the target value
+func (q *Queries) CopyTagValueChildren(ctx context.Context, arg CopyTagValueChildrenParams) error {
+	_, err := q.db.Exec(ctx, copyTagValueChildren, arg.TargetID, arg.SourceID)
+	return err
+}
+
+const countTeamTagValueUploads = `-- name: CountTeamTagValueUploads :many
+SELECT t.value_id, COUNT(*)::INTEGER AS uploads FROM upload_ref_tags t
+  INNER JOIN upload_refs r ON r.id = t.upload_ref_id
+  WHERE r.team_id = $1 AND r.status = 'active'
+  GROUP BY t.value_id
+`
+
+type CountTeamTagValueUploadsRow struct {
+	ValueID int32 `json:"value_id"`
+	Uploads int32 `json:"uploads"`
+}
+
+// number of active uploads each value is tagged on
+func (q *Queries) CountTeamTagValueUploads(ctx context.Context, teamID int32) ([]CountTeamTagValueUploadsRow, error) {
+	rows, err := q.db.Query(ctx, countTeamTagValueUploads, teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountTeamTagValueUploadsRow
+	for rows.Next() {
+		var i CountTeamTagValueUploadsRow
+		if err := rows.Scan(&i.ValueID, &i.Uploads); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createFilter = `-- name: CreateFilter :exec
 INSERT INTO member_filters (membership_id, key_id, value_id)
 VALUES ($1, $2, $3)
@@ -29,17 +128,25 @@ func (q *Queries) CreateFilter(ctx context.Context, arg CreateFilterParams) erro
 }
 
 const createTagKey = `-- name: CreateTagKey :one
-INSERT INTO tag_keys (team_id, name, data_type) VALUES ($1, $2, $3) RETURNING id, team_id, name, data_type, created_at
+INSERT INTO tag_keys (team_id, name, data_type, mode, requires_approval) VALUES ($1, $2, $3, $4, $5) RETURNING id, team_id, name, data_type, created_at, mode, requires_approval, archived_at
 `
 
 type CreateTagKeyParams struct {
-	TeamID   int32       `json:"team_id"`
-	Name     string      `json:"name"`
-	DataType TagDataType `json:"data_type"`
+	TeamID           int32       `json:"team_id"`
+	Name             string      `json:"name"`
+	DataType         TagDataType `json:"data_type"`
+	Mode             TagKeyMode  `json:"mode"`
+	RequiresApproval bool        `json:"requires_approval"`
 }
 
 func (q *Queries) CreateTagKey(ctx context.Context, arg CreateTagKeyParams) (TagKey, error) {
-	row := q.db.QueryRow(ctx, createTagKey, arg.TeamID, arg.Name, arg.DataType)
+	row := q.db.QueryRow(ctx, createTagKey,
+		arg.TeamID,
+		arg.Name,
+		arg.DataType,
+		arg.Mode,
+		arg.RequiresApproval,
+	)
 	var i TagKey
 	err := row.Scan(
 		&i.ID,
@@ -47,31 +154,56 @@ func (q *Queries) CreateTagKey(ctx context.Context, arg CreateTagKeyParams) (Tag
 		&i.Name,
 		&i.DataType,
 		&i.CreatedAt,
+		&i.Mode,
+		&i.RequiresApproval,
+		&i.ArchivedAt,
 	)
 	return i, err
 }
 
 const createTagValue = `-- name: CreateTagValue :one
-INSERT INTO tag_values (key_id, value) VALUES ($1, $2) RETURNING id, key_id, value, created_at
+INSERT INTO tag_values (key_id, value, num_value, sort_order)
+VALUES ($1, $2, $3, (SELECT COALESCE(NULLIF(MAX(sort_order), 0) + 1, 0) FROM tag_values WHERE key_id = $1))
+RETURNING id, key_id, value, created_at, num_value, approved, sort_order
 `
 
 type CreateTagValueParams struct {
-	KeyID int32  `json:"key_id"`
-	Value string `json:"value"`
+	KeyID    int32    `json:"key_id"`
+	Value    string   `json:"value"`
+	NumValue *float64 `json:"num_value"`
 }
 
+// new values go after the ordered ones of a key a mod reordered, see ReorderTagValues,
+// and stay at 0 to be sorted by value otherwise
 func (q *Queries) CreateTagValue(ctx context.Context, arg CreateTagValueParams) (TagValue, error) {
-	row := q.db.QueryRow(ctx, createTagValue, arg.KeyID, arg.Value)
+	row := q.db.QueryRow(ctx, createTagValue, arg.KeyID, arg.Value, arg.NumValue)
 	var i TagValue
 	err := row.Scan(
 		&i.ID,
 		&i.KeyID,
 		&i.Value,
 		&i.CreatedAt,
+		&i.NumValue,
+		&i.Approved,
+		&i.SortOrder,
 	)
 	return i, err
 }
 
+const createTagValueParent = `-- name: CreateTagValueParent :exec
+INSERT INTO tag_value_parents (value_id, parent_value_id) VALUES ($1, $2)
+`
+
+type CreateTagValueParentParams struct {
+	ValueID       int32 `json:"value_id"`
+	ParentValueID int32 `json:"parent_value_id"`
+}
+
+func (q *Queries) CreateTagValueParent(ctx context.Context, arg CreateTagValueParentParams) error {
+	_, err := q.db.Exec(ctx, createTagValueParent, arg.ValueID, arg.ParentValueID)
+	return err
+}
+
 const deleteAllFilters = `-- name: DeleteAllFilters :exec
 DELETE FROM member_filters WHERE membership_id = $1
 `
@@ -82,11 +214,16 @@ func (q *Queries) DeleteAllFilters(ctx context.Context, membershipID int32) erro
 }
 
 const deleteTagKey = `-- name: DeleteTagKey :one
-DELETE FROM tag_keys WHERE id = $1 RETURNING id, team_id, name, data_type, created_at
+DELETE FROM tag_keys WHERE id = $1 AND team_id = $2 RETURNING id, team_id, name, data_type, created_at, mode, requires_approval, archived_at
 `
 
-func (q *Queries) DeleteTagKey(ctx context.Context, id int32) (TagKey, error) {
-	row := q.db.QueryRow(ctx, deleteTagKey, id)
+type DeleteTagKeyParams struct {
+	ID     int32 `json:"id"`
+	TeamID int32 `json:"team_id"`
+}
+
+func (q *Queries) DeleteTagKey(ctx context.Context, arg DeleteTagKeyParams) (TagKey, error) {
+	row := q.db.QueryRow(ctx, deleteTagKey, arg.ID, arg.TeamID)
 	var i TagKey
 	err := row.Scan(
 		&i.ID,
@@ -94,12 +231,15 @@ func (q *Queries) DeleteTagKey(ctx context.Context, id int32) (TagKey, error) {
 		&i.Name,
 		&i.DataType,
 		&i.CreatedAt,
+		&i.Mode,
+		&i.RequiresApproval,
+		&i.ArchivedAt,
 	)
 	return i, err
 }
 
 const deleteTagValue = `-- name: DeleteTagValue :one
-DELETE FROM tag_values WHERE id = $1 RETURNING id, key_id, value, created_at
+DELETE FROM tag_values WHERE id = $1 RETURNING id, key_id, value, created_at, num_value, approved, sort_order
 `
 
 func (q *Queries) DeleteTagValue(ctx context.Context, id int32) (TagValue, error) {
@@ -110,10 +250,252 @@ func (q *Queries) DeleteTagValue(ctx context.Context, id int32) (TagValue, error
 		&i.KeyID,
 		&i.Value,
 		&i.CreatedAt,
+		&i.NumValue,
+		&i.Approved,
+		&i.SortOrder,
+	)
+	return i, err
+}
+
+const deleteTagValueParents = `-- name: DeleteTagValueParents :exec
+DELETE FROM tag_value_parents WHERE value_id = $1
+`
+
+func (q *Queries) DeleteTagValueParents(ctx context.Context, valueID int32) error {
+	_, err := q.db.Exec(ctx, deleteTagValueParents, valueID)
+	return err
+}
+
+const deleteUploadRefTagsByKey = `-- name: DeleteUploadRefTagsByKey :exec
+DELETE FROM upload_ref_tags WHERE key_id = $1
+`
+
+func (q *Queries) DeleteUploadRefTagsByKey(ctx context.Context, keyID int32) error {
+	_, err := q.db.Exec(ctx, deleteUploadRefTagsByKey, keyID)
+	return err
+}
+
+const deleteUploadRefTagsByValue = `-- name: DeleteUploadRefTagsByValue :exec
+DELETE FROM upload_ref_tags WHERE value_id = $1
+`
+
+func (q *Queries) DeleteUploadRefTagsByValue(ctx context.Context, valueID int32) error {
+	_, err := q.db.Exec(ctx, deleteUploadRefTagsByValue, valueID)
+	return err
+}
+
+const getOrCreateTagValue = `-- name: GetOrCreateTagValue :one
+INSERT INTO tag_values (key_id, value, num_value, approved, sort_order)
+VALUES ($1, $2, $3, $4, (SELECT COALESCE(NULLIF(MAX(sort_order), 0) + 1, 0) FROM tag_values WHERE key_id = $1))
+ON CONFLICT (key_id, value) DO UPDATE SET value = EXCLUDED.value
+RETURNING id, key_id, value, created_at, num_value, approved, sort_order
+`
+
+type GetOrCreateTagValueParams struct {
+	KeyID    int32    `json:"key_id"`
+	Value    string   `json:"value"`
+	NumValue *float64 `json:"num_value"`
+	Approved bool     `json:"approved"`
+}
+
+// placed like CreateTagValue
+func (q *Queries) GetOrCreateTagValue(ctx context.Context, arg GetOrCreateTagValueParams) (TagValue, error) {
+	row := q.db.QueryRow(ctx, getOrCreateTagValue,
+		arg.KeyID,
+		arg.Value,
+		arg.NumValue,
+		arg.Approved,
+	)
+	var i TagValue
+	err := row.Scan(
+		&i.ID,
+		&i.KeyID,
+		&i.Value,
+		&i.CreatedAt,
+		&i.NumValue,
+		&i.Approved,
+		&i.SortOrder,
 	)
 	return i, err
 }
 
+const getTagKeyByID = `-- name: GetTagKeyByID :one
+SELECT id, team_id, name, data_type, created_at, mode, requires_approval, archived_at FROM tag_keys WHERE id = $1 AND team_id = $2
+`
+
+type GetTagKeyByIDParams struct {
+	ID     int32 `json:"id"`
+	TeamID int32 `json:"team_id"`
+}
+
+func (q *Queries) GetTagKeyByID(ctx context.Context, arg GetTagKeyByIDParams) (TagKey, error) {
+	row := q.db.QueryRow(ctx, getTagKeyByID, arg.ID, arg.TeamID)
+	var i TagKey
+	err := row.Scan(
+		&i.ID,
+		&i.TeamID,
+		&i.Name,
+		&i.DataType,
+		&i.CreatedAt,
+		&i.Mode,
+		&i.RequiresApproval,
+		&i.ArchivedAt,
+	)
+	return i, err
+}
+
+const getTagKeyByName = `-- name: GetTagKeyByName :one
+SELECT id, team_id, name, data_type, created_at, mode, requires_approval, archived_at FROM tag_keys WHERE team_id = $1 AND name = $2
+`
+
+type GetTagKeyByNameParams struct {
+	TeamID int32  `json:"team_id"`
+	Name   string `json:"name"`
+}
+
+func (q *Queries) GetTagKeyByName(ctx context.Context, arg GetTagKeyByNameParams) (TagKey, error) {
+	row := q.db.QueryRow(ctx, getTagKeyByName, arg.TeamID, arg.Name)
+	var i TagKey
+	err := row.Scan(
+		&i.ID,
+		&i.TeamID,
+		&i.Name,
+		&i.DataType,
+		&i.CreatedAt,
+		&i.Mode,
+		&i.RequiresApproval,
+		&i.ArchivedAt,
+	)
+	return i, err
+}
+
+const getTagKeyImpact = `-- name: GetTagKeyImpact :one
+SELECT
+  (SELECT COUNT(DISTINCT t.upload_ref_id) FROM upload_ref_tags t WHERE t.key_id = $1) AS uploads,
+  (SELECT COUNT(*) FROM member_filters f WHERE f.key_id = $1) AS filters,
+  (SELECT COUNT(*) FROM tag_values v WHERE v.key_id = $1) AS tag_values
+`
+
+type GetTagKeyImpactRow struct {
+	Uploads   int64 `json:"uploads"`
+	Filters   int64 `json:"filters"`
+	TagValues int64 `json:"tag_values"`
+}
+
+func (q *Queries) GetTagKeyImpact(ctx context.Context, keyID int32) (GetTagKeyImpactRow, error) {
+	row := q.db.QueryRow(ctx, getTagKeyImpact, keyID)
+	var i GetTagKeyImpactRow
+	err := row.Scan(&i.Uploads, &i.Filters, &i.TagValues)
+	return i, err
+}
+
+const getTagValueByID = `-- name: GetTagValueByID :one
+SELECT v.id, v.key_id, v.value, v.created_at, v.num_value, v.approved, v.sort_order FROM tag_values v
+  INNER JOIN tag_keys k ON k.id = v.key_id
+  WHERE v.id = $1 AND k.team_id = $2
+`
+
+type GetTagValueByIDParams struct {
+	ID     int32 `json:"id"`
+	TeamID int32 `json:"team_id"`
+}
+
+func (q *Queries) GetTagValueByID(ctx context.Context, arg GetTagValueByIDParams) (TagValue, error) {
+	row := q.db.QueryRow(ctx, getTagValueByID, arg.ID, arg.TeamID)
+	var i TagValue
+	err := row.Scan(
+		&i.ID,
+		&i.KeyID,
+		&i.Value,
+		&i.CreatedAt,
+		&i.NumValue,
+		&i.Approved,
+		&i.SortOrder,
+	)
+	return i, err
+}
+
+const getTagValueByValue = `-- name: GetTagValueByValue :one
+SELECT id, key_id, value, created_at, num_value, approved, sort_order FROM tag_values WHERE key_id = $1 AND value = $2
+`
+
+type GetTagValueByValueParams struct {
+	KeyID int32  `json:"key_id"`
+	Value string `json:"value"`
+}
+
+func (q *Queries) GetTagValueByValue(ctx context.Context, arg GetTagValueByValueParams) (TagValue, error) {
+	row := q.db.QueryRow(ctx, getTagValueByValue, arg.KeyID, arg.Value)
+	var i TagValue
+	err := row.Scan(
+		&i.ID,
+		&i.KeyID,
+		&i.Value,
+		&i.CreatedAt,
+		&i.NumValue,
+		&i.Approved,
+		&i.SortOrder,
+	)
+	return i, err
+}
+
+const listDependentOptions = `-- name: ListDependentOptions :many
+SELECT v.id, v.key_id, v.value, v.created_at, v.num_value, v.approved, v.sort_order FROM tag_values v
+  WHERE v.key_id = $1 AND v.approved
+    AND NOT EXISTS (
+      SELECT 1 FROM tag_value_parents p
+      INNER JOIN tag_values pv ON pv.id = p.parent_value_id
+      WHERE p.value_id = v.id
+        -- constraints only apply to keys that have a chosen value
+        AND EXISTS (
+          SELECT 1 FROM tag_values cv
+          WHERE cv.id = ANY($2::INTEGER[]) AND cv.key_id = pv.key_id
+        )
+        AND NOT EXISTS (
+          SELECT 1 FROM tag_value_parents p2
+          INNER JOIN tag_values pv2 ON pv2.id = p2.parent_value_id
+          WHERE p2.value_id = v.id
+            AND pv2.key_id = pv.key_id
+            AND p2.parent_value_id = ANY($2::INTEGER[])
+        )
+    )
+  ORDER BY v.sort_order, v.num_value, v.value
+`
+
+type ListDependentOptionsParams struct {
+	KeyID    int32   `json:"key_id"`
+	ValueIds []int32 `json:"value_ids"`
+}
+
+// approved values of a key that are valid alongside the chosen values
+func (q *Queries) ListDependentOptions(ctx context.Context, arg ListDependentOptionsParams) ([]TagValue, error) {
+	rows, err := q.db.Query(ctx, listDependentOptions, arg.KeyID, arg.ValueIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TagValue
+	for rows.Next() {
+		var i TagValue
+		if err := rows.Scan(
+			&i.ID,
+			&i.KeyID,
+			&i.Value,
+			&i.CreatedAt,
+			&i.NumValue,
+			&i.Approved,
+			&i.SortOrder,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listFilters = `-- name: ListFilters :many
 SELECT
   k.id AS key_id,
@@ -123,14 +505,19 @@ SELECT
   JSONB_AGG(
     JSONB_BUILD_OBJECT(
       'id', v.id,
-      'value', v.value
+      'value', v.value,
+      'parent_ids', COALESCE(
+        (SELECT JSONB_AGG(p.parent_value_id) FROM tag_value_parents p WHERE p.value_id = v.id),
+        '[]'::JSONB
+      )
     )
-    ORDER BY v.value
+    ORDER BY v.sort_order, v.num_value, v.value
   ) FILTER (WHERE v.id IS NOT NULL) AS options
   FROM tag_keys k
   LEFT JOIN member_filters f ON f.key_id = k.id AND f.membership_id = $1
   LEFT JOIN tag_values sv ON sv.id = f.value_id
-  LEFT JOIN tag_values v ON v.key_id = k.id
+  LEFT JOIN tag_values v ON v.key_id = k.id AND v.approved
+  WHERE k.archived_at IS NULL
   GROUP BY k.id, k.name, sv.id, sv.value
 `
 
@@ -168,18 +555,357 @@ func (q *Queries) ListFilters(ctx context.Context, membershipID int32) ([]ListFi
 	return items, nil
 }
 
+const listPendingTagValues = `-- name: ListPendingTagValues :many
+SELECT v.id, v.key_id, v.value, v.created_at, v.num_value, v.approved, v.sort_order FROM tag_values v
+  INNER JOIN tag_keys k ON k.id = v.key_id
+  WHERE k.team_id = $1 AND NOT v.approved
+  ORDER BY v.created_at
+`
+
+func (q *Queries) ListPendingTagValues(ctx context.Context, teamID int32) ([]TagValue, error) {
+	rows, err := q.db.Query(ctx, listPendingTagValues, teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TagValue
+	for rows.Next() {
+		var i TagValue
+		if err := rows.Scan(
+			&i.ID,
+			&i.KeyID,
+			&i.Value,
+			&i.CreatedAt,
+			&i.NumValue,
+			&i.Approved,
+			&i.SortOrder,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTagValueConflicts = `-- name: ListTagValueConflicts :many
+SELECT DISTINCT v.id, v.value FROM tag_values v
+  INNER JOIN tag_value_parents p ON p.value_id = v.id
+  INNER JOIN tag_values pv ON pv.id = p.parent_value_id
+  WHERE v.id = ANY($1::INTEGER[])
+    AND EXISTS (
+      SELECT 1 FROM tag_values cv
+      WHERE cv.id = ANY($1::INTEGER[]) AND cv.key_id = pv.key_id
+    )
+    AND NOT EXISTS (
+      SELECT 1 FROM tag_value_parents p2
+      INNER JOIN tag_values pv2 ON pv2.id = p2.parent_value_id
+      WHERE p2.value_id = v.id
+        AND pv2.key_id = pv.key_id
+        AND p2.parent_value_id = ANY($1::INTEGER[])
+    )
+`
+
+type ListTagValueConflictsRow struct {
+	ID    int32  `json:"id"`
+	Value string `json:"value"`
+}
+
+// chosen values whose parents on a chosen key are not among the chosen values
+func (q *Queries) ListTagValueConflicts(ctx context.Context, valueIds []int32) ([]ListTagValueConflictsRow, error) {
+	rows, err := q.db.Query(ctx, listTagValueConflicts, valueIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTagValueConflictsRow
+	for rows.Next() {
+		var i ListTagValueConflictsRow
+		if err := rows.Scan(&i.ID, &i.Value); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTagValueCooccurrences = `-- name: ListTagValueCooccurrences :many
+SELECT a.value_id, b.value_id AS other_value_id, COUNT(*)::INTEGER AS uploads
+  FROM upload_ref_tags a
+  INNER JOIN upload_ref_tags b ON b.upload_ref_id = a.upload_ref_id AND b.value_id <> a.value_id
+  INNER JOIN upload_refs r ON r.id = a.upload_ref_id
+  WHERE r.team_id = $1 AND r.status = 'active' AND a.value_id = ANY($2::INTEGER[])
+  GROUP BY a.value_id, b.value_id
+`
+
+type ListTagValueCooccurrencesParams struct {
+	TeamID   int32   `json:"team_id"`
+	ValueIds []int32 `json:"value_ids"`
+}
+
+type ListTagValueCooccurrencesRow struct {
+	ValueID      int32 `json:"value_id"`
+	OtherValueID int32 `json:"other_value_id"`
+	Uploads      int32 `json:"uploads"`
+}
+
+// how many active uploads are tagged with each given value and another value
+func (q *Queries) ListTagValueCooccurrences(ctx context.Context, arg ListTagValueCooccurrencesParams) ([]ListTagValueCooccurrencesRow, error) {
+	rows, err := q.db.Query(ctx, listTagValueCooccurrences, arg.TeamID, arg.ValueIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTagValueCooccurrencesRow
+	for rows.Next() {
+		var i ListTagValueCooccurrencesRow
+		if err := rows.Scan(&i.ValueID, &i.OtherValueID, &i.Uploads); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTagValueParents = `-- name: ListTagValueParents :many
+SELECT p.id, p.key_id, p.value, p.created_at, p.num_value, p.approved, p.sort_order FROM tag_values p
+  INNER JOIN tag_value_parents tp ON tp.parent_value_id = p.id
+  WHERE tp.value_id = $1
+  ORDER BY p.key_id, p.id
+`
+
+func (q *Queries) ListTagValueParents(ctx context.Context, valueID int32) ([]TagValue, error) {
+	rows, err := q.db.Query(ctx, listTagValueParents, valueID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TagValue
+	for rows.Next() {
+		var i TagValue
+		if err := rows.Scan(
+			&i.ID,
+			&i.KeyID,
+			&i.Value,
+			&i.CreatedAt,
+			&i.NumValue,
+			&i.Approved,
+			&i.SortOrder,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTeamTagKeys = `-- name: ListTeamTagKeys :many
+SELECT id, team_id, name, data_type, created_at, mode, requires_approval, archived_at FROM tag_keys WHERE team_id = $1 ORDER BY id
+`
+
+func (q *Queries) ListTeamTagKeys(ctx context.Context, teamID int32) ([]TagKey, error) {
+	rows, err := q.db.Query(ctx, listTeamTagKeys, teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TagKey
+	for rows.Next() {
+		var i TagKey
+		if err := rows.Scan(
+			&i.ID,
+			&i.TeamID,
+			&i.Name,
+			&i.DataType,
+			&i.CreatedAt,
+			&i.Mode,
+			&i.RequiresApproval,
+			&i.ArchivedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTeamTagValueParents = `-- name: ListTeamTagValueParents :many
+SELECT p.value_id, p.parent_value_id FROM tag_value_parents p
+  INNER JOIN tag_values v ON v.id = p.value_id
+  INNER JOIN tag_keys k ON k.id = v.key_id
+  WHERE k.team_id = $1
+  ORDER BY p.value_id, p.parent_value_id
+`
+
+type ListTeamTagValueParentsRow struct {
+	ValueID       int32 `json:"value_id"`
+	ParentValueID int32 `json:"parent_value_id"`
+}
+
+func (q *Queries) ListTeamTagValueParents(ctx context.Context, teamID int32) ([]ListTeamTagValueParentsRow, error) {
+	rows, err := q.db.Query(ctx, listTeamTagValueParents, teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTeamTagValueParentsRow
+	for rows.Next() {
+		var i ListTeamTagValueParentsRow
+		if err := rows.Scan(&i.ValueID, &i.ParentValueID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTeamTagValues = `-- name: ListTeamTagValues :many
+SELECT v.id, v.key_id, v.value, v.created_at, v.num_value, v.approved, v.sort_order FROM tag_values v
+  INNER JOIN tag_keys k ON k.id = v.key_id
+  WHERE k.team_id = $1
+  ORDER BY v.key_id, v.sort_order, v.id
+`
+
+func (q *Queries) ListTeamTagValues(ctx context.Context, teamID int32) ([]TagValue, error) {
+	rows, err := q.db.Query(ctx, listTeamTagValues, teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TagValue
+	for rows.Next() {
+		var i TagValue
+		if err := rows.Scan(
+			&i.ID,
+			&i.KeyID,
+			&i.Value,
+			&i.CreatedAt,
+			&i.NumValue,
+			&i.Approved,
+			&i.SortOrder,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const reorderTagValues = `-- name: ReorderTagValues :exec
+UPDATE tag_values v SET sort_order = COALESCE(o.ord, CARDINALITY($1::INTEGER[]) + 1)::INTEGER
+  FROM tag_values t
+  LEFT JOIN UNNEST($1::INTEGER[]) WITH ORDINALITY AS o(id, ord) ON o.id = t.id
+  WHERE v.id = t.id AND v.key_id = $2
+`
+
+type ReorderTagValuesParams struct {
+	ValueIds []int32 `json:"value_ids"`
+	KeyID    int32   `json:"key_id"`
+}
+
+// values not listed are placed after the listed ones
+func (q *Queries) ReorderTagValues(ctx context.Context, arg ReorderTagValuesParams) error {
+	_, err := q.db.Exec(ctx, reorderTagValues, arg.ValueIds, arg.KeyID)
+	return err
+}
+
+const repointMemberFilters = `-- name: RepointMemberFilters :exec
+UPDATE member_filters SET value_id = $1 WHERE value_id = $2
+`
+
+type RepointMemberFiltersParams struct {
+	TargetID int32 `json:"target_id"`
+	SourceID int32 `json:"source_id"`
+}
+
+func (q *Queries) RepointMemberFilters(ctx context.Context, arg RepointMemberFiltersParams) error {
+	_, err := q.db.Exec(ctx, repointMemberFilters, arg.TargetID, arg.SourceID)
+	return err
+}
+
+const repointUploadRefTags = `-- name: RepointUploadRefTags :exec
+UPDATE upload_ref_tags SET value_id = $1 WHERE value_id = $2
+`
+
+type RepointUploadRefTagsParams struct {
+	TargetID int32 `json:"target_id"`
+	SourceID int32 `json:"source_id"`
+}
+
+func (q *Queries) RepointUploadRefTags(ctx context.Context, arg RepointUploadRefTagsParams) error {
+	_, err := q.db.Exec(ctx, repointUploadRefTags, arg.TargetID, arg.SourceID)
+	return err
+}
+
+const restoreTagKey = `-- name: RestoreTagKey :one
+UPDATE tag_keys SET archived_at = NULL WHERE id = $1 AND team_id = $2 RETURNING id, team_id, name, data_type, created_at, mode, requires_approval, archived_at
+`
+
+type RestoreTagKeyParams struct {
+	ID     int32 `json:"id"`
+	TeamID int32 `json:"team_id"`
+}
+
+func (q *Queries) RestoreTagKey(ctx context.Context, arg RestoreTagKeyParams) (TagKey, error) {
+	row := q.db.QueryRow(ctx, restoreTagKey, arg.ID, arg.TeamID)
+	var i TagKey
+	err := row.Scan(
+		&i.ID,
+		&i.TeamID,
+		&i.Name,
+		&i.DataType,
+		&i.CreatedAt,
+		&i.Mode,
+		&i.RequiresApproval,
+		&i.ArchivedAt,
+	)
+	return i, err
+}
+
 const updateTagKey = `-- name: UpdateTagKey :one
-UPDATE tag_keys SET name = $2, data_type = $3 WHERE id = $1 RETURNING id, team_id, name, data_type, created_at
+UPDATE tag_keys SET
+  name = $1,
+  mode = COALESCE($2, mode),
+  requires_approval = COALESCE($3, requires_approval)
+WHERE id = $4 AND team_id = $5 RETURNING id, team_id, name, data_type, created_at, mode, requires_approval, archived_at
 `
 
 type UpdateTagKeyParams struct {
-	ID       int32       `json:"id"`
-	Name     string      `json:"name"`
-	DataType TagDataType `json:"data_type"`
+	Name             string         `json:"name"`
+	Mode             NullTagKeyMode `json:"mode"`
+	RequiresApproval pgtype.Bool    `json:"requires_approval"`
+	ID               int32          `json:"id"`
+	TeamID           int32          `json:"team_id"`
 }
 
 func (q *Queries) UpdateTagKey(ctx context.Context, arg UpdateTagKeyParams) (TagKey, error) {
-	row := q.db.QueryRow(ctx, updateTagKey, arg.ID, arg.Name, arg.DataType)
+	row := q.db.QueryRow(ctx, updateTagKey,
+		arg.Name,
+		arg.Mode,
+		arg.RequiresApproval,
+		arg.ID,
+		arg.TeamID,
+	)
 	var i TagKey
 	err := row.Scan(
 		&i.ID,
@@ -187,6 +913,34 @@ func (q *Queries) UpdateTagKey(ctx context.Context, arg UpdateTagKeyParams) (Tag
 		&i.Name,
 		&i.DataType,
 		&i.CreatedAt,
+		&i.Mode,
+		&i.RequiresApproval,
+		&i.ArchivedAt,
+	)
+	return i, err
+}
+
+const updateTagValue = `-- name: UpdateTagValue :one
+UPDATE tag_values SET value = $2, num_value = $3 WHERE id = $1 RETURNING id, key_id, value, created_at, num_value, approved, sort_order
+`
+
+type UpdateTagValueParams struct {
+	ID       int32    `json:"id"`
+	Value    string   `json:"value"`
+	NumValue *float64 `json:"num_value"`
+}
+
+func (q *Queries) UpdateTagValue(ctx context.Context, arg UpdateTagValueParams) (TagValue, error) {
+	row := q.db.QueryRow(ctx, updateTagValue, arg.ID, arg.Value, arg.NumValue)
+	var i TagValue
+	err := row.Scan(
+		&i.ID,
+		&i.KeyID,
+		&i.Value,
+		&i.CreatedAt,
+		&i.NumValue,
+		&i.Approved,
+		&i.SortOrder,
 	)
 	return i, err
 }
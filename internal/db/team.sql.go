@@ -73,6 +73,25 @@ func (q *Queries) GetTeamMembershipByUserID(ctx context.Context, arg GetTeamMemb
 	return i, err
 }
 
+const getTeamSettings = `-- name: GetTeamSettings :one
+SELECT team_id, allowed_mime_types, updated_at, team_quota, uploader_quota, allowed_extensions, max_file_size FROM team_settings WHERE team_id = $1
+`
+
+func (q *Queries) GetTeamSettings(ctx context.Context, teamID int32) (TeamSetting, error) {
+	row := q.db.QueryRow(ctx, getTeamSettings, teamID)
+	var i TeamSetting
+	err := row.Scan(
+		&i.TeamID,
+		&i.AllowedMimeTypes,
+		&i.UpdatedAt,
+		&i.TeamQuota,
+		&i.UploaderQuota,
+		&i.AllowedExtensions,
+		&i.MaxFileSize,
+	)
+	return i, err
+}
+
 const getTeamsByUserID = `-- name: GetTeamsByUserID :many
 SELECT
   t.id AS team_id,
@@ -121,3 +140,184 @@ func (q *Queries) GetTeamsByUserID(ctx context.Context, userID int32) ([]GetTeam
 	}
 	return items, nil
 }
+
+const getUserMembership = `-- name: GetUserMembership :one
+SELECT id, team_id, user_id, role, joined_at FROM team_memberships WHERE user_id = $1
+`
+
+// users belong to a single team
+func (q *Queries) GetUserMembership(ctx context.Context, userID int32) (TeamMembership, error) {
+	row := q.db.QueryRow(ctx, getUserMembership, userID)
+	var i TeamMembership
+	err := row.Scan(
+		&i.ID,
+		&i.TeamID,
+		&i.UserID,
+		&i.Role,
+		&i.JoinedAt,
+	)
+	return i, err
+}
+
+const listTeamMemberFilters = `-- name: ListTeamMemberFilters :many
+SELECT f.membership_id, f.key_id, f.value_id
+  FROM member_filters f
+  INNER JOIN team_memberships m ON m.id = f.membership_id
+  WHERE m.team_id = $1
+  ORDER BY f.id
+`
+
+type ListTeamMemberFiltersRow struct {
+	MembershipID int32 `json:"membership_id"`
+	KeyID        int32 `json:"key_id"`
+	ValueID      int32 `json:"value_id"`
+}
+
+func (q *Queries) ListTeamMemberFilters(ctx context.Context, teamID int32) ([]ListTeamMemberFiltersRow, error) {
+	rows, err := q.db.Query(ctx, listTeamMemberFilters, teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTeamMemberFiltersRow
+	for rows.Next() {
+		var i ListTeamMemberFiltersRow
+		if err := rows.Scan(&i.MembershipID, &i.KeyID, &i.ValueID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTeamMembers = `-- name: ListTeamMembers :many
+SELECT m.id, m.role, m.joined_at, u.email, u.name
+  FROM team_memberships m
+  INNER JOIN users u ON u.id = m.user_id
+  WHERE m.team_id = $1
+  ORDER BY m.id
+`
+
+type ListTeamMembersRow struct {
+	ID       int32        `json:"id"`
+	Role     TeamUserRole `json:"role"`
+	JoinedAt time.Time    `json:"joined_at"`
+	Email    string       `json:"email"`
+	Name     string       `json:"name"`
+}
+
+func (q *Queries) ListTeamMembers(ctx context.Context, teamID int32) ([]ListTeamMembersRow, error) {
+	rows, err := q.db.Query(ctx, listTeamMembers, teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTeamMembersRow
+	for rows.Next() {
+		var i ListTeamMembersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Role,
+			&i.JoinedAt,
+			&i.Email,
+			&i.Name,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockTeamSettings = `-- name: LockTeamSettings :one
+SELECT team_id, allowed_mime_types, updated_at, team_quota, uploader_quota, allowed_extensions, max_file_size FROM team_settings WHERE team_id = $1 FOR UPDATE
+`
+
+// uploads of the team lock its settings while checking its quotas
+func (q *Queries) LockTeamSettings(ctx context.Context, teamID int32) (TeamSetting, error) {
+	row := q.db.QueryRow(ctx, lockTeamSettings, teamID)
+	var i TeamSetting
+	err := row.Scan(
+		&i.TeamID,
+		&i.AllowedMimeTypes,
+		&i.UpdatedAt,
+		&i.TeamQuota,
+		&i.UploaderQuota,
+		&i.AllowedExtensions,
+		&i.MaxFileSize,
+	)
+	return i, err
+}
+
+const upsertTeamQuotas = `-- name: UpsertTeamQuotas :one
+INSERT INTO team_settings (team_id, team_quota, uploader_quota)
+VALUES ($1, $2, $3)
+ON CONFLICT (team_id) DO UPDATE
+SET team_quota = EXCLUDED.team_quota, uploader_quota = EXCLUDED.uploader_quota, updated_at = NOW()
+RETURNING team_id, allowed_mime_types, updated_at, team_quota, uploader_quota, allowed_extensions, max_file_size
+`
+
+type UpsertTeamQuotasParams struct {
+	TeamID        int32  `json:"team_id"`
+	TeamQuota     *int64 `json:"team_quota"`
+	UploaderQuota *int64 `json:"uploader_quota"`
+}
+
+func (q *Queries) UpsertTeamQuotas(ctx context.Context, arg UpsertTeamQuotasParams) (TeamSetting, error) {
+	row := q.db.QueryRow(ctx, upsertTeamQuotas, arg.TeamID, arg.TeamQuota, arg.UploaderQuota)
+	var i TeamSetting
+	err := row.Scan(
+		&i.TeamID,
+		&i.AllowedMimeTypes,
+		&i.UpdatedAt,
+		&i.TeamQuota,
+		&i.UploaderQuota,
+		&i.AllowedExtensions,
+		&i.MaxFileSize,
+	)
+	return i, err
+}
+
+const upsertTeamSettings = `-- name: UpsertTeamSettings :one
+INSERT INTO team_settings (team_id, allowed_mime_types, allowed_extensions, max_file_size)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (team_id) DO UPDATE
+SET allowed_mime_types = EXCLUDED.allowed_mime_types,
+  allowed_extensions = EXCLUDED.allowed_extensions,
+  max_file_size = EXCLUDED.max_file_size,
+  updated_at = NOW()
+RETURNING team_id, allowed_mime_types, updated_at, team_quota, uploader_quota, allowed_extensions, max_file_size
+`
+
+type UpsertTeamSettingsParams struct {
+	TeamID            int32    `json:"team_id"`
+	AllowedMimeTypes  []string `json:"allowed_mime_types"`
+	AllowedExtensions []string `json:"allowed_extensions"`
+	MaxFileSize       *int64   `json:"max_file_size"`
+}
+
+func (q *Queries) UpsertTeamSettings(ctx context.Context, arg UpsertTeamSettingsParams) (TeamSetting, error) {
+	row := q.db.QueryRow(ctx, upsertTeamSettings,
+		arg.TeamID,
+		arg.AllowedMimeTypes,
+		arg.AllowedExtensions,
+		arg.MaxFileSize,
+	)
+	var i TeamSetting
+	err := row.Scan(
+		&i.TeamID,
+		&i.AllowedMimeTypes,
+		&i.UpdatedAt,
+		&i.TeamQuota,
+		&i.UploaderQuota,
+		&i.AllowedExtensions,
+		&i.MaxFileSize,
+	)
+	return i, err
+}
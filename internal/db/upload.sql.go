@@ -10,17 +10,106 @@ import (
 	"time"
 )
 
-const createUploadRef = `-- name: CreateUploadRef :one
-INSERT INTO upload_refs (upload_id, team_id, uploader_id, file_name)
-VALUES ($1, $2, $3, $4)
-RETURNING id, upload_id, uploader_id, team_id, file_name, created_at
+const claimUpload = `-- name: ClaimUpload :one
+UPDATE uploads SET orphaned_at = NULL
+WHERE file_sha256 = $1 AND file_size = $2
+RETURNING id, storage_key, file_sha256, file_size, file_mime_type, created_at, scan_status, scan_signature, scanned_at, orphaned_at
 `
 
-type CreateUploadRefParams struct {
-	UploadID   int32  `json:"upload_id"`
+type ClaimUploadParams struct {
+	FileSha256 string `json:"file_sha256"`
+	FileSize   int64  `json:"file_size"`
+}
+
+// locks an existing upload for a new ref, reviving it if it was orphaned
+func (q *Queries) ClaimUpload(ctx context.Context, arg ClaimUploadParams) (Upload, error) {
+	row := q.db.QueryRow(ctx, claimUpload, arg.FileSha256, arg.FileSize)
+	var i Upload
+	err := row.Scan(
+		&i.ID,
+		&i.StorageKey,
+		&i.FileSha256,
+		&i.FileSize,
+		&i.FileMimeType,
+		&i.CreatedAt,
+		&i.ScanStatus,
+		&i.ScanSignature,
+		&i.ScannedAt,
+		&i.OrphanedAt,
+	)
+	return i, err
+}
+
+const consumeUploadPresign = `-- name: ConsumeUploadPresign :one
+DELETE FROM upload_presigns
+WHERE storage_key = $1 AND team_id = $2 AND uploader_id = $3
+RETURNING id, storage_key, team_id, uploader_id, file_name, file_mime_type, file_size, expires_at, created_at
+`
+
+type ConsumeUploadPresignParams struct {
+	StorageKey string `json:"storage_key"`
 	TeamID     int32  `json:"team_id"`
 	UploaderID int32  `json:"uploader_id"`
-	FileName   string `json:"file_name"`
+}
+
+// removes the presign of a key issued to the uploader, so it is completed once
+func (q *Queries) ConsumeUploadPresign(ctx context.Context, arg ConsumeUploadPresignParams) (UploadPresign, error) {
+	row := q.db.QueryRow(ctx, consumeUploadPresign, arg.StorageKey, arg.TeamID, arg.UploaderID)
+	var i UploadPresign
+	err := row.Scan(
+		&i.ID,
+		&i.StorageKey,
+		&i.TeamID,
+		&i.UploaderID,
+		&i.FileName,
+		&i.FileMimeType,
+		&i.FileSize,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createUploadPresign = `-- name: CreateUploadPresign :exec
+INSERT INTO upload_presigns (storage_key, team_id, uploader_id, file_name, file_mime_type, file_size, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+`
+
+type CreateUploadPresignParams struct {
+	StorageKey   string    `json:"storage_key"`
+	TeamID       int32     `json:"team_id"`
+	UploaderID   int32     `json:"uploader_id"`
+	FileName     string    `json:"file_name"`
+	FileMimeType string    `json:"file_mime_type"`
+	FileSize     int64     `json:"file_size"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreateUploadPresign(ctx context.Context, arg CreateUploadPresignParams) error {
+	_, err := q.db.Exec(ctx, createUploadPresign,
+		arg.StorageKey,
+		arg.TeamID,
+		arg.UploaderID,
+		arg.FileName,
+		arg.FileMimeType,
+		arg.FileSize,
+		arg.ExpiresAt,
+	)
+	return err
+}
+
+const createUploadRef = `-- name: CreateUploadRef :one
+INSERT INTO upload_refs (upload_id, team_id, uploader_id, file_name, status)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, upload_id, uploader_id, team_id, file_name, created_at, status
+`
+
+type CreateUploadRefParams struct {
+	UploadID   int32           `json:"upload_id"`
+	TeamID     int32           `json:"team_id"`
+	UploaderID int32           `json:"uploader_id"`
+	FileName   string          `json:"file_name"`
+	Status     UploadRefStatus `json:"status"`
 }
 
 func (q *Queries) CreateUploadRef(ctx context.Context, arg CreateUploadRefParams) (UploadRef, error) {
@@ -29,6 +118,7 @@ func (q *Queries) CreateUploadRef(ctx context.Context, arg CreateUploadRefParams
 		arg.TeamID,
 		arg.UploaderID,
 		arg.FileName,
+		arg.Status,
 	)
 	var i UploadRef
 	err := row.Scan(
@@ -38,6 +128,7 @@ func (q *Queries) CreateUploadRef(ctx context.Context, arg CreateUploadRefParams
 		&i.TeamID,
 		&i.FileName,
 		&i.CreatedAt,
+		&i.Status,
 	)
 	return i, err
 }
@@ -58,36 +149,112 @@ func (q *Queries) CreateUploadRefTags(ctx context.Context, arg CreateUploadRefTa
 	return err
 }
 
+const deleteExpiredUploadPresign = `-- name: DeleteExpiredUploadPresign :one
+DELETE FROM upload_presigns
+WHERE id = (
+  SELECT p.id FROM upload_presigns p
+  WHERE p.id = $1 AND p.expires_at < $2::TIMESTAMP
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING id, storage_key, team_id, uploader_id, file_name, file_mime_type, file_size, expires_at, created_at
+`
+
+type DeleteExpiredUploadPresignParams struct {
+	ID     int32     `json:"id"`
+	Cutoff time.Time `json:"cutoff"`
+}
+
+// skips presigns locked by a concurrent completion
+func (q *Queries) DeleteExpiredUploadPresign(ctx context.Context, arg DeleteExpiredUploadPresignParams) (UploadPresign, error) {
+	row := q.db.QueryRow(ctx, deleteExpiredUploadPresign, arg.ID, arg.Cutoff)
+	var i UploadPresign
+	err := row.Scan(
+		&i.ID,
+		&i.StorageKey,
+		&i.TeamID,
+		&i.UploaderID,
+		&i.FileName,
+		&i.FileMimeType,
+		&i.FileSize,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteUpload = `-- name: DeleteUpload :exec
+DELETE FROM uploads WHERE id = $1
+`
+
+func (q *Queries) DeleteUpload(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, deleteUpload, id)
+	return err
+}
+
+const deleteUploadRef = `-- name: DeleteUploadRef :exec
+DELETE FROM upload_refs WHERE id = $1
+`
+
+func (q *Queries) DeleteUploadRef(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, deleteUploadRef, id)
+	return err
+}
+
+const deleteUploadRefTags = `-- name: DeleteUploadRefTags :exec
+DELETE FROM upload_ref_tags WHERE upload_ref_id = $1
+`
+
+func (q *Queries) DeleteUploadRefTags(ctx context.Context, uploadRefID int32) error {
+	_, err := q.db.Exec(ctx, deleteUploadRefTags, uploadRefID)
+	return err
+}
+
 const getOrCreateUpload = `-- name: GetOrCreateUpload :one
-INSERT INTO uploads (storage_key, file_sha256, file_size, file_mime_type)
-VALUES ($1, $2, $3, $4)
-ON CONFLICT (file_sha256, file_size) DO NOTHING
-RETURNING id, storage_key, file_sha256, file_size, file_mime_type, created_at, (xmax = 0) as created
+INSERT INTO uploads (storage_key, file_sha256, file_size, file_mime_type, scan_status, scan_signature, scanned_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (file_sha256, file_size) DO UPDATE
+SET scan_status = CASE WHEN uploads.scan_status = 'unscanned' THEN EXCLUDED.scan_status ELSE uploads.scan_status END,
+  scan_signature = CASE WHEN uploads.scan_status = 'unscanned' THEN EXCLUDED.scan_signature ELSE uploads.scan_signature END,
+  scanned_at = CASE WHEN uploads.scan_status = 'unscanned' THEN EXCLUDED.scanned_at ELSE uploads.scanned_at END,
+  orphaned_at = NULL
+RETURNING id, storage_key, file_sha256, file_size, file_mime_type, created_at, scan_status, scan_signature, scanned_at, orphaned_at, (xmax = 0) as created
 `
 
 type GetOrCreateUploadParams struct {
-	StorageKey   string `json:"storage_key"`
-	FileSha256   string `json:"file_sha256"`
-	FileSize     int64  `json:"file_size"`
-	FileMimeType string `json:"file_mime_type"`
+	StorageKey    string           `json:"storage_key"`
+	FileSha256    string           `json:"file_sha256"`
+	FileSize      int64            `json:"file_size"`
+	FileMimeType  string           `json:"file_mime_type"`
+	ScanStatus    UploadScanStatus `json:"scan_status"`
+	ScanSignature string           `json:"scan_signature"`
+	ScannedAt     *time.Time       `json:"scanned_at"`
 }
 
 type GetOrCreateUploadRow struct {
-	ID           int32     `json:"id"`
-	StorageKey   string    `json:"storage_key"`
-	FileSha256   string    `json:"file_sha256"`
-	FileSize     int64     `json:"file_size"`
-	FileMimeType string    `json:"file_mime_type"`
-	CreatedAt    time.Time `json:"created_at"`
-	Created      bool      `json:"created"`
+	ID            int32            `json:"id"`
+	StorageKey    string           `json:"storage_key"`
+	FileSha256    string           `json:"file_sha256"`
+	FileSize      int64            `json:"file_size"`
+	FileMimeType  string           `json:"file_mime_type"`
+	CreatedAt     time.Time        `json:"created_at"`
+	ScanStatus    UploadScanStatus `json:"scan_status"`
+	ScanSignature string           `json:"scan_signature"`
+	ScannedAt     *time.Time       `json:"scanned_at"`
+	OrphanedAt    *time.Time       `json:"orphaned_at"`
+	Created       bool             `json:"created"`
 }
 
+// the update makes RETURNING yield the existing row on conflict and revives orphaned uploads;
+// a scan result is only recorded if the existing row was never scanned
 func (q *Queries) GetOrCreateUpload(ctx context.Context, arg GetOrCreateUploadParams) (GetOrCreateUploadRow, error) {
 	row := q.db.QueryRow(ctx, getOrCreateUpload,
 		arg.StorageKey,
 		arg.FileSha256,
 		arg.FileSize,
 		arg.FileMimeType,
+		arg.ScanStatus,
+		arg.ScanSignature,
+		arg.ScannedAt,
 	)
 	var i GetOrCreateUploadRow
 	err := row.Scan(
@@ -97,7 +264,910 @@ func (q *Queries) GetOrCreateUpload(ctx context.Context, arg GetOrCreateUploadPa
 		&i.FileSize,
 		&i.FileMimeType,
 		&i.CreatedAt,
+		&i.ScanStatus,
+		&i.ScanSignature,
+		&i.ScannedAt,
+		&i.OrphanedAt,
 		&i.Created,
 	)
 	return i, err
 }
+
+const getTeamUploadRef = `-- name: GetTeamUploadRef :one
+SELECT id, upload_id, uploader_id, team_id, file_name, created_at, status FROM upload_refs WHERE upload_id = $1 AND team_id = $2
+`
+
+type GetTeamUploadRefParams struct {
+	UploadID int32 `json:"upload_id"`
+	TeamID   int32 `json:"team_id"`
+}
+
+func (q *Queries) GetTeamUploadRef(ctx context.Context, arg GetTeamUploadRefParams) (UploadRef, error) {
+	row := q.db.QueryRow(ctx, getTeamUploadRef, arg.UploadID, arg.TeamID)
+	var i UploadRef
+	err := row.Scan(
+		&i.ID,
+		&i.UploadID,
+		&i.UploaderID,
+		&i.TeamID,
+		&i.FileName,
+		&i.CreatedAt,
+		&i.Status,
+	)
+	return i, err
+}
+
+const getTeamUsage = `-- name: GetTeamUsage :one
+SELECT COALESCE(SUM(u.file_size), 0)::BIGINT AS bytes, COUNT(*) AS uploads
+  FROM uploads u
+  WHERE u.id IN (
+    SELECT v.upload_id FROM upload_versions v
+    INNER JOIN upload_refs r ON r.id = v.upload_ref_id
+    WHERE r.team_id = $1 AND r.status = 'active'
+  )
+`
+
+type GetTeamUsageRow struct {
+	Bytes   int64 `json:"bytes"`
+	Uploads int64 `json:"uploads"`
+}
+
+// every stored version counts, an upload kept by several refs of the team counts once
+func (q *Queries) GetTeamUsage(ctx context.Context, teamID int32) (GetTeamUsageRow, error) {
+	row := q.db.QueryRow(ctx, getTeamUsage, teamID)
+	var i GetTeamUsageRow
+	err := row.Scan(&i.Bytes, &i.Uploads)
+	return i, err
+}
+
+const getUploadByHash = `-- name: GetUploadByHash :one
+SELECT id, storage_key, file_sha256, file_size, file_mime_type, created_at, scan_status, scan_signature, scanned_at, orphaned_at FROM uploads WHERE file_sha256 = $1 AND file_size = $2
+`
+
+type GetUploadByHashParams struct {
+	FileSha256 string `json:"file_sha256"`
+	FileSize   int64  `json:"file_size"`
+}
+
+func (q *Queries) GetUploadByHash(ctx context.Context, arg GetUploadByHashParams) (Upload, error) {
+	row := q.db.QueryRow(ctx, getUploadByHash, arg.FileSha256, arg.FileSize)
+	var i Upload
+	err := row.Scan(
+		&i.ID,
+		&i.StorageKey,
+		&i.FileSha256,
+		&i.FileSize,
+		&i.FileMimeType,
+		&i.CreatedAt,
+		&i.ScanStatus,
+		&i.ScanSignature,
+		&i.ScannedAt,
+		&i.OrphanedAt,
+	)
+	return i, err
+}
+
+const getUploadByRefID = `-- name: GetUploadByRefID :one
+SELECT u.id, u.storage_key, u.file_sha256, u.file_size, u.file_mime_type, u.created_at, u.scan_status, u.scan_signature, u.scanned_at, u.orphaned_at FROM uploads u
+INNER JOIN upload_refs r ON r.upload_id = u.id
+WHERE r.id = $1 AND r.team_id = $2
+`
+
+type GetUploadByRefIDParams struct {
+	ID     int32 `json:"id"`
+	TeamID int32 `json:"team_id"`
+}
+
+func (q *Queries) GetUploadByRefID(ctx context.Context, arg GetUploadByRefIDParams) (Upload, error) {
+	row := q.db.QueryRow(ctx, getUploadByRefID, arg.ID, arg.TeamID)
+	var i Upload
+	err := row.Scan(
+		&i.ID,
+		&i.StorageKey,
+		&i.FileSha256,
+		&i.FileSize,
+		&i.FileMimeType,
+		&i.CreatedAt,
+		&i.ScanStatus,
+		&i.ScanSignature,
+		&i.ScannedAt,
+		&i.OrphanedAt,
+	)
+	return i, err
+}
+
+const getUploadPresign = `-- name: GetUploadPresign :one
+SELECT id, storage_key, team_id, uploader_id, file_name, file_mime_type, file_size, expires_at, created_at FROM upload_presigns
+WHERE storage_key = $1 AND team_id = $2 AND uploader_id = $3
+`
+
+type GetUploadPresignParams struct {
+	StorageKey string `json:"storage_key"`
+	TeamID     int32  `json:"team_id"`
+	UploaderID int32  `json:"uploader_id"`
+}
+
+func (q *Queries) GetUploadPresign(ctx context.Context, arg GetUploadPresignParams) (UploadPresign, error) {
+	row := q.db.QueryRow(ctx, getUploadPresign, arg.StorageKey, arg.TeamID, arg.UploaderID)
+	var i UploadPresign
+	err := row.Scan(
+		&i.ID,
+		&i.StorageKey,
+		&i.TeamID,
+		&i.UploaderID,
+		&i.FileName,
+		&i.FileMimeType,
+		&i.FileSize,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getUploadRef = `-- name: GetUploadRef :one
+SELECT id, upload_id, uploader_id, team_id, file_name, created_at, status FROM upload_refs WHERE id = $1 AND team_id = $2 FOR UPDATE
+`
+
+type GetUploadRefParams struct {
+	ID     int32 `json:"id"`
+	TeamID int32 `json:"team_id"`
+}
+
+func (q *Queries) GetUploadRef(ctx context.Context, arg GetUploadRefParams) (UploadRef, error) {
+	row := q.db.QueryRow(ctx, getUploadRef, arg.ID, arg.TeamID)
+	var i UploadRef
+	err := row.Scan(
+		&i.ID,
+		&i.UploadID,
+		&i.UploaderID,
+		&i.TeamID,
+		&i.FileName,
+		&i.CreatedAt,
+		&i.Status,
+	)
+	return i, err
+}
+
+const getUploadRefText = `-- name: GetUploadRefText :one
+SELECT x.content FROM upload_refs r
+  INNER JOIN upload_texts x ON x.upload_id = r.upload_id
+  WHERE r.team_id = $1 AND r.id = $2
+`
+
+type GetUploadRefTextParams struct {
+	TeamID int32 `json:"team_id"`
+	ID     int32 `json:"id"`
+}
+
+func (q *Queries) GetUploadRefText(ctx context.Context, arg GetUploadRefTextParams) (string, error) {
+	row := q.db.QueryRow(ctx, getUploadRefText, arg.TeamID, arg.ID)
+	var content string
+	err := row.Scan(&content)
+	return content, err
+}
+
+const getUploaderUsage = `-- name: GetUploaderUsage :one
+SELECT COALESCE(SUM(u.file_size), 0)::BIGINT AS bytes, COUNT(*) AS uploads
+  FROM uploads u
+  WHERE u.id IN (
+    SELECT v.upload_id FROM upload_versions v
+    INNER JOIN upload_refs r ON r.id = v.upload_ref_id
+    WHERE r.team_id = $1 AND v.uploader_id = $2 AND r.status = 'active'
+  )
+`
+
+type GetUploaderUsageParams struct {
+	TeamID     int32 `json:"team_id"`
+	UploaderID int32 `json:"uploader_id"`
+}
+
+type GetUploaderUsageRow struct {
+	Bytes   int64 `json:"bytes"`
+	Uploads int64 `json:"uploads"`
+}
+
+func (q *Queries) GetUploaderUsage(ctx context.Context, arg GetUploaderUsageParams) (GetUploaderUsageRow, error) {
+	row := q.db.QueryRow(ctx, getUploaderUsage, arg.TeamID, arg.UploaderID)
+	var i GetUploaderUsageRow
+	err := row.Scan(&i.Bytes, &i.Uploads)
+	return i, err
+}
+
+const isUploadCounted = `-- name: IsUploadCounted :one
+SELECT
+  EXISTS (
+    SELECT 1 FROM upload_versions v
+    INNER JOIN upload_refs r ON r.id = v.upload_ref_id
+    WHERE r.team_id = $1 AND v.upload_id = $2 AND r.status = 'active'
+  ) AS by_team,
+  EXISTS (
+    SELECT 1 FROM upload_versions v
+    INNER JOIN upload_refs r ON r.id = v.upload_ref_id
+    WHERE r.team_id = $1 AND v.upload_id = $2 AND v.uploader_id = $3 AND r.status = 'active'
+  ) AS by_uploader
+`
+
+type IsUploadCountedParams struct {
+	TeamID     int32 `json:"team_id"`
+	UploadID   int32 `json:"upload_id"`
+	UploaderID int32 `json:"uploader_id"`
+}
+
+type IsUploadCountedRow struct {
+	ByTeam     bool `json:"by_team"`
+	ByUploader bool `json:"by_uploader"`
+}
+
+// whether an upload already counts towards the team's and the uploader's usage
+func (q *Queries) IsUploadCounted(ctx context.Context, arg IsUploadCountedParams) (IsUploadCountedRow, error) {
+	row := q.db.QueryRow(ctx, isUploadCounted, arg.TeamID, arg.UploadID, arg.UploaderID)
+	var i IsUploadCountedRow
+	err := row.Scan(&i.ByTeam, &i.ByUploader)
+	return i, err
+}
+
+const listExpiredUploadPresigns = `-- name: ListExpiredUploadPresigns :many
+SELECT id, storage_key, team_id, uploader_id, file_name, file_mime_type, file_size, expires_at, created_at FROM upload_presigns
+WHERE expires_at < $1::TIMESTAMP
+ORDER BY id
+LIMIT $2
+`
+
+type ListExpiredUploadPresignsParams struct {
+	Cutoff time.Time `json:"cutoff"`
+	Lim    int32     `json:"lim"`
+}
+
+func (q *Queries) ListExpiredUploadPresigns(ctx context.Context, arg ListExpiredUploadPresignsParams) ([]UploadPresign, error) {
+	rows, err := q.db.Query(ctx, listExpiredUploadPresigns, arg.Cutoff, arg.Lim)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []UploadPresign
+	for rows.Next() {
+		var i UploadPresign
+		if err := rows.Scan(
+			&i.ID,
+			&i.StorageKey,
+			&i.TeamID,
+			&i.UploaderID,
+			&i.FileName,
+			&i.FileMimeType,
+			&i.FileSize,
+			&i.ExpiresAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOrphanedUploads = `-- name: ListOrphanedUploads :many
+SELECT u.id, u.storage_key, u.file_sha256, u.file_size, u.file_mime_type, u.created_at, u.scan_status, u.scan_signature, u.scanned_at, u.orphaned_at FROM uploads u
+WHERE COALESCE(u.orphaned_at, u.created_at) < $1::TIMESTAMP
+  AND NOT EXISTS (SELECT 1 FROM upload_refs r WHERE r.upload_id = u.id)
+  AND NOT EXISTS (SELECT 1 FROM upload_versions v WHERE v.upload_id = u.id)
+ORDER BY u.id
+LIMIT $2
+`
+
+type ListOrphanedUploadsParams struct {
+	Cutoff time.Time `json:"cutoff"`
+	Lim    int32     `json:"lim"`
+}
+
+// uploads without refs, orphaned (or created, if they never had a ref) before the cutoff
+func (q *Queries) ListOrphanedUploads(ctx context.Context, arg ListOrphanedUploadsParams) ([]Upload, error) {
+	rows, err := q.db.Query(ctx, listOrphanedUploads, arg.Cutoff, arg.Lim)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Upload
+	for rows.Next() {
+		var i Upload
+		if err := rows.Scan(
+			&i.ID,
+			&i.StorageKey,
+			&i.FileSha256,
+			&i.FileSize,
+			&i.FileMimeType,
+			&i.CreatedAt,
+			&i.ScanStatus,
+			&i.ScanSignature,
+			&i.ScannedAt,
+			&i.OrphanedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRejectedUploads = `-- name: ListRejectedUploads :many
+SELECT
+  r.id,
+  r.file_name,
+  r.uploader_id,
+  r.created_at,
+  u.scan_signature,
+  u.scanned_at
+  FROM upload_refs r
+  INNER JOIN uploads u ON u.id = r.upload_id
+  WHERE r.team_id = $1 AND r.status = 'rejected'
+  ORDER BY r.created_at DESC, r.id DESC
+`
+
+type ListRejectedUploadsRow struct {
+	ID            int32      `json:"id"`
+	FileName      string     `json:"file_name"`
+	UploaderID    int32      `json:"uploader_id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ScanSignature string     `json:"scan_signature"`
+	ScannedAt     *time.Time `json:"scanned_at"`
+}
+
+func (q *Queries) ListRejectedUploads(ctx context.Context, teamID int32) ([]ListRejectedUploadsRow, error) {
+	rows, err := q.db.Query(ctx, listRejectedUploads, teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListRejectedUploadsRow
+	for rows.Next() {
+		var i ListRejectedUploadsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.FileName,
+			&i.UploaderID,
+			&i.CreatedAt,
+			&i.ScanSignature,
+			&i.ScannedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTeamUploadRefTags = `-- name: ListTeamUploadRefTags :many
+SELECT t.upload_ref_id, t.key_id, t.value_id FROM upload_ref_tags t
+  INNER JOIN upload_refs r ON r.id = t.upload_ref_id
+  WHERE r.team_id = $1 AND r.status = 'active'
+  ORDER BY t.upload_ref_id, t.key_id
+`
+
+type ListTeamUploadRefTagsRow struct {
+	UploadRefID int32 `json:"upload_ref_id"`
+	KeyID       int32 `json:"key_id"`
+	ValueID     int32 `json:"value_id"`
+}
+
+func (q *Queries) ListTeamUploadRefTags(ctx context.Context, teamID int32) ([]ListTeamUploadRefTagsRow, error) {
+	rows, err := q.db.Query(ctx, listTeamUploadRefTags, teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTeamUploadRefTagsRow
+	for rows.Next() {
+		var i ListTeamUploadRefTagsRow
+		if err := rows.Scan(&i.UploadRefID, &i.KeyID, &i.ValueID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTeamUploadRefs = `-- name: ListTeamUploadRefs :many
+SELECT
+  r.id,
+  r.file_name,
+  r.created_at,
+  u.storage_key,
+  u.file_sha256,
+  u.file_size,
+  u.file_mime_type,
+  us.email AS uploader_email
+  FROM upload_refs r
+  INNER JOIN uploads u ON u.id = r.upload_id
+  INNER JOIN users us ON us.id = r.uploader_id
+  WHERE r.team_id = $1 AND r.status = 'active'
+  ORDER BY r.id
+`
+
+type ListTeamUploadRefsRow struct {
+	ID            int32     `json:"id"`
+	FileName      string    `json:"file_name"`
+	CreatedAt     time.Time `json:"created_at"`
+	StorageKey    string    `json:"storage_key"`
+	FileSha256    string    `json:"file_sha256"`
+	FileSize      int64     `json:"file_size"`
+	FileMimeType  string    `json:"file_mime_type"`
+	UploaderEmail string    `json:"uploader_email"`
+}
+
+func (q *Queries) ListTeamUploadRefs(ctx context.Context, teamID int32) ([]ListTeamUploadRefsRow, error) {
+	rows, err := q.db.Query(ctx, listTeamUploadRefs, teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTeamUploadRefsRow
+	for rows.Next() {
+		var i ListTeamUploadRefsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.FileName,
+			&i.CreatedAt,
+			&i.StorageKey,
+			&i.FileSha256,
+			&i.FileSize,
+			&i.FileMimeType,
+			&i.UploaderEmail,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUploadDerivativeKeys = `-- name: ListUploadDerivativeKeys :many
+SELECT storage_key FROM upload_derivatives WHERE upload_id = $1
+`
+
+func (q *Queries) ListUploadDerivativeKeys(ctx context.Context, uploadID int32) ([]string, error) {
+	rows, err := q.db.Query(ctx, listUploadDerivativeKeys, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var storage_key string
+		if err := rows.Scan(&storage_key); err != nil {
+			return nil, err
+		}
+		items = append(items, storage_key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUploadDerivatives = `-- name: ListUploadDerivatives :many
+SELECT d.id, d.upload_id, d.kind, d.page, d.storage_key, d.mime_type, d.width, d.height, d.created_at FROM upload_derivatives d
+INNER JOIN upload_refs r ON r.upload_id = d.upload_id
+WHERE r.id = $1 AND r.team_id = $2
+ORDER BY d.kind, d.page
+`
+
+type ListUploadDerivativesParams struct {
+	ID     int32 `json:"id"`
+	TeamID int32 `json:"team_id"`
+}
+
+func (q *Queries) ListUploadDerivatives(ctx context.Context, arg ListUploadDerivativesParams) ([]UploadDerivative, error) {
+	rows, err := q.db.Query(ctx, listUploadDerivatives, arg.ID, arg.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []UploadDerivative
+	for rows.Next() {
+		var i UploadDerivative
+		if err := rows.Scan(
+			&i.ID,
+			&i.UploadID,
+			&i.Kind,
+			&i.Page,
+			&i.StorageKey,
+			&i.MimeType,
+			&i.Width,
+			&i.Height,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUploaderUsage = `-- name: ListUploaderUsage :many
+SELECT
+  s.uploader_id,
+  usr.name AS uploader_name,
+  SUM(u.file_size)::BIGINT AS bytes,
+  COUNT(*) AS uploads
+  FROM (
+    SELECT DISTINCT v.uploader_id, v.upload_id FROM upload_versions v
+    INNER JOIN upload_refs r ON r.id = v.upload_ref_id
+    WHERE r.team_id = $1 AND r.status = 'active'
+  ) s
+  INNER JOIN uploads u ON u.id = s.upload_id
+  INNER JOIN users usr ON usr.id = s.uploader_id
+  GROUP BY s.uploader_id, usr.name
+  ORDER BY bytes DESC, s.uploader_id
+`
+
+type ListUploaderUsageRow struct {
+	UploaderID   int32  `json:"uploader_id"`
+	UploaderName string `json:"uploader_name"`
+	Bytes        int64  `json:"bytes"`
+	Uploads      int64  `json:"uploads"`
+}
+
+func (q *Queries) ListUploaderUsage(ctx context.Context, teamID int32) ([]ListUploaderUsageRow, error) {
+	rows, err := q.db.Query(ctx, listUploaderUsage, teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUploaderUsageRow
+	for rows.Next() {
+		var i ListUploaderUsageRow
+		if err := rows.Scan(
+			&i.UploaderID,
+			&i.UploaderName,
+			&i.Bytes,
+			&i.Uploads,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUploads = `-- name: ListUploads :many
+SELECT
+  r.id,
+  r.file_name,
+  r.uploader_id,
+  r.created_at,
+  u.storage_key,
+  u.file_mime_type,
+  u.file_size,
+  COALESCE((SELECT v.version FROM upload_versions v WHERE v.upload_ref_id = r.id AND v.upload_id = r.upload_id), 1)::INTEGER AS version,
+  (SELECT COUNT(*) FROM upload_versions v WHERE v.upload_ref_id = r.id) AS version_count,
+  (CASE WHEN $1::TEXT = '' THEN 0 ELSE ts_rank(x.search_vector, query) END)::REAL AS rank,
+  (CASE WHEN $1::TEXT = '' OR x.content IS NULL THEN ''
+    -- the text comes from untrusted files, only the highlighting is markup
+    ELSE ts_headline('english', REPLACE(REPLACE(REPLACE(x.content, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), query, 'MaxFragments=2, MaxWords=20, MinWords=5, StartSel=<b>, StopSel=</b>')
+  END)::TEXT AS snippet,
+  COALESCE(
+    (
+      SELECT JSONB_AGG(
+        JSONB_BUILD_OBJECT(
+          'key_id', t.key_id,
+          'key', k.name,
+          'value_id', t.value_id,
+          'value', v.value
+        )
+        ORDER BY k.name
+      )
+      FROM upload_ref_tags t
+      INNER JOIN tag_keys k ON k.id = t.key_id
+      INNER JOIN tag_values v ON v.id = t.value_id
+      WHERE t.upload_ref_id = r.id AND k.archived_at IS NULL
+    ),
+    '[]'::JSONB
+  ) AS tags
+  FROM upload_refs r
+  INNER JOIN uploads u ON u.id = r.upload_id
+  LEFT JOIN upload_texts x ON x.upload_id = u.id
+  CROSS JOIN websearch_to_tsquery('english', $1::TEXT) AS query
+  WHERE r.team_id = $2
+    AND r.status = 'active'
+    -- full-text search, skipped when q is empty
+    AND ($1::TEXT = '' OR x.search_vector @@ query)
+    -- every requested value must be tagged on the upload
+    AND (
+      SELECT COUNT(*) FROM upload_ref_tags t
+      WHERE t.upload_ref_id = r.id AND t.value_id = ANY($3::INTEGER[])
+    ) = CARDINALITY($3::INTEGER[])
+    -- every requested range must be satisfied by a number-typed tag of the upload
+    AND NOT EXISTS (
+      SELECT 1
+      FROM UNNEST(
+        $4::INTEGER[],
+        $5::DOUBLE PRECISION[],
+        $6::DOUBLE PRECISION[]
+      ) AS rg(key_id, min_value, max_value)
+      WHERE NOT EXISTS (
+        SELECT 1 FROM upload_ref_tags t
+        INNER JOIN tag_values v ON v.id = t.value_id
+        WHERE t.upload_ref_id = r.id
+          AND t.key_id = rg.key_id
+          AND v.num_value BETWEEN rg.min_value AND rg.max_value
+      )
+    )
+  ORDER BY rank DESC, r.created_at DESC, r.id DESC
+  LIMIT $7 OFFSET $8
+`
+
+type ListUploadsParams struct {
+	Q           string    `json:"q"`
+	TeamID      int32     `json:"team_id"`
+	ValueIds    []int32   `json:"value_ids"`
+	RangeKeyIds []int32   `json:"range_key_ids"`
+	RangeMins   []float64 `json:"range_mins"`
+	RangeMaxs   []float64 `json:"range_maxs"`
+	Lim         int32     `json:"lim"`
+	Off         int32     `json:"off"`
+}
+
+type ListUploadsRow struct {
+	ID           int32     `json:"id"`
+	FileName     string    `json:"file_name"`
+	UploaderID   int32     `json:"uploader_id"`
+	CreatedAt    time.Time `json:"created_at"`
+	StorageKey   string    `json:"storage_key"`
+	FileMimeType string    `json:"file_mime_type"`
+	FileSize     int64     `json:"file_size"`
+	Version      int32     `json:"version"`
+	VersionCount int64     `json:"version_count"`
+	Rank         float32   `json:"rank"`
+	Snippet      string    `json:"snippet"`
+	Tags         []byte    `json:"tags"`
+}
+
+func (q *Queries) ListUploads(ctx context.Context, arg ListUploadsParams) ([]ListUploadsRow, error) {
+	rows, err := q.db.Query(ctx, listUploads,
+		arg.Q,
+		arg.TeamID,
+		arg.ValueIds,
+		arg.RangeKeyIds,
+		arg.RangeMins,
+		arg.RangeMaxs,
+		arg.Lim,
+		arg.Off,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUploadsRow
+	for rows.Next() {
+		var i ListUploadsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.FileName,
+			&i.UploaderID,
+			&i.CreatedAt,
+			&i.StorageKey,
+			&i.FileMimeType,
+			&i.FileSize,
+			&i.Version,
+			&i.VersionCount,
+			&i.Rank,
+			&i.Snippet,
+			&i.Tags,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockOrphanedUpload = `-- name: LockOrphanedUpload :one
+SELECT u.id, u.storage_key, u.file_sha256, u.file_size, u.file_mime_type, u.created_at, u.scan_status, u.scan_signature, u.scanned_at, u.orphaned_at FROM uploads u
+WHERE u.id = $1
+  AND COALESCE(u.orphaned_at, u.created_at) < $2::TIMESTAMP
+  AND NOT EXISTS (SELECT 1 FROM upload_refs r WHERE r.upload_id = u.id)
+  AND NOT EXISTS (SELECT 1 FROM upload_versions v WHERE v.upload_id = u.id)
+FOR UPDATE SKIP LOCKED
+`
+
+type LockOrphanedUploadParams struct {
+	ID     int32     `json:"id"`
+	Cutoff time.Time `json:"cutoff"`
+}
+
+// skips uploads locked by a concurrent GetOrCreateUpload, which is about to add a ref
+func (q *Queries) LockOrphanedUpload(ctx context.Context, arg LockOrphanedUploadParams) (Upload, error) {
+	row := q.db.QueryRow(ctx, lockOrphanedUpload, arg.ID, arg.Cutoff)
+	var i Upload
+	err := row.Scan(
+		&i.ID,
+		&i.StorageKey,
+		&i.FileSha256,
+		&i.FileSize,
+		&i.FileMimeType,
+		&i.CreatedAt,
+		&i.ScanStatus,
+		&i.ScanSignature,
+		&i.ScannedAt,
+		&i.OrphanedAt,
+	)
+	return i, err
+}
+
+const markUploadOrphaned = `-- name: MarkUploadOrphaned :execrows
+UPDATE uploads SET orphaned_at = NOW()
+WHERE id = $1
+  AND NOT EXISTS (SELECT 1 FROM upload_refs r WHERE r.upload_id = $1)
+  AND NOT EXISTS (SELECT 1 FROM upload_versions v WHERE v.upload_id = $1)
+`
+
+// only marks the upload if no refs or versions are left
+func (q *Queries) MarkUploadOrphaned(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.Exec(ctx, markUploadOrphaned, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const quarantineUpload = `-- name: QuarantineUpload :exec
+UPDATE uploads SET storage_key = $2 WHERE id = $1
+`
+
+type QuarantineUploadParams struct {
+	ID         int32  `json:"id"`
+	StorageKey string `json:"storage_key"`
+}
+
+// points an upload found infected after it was stored at its quarantined object
+func (q *Queries) QuarantineUpload(ctx context.Context, arg QuarantineUploadParams) error {
+	_, err := q.db.Exec(ctx, quarantineUpload, arg.ID, arg.StorageKey)
+	return err
+}
+
+const rejectUploadRefs = `-- name: RejectUploadRefs :many
+UPDATE upload_refs SET status = 'rejected'
+WHERE upload_id = $1 AND status = 'active'
+RETURNING id, upload_id, uploader_id, team_id, file_name, created_at, status
+`
+
+// rejects the active refs of an infected upload in every team
+func (q *Queries) RejectUploadRefs(ctx context.Context, uploadID int32) ([]UploadRef, error) {
+	rows, err := q.db.Query(ctx, rejectUploadRefs, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []UploadRef
+	for rows.Next() {
+		var i UploadRef
+		if err := rows.Scan(
+			&i.ID,
+			&i.UploadID,
+			&i.UploaderID,
+			&i.TeamID,
+			&i.FileName,
+			&i.CreatedAt,
+			&i.Status,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const renameUploadRef = `-- name: RenameUploadRef :one
+UPDATE upload_refs SET file_name = $2 WHERE id = $1
+RETURNING id, upload_id, uploader_id, team_id, file_name, created_at, status
+`
+
+type RenameUploadRefParams struct {
+	ID       int32  `json:"id"`
+	FileName string `json:"file_name"`
+}
+
+func (q *Queries) RenameUploadRef(ctx context.Context, arg RenameUploadRefParams) (UploadRef, error) {
+	row := q.db.QueryRow(ctx, renameUploadRef, arg.ID, arg.FileName)
+	var i UploadRef
+	err := row.Scan(
+		&i.ID,
+		&i.UploadID,
+		&i.UploaderID,
+		&i.TeamID,
+		&i.FileName,
+		&i.CreatedAt,
+		&i.Status,
+	)
+	return i, err
+}
+
+const upsertUploadDerivative = `-- name: UpsertUploadDerivative :one
+INSERT INTO upload_derivatives (upload_id, kind, page, storage_key, mime_type, width, height)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (upload_id, kind, page) DO UPDATE
+SET storage_key = EXCLUDED.storage_key,
+  mime_type = EXCLUDED.mime_type,
+  width = EXCLUDED.width,
+  height = EXCLUDED.height,
+  created_at = NOW()
+RETURNING id, upload_id, kind, page, storage_key, mime_type, width, height, created_at
+`
+
+type UpsertUploadDerivativeParams struct {
+	UploadID   int32  `json:"upload_id"`
+	Kind       string `json:"kind"`
+	Page       int32  `json:"page"`
+	StorageKey string `json:"storage_key"`
+	MimeType   string `json:"mime_type"`
+	Width      int32  `json:"width"`
+	Height     int32  `json:"height"`
+}
+
+func (q *Queries) UpsertUploadDerivative(ctx context.Context, arg UpsertUploadDerivativeParams) (UploadDerivative, error) {
+	row := q.db.QueryRow(ctx, upsertUploadDerivative,
+		arg.UploadID,
+		arg.Kind,
+		arg.Page,
+		arg.StorageKey,
+		arg.MimeType,
+		arg.Width,
+		arg.Height,
+	)
+	var i UploadDerivative
+	err := row.Scan(
+		&i.ID,
+		&i.UploadID,
+		&i.Kind,
+		&i.Page,
+		&i.StorageKey,
+		&i.MimeType,
+		&i.Width,
+		&i.Height,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const upsertUploadText = `-- name: UpsertUploadText :exec
+INSERT INTO upload_texts (upload_id, content)
+VALUES ($1, $2)
+ON CONFLICT (upload_id) DO UPDATE
+SET content = EXCLUDED.content, created_at = NOW()
+`
+
+type UpsertUploadTextParams struct {
+	UploadID int32  `json:"upload_id"`
+	Content  string `json:"content"`
+}
+
+func (q *Queries) UpsertUploadText(ctx context.Context, arg UpsertUploadTextParams) error {
+	_, err := q.db.Exec(ctx, upsertUploadText, arg.UploadID, arg.Content)
+	return err
+}
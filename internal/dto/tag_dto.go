@@ -31,16 +31,46 @@ type UpdateFiltersBody struct {
 
 type CreateTagKeyBody struct {
 	Name     string         `json:"name" validate:"required,min=2,max=100"`
-	DataType db.TagDataType `json:"data_type" validate:"required,min=2,max=100"`
+	DataType db.TagDataType `json:"data_type" validate:"required,oneof=string number boolean"`
+	// closed (default) keys accept predefined values only
+	Mode             db.TagKeyMode `json:"mode" validate:"omitempty,oneof=closed open"`
+	RequiresApproval bool          `json:"requires_approval"`
 }
 
 type UpdateTagKeyBody struct {
 	Name string `json:"name" validate:"required,min=2,max=100"`
 	// data type NOT allowed
+	// left unchanged when omitted
+	Mode             *db.TagKeyMode `json:"mode" validate:"omitempty,oneof=closed open"`
+	RequiresApproval *bool          `json:"requires_approval"`
 }
 
 type CreateTagValueBody struct {
-	Value string `json:"value" validate:"required,min=2,max=100"`
+	// validated against the key's data type by the service
+	Value string `json:"value" validate:"required,min=1,max=100"`
+}
+
+type UpdateTagValueBody struct {
+	Value string `json:"value" validate:"required,min=1,max=100"`
+}
+
+type MergeTagValueBody struct {
+	TargetID int32 `json:"target_id" validate:"required"`
+}
+
+type ReorderTagValuesBody struct {
+	ValueIDs []int32 `json:"value_ids" validate:"required"`
+}
+
+type SetTagValueParentsBody struct {
+	ParentIDs []int32 `json:"parent_ids"`
+}
+
+type SuggestTagsBody struct {
+	// name of the file to tag, defaults to the upload's name
+	Name string `json:"name" validate:"max=255"`
+	// an upload of the team whose extracted text is matched as well
+	UploadID int32 `json:"upload_id" validate:"omitempty,min=1"`
 }
 
 // ------ request ------
@@ -65,6 +95,16 @@ type UpdateTagKeyRequest struct {
 
 type DeleteTagKeyRequest struct {
 	TagPathParams
+	// permanently delete the key, its values and upload tags instead of archiving it
+	Force bool `query:"force"`
+}
+
+type RestoreTagKeyRequest struct {
+	TagPathParams
+}
+
+type GetTagKeyImpactRequest struct {
+	TagPathParams
 }
 
 type GetTagValuesRequest struct {
@@ -80,6 +120,49 @@ type DeleteTagValueRequest struct {
 	TagValuePathParams
 }
 
+type UpdateTagValueRequest struct {
+	TagValuePathParams
+	UpdateTagValueBody
+}
+
+type MergeTagValueRequest struct {
+	TagValuePathParams
+	MergeTagValueBody
+}
+
+type ReorderTagValuesRequest struct {
+	TagPathParams
+	ReorderTagValuesBody
+}
+
+type ListTagOptionsRequest struct {
+	TagPathParams
+	// already chosen values of other keys
+	Values []int32 `query:"value"`
+}
+
+type SetTagValueParentsRequest struct {
+	TagValuePathParams
+	SetTagValueParentsBody
+}
+
+type ListPendingTagValuesRequest struct {
+	TeamPathParams
+}
+
+type ApproveTagValueRequest struct {
+	TagValuePathParams
+}
+
+type RejectTagValueRequest struct {
+	TagValuePathParams
+}
+
+type SuggestTagsRequest struct {
+	TeamPathParams
+	SuggestTagsBody
+}
+
 // ------ response ------
 type ListFiltersResponse struct {
 	Data []db.Tag `json:"data"`
@@ -97,6 +180,14 @@ type DeleteTagKeyResponse struct {
 	Data *db.TagKey `json:"data"`
 }
 
+type RestoreTagKeyResponse struct {
+	Data *db.TagKey `json:"data"`
+}
+
+type GetTagKeyImpactResponse struct {
+	Data *db.GetTagKeyImpactRow `json:"data"`
+}
+
 type CreateTagValueResponse struct {
 	Data *db.TagValue `json:"data"`
 }
@@ -105,6 +196,45 @@ type UpdateTagValueResponse struct {
 	Data *db.TagValue `json:"data"`
 }
 
+type MergeTagValueResponse struct {
+	Data *db.TagValue `json:"data"`
+}
+
 type DeleteTagValueResponse struct {
 	Data *db.TagValue `json:"data"`
 }
+
+type ListPendingTagValuesResponse struct {
+	Data []db.TagValue `json:"data"`
+}
+
+type ApproveTagValueResponse struct {
+	Data *db.TagValue `json:"data"`
+}
+
+type RejectTagValueResponse struct {
+	Data *db.TagValue `json:"data"`
+}
+
+type ListTagOptionsResponse struct {
+	Data []db.TagValue `json:"data"`
+}
+
+type TagSuggestionResponse struct {
+	ValueID int32   `json:"value_id"`
+	Value   string  `json:"value"`
+	Score   float64 `json:"score"`
+	// "name", "text" and "related" when often tagged along with another suggestion
+	Reasons []string `json:"reasons"`
+}
+
+type TagKeySuggestionsResponse struct {
+	KeyID   int32  `json:"key_id"`
+	KeyName string `json:"key_name"`
+	// best first, empty when no value fits
+	Candidates []TagSuggestionResponse `json:"candidates"`
+}
+
+type SuggestTagsResponse struct {
+	Data []TagKeySuggestionsResponse `json:"data"`
+}
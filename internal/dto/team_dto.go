@@ -1,6 +1,11 @@
 package dto
 
-import "github.com/skndash96/lastnight-backend/internal/db"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/skndash96/lastnight-backend/internal/db"
+)
 
 type GetTeamsResponse struct {
 	Data []db.GetTeamsByUserIDRow `json:"data"`
@@ -9,3 +14,69 @@ type GetTeamsResponse struct {
 type JoinTeamResponse struct {
 	Data *db.Team `json:"data"`
 }
+
+type TeamJobPathParams struct {
+	TeamPathParams
+	JobID int32 `param:"jobID" validate:"required"`
+}
+
+type StartTeamExportBody struct {
+	// also export the files of uploads, not only their metadata
+	IncludeObjects bool `json:"include_objects"`
+}
+
+type StartTeamExportRequest struct {
+	TeamPathParams
+	StartTeamExportBody
+}
+
+type StartTeamImportRequest struct {
+	TeamPathParams
+}
+
+type ListTeamJobsRequest struct {
+	TeamPathParams
+}
+
+type GetTeamJobRequest struct {
+	TeamJobPathParams
+}
+
+type TeamJobResponse struct {
+	ID             int32            `json:"id"`
+	Kind           db.TeamJobKind   `json:"kind"`
+	Status         db.TeamJobStatus `json:"status"`
+	IncludeObjects bool             `json:"include_objects"`
+	// counts of what was exported or imported, once the job finished
+	Report     json.RawMessage `json:"report" swaggertype:"object"`
+	Error      string          `json:"error,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	FinishedAt *time.Time      `json:"finished_at"`
+	// download URL of an export's archive, until it is deleted
+	Url string `json:"url,omitempty"`
+}
+
+type TeamJobDataResponse struct {
+	Data TeamJobResponse `json:"data"`
+}
+
+type ListTeamJobsResponse struct {
+	Data []TeamJobResponse `json:"data"`
+}
+
+type TeamNotificationPathParams struct {
+	TeamPathParams
+	NotificationID int32 `param:"notificationID" validate:"required"`
+}
+
+type ListTeamNotificationsRequest struct {
+	TeamPathParams
+}
+
+type MarkTeamNotificationReadRequest struct {
+	TeamNotificationPathParams
+}
+
+type ListTeamNotificationsResponse struct {
+	Data []db.TeamNotification `json:"data"`
+}
@@ -1,5 +1,50 @@
 package dto
 
+import (
+	"time"
+
+	"github.com/skndash96/lastnight-backend/internal/db"
+)
+
+// ------ path ------
+type UploadPathParams struct {
+	TeamPathParams
+	UploadID int32 `param:"uploadID" validate:"required"`
+}
+
+type MultipartUploadPathParams struct {
+	TeamPathParams
+	MultipartUploadID int32 `param:"multipartID" validate:"required"`
+}
+
+// ------ query ------
+type ListUploadsQuery struct {
+	// full-text search over the contents of uploads
+	Q string `query:"q"`
+	// tag value IDs, all of which must be present on an upload
+	Values []int32 `query:"value"`
+	// number ranges as "keyID:min:max", bounds inclusive
+	Ranges []string `query:"range"`
+	Limit  int32    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Offset int32    `query:"offset" validate:"omitempty,min=0"`
+}
+
+type ExportUploadsQuery struct {
+	// same filters as ListUploadsQuery, every match is exported
+	Q      string   `query:"q"`
+	Values []int32  `query:"value"`
+	Ranges []string `query:"range"`
+}
+
+type UploadQuery struct {
+	// file name, defaults to the name of the multipart file part
+	Name string `query:"name"`
+	// JSON array of tags, see UploadTagBody
+	Tags string `query:"tags"`
+	// upload ID to add the file to as its next version, instead of a new upload
+	VersionOf int32 `query:"version_of" validate:"omitempty,min=1"`
+}
+
 // ------ body ------
 type PresignUploadBody struct {
 	Name     string `json:"name"`
@@ -7,17 +52,108 @@ type PresignUploadBody struct {
 	Size     int64  `json:"size"`
 }
 
+type UploadTagBody struct {
+	KeyID int32 `json:"keyID"`
+	// either an existing value, or a free-form value for open keys
+	ValueID int32  `json:"valueID"`
+	Value   string `json:"value"`
+}
+
 type CompleteUploadBody struct {
-	Key      string `json:"key"`
-	Name     string `json:"name"`
-	MimeType string `json:"mime_type"`
-	Tags     []struct {
-		KeyID   int32 `json:"keyID"`
-		ValueID int32 `json:"valueID"`
-	} `json:"tags"`
+	Key  string          `json:"key"`
+	Name string          `json:"name"`
+	Tags []UploadTagBody `json:"tags"`
+	// upload ID to add the file to as its next version, instead of a new upload
+	VersionOf int32 `json:"version_of" validate:"omitempty,min=1"`
+}
+
+type PresignImageUploadBody struct {
+	Images []struct {
+		MimeType string `json:"mime_type"`
+		Size     int64  `json:"size"`
+	} `json:"images" validate:"required,min=1"`
+}
+
+type CompleteImageUploadBody struct {
+	SetID string `json:"set_id" validate:"required"`
+	// image keys in page order
+	Keys []string        `json:"keys" validate:"required,min=1"`
+	Name string          `json:"name" validate:"required"`
+	Tags []UploadTagBody `json:"tags"`
+	// upload ID to add the file to as its next version, instead of a new upload
+	VersionOf int32 `json:"version_of" validate:"omitempty,min=1"`
+}
+
+type UpdateUploadSettingsBody struct {
+	// sniffed content types accepted on upload, null restores the server default.
+	// HTML and XML, and types uploads are never detected as, are refused
+	AllowedMimeTypes []string `json:"allowed_mime_types" validate:"omitempty,min=1"`
+	// file name extensions such as ".pdf", null accepts any
+	AllowedExtensions []string `json:"allowed_extensions" validate:"omitempty,min=1"`
+	// bytes per file, null falls back to the server limit which also caps this one
+	MaxFileSize *int64 `json:"max_file_size" validate:"omitempty,min=1"`
+}
+
+type CheckUploadBody struct {
+	// SHA-256 of the file, hex or base64 encoded
+	SHA256 string `json:"sha256" validate:"required"`
+	Size   int64  `json:"size" validate:"required,min=1"`
+	// when set and the team can download the file already, it is added under this name
+	Name string          `json:"name"`
+	Tags []UploadTagBody `json:"tags"`
+	// upload ID to add the file to as its next version, instead of a new upload
+	VersionOf int32 `json:"version_of" validate:"omitempty,min=1"`
+}
+
+type CreateMultipartUploadBody struct {
+	Name     string `json:"name" validate:"required"`
+	MimeType string `json:"mime_type" validate:"required"`
+	Size     int64  `json:"size" validate:"required,min=1"`
+}
+
+type PresignUploadPartsBody struct {
+	PartNumbers []int32 `json:"part_numbers" validate:"required,min=1,max=1000"`
+}
+
+type CompleteMultipartUploadBody struct {
+	Tags []UploadTagBody `json:"tags"`
+	// upload ID to add the file to as its next version, instead of a new upload
+	VersionOf int32 `json:"version_of" validate:"omitempty,min=1"`
+}
+
+type UpdateQuotasBody struct {
+	// bytes the team may store, null is unlimited
+	TeamQuota *int64 `json:"team_quota" validate:"omitempty,min=1"`
+	// bytes each uploader may store within the team, null is unlimited
+	UploaderQuota *int64 `json:"uploader_quota" validate:"omitempty,min=1"`
+}
+
+type UpdateUploadBody struct {
+	FileName *string `json:"file_name" validate:"omitempty,min=1"`
+	// replaces every tag of the upload when set
+	Tags *[]UploadTagBody `json:"tags"`
+}
+
+type SetCurrentUploadVersionBody struct {
+	Version int32 `json:"version" validate:"required,min=1"`
 }
 
 // ------ request ------
+type ListUploadsRequest struct {
+	TeamPathParams
+	ListUploadsQuery
+}
+
+type ExportUploadsRequest struct {
+	TeamPathParams
+	ExportUploadsQuery
+}
+
+type UploadRequest struct {
+	TeamPathParams
+	UploadQuery
+}
+
 type PresignUploadRequest struct {
 	TeamPathParams
 	PresignUploadBody
@@ -28,8 +164,204 @@ type CompleteUploadRequest struct {
 	CompleteUploadBody
 }
 
+type PresignImageUploadRequest struct {
+	TeamPathParams
+	PresignImageUploadBody
+}
+
+type CompleteImageUploadRequest struct {
+	TeamPathParams
+	CompleteImageUploadBody
+}
+
+type GetUploadSettingsRequest struct {
+	TeamPathParams
+}
+
+type UpdateUploadSettingsRequest struct {
+	TeamPathParams
+	UpdateUploadSettingsBody
+}
+
+type GetUsageRequest struct {
+	TeamPathParams
+}
+
+type UpdateQuotasRequest struct {
+	TeamPathParams
+	UpdateQuotasBody
+}
+
+type ListUploadDerivativesRequest struct {
+	UploadPathParams
+}
+
+type RegenerateUploadDerivativesRequest struct {
+	UploadPathParams
+}
+
+type ListRejectedUploadsRequest struct {
+	TeamPathParams
+}
+
+type UpdateUploadRequest struct {
+	UploadPathParams
+	UpdateUploadBody
+}
+
+type DeleteUploadRequest struct {
+	UploadPathParams
+}
+
+type ListUploadVersionsRequest struct {
+	UploadPathParams
+}
+
+type SetCurrentUploadVersionRequest struct {
+	UploadPathParams
+	SetCurrentUploadVersionBody
+}
+
+type CheckUploadRequest struct {
+	TeamPathParams
+	CheckUploadBody
+}
+
+type CreateMultipartUploadRequest struct {
+	TeamPathParams
+	CreateMultipartUploadBody
+}
+
+type ListMultipartUploadsRequest struct {
+	TeamPathParams
+}
+
+type GetMultipartUploadRequest struct {
+	MultipartUploadPathParams
+}
+
+type PresignUploadPartsRequest struct {
+	MultipartUploadPathParams
+	PresignUploadPartsBody
+}
+
+type CompleteMultipartUploadRequest struct {
+	MultipartUploadPathParams
+	CompleteMultipartUploadBody
+}
+
+type AbortMultipartUploadRequest struct {
+	MultipartUploadPathParams
+}
+
 // ------ response ------
+type ListUploadsResponse struct {
+	Data []db.UploadItem `json:"data"`
+}
+
+type ListRejectedUploadsResponse struct {
+	Data []db.ListRejectedUploadsRow `json:"data"`
+}
+
+type UpdateUploadResponse struct {
+	Data *db.UploadRef `json:"data"`
+}
+
+type CheckUploadResponse struct {
+	// the team can download the file already, as a version of one of its
+	// uploads, and it need not be uploaded
+	Exists bool `json:"exists"`
+	// the team already has the file
+	InTeam bool `json:"in_team"`
+	// the team's existing upload, or the upload added by this check
+	Data *db.UploadRef `json:"data"`
+}
+
 type PresignUploadResponse struct {
 	Url    string            `json:"url"`
 	Fields map[string]string `json:"fields"`
 }
+
+type PresignImagePartResponse struct {
+	Key    string            `json:"key"`
+	Url    string            `json:"url"`
+	Fields map[string]string `json:"fields"`
+}
+
+type PresignImageUploadResponse struct {
+	SetID string                     `json:"set_id"`
+	Parts []PresignImagePartResponse `json:"parts"`
+}
+
+type UploadSettingsResponse struct {
+	AllowedMimeTypes  []string `json:"allowed_mime_types"`
+	IsDefault         bool     `json:"is_default"`
+	AllowedExtensions []string `json:"allowed_extensions"`
+	MaxFileSize       *int64   `json:"max_file_size"`
+}
+
+type UsageResponse struct {
+	Bytes         int64                     `json:"bytes"`
+	Uploads       int64                     `json:"uploads"`
+	TeamQuota     *int64                    `json:"team_quota"`
+	UploaderQuota *int64                    `json:"uploader_quota"`
+	Uploaders     []db.ListUploaderUsageRow `json:"uploaders"`
+}
+
+type UploadDerivativeResponse struct {
+	// thumbnail or preview
+	Kind   string `json:"kind"`
+	Page   int32  `json:"page"`
+	Width  int32  `json:"width"`
+	Height int32  `json:"height"`
+	Url    string `json:"url"`
+}
+
+type ListUploadDerivativesResponse struct {
+	Data []UploadDerivativeResponse `json:"data"`
+}
+
+type UploadVersionResponse struct {
+	Version      int32     `json:"version"`
+	FileName     string    `json:"file_name"`
+	UploaderID   int32     `json:"uploader_id"`
+	FileMimeType string    `json:"file_mime_type"`
+	FileSize     int64     `json:"file_size"`
+	CreatedAt    time.Time `json:"created_at"`
+	// the version the upload is listed and searched with
+	Current bool   `json:"current"`
+	Url     string `json:"url"`
+}
+
+type ListUploadVersionsResponse struct {
+	Data []UploadVersionResponse `json:"data"`
+}
+
+type MultipartUploadResponse struct {
+	Data *db.MultipartUpload `json:"data"`
+}
+
+type ListMultipartUploadsResponse struct {
+	Data []db.MultipartUpload `json:"data"`
+}
+
+type UploadPartResponse struct {
+	PartNumber int    `json:"part_number"`
+	Size       int64  `json:"size"`
+	ETag       string `json:"etag"`
+}
+
+type MultipartUploadStatusResponse struct {
+	Data db.MultipartUpload `json:"data"`
+	// parts uploaded so far
+	Parts []UploadPartResponse `json:"parts"`
+}
+
+type PresignedUploadPartResponse struct {
+	PartNumber int32  `json:"part_number"`
+	Url        string `json:"url"`
+}
+
+type PresignUploadPartsResponse struct {
+	Parts []PresignedUploadPartResponse `json:"parts"`
+}
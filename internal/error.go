@@ -70,6 +70,8 @@ func mapSrvErrorToApiError(err *service.SrvError) int {
 		code = http.StatusNotFound
 	case service.SrvErrConflict:
 		code = http.StatusConflict
+	case service.SrvErrRateLimited:
+		code = http.StatusTooManyRequests
 	default:
 		code = http.StatusInternalServerError
 	}
@@ -111,6 +113,8 @@ func CustomValidationErrMsg(error *validator.ValidationErrors) string {
 			msg = fmt.Sprintf("%s must be at least %s characters long.", fieldName, fieldParam)
 		case "max":
 			msg = fmt.Sprintf("%s cannot exceed %s characters.", fieldName, fieldParam)
+		case "oneof":
+			msg = fmt.Sprintf("%s must be one of: %s.", fieldName, fieldParam)
 		default:
 			msg = fmt.Sprintf("%s failed validation on the '%s' rule.", fieldName, fieldErr.Tag())
 		}
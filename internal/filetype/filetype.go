@@ -0,0 +1,155 @@
+// Package filetype detects the content type of uploaded files from their magic bytes.
+package filetype
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"slices"
+)
+
+const (
+	PDF  = "application/pdf"
+	ZIP  = "application/zip"
+	DOCX = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	XLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	PPTX = "application/vnd.openxmlformats-officedocument.presentationml.presentation"
+	// legacy .doc, .xls and .ppt files share the OLE2 container format
+	OLE = "application/x-ole-storage"
+)
+
+var (
+	pdfMagic = []byte("%PDF-")
+	zipMagic = []byte("PK\x03\x04")
+	// end of central directory record, present at the end of every zip archive
+	zipEnd   = []byte("PK\x05\x06")
+	oleMagic = []byte("\xD0\xCF\x11\xE0\xA1\xB1\x1A\xE1")
+	encrypt  = []byte("/Encrypt")
+)
+
+// PDF readers look for the header anywhere within the first 1024 bytes
+const pdfHeaderWindow = 1024
+
+// browsers sniff HTML from the first 512 bytes of a response
+const markupSniffWindow = 512
+
+// content types Detect returns, http.DetectContentType's without parameters
+// besides the formats detected by their magic bytes above
+var detectable = []string{
+	PDF, ZIP, DOCX, XLSX, PPTX, OLE,
+	"text/plain", "text/html", "text/xml",
+	"image/jpeg", "image/png", "image/gif", "image/webp", "image/bmp", "image/x-icon", "image/vnd.microsoft.icon",
+	"audio/aiff", "audio/mpeg", "audio/midi", "audio/wave", "application/ogg",
+	"video/avi", "video/mp4", "video/webm",
+	"font/ttf", "font/otf", "font/collection", "font/woff", "font/woff2", "application/vnd.ms-fontobject",
+	"application/postscript", "application/x-gzip", "application/x-rar-compressed", "application/wasm",
+	"application/octet-stream",
+}
+
+// content types browsers render as documents that can run scripts
+var activeContent = []string{"text/html", "text/xml"}
+
+// Detect returns the content type of a file given its leading bytes.
+// Office Open XML documents are told apart by the names of their zip entries,
+// so head should be large enough to cover the first few local file headers.
+func Detect(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, pdfMagic):
+		return PDF
+	case bytes.HasPrefix(head, oleMagic):
+		return OLE
+	case bytes.HasPrefix(head, zipMagic):
+		return detectZip(head)
+	}
+
+	mime := http.DetectContentType(head)
+	if i := bytes.IndexByte([]byte(mime), ';'); i >= 0 {
+		mime = mime[:i]
+	}
+	return mime
+}
+
+func detectZip(head []byte) string {
+	switch {
+	case bytes.Contains(head, []byte("word/")):
+		return DOCX
+	case bytes.Contains(head, []byte("xl/")):
+		return XLSX
+	case bytes.Contains(head, []byte("ppt/")):
+		return PPTX
+	}
+	return ZIP
+}
+
+func isZip(mime string) bool {
+	return mime == ZIP || mime == DOCX || mime == XLSX || mime == PPTX
+}
+
+// IsPolyglot reports whether a file of the detected mime type also looks like a
+// valid file of another type, given its leading and trailing bytes.
+func IsPolyglot(mime string, head, tail []byte) bool {
+	// a PDF header after some prefix is still opened by PDF readers
+	window := head
+	if len(window) > pdfHeaderWindow {
+		window = window[:pdfHeaderWindow]
+	}
+	if mime != PDF && bytes.Contains(window, pdfMagic) {
+		return true
+	}
+
+	// zip readers locate the archive from its end, regardless of what precedes it
+	if !isZip(mime) && bytes.Contains(tail, zipEnd) {
+		return true
+	}
+
+	// only markup where browsers sniff it, documents about HTML may quote it further in
+	markup := head
+	if len(markup) > markupSniffWindow {
+		markup = markup[:markupSniffWindow]
+	}
+	lower := bytes.ToLower(markup)
+	if !bytes.HasPrefix([]byte(mime), []byte("text/")) &&
+		(bytes.Contains(lower, []byte("<script")) || bytes.Contains(lower, []byte("<html"))) {
+		return true
+	}
+
+	return false
+}
+
+// IsEncryptedPDF reports whether a PDF declares an encryption dictionary. The
+// trailer is at the end of the file, or at the start for linearized files.
+func IsEncryptedPDF(head, tail []byte) bool {
+	return bytes.Contains(head, encrypt) || bytes.Contains(tail, encrypt)
+}
+
+// declared content types clients send for files that are detected as another type
+var aliases = map[string][]string{
+	OLE: {"application/msword", "application/vnd.ms-excel", "application/vnd.ms-powerpoint"},
+	ZIP: {"application/x-zip-compressed"},
+}
+
+// Matches reports whether a content type declared by a client, e.g. in a
+// presign request, corresponds to the detected type.
+func Matches(detected, declared string) bool {
+	if detected == declared {
+		return true
+	}
+	for _, alias := range aliases[detected] {
+		if alias == declared {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckAllowable fails if uploads of a content type cannot be allowed, because
+// Detect never returns it or browsers would run it as active content.
+func CheckAllowable(mime string) error {
+	if slices.Contains(activeContent, mime) {
+		return fmt.Errorf("%s files cannot be allowed, browsers run scripts in them", mime)
+	}
+	if !slices.Contains(detectable, mime) {
+		return fmt.Errorf("%s cannot be allowed, uploads are never detected as it", mime)
+	}
+	return nil
+}
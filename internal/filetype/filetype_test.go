@@ -0,0 +1,60 @@
+package filetype
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestIsPolyglot(t *testing.T) {
+	pdf := append([]byte("%PDF-1.7\n"), bytes.Repeat([]byte("x"), 1024)...)
+
+	tests := []struct {
+		name string
+		mime string
+		head []byte
+		tail []byte
+		want bool
+	}{
+		{"plain pdf", PDF, pdf, nil, false},
+		{"pdf header in a zip", ZIP, []byte("PK\x03\x04 %PDF-1.7"), []byte("PK\x05\x06"), true},
+		{"pdf header past the window", ZIP, append(bytes.Repeat([]byte("x"), pdfHeaderWindow), "%PDF-"...), []byte("PK\x05\x06"), false},
+		{"zip appended to a pdf", PDF, pdf, []byte("PK\x05\x06"), true},
+		{"docx", DOCX, []byte("PK\x03\x04word/"), []byte("PK\x05\x06"), false},
+		{"html at the start of a pdf", PDF, []byte("%PDF-1.7\n<html><script>"), nil, true},
+		{"html quoted inside a pdf", PDF, append(pdf, "<html><script>"...), nil, false},
+		{"html in a text file", "text/plain", []byte("<html>"), nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsPolyglot(tt.mime, tt.head, tt.tail); got != tt.want {
+				t.Errorf("IsPolyglot() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckAllowable(t *testing.T) {
+	tests := []struct {
+		mime string
+		ok   bool
+	}{
+		{PDF, true},
+		{DOCX, true},
+		{"image/png", true},
+		{"text/plain", true},
+		{"text/html", false},
+		{"text/xml", false},
+		{"image/svg+xml", false},
+		{"application/msword", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mime, func(t *testing.T) {
+			if err := CheckAllowable(tt.mime); (err == nil) != tt.ok {
+				t.Errorf("CheckAllowable() error = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/joho/godotenv"
+	"github.com/skndash96/lastnight-backend/internal/config"
+	"github.com/skndash96/lastnight-backend/internal/provider"
+	"github.com/skndash96/lastnight-backend/internal/service"
+)
+
+// GC deletes uploads without refs once, from the command line.
+func GC(args []string) error {
+	_ = godotenv.Load()
+
+	ctx := context.Background()
+
+	appCfg := config.New()
+
+	fs := flag.NewFlagSet("gc", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "list the uploads that would be deleted without deleting them")
+	grace := fs.Duration("grace", appCfg.GC.GracePeriod, "only delete uploads without refs for longer than this")
+	batch := fs.Int("batch", int(appCfg.GC.BatchSize), "maximum number of uploads to delete")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	appCfg.GC.BatchSize = int32(*batch)
+
+	pool, err := pgxpool.New(ctx, appCfg.DbURL)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	uploadProvider, err := provider.NewUploadProvider(appCfg.Storage)
+	if err != nil {
+		return err
+	}
+
+	gcSrv := service.NewGCService(appCfg.GC, uploadProvider, pool)
+
+	result, err := gcSrv.Collect(ctx, *grace, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	verb := "deleted"
+	if *dryRun {
+		verb = "would delete"
+	}
+
+	for _, upload := range result.Uploads {
+		fmt.Printf("%s upload %d %s (%d bytes)\n", verb, upload.ID, upload.StorageKey, upload.FileSize)
+	}
+	fmt.Printf("%s %d uploads (%d bytes), skipped %d\n", verb, len(result.Uploads), result.Bytes, result.Skipped)
+
+	abortVerb := "aborted"
+	if *dryRun {
+		abortVerb = "would abort"
+	}
+
+	for _, upload := range result.MultipartUploads {
+		fmt.Printf("%s multipart upload %d %s (expired %s)\n", abortVerb, upload.ID, upload.StorageKey, upload.ExpiresAt.Format(time.RFC3339))
+	}
+	fmt.Printf("%s %d expired multipart uploads\n", abortVerb, len(result.MultipartUploads))
+
+	for _, presign := range result.Presigns {
+		fmt.Printf("%s presigned upload %d %s (expired %s)\n", verb, presign.ID, presign.StorageKey, presign.ExpiresAt.Format(time.RFC3339))
+	}
+	fmt.Printf("%s %d expired presigned uploads\n", verb, len(result.Presigns))
+
+	for _, job := range result.JobArchives {
+		fmt.Printf("%s archive of team job %d %s (finished %s)\n", verb, job.ID, job.StorageKey, job.FinishedAt.Format(time.RFC3339))
+	}
+	fmt.Printf("%s %d team export archives\n", verb, len(result.JobArchives))
+
+	return nil
+}
@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/skndash96/lastnight-backend/internal/auth"
+	"github.com/skndash96/lastnight-backend/internal/dto"
+)
+
+// @Summary Create multipart upload
+// @Description Start a resumable upload of a large file. Upload part_count parts of part_size bytes (the last one may be smaller) to URLs from the parts route, then complete it. Unfinished uploads expire.
+// @Tags Upload
+// @Accept json
+// @Param teamID path string true "Team ID"
+// @Param upload_request body dto.CreateMultipartUploadBody true "Multipart upload request"
+// @Produce json
+// @Success 201 {object} dto.MultipartUploadResponse
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/uploads/multipart [post]
+func (h *uploadHandler) CreateMultipartUpload(c echo.Context) error {
+	session, ok := auth.GetSession(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	v := dto.CreateMultipartUploadRequest{}
+	if err := c.Bind(&v); err != nil {
+		return err
+	}
+
+	if err := c.Validate(&v); err != nil {
+		return err
+	}
+
+	upload, err := h.uploadSrv.CreateMultipartUpload(c.Request().Context(), session.TeamID, session.UserID, v.Name, v.MimeType, v.Size)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, &dto.MultipartUploadResponse{
+		Data: upload,
+	})
+}
+
+// @Summary List multipart uploads
+// @Description List the unfinished multipart uploads of the current user, to resume them.
+// @Tags Upload
+// @Param teamID path string true "Team ID"
+// @Produce json
+// @Success 200 {object} dto.ListMultipartUploadsResponse
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/uploads/multipart [get]
+func (h *uploadHandler) ListMultipartUploads(c echo.Context) error {
+	session, ok := auth.GetSession(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	uploads, err := h.uploadSrv.ListMultipartUploads(c.Request().Context(), session.TeamID, session.UserID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &dto.ListMultipartUploadsResponse{
+		Data: uploads,
+	})
+}
+
+// @Summary Get multipart upload
+// @Description Get a multipart upload with the parts uploaded so far. Resume by uploading the missing parts.
+// @Tags Upload
+// @Param teamID path string true "Team ID"
+// @Param multipartID path string true "Multipart upload ID"
+// @Produce json
+// @Success 200 {object} dto.MultipartUploadStatusResponse
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/uploads/multipart/{multipartID} [get]
+func (h *uploadHandler) GetMultipartUpload(c echo.Context) error {
+	session, ok := auth.GetSession(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	v := dto.GetMultipartUploadRequest{}
+	if err := c.Bind(&v); err != nil {
+		return err
+	}
+
+	if err := c.Validate(&v); err != nil {
+		return err
+	}
+
+	status, err := h.uploadSrv.GetMultipartUpload(c.Request().Context(), session.TeamID, session.UserID, v.MultipartUploadID)
+	if err != nil {
+		return err
+	}
+
+	parts := make([]dto.UploadPartResponse, len(status.Parts))
+	for i, part := range status.Parts {
+		parts[i] = dto.UploadPartResponse{
+			PartNumber: part.PartNumber,
+			Size:       part.Size,
+			ETag:       part.ETag,
+		}
+	}
+
+	return c.JSON(http.StatusOK, &dto.MultipartUploadStatusResponse{
+		Data:  status.MultipartUpload,
+		Parts: parts,
+	})
+}
+
+// @Summary Create pre-signed part requests
+// @Description Create pre-signed URLs to PUT parts of a multipart upload to. URLs expire quickly, request them in batches as the upload progresses.
+// @Tags Upload
+// @Accept json
+// @Param teamID path string true "Team ID"
+// @Param multipartID path string true "Multipart upload ID"
+// @Param parts_request body dto.PresignUploadPartsBody true "Part numbers"
+// @Produce json
+// @Success 200 {object} dto.PresignUploadPartsResponse
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/uploads/multipart/{multipartID}/parts [post]
+func (h *uploadHandler) PresignUploadParts(c echo.Context) error {
+	session, ok := auth.GetSession(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	v := dto.PresignUploadPartsRequest{}
+	if err := c.Bind(&v); err != nil {
+		return err
+	}
+
+	if err := c.Validate(&v); err != nil {
+		return err
+	}
+
+	presigned, err := h.uploadSrv.PresignUploadParts(c.Request().Context(), session.TeamID, session.UserID, v.MultipartUploadID, v.PartNumbers)
+	if err != nil {
+		return err
+	}
+
+	parts := make([]dto.PresignedUploadPartResponse, len(presigned))
+	for i, part := range presigned {
+		parts[i] = dto.PresignedUploadPartResponse{
+			PartNumber: part.PartNumber,
+			Url:        part.Url.String(),
+		}
+	}
+
+	return c.JSON(http.StatusOK, &dto.PresignUploadPartsResponse{
+		Parts: parts,
+	})
+}
+
+// @Summary Complete multipart upload
+// @Description Call this route once every part is uploaded. Assembles the parts and processes the file like a completed upload. If that fails with a server error, the multipart upload is kept and completing it can be retried.
+// @Tags Upload
+// @Accept json
+// @Param teamID path string true "Team ID"
+// @Param multipartID path string true "Multipart upload ID"
+// @Param upload_request body dto.CompleteMultipartUploadBody true "Complete multipart upload request"
+// @Success 201
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/uploads/multipart/{multipartID}/complete [post]
+func (h *uploadHandler) CompleteMultipartUpload(c echo.Context) error {
+	session, ok := auth.GetSession(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	v := dto.CompleteMultipartUploadRequest{}
+	if err := c.Bind(&v); err != nil {
+		return err
+	}
+
+	if err := c.Validate(&v); err != nil {
+		return err
+	}
+
+	err := h.uploadSrv.CompleteMultipartUpload(c.Request().Context(), session.TeamID, session.UserID, v.MultipartUploadID, v.VersionOf, toUploadTagInputs(v.Tags))
+	if err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusCreated)
+}
+
+// @Summary Abort multipart upload
+// @Description Cancel a multipart upload and discard its uploaded parts.
+// @Tags Upload
+// @Param teamID path string true "Team ID"
+// @Param multipartID path string true "Multipart upload ID"
+// @Success 204
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/uploads/multipart/{multipartID} [delete]
+func (h *uploadHandler) AbortMultipartUpload(c echo.Context) error {
+	session, ok := auth.GetSession(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	v := dto.AbortMultipartUploadRequest{}
+	if err := c.Bind(&v); err != nil {
+		return err
+	}
+
+	if err := c.Validate(&v); err != nil {
+		return err
+	}
+
+	if err := h.uploadSrv.AbortMultipartUpload(c.Request().Context(), session.TeamID, session.UserID, v.MultipartUploadID); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/labstack/echo/v4"
+	"github.com/skndash96/lastnight-backend/internal/provider"
+)
+
+// storageHandler serves the signed URLs of the local storage driver, in place
+// of the S3 endpoints clients use with MinIO.
+type storageHandler struct {
+	storage *provider.LocalProvider
+}
+
+func NewStorageHandler(storage *provider.LocalProvider) *storageHandler {
+	return &storageHandler{
+		storage: storage,
+	}
+}
+
+// @Summary Upload a file to a presigned POST
+// @Description Stores a file uploaded with the fields of a presigned upload, like an S3 POST policy. Only served with the local storage driver. The fields must come before the file.
+// @Tags Storage
+// @Accept mpfd
+// @Success 204
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/storage/uploads [post]
+func (h *storageHandler) Upload(c echo.Context) error {
+	reader, err := c.Request().MultipartReader()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid multipart body")
+	}
+
+	fields := map[string]string{}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return echo.NewHTTPError(http.StatusBadRequest, "missing file part")
+		}
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid multipart body")
+		}
+
+		if part.FormName() == "file" {
+			if err := h.storage.ReceiveUpload(c.Request().Context(), fields, part); err != nil {
+				return storageError(err)
+			}
+
+			return c.NoContent(http.StatusNoContent)
+		}
+
+		value, err := io.ReadAll(io.LimitReader(part, maxUploadFieldSize))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid multipart body")
+		}
+		fields[part.FormName()] = string(value)
+
+		part.Close()
+	}
+}
+
+// @Summary Upload a part of a multipart upload
+// @Description Stores one part PUT to a presigned part URL, which must have exactly the presigned size, and returns its ETag header. Only served with the local storage driver.
+// @Tags Storage
+// @Param uploadID path string true "Upload ID"
+// @Param partNumber path int true "Part number"
+// @Param key query string true "Object key"
+// @Param parts query int true "Number of parts of the upload"
+// @Param size query int true "Size of the part"
+// @Param expires query int true "Expiry as a unix timestamp"
+// @Param signature query string true "Signature"
+// @Success 200
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/storage/parts/{uploadID}/{partNumber} [put]
+func (h *storageHandler) UploadPart(c echo.Context) error {
+	etag, err := h.storage.ReceiveUploadPart(c.Request().Context(), c.Param("uploadID"), c.Param("partNumber"), c.QueryParams(), c.Request().Body)
+	if err != nil {
+		return storageError(err)
+	}
+
+	c.Response().Header().Set("ETag", etag)
+	return c.NoContent(http.StatusOK)
+}
+
+// @Summary Download an object
+// @Description Serves an object at a presigned URL as an attachment, supporting range requests. Only served with the local storage driver.
+// @Tags Storage
+// @Param key path string true "Object key"
+// @Param expires query int true "Expiry as a unix timestamp"
+// @Param signature query string true "Signature"
+// @Success 200
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/storage/objects/{key} [get]
+func (h *storageHandler) Download(c echo.Context) error {
+	key, err := url.PathUnescape(c.Param("*"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid object key")
+	}
+
+	f, err := h.storage.OpenSignedObject(key, c.QueryParam("expires"), c.QueryParam("signature"))
+	if err != nil {
+		return storageError(err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	// objects are served from the API's own origin, and their extensions come
+	// from client file names, so they must never be rendered as a page
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentType, "application/octet-stream")
+	header.Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(key)}))
+	header.Set(echo.HeaderXContentTypeOptions, "nosniff")
+	header.Set(echo.HeaderContentSecurityPolicy, "default-src 'none'; sandbox")
+
+	http.ServeContent(c.Response(), c.Request(), path.Base(key), info.ModTime(), f)
+	return nil
+}
+
+func storageError(err error) error {
+	switch {
+	case errors.Is(err, provider.ErrInvalidSignature):
+		return echo.NewHTTPError(http.StatusForbidden, "invalid or expired signature")
+	case errors.Is(err, provider.ErrObjectNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "object not found")
+	case errors.Is(err, provider.ErrFileTooLarge):
+		return echo.NewHTTPError(http.StatusBadRequest, "file is too large")
+	case errors.Is(err, provider.ErrSizeMismatch):
+		return echo.NewHTTPError(http.StatusBadRequest, "file size does not match the presigned size")
+	default:
+		return err
+	}
+}
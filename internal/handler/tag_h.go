@@ -73,7 +73,7 @@ func (h *tagHandler) UpdateFilters(c echo.Context) error {
 		args = append(args, []int32{f.KeyID, f.ValueID})
 	}
 
-	err := h.tagSrv.UpdateFilters(c.Request().Context(), session.MembershipID, &args)
+	err := h.tagSrv.UpdateFilters(c.Request().Context(), session.TeamID, session.MembershipID, &args)
 	if err != nil {
 		return err
 	}
@@ -100,7 +100,7 @@ func (h *tagHandler) CreateTagKey(c echo.Context) error {
 		return err
 	}
 
-	tag, err := h.tagSrv.CreateTagKey(c.Request().Context(), v.TeamID, v.Name, v.DataType)
+	tag, err := h.tagSrv.CreateTagKey(c.Request().Context(), v.TeamID, v.Name, v.DataType, v.Mode, v.RequiresApproval)
 	if err != nil {
 		return err
 	}
@@ -112,7 +112,7 @@ func (h *tagHandler) CreateTagKey(c echo.Context) error {
 
 // @Summary Update Tag Key
 // @Tags Tag
-// @Description Update a tag key
+// @Description Rename a tag key or change its mode. Only mods can update tag keys
 // @Param teamID path string true "Team ID"
 // @Param tagID path string true "Tag ID"
 // @Param tag body dto.UpdateTagKeyBody true "Tag"
@@ -130,7 +130,7 @@ func (h *tagHandler) UpdateTagKey(c echo.Context) error {
 		return err
 	}
 
-	tag, err := h.tagSrv.UpdateTagKey(c.Request().Context(), v.TagID, v.Name)
+	tag, err := h.tagSrv.UpdateTagKey(c.Request().Context(), v.TeamID, v.TagID, v.Name, v.Mode, v.RequiresApproval)
 	if err != nil {
 		return err
 	}
@@ -142,9 +142,10 @@ func (h *tagHandler) UpdateTagKey(c echo.Context) error {
 
 // @Summary Delete Tag Key
 // @Tags Tag
-// @Description Delete a tag key
+// @Description Archive a tag key, or permanently delete it with force. Only mods can delete tag keys
 // @Param teamID path string true "Team ID"
 // @Param tagID path string true "Tag ID"
+// @Param force query bool false "Permanently delete"
 // @Produce json
 // @Success 200 {object} dto.DeleteTagKeyResponse
 // @Failure default {object} dto.ErrorResponse
@@ -160,7 +161,7 @@ func (h *tagHandler) DeleteTagKey(c echo.Context) error {
 		return err
 	}
 
-	tag, err := h.tagSrv.DeleteTagKey(c.Request().Context(), v.TagID)
+	tag, err := h.tagSrv.DeleteTagKey(c.Request().Context(), v.TeamID, v.TagID, v.Force)
 	if err != nil {
 		return err
 	}
@@ -170,6 +171,66 @@ func (h *tagHandler) DeleteTagKey(c echo.Context) error {
 	})
 }
 
+// @Summary Restore Tag Key
+// @Tags Tag
+// @Description Restore an archived tag key
+// @Param teamID path string true "Team ID"
+// @Param tagID path string true "Tag ID"
+// @Produce json
+// @Success 200 {object} dto.RestoreTagKeyResponse
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/tags/{tagID}/restore [post]
+func (h *tagHandler) RestoreTagKey(c echo.Context) error {
+	v := new(dto.RestoreTagKeyRequest)
+
+	if err := c.Bind(v); err != nil {
+		return err
+	}
+
+	if err := c.Validate(v); err != nil {
+		return err
+	}
+
+	tag, err := h.tagSrv.RestoreTagKey(c.Request().Context(), v.TeamID, v.TagID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, dto.RestoreTagKeyResponse{
+		Data: tag,
+	})
+}
+
+// @Summary Tag Key Impact
+// @Tags Tag
+// @Description Preview how many uploads, filters and values deleting a tag key would affect
+// @Param teamID path string true "Team ID"
+// @Param tagID path string true "Tag ID"
+// @Produce json
+// @Success 200 {object} dto.GetTagKeyImpactResponse
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/tags/{tagID}/impact [get]
+func (h *tagHandler) GetTagKeyImpact(c echo.Context) error {
+	v := new(dto.GetTagKeyImpactRequest)
+
+	if err := c.Bind(v); err != nil {
+		return err
+	}
+
+	if err := c.Validate(v); err != nil {
+		return err
+	}
+
+	impact, err := h.tagSrv.GetTagKeyImpact(c.Request().Context(), v.TeamID, v.TagID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, dto.GetTagKeyImpactResponse{
+		Data: impact,
+	})
+}
+
 // @Summary Create Tag Value
 // @Tags Tag
 // @Description Create a new tag value
@@ -191,7 +252,7 @@ func (h *tagHandler) CreateTagValue(c echo.Context) error {
 		return err
 	}
 
-	value, err := h.tagSrv.CreateTagValue(c.Request().Context(), v.TagID, v.Value)
+	value, err := h.tagSrv.CreateTagValue(c.Request().Context(), v.TeamID, v.TagID, v.Value)
 	if err != nil {
 		return err
 	}
@@ -201,6 +262,98 @@ func (h *tagHandler) CreateTagValue(c echo.Context) error {
 	})
 }
 
+// @Summary Rename Tag Value
+// @Tags Tag
+// @Description Rename a tag value
+// @Param teamID path string true "Team ID"
+// @Param tagID path string true "Tag ID"
+// @Param tagValueID path string true "Value ID"
+// @Param value body dto.UpdateTagValueBody true "Value"
+// @Produce json
+// @Success 200 {object} dto.UpdateTagValueResponse
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/tags/{tagID}/values/{tagValueID} [patch]
+func (h *tagHandler) UpdateTagValue(c echo.Context) error {
+	v := new(dto.UpdateTagValueRequest)
+
+	if err := c.Bind(v); err != nil {
+		return err
+	}
+
+	if err := c.Validate(v); err != nil {
+		return err
+	}
+
+	value, err := h.tagSrv.RenameTagValue(c.Request().Context(), v.TeamID, v.TagID, v.TagValueID, v.Value)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, dto.UpdateTagValueResponse{
+		Data: value,
+	})
+}
+
+// @Summary Merge Tag Value
+// @Tags Tag
+// @Description Merge a tag value into another value of the same key, moving its uploads and filters
+// @Param teamID path string true "Team ID"
+// @Param tagID path string true "Tag ID"
+// @Param tagValueID path string true "Value ID"
+// @Param target body dto.MergeTagValueBody true "Target"
+// @Produce json
+// @Success 200 {object} dto.MergeTagValueResponse
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/tags/{tagID}/values/{tagValueID}/merge [post]
+func (h *tagHandler) MergeTagValue(c echo.Context) error {
+	v := new(dto.MergeTagValueRequest)
+
+	if err := c.Bind(v); err != nil {
+		return err
+	}
+
+	if err := c.Validate(v); err != nil {
+		return err
+	}
+
+	value, err := h.tagSrv.MergeTagValue(c.Request().Context(), v.TeamID, v.TagID, v.TagValueID, v.TargetID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, dto.MergeTagValueResponse{
+		Data: value,
+	})
+}
+
+// @Summary Reorder Tag Values
+// @Tags Tag
+// @Description Set the display order of a tag key's values
+// @Param teamID path string true "Team ID"
+// @Param tagID path string true "Tag ID"
+// @Param order body dto.ReorderTagValuesBody true "Order"
+// @Produce json
+// @Success 200
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/tags/{tagID}/values/order [put]
+func (h *tagHandler) ReorderTagValues(c echo.Context) error {
+	v := new(dto.ReorderTagValuesRequest)
+
+	if err := c.Bind(v); err != nil {
+		return err
+	}
+
+	if err := c.Validate(v); err != nil {
+		return err
+	}
+
+	if err := h.tagSrv.ReorderTagValues(c.Request().Context(), v.TeamID, v.TagID, v.ValueIDs); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
 // @Summary Delete Tag Value
 // @Tags Tag
 // @Description Delete a tag value
@@ -231,3 +384,205 @@ func (h *tagHandler) DeleteTagValue(c echo.Context) error {
 		Data: value,
 	})
 }
+
+// @Summary List Pending Tag Values
+// @Tags Tag
+// @Description List free-form tag values awaiting mod approval
+// @Param teamID path string true "Team ID"
+// @Produce json
+// @Success 200 {object} dto.ListPendingTagValuesResponse
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/tags/values/pending [get]
+func (h *tagHandler) ListPendingTagValues(c echo.Context) error {
+	v := new(dto.ListPendingTagValuesRequest)
+
+	if err := c.Bind(v); err != nil {
+		return err
+	}
+
+	if err := c.Validate(v); err != nil {
+		return err
+	}
+
+	values, err := h.tagSrv.ListPendingTagValues(c.Request().Context(), v.TeamID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, dto.ListPendingTagValuesResponse{
+		Data: values,
+	})
+}
+
+// @Summary Approve Tag Value
+// @Tags Tag
+// @Description Approve a pending free-form tag value
+// @Param teamID path string true "Team ID"
+// @Param tagID path string true "Tag ID"
+// @Param tagValueID path string true "Value ID"
+// @Produce json
+// @Success 200 {object} dto.ApproveTagValueResponse
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/tags/{tagID}/values/{tagValueID}/approve [post]
+func (h *tagHandler) ApproveTagValue(c echo.Context) error {
+	v := new(dto.ApproveTagValueRequest)
+
+	if err := c.Bind(v); err != nil {
+		return err
+	}
+
+	if err := c.Validate(v); err != nil {
+		return err
+	}
+
+	value, err := h.tagSrv.ApproveTagValue(c.Request().Context(), v.TeamID, v.TagID, v.TagValueID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, dto.ApproveTagValueResponse{
+		Data: value,
+	})
+}
+
+// @Summary Reject Tag Value
+// @Tags Tag
+// @Description Reject a pending free-form tag value. It is deleted and removed from the uploads tagged with it
+// @Param teamID path string true "Team ID"
+// @Param tagID path string true "Tag ID"
+// @Param tagValueID path string true "Value ID"
+// @Produce json
+// @Success 200 {object} dto.RejectTagValueResponse
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/tags/{tagID}/values/{tagValueID}/reject [post]
+func (h *tagHandler) RejectTagValue(c echo.Context) error {
+	v := new(dto.RejectTagValueRequest)
+
+	if err := c.Bind(v); err != nil {
+		return err
+	}
+
+	if err := c.Validate(v); err != nil {
+		return err
+	}
+
+	value, err := h.tagSrv.RejectTagValue(c.Request().Context(), v.TeamID, v.TagID, v.TagValueID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, dto.RejectTagValueResponse{
+		Data: value,
+	})
+}
+
+// @Summary List Tag Options
+// @Tags Tag
+// @Description List values of a tag key that are valid under the already chosen values
+// @Param teamID path string true "Team ID"
+// @Param tagID path string true "Tag ID"
+// @Param value query []int false "Chosen value IDs" collectionFormat(multi)
+// @Produce json
+// @Success 200 {object} dto.ListTagOptionsResponse
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/tags/{tagID}/options [get]
+func (h *tagHandler) ListTagOptions(c echo.Context) error {
+	v := new(dto.ListTagOptionsRequest)
+
+	if err := c.Bind(v); err != nil {
+		return err
+	}
+
+	if err := c.Validate(v); err != nil {
+		return err
+	}
+
+	options, err := h.tagSrv.ListDependentOptions(c.Request().Context(), v.TeamID, v.TagID, v.Values)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, dto.ListTagOptionsResponse{
+		Data: options,
+	})
+}
+
+// @Summary Set Tag Value Parents
+// @Tags Tag
+// @Description Set the values of other keys a tag value is valid under
+// @Param teamID path string true "Team ID"
+// @Param tagID path string true "Tag ID"
+// @Param tagValueID path string true "Value ID"
+// @Param parents body dto.SetTagValueParentsBody true "Parents"
+// @Produce json
+// @Success 200
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/tags/{tagID}/values/{tagValueID}/parents [put]
+func (h *tagHandler) SetTagValueParents(c echo.Context) error {
+	v := new(dto.SetTagValueParentsRequest)
+
+	if err := c.Bind(v); err != nil {
+		return err
+	}
+
+	if err := c.Validate(v); err != nil {
+		return err
+	}
+
+	err := h.tagSrv.SetTagValueParents(c.Request().Context(), v.TeamID, v.TagID, v.TagValueID, v.ParentIDs)
+	if err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+// @Summary Suggest Tags
+// @Tags Tag
+// @Description Suggest values of each tag key for a file, scored from 0 to 1 by how well they match its name and, given an upload, the text extracted from it, and by which values were tagged together on the team's uploads
+// @Accept json
+// @Param teamID path string true "Team ID"
+// @Param suggest_request body dto.SuggestTagsBody true "File to suggest tags for"
+// @Produce json
+// @Success 200 {object} dto.SuggestTagsResponse
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/tags/suggestions [post]
+func (h *tagHandler) SuggestTags(c echo.Context) error {
+	v := new(dto.SuggestTagsRequest)
+
+	if err := c.Bind(v); err != nil {
+		return err
+	}
+
+	if err := c.Validate(v); err != nil {
+		return err
+	}
+
+	suggestions, err := h.tagSrv.SuggestTags(c.Request().Context(), v.TeamID, v.Name, v.UploadID)
+	if err != nil {
+		return err
+	}
+
+	out := make([]dto.TagKeySuggestionsResponse, len(suggestions))
+	for i, key := range suggestions {
+		candidates := make([]dto.TagSuggestionResponse, len(key.Candidates))
+		for j, s := range key.Candidates {
+			candidates[j] = dto.TagSuggestionResponse{
+				ValueID: s.ValueID,
+				Value:   s.Value,
+				Score:   s.Score,
+				Reasons: s.Reasons,
+			}
+		}
+
+		out[i] = dto.TagKeySuggestionsResponse{
+			KeyID:      key.KeyID,
+			KeyName:    key.KeyName,
+			Candidates: candidates,
+		}
+	}
+
+	return c.JSON(http.StatusOK, dto.SuggestTagsResponse{
+		Data: out,
+	})
+}
@@ -68,3 +68,66 @@ func (h *teamHandler) JoinDefaultTeam(c echo.Context) error {
 
 	return nil
 }
+
+// @Summary List notifications
+// @Tags Team
+// @Description List the user's latest notifications in the team, newest first. Mods are notified of uploads rejected by the malware scan.
+// @Param teamID path string true "Team ID"
+// @Produce json
+// @Success 200 {object} dto.ListTeamNotificationsResponse
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/notifications [get]
+func (h *teamHandler) ListNotifications(c echo.Context) error {
+	session, ok := auth.GetSession(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	v := new(dto.ListTeamNotificationsRequest)
+	if err := c.Bind(v); err != nil {
+		return err
+	}
+
+	if err := c.Validate(v); err != nil {
+		return err
+	}
+
+	notifications, err := h.teamSrv.ListNotifications(c.Request().Context(), session.MembershipID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &dto.ListTeamNotificationsResponse{
+		Data: notifications,
+	})
+}
+
+// @Summary Mark notification read
+// @Tags Team
+// @Description Mark one of the user's notifications in the team as read
+// @Param teamID path string true "Team ID"
+// @Param notificationID path string true "Notification ID"
+// @Success 200
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/notifications/{notificationID}/read [post]
+func (h *teamHandler) MarkNotificationRead(c echo.Context) error {
+	session, ok := auth.GetSession(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	v := new(dto.MarkTeamNotificationReadRequest)
+	if err := c.Bind(v); err != nil {
+		return err
+	}
+
+	if err := c.Validate(v); err != nil {
+		return err
+	}
+
+	if err := h.teamSrv.MarkNotificationRead(c.Request().Context(), session.MembershipID, v.NotificationID); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusOK)
+}
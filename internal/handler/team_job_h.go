@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/skndash96/lastnight-backend/internal/auth"
+	"github.com/skndash96/lastnight-backend/internal/db"
+	"github.com/skndash96/lastnight-backend/internal/dto"
+	"github.com/skndash96/lastnight-backend/internal/service"
+)
+
+type teamJobHandler struct {
+	archiveSrv *service.TeamArchiveService
+}
+
+func NewTeamJobHandler(archiveSrv *service.TeamArchiveService) *teamJobHandler {
+	return &teamJobHandler{
+		archiveSrv: archiveSrv,
+	}
+}
+
+// @Summary Start team export
+// @Description Start exporting the team's tag keys and values, members with their filters, and uploads with their tags to a versioned ZIP archive. With include_objects, the files are included as well. Poll the job for the download URL of the archive. Only mods can export the team.
+// @Tags Team
+// @Accept json
+// @Param teamID path string true "Team ID"
+// @Param export_request body dto.StartTeamExportBody true "Export request"
+// @Produce json
+// @Success 202 {object} dto.TeamJobDataResponse
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/jobs/export [post]
+func (h *teamJobHandler) StartTeamExport(c echo.Context) error {
+	session, ok := auth.GetSession(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	v := dto.StartTeamExportRequest{}
+	if err := c.Bind(&v); err != nil {
+		return err
+	}
+
+	if err := c.Validate(&v); err != nil {
+		return err
+	}
+
+	job, err := h.archiveSrv.StartTeamExport(c.Request().Context(), session.TeamID, session.UserID, v.IncludeObjects)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusAccepted, &dto.TeamJobDataResponse{
+		Data: toTeamJobResponse(job),
+	})
+}
+
+// @Summary Start team import
+// @Description Start importing an archive of a team export, sent as the request body. Tag keys are matched by name and values by value, and IDs are remapped. Members are matched by email to members of the team, no users are added to it. Uploads are added when their file is included in the archive, or matched when the team has it already. Poll the job for the report. Only mods can import into the team.
+// @Tags Team
+// @Accept application/zip
+// @Param teamID path string true "Team ID"
+// @Produce json
+// @Success 202 {object} dto.TeamJobDataResponse
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/jobs/import [post]
+func (h *teamJobHandler) StartTeamImport(c echo.Context) error {
+	session, ok := auth.GetSession(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	// c.Bind would try to decode the archive
+	v := dto.StartTeamImportRequest{}
+	if err := (&echo.DefaultBinder{}).BindPathParams(c, &v); err != nil {
+		return err
+	}
+
+	if err := c.Validate(&v); err != nil {
+		return err
+	}
+
+	job, err := h.archiveSrv.StartTeamImport(c.Request().Context(), session.TeamID, session.UserID, c.Request().Body)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusAccepted, &dto.TeamJobDataResponse{
+		Data: toTeamJobResponse(job),
+	})
+}
+
+// @Summary List team jobs
+// @Description List the team's latest exports and imports, newest first. Only mods can list them.
+// @Tags Team
+// @Param teamID path string true "Team ID"
+// @Produce json
+// @Success 200 {object} dto.ListTeamJobsResponse
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/jobs [get]
+func (h *teamJobHandler) ListTeamJobs(c echo.Context) error {
+	session, ok := auth.GetSession(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	v := dto.ListTeamJobsRequest{}
+	if err := c.Bind(&v); err != nil {
+		return err
+	}
+
+	if err := c.Validate(&v); err != nil {
+		return err
+	}
+
+	jobs, err := h.archiveSrv.ListTeamJobs(c.Request().Context(), session.TeamID)
+	if err != nil {
+		return err
+	}
+
+	out := make([]dto.TeamJobResponse, len(jobs))
+	for i := range jobs {
+		out[i] = toTeamJobResponse(&jobs[i])
+	}
+
+	return c.JSON(http.StatusOK, &dto.ListTeamJobsResponse{
+		Data: out,
+	})
+}
+
+// @Summary Get team job
+// @Description Get an export or import with its report once finished, and for exports the presigned download URL of the archive until it is deleted. Only mods can get them.
+// @Tags Team
+// @Param teamID path string true "Team ID"
+// @Param jobID path string true "Job ID"
+// @Produce json
+// @Success 200 {object} dto.TeamJobDataResponse
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/jobs/{jobID} [get]
+func (h *teamJobHandler) GetTeamJob(c echo.Context) error {
+	session, ok := auth.GetSession(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	v := dto.GetTeamJobRequest{}
+	if err := c.Bind(&v); err != nil {
+		return err
+	}
+
+	if err := c.Validate(&v); err != nil {
+		return err
+	}
+
+	job, err := h.archiveSrv.GetTeamJob(c.Request().Context(), session.TeamID, v.JobID)
+	if err != nil {
+		return err
+	}
+
+	out := toTeamJobResponse(&job.TeamJob)
+	if job.Url != nil {
+		out.Url = job.Url.String()
+	}
+
+	return c.JSON(http.StatusOK, &dto.TeamJobDataResponse{
+		Data: out,
+	})
+}
+
+func toTeamJobResponse(job *db.TeamJob) dto.TeamJobResponse {
+	return dto.TeamJobResponse{
+		ID:             job.ID,
+		Kind:           job.Kind,
+		Status:         job.Status,
+		IncludeObjects: job.IncludeObjects,
+		Report:         job.Report,
+		Error:          job.Error,
+		CreatedAt:      job.CreatedAt,
+		FinishedAt:     job.FinishedAt,
+	}
+}
@@ -1,7 +1,14 @@
 package handler
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/skndash96/lastnight-backend/internal/auth"
@@ -19,6 +26,238 @@ func NewUploadHandler(uploadSrv *service.UploadService) *uploadHandler {
 	}
 }
 
+// @Summary List uploads
+// @Description List uploads of a team, optionally filtered by tag values and number ranges. With q, uploads are searched by content and ranked by relevance.
+// @Tags Upload
+// @Param teamID path string true "Team ID"
+// @Param q query string false "Full-text search query"
+// @Param value query []int false "Tag value IDs" collectionFormat(multi)
+// @Param range query []string false "Number ranges as keyID:min:max" collectionFormat(multi)
+// @Param limit query int false "Limit"
+// @Param offset query int false "Offset"
+// @Produce json
+// @Success 200 {object} dto.ListUploadsResponse
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/uploads [get]
+func (h *uploadHandler) ListUploads(c echo.Context) error {
+	session, ok := auth.GetSession(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	v := dto.ListUploadsRequest{}
+	if err := c.Bind(&v); err != nil {
+		return err
+	}
+
+	if err := c.Validate(&v); err != nil {
+		return err
+	}
+
+	ranges := make([]service.RangeFilter, len(v.Ranges))
+	for i, raw := range v.Ranges {
+		rg, err := parseRangeFilter(raw)
+		if err != nil {
+			return err
+		}
+		ranges[i] = rg
+	}
+
+	uploads, err := h.uploadSrv.ListUploads(c.Request().Context(), session.TeamID, v.Q, v.Values, ranges, v.Limit, v.Offset)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &dto.ListUploadsResponse{
+		Data: uploads,
+	})
+}
+
+// @Summary Export uploads
+// @Description Download every upload matching the listing filters as one ZIP, named by file name, with a manifest.json of their tags. The ZIP is streamed, so a failure midway leaves it truncated. Exports are capped in total size and rate limited per user, exports refused for their filters do not count.
+// @Tags Upload
+// @Param teamID path string true "Team ID"
+// @Param q query string false "Full-text search query"
+// @Param value query []int false "Tag value IDs" collectionFormat(multi)
+// @Param range query []string false "Number ranges as keyID:min:max" collectionFormat(multi)
+// @Produce application/zip
+// @Success 200 {file} binary
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/uploads/export [get]
+func (h *uploadHandler) ExportUploads(c echo.Context) error {
+	session, ok := auth.GetSession(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	v := dto.ExportUploadsRequest{}
+	if err := c.Bind(&v); err != nil {
+		return err
+	}
+
+	if err := c.Validate(&v); err != nil {
+		return err
+	}
+
+	ranges := make([]service.RangeFilter, len(v.Ranges))
+	for i, raw := range v.Ranges {
+		rg, err := parseRangeFilter(raw)
+		if err != nil {
+			return err
+		}
+		ranges[i] = rg
+	}
+
+	ctx := c.Request().Context()
+
+	export, err := h.uploadSrv.PrepareExport(ctx, session.TeamID, session.UserID, service.ExportFilters{
+		Q:        v.Q,
+		ValueIDs: v.Values,
+		Ranges:   ranges,
+	})
+	if err != nil {
+		return err
+	}
+
+	filename := fmt.Sprintf("uploads-%s.zip", time.Now().Format("20060102-150405"))
+	c.Response().Header().Set(echo.HeaderContentType, "application/zip")
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	c.Response().WriteHeader(http.StatusOK)
+
+	// the status is sent already, errors can only cut the ZIP short
+	if err := h.uploadSrv.WriteExport(ctx, c.Response(), export); err != nil {
+		fmt.Printf("export of %d uploads for team %d failed: %v\n", len(export.Uploads), session.TeamID, err)
+	}
+
+	return nil
+}
+
+// parseRangeFilter parses "keyID:min:max"
+func parseRangeFilter(raw string) (service.RangeFilter, error) {
+	invalid := echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid range %q, expected keyID:min:max", raw))
+
+	parts := strings.Split(raw, ":")
+	if len(parts) != 3 {
+		return service.RangeFilter{}, invalid
+	}
+
+	keyID, err := strconv.ParseInt(parts[0], 10, 32)
+	if err != nil {
+		return service.RangeFilter{}, invalid
+	}
+
+	min, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return service.RangeFilter{}, invalid
+	}
+
+	max, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil {
+		return service.RangeFilter{}, invalid
+	}
+
+	return service.RangeFilter{
+		KeyID: int32(keyID),
+		Min:   min,
+		Max:   max,
+	}, nil
+}
+
+// @Summary Update upload
+// @Description Rename an upload and/or replace its tags. Uploaders can edit their own uploads, mods any upload of the team. Uploads rejected by the malware scan can only be edited by mods.
+// @Tags Upload
+// @Accept json
+// @Param teamID path string true "Team ID"
+// @Param uploadID path string true "Upload ID"
+// @Param upload body dto.UpdateUploadBody true "Upload changes"
+// @Produce json
+// @Success 200 {object} dto.UpdateUploadResponse
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/uploads/{uploadID} [patch]
+func (h *uploadHandler) UpdateUpload(c echo.Context) error {
+	session, ok := auth.GetSession(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	v := dto.UpdateUploadRequest{}
+	if err := c.Bind(&v); err != nil {
+		return err
+	}
+
+	if err := c.Validate(&v); err != nil {
+		return err
+	}
+
+	var tags []service.UploadTagInput
+	if v.Tags != nil {
+		tags = toUploadTagInputs(*v.Tags)
+	}
+
+	ref, err := h.uploadSrv.UpdateUploadRef(c.Request().Context(), session.TeamID, session.UserID, session.Role, v.UploadID, v.FileName, tags)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &dto.UpdateUploadResponse{
+		Data: ref,
+	})
+}
+
+// @Summary Delete upload
+// @Description Remove an upload from the team. Uploaders can delete their own uploads, mods any upload of the team. Uploads rejected by the malware scan can only be deleted by mods.
+// @Tags Upload
+// @Param teamID path string true "Team ID"
+// @Param uploadID path string true "Upload ID"
+// @Success 204
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/uploads/{uploadID} [delete]
+func (h *uploadHandler) DeleteUpload(c echo.Context) error {
+	session, ok := auth.GetSession(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	v := dto.DeleteUploadRequest{}
+	if err := c.Bind(&v); err != nil {
+		return err
+	}
+
+	if err := c.Validate(&v); err != nil {
+		return err
+	}
+
+	if err := h.uploadSrv.DeleteUploadRef(c.Request().Context(), session.TeamID, session.UserID, session.Role, v.UploadID); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// @Summary List rejected uploads
+// @Description List uploads of a team that failed the malware scan. Only mods can list rejected uploads.
+// @Tags Upload
+// @Param teamID path string true "Team ID"
+// @Produce json
+// @Success 200 {object} dto.ListRejectedUploadsResponse
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/uploads/rejected [get]
+func (h *uploadHandler) ListRejectedUploads(c echo.Context) error {
+	session, ok := auth.GetSession(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	uploads, err := h.uploadSrv.ListRejectedUploads(c.Request().Context(), session.TeamID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &dto.ListRejectedUploadsResponse{
+		Data: uploads,
+	})
+}
+
 // @Summary Create pre-signed request
 // @Description Create a pre-signed request for uploading files to S3 via POST policy
 // @Tags Upload
@@ -44,7 +283,7 @@ func (h *uploadHandler) PresignUpload(c echo.Context) error {
 		return err
 	}
 
-	result, err := h.uploadSrv.PresignUpload(c.Request().Context(), session.TeamID, v.Name, v.MimeType, v.Size)
+	result, err := h.uploadSrv.PresignUpload(c.Request().Context(), session.TeamID, session.UserID, v.Name, v.MimeType, v.Size)
 	if err != nil {
 		return err
 	}
@@ -82,12 +321,7 @@ func (h *uploadHandler) CompleteUpload(c echo.Context) error {
 		return err
 	}
 
-	tags := make([][]int32, len(v.Tags))
-	for i, tag := range v.Tags {
-		tags[i] = []int32{tag.KeyID, tag.ValueID}
-	}
-
-	err := h.uploadSrv.CompleteUpload(c.Request().Context(), session.TeamID, session.UserID, v.Key, v.Name, v.MimeType, tags)
+	err := h.uploadSrv.CompleteUpload(c.Request().Context(), session.TeamID, session.UserID, v.Key, v.Name, v.VersionOf, toUploadTagInputs(v.Tags))
 	if err != nil {
 		return err
 	}
@@ -96,3 +330,557 @@ func (h *uploadHandler) CompleteUpload(c echo.Context) error {
 
 	return nil
 }
+
+// @Summary Check upload
+// @Description Check by hash and size whether the team already has a file before uploading it. in_team tells whether it is one of the team's uploads. exists also covers files the team can download as a version of one of its uploads, and with a name given those are added without uploading them again. Files of other teams are never matched.
+// @Tags Upload
+// @Accept json
+// @Param teamID path string true "Team ID"
+// @Param check_request body dto.CheckUploadBody true "Check upload request"
+// @Produce json
+// @Success 200 {object} dto.CheckUploadResponse
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/uploads/check [post]
+func (h *uploadHandler) CheckUpload(c echo.Context) error {
+	session, ok := auth.GetSession(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	v := dto.CheckUploadRequest{}
+	if err := c.Bind(&v); err != nil {
+		return err
+	}
+
+	if err := c.Validate(&v); err != nil {
+		return err
+	}
+
+	result, err := h.uploadSrv.CheckUpload(c.Request().Context(), session.TeamID, session.UserID, v.SHA256, v.Size, v.Name, v.VersionOf, toUploadTagInputs(v.Tags))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &dto.CheckUploadResponse{
+		Exists: result.Exists,
+		InTeam: result.InTeam,
+		Data:   result.Ref,
+	})
+}
+
+// maximum size of the non-file fields of a multipart upload
+const maxUploadFieldSize = 64 * 1024
+
+// @Summary Upload file
+// @Description Upload a file through the server, as an alternative to the pre-signed POST flow. Send either multipart/form-data, with the name and tags fields before the file part, or the raw file as the request body with name and tags in the query. The file is streamed to storage and then processed like a completed upload.
+// @Tags Upload
+// @Accept multipart/form-data
+// @Accept application/octet-stream
+// @Param teamID path string true "Team ID"
+// @Param file formData file false "File, for multipart requests"
+// @Param name formData string false "File name, defaults to the name of the file part"
+// @Param tags formData string false "JSON array of tags"
+// @Param name query string false "File name, for raw requests"
+// @Param tags query string false "JSON array of tags, for raw requests"
+// @Param version_of formData int false "Upload ID to add the file to as a new version"
+// @Param version_of query int false "Upload ID to add the file to as a new version, for raw requests"
+// @Success 201
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/uploads [post]
+func (h *uploadHandler) Upload(c echo.Context) error {
+	session, ok := auth.GetSession(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	// c.Bind would read a multipart body into memory before we get to stream it
+	v := dto.UploadRequest{}
+	binder := &echo.DefaultBinder{}
+	if err := binder.BindPathParams(c, &v); err != nil {
+		return err
+	}
+	if err := binder.BindQueryParams(c, &v); err != nil {
+		return err
+	}
+
+	if err := c.Validate(&v); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+
+	mediaType, _, _ := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+	if mediaType != echo.MIMEMultipartForm {
+		tags, err := parseUploadTags(v.Tags)
+		if err != nil {
+			return err
+		}
+
+		if err := h.uploadSrv.Upload(ctx, session.TeamID, session.UserID, v.Name, c.Request().Body, v.VersionOf, tags); err != nil {
+			return err
+		}
+
+		return c.NoContent(http.StatusCreated)
+	}
+
+	reader, err := c.Request().MultipartReader()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid multipart body")
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return echo.NewHTTPError(http.StatusBadRequest, "missing file part")
+		}
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid multipart body")
+		}
+
+		switch part.FormName() {
+		case "name", "tags", "version_of":
+			value, err := io.ReadAll(io.LimitReader(part, maxUploadFieldSize))
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, "invalid multipart body")
+			}
+			switch part.FormName() {
+			case "name":
+				v.Name = string(value)
+			case "tags":
+				v.Tags = string(value)
+			case "version_of":
+				versionOf, err := strconv.ParseInt(string(value), 10, 32)
+				if err != nil || versionOf < 1 {
+					return echo.NewHTTPError(http.StatusBadRequest, "version_of must be an upload ID")
+				}
+				v.VersionOf = int32(versionOf)
+			}
+
+		case "file":
+			tags, err := parseUploadTags(v.Tags)
+			if err != nil {
+				return err
+			}
+
+			name := v.Name
+			if name == "" {
+				name = part.FileName()
+			}
+
+			if err := h.uploadSrv.Upload(ctx, session.TeamID, session.UserID, name, part, v.VersionOf, tags); err != nil {
+				return err
+			}
+
+			return c.NoContent(http.StatusCreated)
+		}
+
+		part.Close()
+	}
+}
+
+// parseUploadTags parses a JSON array of tags, as sent with direct uploads
+func parseUploadTags(raw string) ([]service.UploadTagInput, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	tags := []dto.UploadTagBody{}
+	if err := json.Unmarshal([]byte(raw), &tags); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "tags must be a JSON array of tags")
+	}
+
+	return toUploadTagInputs(tags), nil
+}
+
+// @Summary Create pre-signed requests for an image set
+// @Description Create one pre-signed request per image. The images are combined into a single PDF on completion.
+// @Tags Upload
+// @Accept json
+// @Param teamID path string true "Team ID"
+// @Param upload_request body dto.PresignImageUploadBody true "Presign image set request"
+// @Produce json
+// @Success 200 {object} dto.PresignImageUploadResponse
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/uploads/images/presign [post]
+func (h *uploadHandler) PresignImageUpload(c echo.Context) error {
+	session, ok := auth.GetSession(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	v := dto.PresignImageUploadRequest{}
+	if err := c.Bind(&v); err != nil {
+		return err
+	}
+
+	if err := c.Validate(&v); err != nil {
+		return err
+	}
+
+	images := make([]service.ImagePartInput, len(v.Images))
+	for i, img := range v.Images {
+		images[i] = service.ImagePartInput{
+			MimeType: img.MimeType,
+			Size:     img.Size,
+		}
+	}
+
+	result, err := h.uploadSrv.PresignImageUpload(c.Request().Context(), session.TeamID, session.UserID, images)
+	if err != nil {
+		return err
+	}
+
+	parts := make([]dto.PresignImagePartResponse, len(result.Parts))
+	for i, part := range result.Parts {
+		parts[i] = dto.PresignImagePartResponse{
+			Key:    part.Key,
+			Url:    part.Url.String(),
+			Fields: part.Fields,
+		}
+	}
+
+	return c.JSON(http.StatusOK, &dto.PresignImageUploadResponse{
+		SetID: result.SetID,
+		Parts: parts,
+	})
+}
+
+// @Summary Complete image set upload
+// @Description Call this route after uploading all images of a set. Combines the images, in the given order, into a single PDF upload.
+// @Tags Upload
+// @Accept json
+// @Param teamID path string true "Team ID"
+// @Param upload_request body dto.CompleteImageUploadBody true "Complete image set upload request"
+// @Produce json
+// @Success 201
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/uploads/images/complete [post]
+func (h *uploadHandler) CompleteImageUpload(c echo.Context) error {
+	session, ok := auth.GetSession(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	v := dto.CompleteImageUploadRequest{}
+	if err := c.Bind(&v); err != nil {
+		return err
+	}
+
+	if err := c.Validate(&v); err != nil {
+		return err
+	}
+
+	err := h.uploadSrv.CompleteImageUpload(c.Request().Context(), session.TeamID, session.UserID, v.SetID, v.Keys, v.Name, v.VersionOf, toUploadTagInputs(v.Tags))
+	if err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusCreated)
+}
+
+// @Summary Get storage usage
+// @Description Get the bytes stored by the team and each uploader, and the team's quotas. Only mods can view usage.
+// @Tags Upload
+// @Param teamID path string true "Team ID"
+// @Produce json
+// @Success 200 {object} dto.UsageResponse
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/usage [get]
+func (h *uploadHandler) GetUsage(c echo.Context) error {
+	session, ok := auth.GetSession(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	usage, err := h.uploadSrv.GetUsage(c.Request().Context(), session.TeamID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, toUsageResponse(usage))
+}
+
+// @Summary Update storage quotas
+// @Description Set the bytes the team, and each uploader within it, may store. Null is unlimited. Only mods can update quotas.
+// @Tags Upload
+// @Accept json
+// @Param teamID path string true "Team ID"
+// @Param quotas body dto.UpdateQuotasBody true "Quotas"
+// @Produce json
+// @Success 200 {object} dto.UsageResponse
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/usage/quotas [put]
+func (h *uploadHandler) UpdateQuotas(c echo.Context) error {
+	session, ok := auth.GetSession(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	v := dto.UpdateQuotasRequest{}
+	if err := c.Bind(&v); err != nil {
+		return err
+	}
+
+	if err := c.Validate(&v); err != nil {
+		return err
+	}
+
+	usage, err := h.uploadSrv.UpdateQuotas(c.Request().Context(), session.TeamID, v.TeamQuota, v.UploaderQuota)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, toUsageResponse(usage))
+}
+
+func toUsageResponse(usage *service.TeamUsage) *dto.UsageResponse {
+	return &dto.UsageResponse{
+		Bytes:         usage.Bytes,
+		Uploads:       usage.Uploads,
+		TeamQuota:     usage.TeamQuota,
+		UploaderQuota: usage.UploaderQuota,
+		Uploaders:     usage.Uploaders,
+	}
+}
+
+// @Summary Get upload settings
+// @Description Get the content types, file name extensions and file size a team accepts on upload
+// @Tags Upload
+// @Param teamID path string true "Team ID"
+// @Produce json
+// @Success 200 {object} dto.UploadSettingsResponse
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/uploads/settings [get]
+func (h *uploadHandler) GetUploadSettings(c echo.Context) error {
+	session, ok := auth.GetSession(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	settings, err := h.uploadSrv.GetUploadSettings(c.Request().Context(), session.TeamID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, toUploadSettingsResponse(settings))
+}
+
+// @Summary Update upload settings
+// @Description Set the content types, file name extensions and file size a team accepts on upload. Only mods can update settings.
+// @Tags Upload
+// @Accept json
+// @Param teamID path string true "Team ID"
+// @Param settings body dto.UpdateUploadSettingsBody true "Upload settings"
+// @Produce json
+// @Success 200 {object} dto.UploadSettingsResponse
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/uploads/settings [put]
+func (h *uploadHandler) UpdateUploadSettings(c echo.Context) error {
+	session, ok := auth.GetSession(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	v := dto.UpdateUploadSettingsRequest{}
+	if err := c.Bind(&v); err != nil {
+		return err
+	}
+
+	if err := c.Validate(&v); err != nil {
+		return err
+	}
+
+	settings, err := h.uploadSrv.UpdateUploadSettings(c.Request().Context(), session.TeamID, v.AllowedMimeTypes, v.AllowedExtensions, v.MaxFileSize)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, toUploadSettingsResponse(settings))
+}
+
+// @Summary List upload previews
+// @Description List the rendered thumbnail and page previews of an upload with presigned GET URLs
+// @Tags Upload
+// @Param teamID path string true "Team ID"
+// @Param uploadID path string true "Upload ID"
+// @Produce json
+// @Success 200 {object} dto.ListUploadDerivativesResponse
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/uploads/{uploadID}/derivatives [get]
+func (h *uploadHandler) ListUploadDerivatives(c echo.Context) error {
+	session, ok := auth.GetSession(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	v := dto.ListUploadDerivativesRequest{}
+	if err := c.Bind(&v); err != nil {
+		return err
+	}
+
+	if err := c.Validate(&v); err != nil {
+		return err
+	}
+
+	derivatives, err := h.uploadSrv.ListUploadDerivatives(c.Request().Context(), session.TeamID, v.UploadID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &dto.ListUploadDerivativesResponse{
+		Data: toUploadDerivativeResponses(derivatives),
+	})
+}
+
+// @Summary Regenerate upload previews
+// @Description Render the thumbnail and page previews of a PDF upload again, and extract its text for search. Only mods can regenerate previews.
+// @Tags Upload
+// @Param teamID path string true "Team ID"
+// @Param uploadID path string true "Upload ID"
+// @Produce json
+// @Success 200 {object} dto.ListUploadDerivativesResponse
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/uploads/{uploadID}/derivatives [post]
+func (h *uploadHandler) RegenerateUploadDerivatives(c echo.Context) error {
+	session, ok := auth.GetSession(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	v := dto.RegenerateUploadDerivativesRequest{}
+	if err := c.Bind(&v); err != nil {
+		return err
+	}
+
+	if err := c.Validate(&v); err != nil {
+		return err
+	}
+
+	derivatives, err := h.uploadSrv.RegenerateDerivatives(c.Request().Context(), session.TeamID, v.UploadID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &dto.ListUploadDerivativesResponse{
+		Data: toUploadDerivativeResponses(derivatives),
+	})
+}
+
+// @Summary List upload versions
+// @Description List every version of an upload, newest first, with presigned GET URLs. current marks the version the upload is listed and searched with.
+// @Tags Upload
+// @Param teamID path string true "Team ID"
+// @Param uploadID path string true "Upload ID"
+// @Produce json
+// @Success 200 {object} dto.ListUploadVersionsResponse
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/uploads/{uploadID}/versions [get]
+func (h *uploadHandler) ListUploadVersions(c echo.Context) error {
+	session, ok := auth.GetSession(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	v := dto.ListUploadVersionsRequest{}
+	if err := c.Bind(&v); err != nil {
+		return err
+	}
+
+	if err := c.Validate(&v); err != nil {
+		return err
+	}
+
+	versions, err := h.uploadSrv.ListUploadVersions(c.Request().Context(), session.TeamID, v.UploadID)
+	if err != nil {
+		return err
+	}
+
+	out := make([]dto.UploadVersionResponse, len(versions))
+	for i, version := range versions {
+		out[i] = dto.UploadVersionResponse{
+			Version:      version.Version,
+			FileName:     version.FileName,
+			UploaderID:   version.UploaderID,
+			FileMimeType: version.FileMimeType,
+			FileSize:     version.FileSize,
+			CreatedAt:    version.CreatedAt,
+			Current:      version.Current,
+			Url:          version.Url.String(),
+		}
+	}
+
+	return c.JSON(http.StatusOK, &dto.ListUploadVersionsResponse{
+		Data: out,
+	})
+}
+
+// @Summary Set current upload version
+// @Description Make a version the current one of an upload, e.g. to roll back a bad revision. Only mods can set the current version.
+// @Tags Upload
+// @Accept json
+// @Param teamID path string true "Team ID"
+// @Param uploadID path string true "Upload ID"
+// @Param version_request body dto.SetCurrentUploadVersionBody true "Set current version request"
+// @Produce json
+// @Success 200 {object} dto.UpdateUploadResponse
+// @Failure default {object} dto.ErrorResponse
+// @Router /api/teams/{teamID}/uploads/{uploadID}/versions/current [put]
+func (h *uploadHandler) SetCurrentUploadVersion(c echo.Context) error {
+	session, ok := auth.GetSession(c)
+	if !ok {
+		return echo.ErrUnauthorized
+	}
+
+	v := dto.SetCurrentUploadVersionRequest{}
+	if err := c.Bind(&v); err != nil {
+		return err
+	}
+
+	if err := c.Validate(&v); err != nil {
+		return err
+	}
+
+	ref, err := h.uploadSrv.SetCurrentUploadVersion(c.Request().Context(), session.TeamID, v.UploadID, v.Version)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &dto.UpdateUploadResponse{
+		Data: ref,
+	})
+}
+
+func toUploadDerivativeResponses(derivatives []service.UploadDerivative) []dto.UploadDerivativeResponse {
+	out := make([]dto.UploadDerivativeResponse, len(derivatives))
+	for i, d := range derivatives {
+		out[i] = dto.UploadDerivativeResponse{
+			Kind:   d.Kind,
+			Page:   d.Page,
+			Width:  d.Width,
+			Height: d.Height,
+			Url:    d.Url.String(),
+		}
+	}
+	return out
+}
+
+func toUploadTagInputs(tags []dto.UploadTagBody) []service.UploadTagInput {
+	out := make([]service.UploadTagInput, len(tags))
+	for i, tag := range tags {
+		out[i] = service.UploadTagInput{
+			KeyID:   tag.KeyID,
+			ValueID: tag.ValueID,
+			Value:   tag.Value,
+		}
+	}
+	return out
+}
+
+func toUploadSettingsResponse(settings *service.UploadSettings) *dto.UploadSettingsResponse {
+	return &dto.UploadSettingsResponse{
+		AllowedMimeTypes:  settings.AllowedMimeTypes,
+		IsDefault:         settings.IsDefault,
+		AllowedExtensions: settings.AllowedExtensions,
+		MaxFileSize:       settings.MaxFileSize,
+	}
+}
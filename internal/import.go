@@ -0,0 +1,370 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/joho/godotenv"
+	"github.com/skndash96/lastnight-backend/internal/config"
+	"github.com/skndash96/lastnight-backend/internal/provider"
+	"github.com/skndash96/lastnight-backend/internal/service"
+)
+
+// Import adds the files of a directory to a team, tagged as listed in a CSV or
+// JSON manifest, from the command line.
+func Import(args []string) error {
+	_ = godotenv.Load()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	appCfg := config.New()
+
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	teamID := fs.Int("team", 0, "team to import into")
+	userID := fs.Int("user", 0, "member of the team the files are uploaded as")
+	dir := fs.String("dir", "", "directory the manifest's file paths are relative to")
+	manifestPath := fs.String("manifest", "", "CSV or JSON manifest of the files to import and their tags")
+	createValues := fs.Bool("create-values", false, "create tag values missing on closed keys")
+	dryRun := fs.Bool("dry-run", false, "check the files and tags without importing anything")
+	statePath := fs.String("state", "", "file recording imported files, to resume an interrupted import (default <manifest>.state)")
+	reportPath := fs.String("report", "", "write a JSON report of every file to this path")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *teamID <= 0 || *userID <= 0 || *dir == "" || *manifestPath == "" {
+		fs.Usage()
+		return errors.New("-team, -user, -dir and -manifest are required")
+	}
+	if *statePath == "" {
+		*statePath = *manifestPath + ".state"
+	}
+
+	entries, err := readImportManifest(*manifestPath, *dir)
+	if err != nil {
+		return err
+	}
+
+	done, err := readImportState(*statePath)
+	if err != nil {
+		return err
+	}
+
+	pending := []service.ImportEntry{}
+	for _, entry := range entries {
+		if !done[entry.Path] {
+			pending = append(pending, entry)
+		}
+	}
+	if resumed := len(entries) - len(pending); resumed > 0 {
+		fmt.Printf("resuming, %d files were imported by an earlier run\n", resumed)
+	}
+
+	pool, err := pgxpool.New(ctx, appCfg.DbURL)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	uploadProvider, err := provider.NewUploadProvider(appCfg.Storage)
+	if err != nil {
+		return err
+	}
+
+	uploadSrv := service.NewUploadService(ctx, appCfg.Upload, uploadProvider, pool)
+	importSrv := service.NewImportService(uploadSrv, pool)
+
+	var state *os.File
+	if !*dryRun {
+		state, err = os.OpenFile(*statePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+		defer state.Close()
+	}
+
+	report := importReport{
+		TeamID:    int32(*teamID),
+		DryRun:    *dryRun,
+		StartedAt: time.Now(),
+		Resumed:   len(entries) - len(pending),
+		Counts:    map[service.ImportStatus]int{},
+		Results:   []service.ImportResult{},
+	}
+
+	err = importSrv.Import(ctx, int32(*teamID), int32(*userID), pending, service.ImportOptions{
+		CreateValues: *createValues,
+		DryRun:       *dryRun,
+	}, func(result service.ImportResult) {
+		printImportResult(result, *dryRun)
+
+		report.Counts[result.Status]++
+		report.Results = append(report.Results, result)
+		if result.Status == service.ImportStatusImported {
+			report.Bytes += result.Size
+		}
+		for _, value := range result.CreatedValues {
+			if !slices.Contains(report.CreatedValues, value) {
+				report.CreatedValues = append(report.CreatedValues, value)
+			}
+		}
+
+		if state != nil && result.Status != service.ImportStatusFailed {
+			if err := json.NewEncoder(state).Encode(result); err != nil {
+				fmt.Printf("failed to record %s in %s: %v\n", result.Path, *statePath, err)
+			}
+		}
+	})
+	report.FinishedAt = time.Now()
+
+	// previews and text of the last files are still being generated
+	uploadSrv.WaitProcessing()
+
+	printImportSummary(&report)
+
+	if *reportPath != "" {
+		if err := writeImportReport(*reportPath, &report); err != nil {
+			return err
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+	if n := report.Counts[service.ImportStatusFailed]; n > 0 {
+		return fmt.Errorf("%d files failed to import, run again to retry them", n)
+	}
+
+	return nil
+}
+
+type importReport struct {
+	TeamID     int32     `json:"team_id"`
+	DryRun     bool      `json:"dry_run"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	// files skipped as imported by an earlier run
+	Resumed       int                          `json:"resumed"`
+	Counts        map[service.ImportStatus]int `json:"counts"`
+	Bytes         int64                        `json:"bytes"`
+	CreatedValues []string                     `json:"created_values"`
+	Results       []service.ImportResult       `json:"results"`
+}
+
+func printImportResult(result service.ImportResult, dryRun bool) {
+	switch result.Status {
+	case service.ImportStatusFailed:
+		fmt.Printf("failed %s: %s\n", result.Path, result.Error)
+	case service.ImportStatusInTeam:
+		fmt.Printf("skipped %s, already in the team as upload %d\n", result.Path, result.UploadID)
+	default:
+		verb := string(result.Status)
+		if dryRun {
+			verb = "would be " + verb
+		}
+		fmt.Printf("%s %s as %s (%d bytes)\n", verb, result.Path, result.Name, result.Size)
+	}
+
+	for _, value := range result.CreatedValues {
+		fmt.Printf("  new tag value %s\n", value)
+	}
+}
+
+func printImportSummary(report *importReport) {
+	prefix := ""
+	if report.DryRun {
+		prefix = "dry run: "
+	}
+
+	fmt.Printf("%simported %d files (%d bytes), linked %d older versions, skipped %d already in the team, %d failed",
+		prefix,
+		report.Counts[service.ImportStatusImported],
+		report.Bytes,
+		report.Counts[service.ImportStatusLinked],
+		report.Counts[service.ImportStatusInTeam],
+		report.Counts[service.ImportStatusFailed],
+	)
+	if report.Resumed > 0 {
+		fmt.Printf(", %d resumed", report.Resumed)
+	}
+	fmt.Printf(", %d new tag values in %s\n", len(report.CreatedValues), report.FinishedAt.Sub(report.StartedAt).Round(time.Second))
+}
+
+func writeImportReport(path string, report *importReport) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// readImportState returns the paths recorded as done by earlier runs.
+func readImportState(path string) (map[string]bool, error) {
+	done := map[string]bool{}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return done, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		result := service.ImportResult{}
+		// a line cut short by a crash is ignored, its file is imported again
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			continue
+		}
+		done[result.Path] = true
+	}
+
+	return done, scanner.Err()
+}
+
+type importManifestEntry struct {
+	File string `json:"file"`
+	// defaults to the base name of file
+	Name string `json:"name"`
+	// tag key names to value names
+	Tags map[string]string `json:"tags"`
+}
+
+// readImportManifest reads a manifest, by its extension either a JSON array of
+// importManifestEntry or a CSV with a "file" column, an optional "name" column
+// and one column per tag key, whose empty cells are skipped.
+func readImportManifest(path, dir string) ([]service.ImportEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var raw []importManifestEntry
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		if err := json.NewDecoder(f).Decode(&raw); err != nil {
+			return nil, fmt.Errorf("invalid manifest: %w", err)
+		}
+	case ".csv":
+		raw, err = readImportCSV(f)
+		if err != nil {
+			return nil, fmt.Errorf("invalid manifest: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("manifest must be a .csv or .json file")
+	}
+
+	seen := map[string]bool{}
+	entries := make([]service.ImportEntry, 0, len(raw))
+	for i, e := range raw {
+		file := filepath.FromSlash(strings.TrimSpace(e.File))
+		if !filepath.IsLocal(file) {
+			return nil, fmt.Errorf("manifest entry %d: file %q must be a path within the directory", i+1, e.File)
+		}
+
+		entryPath := filepath.Join(dir, file)
+		if seen[entryPath] {
+			return nil, fmt.Errorf("manifest entry %d: file %q is listed twice", i+1, e.File)
+		}
+		seen[entryPath] = true
+
+		name := strings.TrimSpace(e.Name)
+		if name == "" {
+			name = filepath.Base(file)
+		}
+
+		keys := make([]string, 0, len(e.Tags))
+		for key := range e.Tags {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		tags := make([]service.ImportTag, 0, len(keys))
+		for _, key := range keys {
+			tags = append(tags, service.ImportTag{Key: key, Value: e.Tags[key]})
+		}
+
+		entries = append(entries, service.ImportEntry{
+			Path: entryPath,
+			Name: name,
+			Tags: tags,
+		})
+	}
+
+	return entries, nil
+}
+
+func readImportCSV(r io.Reader) ([]importManifestEntry, error) {
+	reader := csv.NewReader(r)
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	fileCol, nameCol := -1, -1
+	for i, col := range header {
+		switch strings.TrimSpace(col) {
+		case "file":
+			fileCol = i
+		case "name":
+			nameCol = i
+		}
+	}
+	if fileCol == -1 {
+		return nil, errors.New(`missing "file" column`)
+	}
+
+	entries := []importManifestEntry{}
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		entry := importManifestEntry{
+			File: row[fileCol],
+			Tags: map[string]string{},
+		}
+		for i, cell := range row {
+			switch {
+			case i == fileCol:
+			case i == nameCol:
+				entry.Name = cell
+			case strings.TrimSpace(cell) != "":
+				entry.Tags[strings.TrimSpace(header[i])] = cell
+			}
+		}
+
+		entries = append(entries, entry)
+	}
+}
@@ -0,0 +1,180 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// tiffHeader builds the TIFF structure of an EXIF segment with a single IFD
+// holding entries of (tag, value) SHORTs.
+func tiffHeader(order binary.ByteOrder, entries ...[2]uint16) []byte {
+	var b bytes.Buffer
+	if order == binary.LittleEndian {
+		b.WriteString("II")
+	} else {
+		b.WriteString("MM")
+	}
+	binary.Write(&b, order, uint16(42))
+	binary.Write(&b, order, uint32(8))
+
+	binary.Write(&b, order, uint16(len(entries)))
+	for _, e := range entries {
+		binary.Write(&b, order, e[0])
+		binary.Write(&b, order, uint16(3))
+		binary.Write(&b, order, uint32(1))
+		binary.Write(&b, order, e[1])
+		binary.Write(&b, order, uint16(0))
+	}
+	binary.Write(&b, order, uint32(0))
+
+	return b.Bytes()
+}
+
+// segment builds a JPEG marker segment with its length.
+func segment(marker byte, payload []byte) []byte {
+	out := []byte{0xFF, marker}
+	out = binary.BigEndian.AppendUint16(out, uint16(len(payload)+2))
+	return append(out, payload...)
+}
+
+func exifSegment(tiff []byte) []byte {
+	return segment(0xE1, append([]byte("Exif\x00\x00"), tiff...))
+}
+
+// jpegWith builds the start of a JPEG with the given segments, up to the start of scan.
+func jpegWith(segments ...[]byte) []byte {
+	out := []byte{0xFF, 0xD8}
+	for _, s := range segments {
+		out = append(out, s...)
+	}
+	return append(out, 0xFF, 0xDA, 0x00, 0x02)
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	orientation := func(o uint16) [2]uint16 { return [2]uint16{0x0112, o} }
+
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"little endian", jpegWith(exifSegment(tiffHeader(binary.LittleEndian, orientation(6)))), 6},
+		{"big endian", jpegWith(exifSegment(tiffHeader(binary.BigEndian, orientation(8)))), 8},
+		{"after other tags", jpegWith(exifSegment(tiffHeader(binary.LittleEndian, [2]uint16{0x010F, 1}, [2]uint16{0x0110, 2}, orientation(3)))), 3},
+		{"after other segments", jpegWith(segment(0xE0, []byte("JFIF\x00")), segment(0xE1, []byte("http://ns.adobe.com/xap/1.0/\x00")), exifSegment(tiffHeader(binary.BigEndian, orientation(5)))), 5},
+		{"upright", jpegWith(exifSegment(tiffHeader(binary.LittleEndian, orientation(1)))), 1},
+		{"orientation 0", jpegWith(exifSegment(tiffHeader(binary.LittleEndian, orientation(0)))), 1},
+		{"orientation 9", jpegWith(exifSegment(tiffHeader(binary.LittleEndian, orientation(9)))), 1},
+		{"orientation 0xFFFF", jpegWith(exifSegment(tiffHeader(binary.BigEndian, orientation(0xFFFF)))), 1},
+		{"no orientation tag", jpegWith(exifSegment(tiffHeader(binary.LittleEndian, [2]uint16{0x010F, 6}))), 1},
+		{"no exif", jpegWith(segment(0xE0, []byte("JFIF\x00"))), 1},
+		{"exif after start of scan", append(jpegWith(), exifSegment(tiffHeader(binary.LittleEndian, orientation(6)))...), 1},
+		{"not a jpeg", []byte("\x89PNG\r\n\x1a\n"), 1},
+		{"empty", nil, 1},
+		{"only start of image", []byte{0xFF, 0xD8}, 1},
+		{"not a marker", []byte{0xFF, 0xD8, 0x00, 0xE1}, 1},
+		{"truncated length", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00}, 1},
+		{"length below 2", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x01, 0xFF, 0xDA}, 1},
+		{"truncated segment", append([]byte{0xFF, 0xD8}, exifSegment(tiffHeader(binary.LittleEndian, orientation(6)))[:20]...), 1},
+		{"unknown byte order", jpegWith(exifSegment(append([]byte("XX"), tiffHeader(binary.LittleEndian, orientation(6))[2:]...))), 1},
+		{"short tiff header", jpegWith(exifSegment([]byte("II*\x00"))), 1},
+		{"ifd past the end", jpegWith(exifSegment([]byte("II*\x00\xFF\xFF\xFF\xFF"))), 1},
+		{"ifd without entry count", jpegWith(exifSegment([]byte("II*\x00\x08\x00\x00\x00"))), 1},
+		{"entries past the end", jpegWith(exifSegment(tiffHeader(binary.LittleEndian, orientation(6))[:20])), 1},
+		{"more entries than present", jpegWith(exifSegment(append([]byte("II*\x00\x08\x00\x00\x00\x05\x00"), make([]byte, 12)...))), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jpegOrientation(tt.data); got != tt.want {
+				t.Errorf("jpegOrientation() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// grayImage builds an image from rows of gray values.
+func grayImage(rows ...[]uint8) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, len(rows[0]), len(rows)))
+	for y, row := range rows {
+		for x, v := range row {
+			img.SetGray(x, y, color.Gray{Y: v})
+		}
+	}
+	return img
+}
+
+// grayRows returns the gray values of an image, row by row.
+func grayRows(img image.Image) [][]uint8 {
+	b := img.Bounds()
+	rows := make([][]uint8, b.Dy())
+	for y := range rows {
+		rows[y] = make([]uint8, b.Dx())
+		for x := range rows[y] {
+			rows[y][x] = color.GrayModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.Gray).Y
+		}
+	}
+	return rows
+}
+
+func TestOrient(t *testing.T) {
+	src := grayImage(
+		[]uint8{0, 10, 20},
+		[]uint8{30, 40, 50},
+	)
+
+	tests := []struct {
+		orientation int
+		want        [][]uint8
+	}{
+		{0, [][]uint8{{0, 10, 20}, {30, 40, 50}}},
+		{1, [][]uint8{{0, 10, 20}, {30, 40, 50}}},
+		{2, [][]uint8{{20, 10, 0}, {50, 40, 30}}},
+		{3, [][]uint8{{50, 40, 30}, {20, 10, 0}}},
+		{4, [][]uint8{{30, 40, 50}, {0, 10, 20}}},
+		{5, [][]uint8{{0, 30}, {10, 40}, {20, 50}}},
+		{6, [][]uint8{{30, 0}, {40, 10}, {50, 20}}},
+		{7, [][]uint8{{50, 20}, {40, 10}, {30, 0}}},
+		{8, [][]uint8{{20, 50}, {10, 40}, {0, 30}}},
+		{9, [][]uint8{{0, 10, 20}, {30, 40, 50}}},
+		{-1, [][]uint8{{0, 10, 20}, {30, 40, 50}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.orientation), func(t *testing.T) {
+			got := grayRows(orient(src, tt.orientation))
+			if !equalRows(got, tt.want) {
+				t.Errorf("orient(%d) = %v, want %v", tt.orientation, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrientSubImage(t *testing.T) {
+	src := grayImage(
+		[]uint8{0, 0, 0},
+		[]uint8{0, 10, 20},
+		[]uint8{0, 30, 40},
+	).SubImage(image.Rect(1, 1, 3, 3))
+
+	got := grayRows(orient(src, 6))
+	if want := [][]uint8{{30, 10}, {40, 20}}; !equalRows(got, want) {
+		t.Errorf("orient(6) = %v, want %v", got, want)
+	}
+}
+
+func equalRows(a, b [][]uint8) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
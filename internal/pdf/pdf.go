@@ -0,0 +1,234 @@
+// Package pdf assembles images into PDF documents, and renders previews of PDFs
+// and extracts their text with the poppler command line tools.
+package pdf
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"io"
+
+	_ "image/gif"
+	_ "image/png"
+)
+
+const (
+	// page width in points (A4), heights follow each image's aspect ratio
+	pageWidth = 595.0
+	// pixels of an image decoded when Options.MaxPixels is not set, about 200MB as RGBA
+	defaultMaxPixels = 50_000_000
+)
+
+var (
+	ErrNoImages      = errors.New("no images to combine")
+	ErrImageTooLarge = errors.New("image dimensions are too large")
+)
+
+type Options struct {
+	// longest side of an image in pixels, larger images are downscaled
+	MaxDimension int
+	// images with more pixels are rejected before decoding them, a small file
+	// can declare huge dimensions
+	MaxPixels   int64
+	JPEGQuality int
+}
+
+// FromImages writes a PDF to w with one page per image, in order. Images are
+// rotated according to their EXIF orientation, downscaled and re-encoded as JPEG.
+func FromImages(w io.Writer, images [][]byte, opts Options) error {
+	if len(images) == 0 {
+		return ErrNoImages
+	}
+
+	pw := NewWriter(w, opts)
+	for _, data := range images {
+		if err := pw.AddImage(data); err != nil {
+			return err
+		}
+	}
+
+	return pw.Close()
+}
+
+// Writer writes a PDF with one page per image added. Each page is written out as
+// it is added, so only one image is held in memory at a time.
+type Writer struct {
+	w    *countingWriter
+	opts Options
+	// byte offset of each object, by object number - 1
+	offsets []int64
+	pageIDs []int
+}
+
+// NewWriter starts a PDF on w, which is finished by Close.
+func NewWriter(w io.Writer, opts Options) *Writer {
+	if opts.JPEGQuality <= 0 {
+		opts.JPEGQuality = 85
+	}
+	if opts.MaxPixels <= 0 {
+		opts.MaxPixels = defaultMaxPixels
+	}
+
+	pw := &Writer{
+		w:    &countingWriter{w: bufio.NewWriter(w)},
+		opts: opts,
+		// the catalog and the page tree are written by Close
+		offsets: make([]int64, 2),
+	}
+	fmt.Fprint(pw.w, "%PDF-1.4\n%\xE2\xE3\xCF\xD3\n")
+
+	return pw
+}
+
+// AddImage adds a page with an image, rotated according to its EXIF orientation,
+// downscaled and re-encoded as JPEG.
+func (pw *Writer) AddImage(data []byte) error {
+	p, err := preparePage(data, pw.opts)
+	if err != nil {
+		return fmt.Errorf("image %d: %w", len(pw.pageIDs)+1, err)
+	}
+
+	pw.writePage(p)
+	return pw.w.err
+}
+
+// Close writes the page tree and the cross-reference table. It fails if no
+// image was added.
+func (pw *Writer) Close() error {
+	if len(pw.pageIDs) == 0 {
+		return ErrNoImages
+	}
+
+	w := pw.w
+
+	pw.startObj(1)
+	fmt.Fprint(w, "<< /Type /Catalog /Pages 2 0 R >>\n")
+	pw.endObj()
+
+	pw.startObj(2)
+	fmt.Fprint(w, "<< /Type /Pages /Kids [")
+	for _, id := range pw.pageIDs {
+		fmt.Fprintf(w, " %d 0 R", id)
+	}
+	fmt.Fprintf(w, " ] /Count %d >>\n", len(pw.pageIDs))
+	pw.endObj()
+
+	xref := w.n
+	fmt.Fprintf(w, "xref\n0 %d\n0000000000 65535 f \n", len(pw.offsets)+1)
+	for _, off := range pw.offsets {
+		fmt.Fprintf(w, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(w, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(pw.offsets)+1, xref)
+
+	if w.err != nil {
+		return w.err
+	}
+	return w.w.Flush()
+}
+
+type page struct {
+	jpeg          []byte
+	width, height int
+}
+
+func preparePage(data []byte, opts Options) (page, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return page{}, err
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > opts.MaxPixels {
+		return page{}, fmt.Errorf("%w: %dx%d", ErrImageTooLarge, cfg.Width, cfg.Height)
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return page{}, err
+	}
+
+	// orienting is cheaper on the downscaled image, and scaling keeps the aspect ratio
+	img = downscale(img, opts.MaxDimension)
+
+	if format == "jpeg" {
+		img = orient(img, jpegOrientation(data))
+	}
+
+	// flatten transparency onto white, JPEG has no alpha
+	b := img.Bounds()
+	flat := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(flat, flat.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, b.Min, draw.Over)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, flat, &jpeg.Options{Quality: opts.JPEGQuality}); err != nil {
+		return page{}, err
+	}
+
+	return page{
+		jpeg:   buf.Bytes(),
+		width:  b.Dx(),
+		height: b.Dy(),
+	}, nil
+}
+
+// writePage writes a page, an image and a content stream object.
+func (pw *Writer) writePage(p page) {
+	w := pw.w
+
+	pageID := pw.newObj()
+	imageID, contentID := pw.newObj(), pw.newObj()
+	pw.pageIDs = append(pw.pageIDs, pageID)
+
+	width := pageWidth
+	height := pageWidth * float64(p.height) / float64(p.width)
+
+	pw.startObj(pageID)
+	fmt.Fprintf(w, "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /XObject << /Im0 %d 0 R >> >> /Contents %d 0 R >>\n", width, height, imageID, contentID)
+	pw.endObj()
+
+	pw.startObj(imageID)
+	fmt.Fprintf(w, "<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /DCTDecode /Length %d >>\nstream\n", p.width, p.height, len(p.jpeg))
+	w.Write(p.jpeg)
+	fmt.Fprint(w, "\nendstream\n")
+	pw.endObj()
+
+	content := fmt.Sprintf("q %.2f 0 0 %.2f 0 0 cm /Im0 Do Q", width, height)
+	pw.startObj(contentID)
+	fmt.Fprintf(w, "<< /Length %d >>\nstream\n%s\nendstream\n", len(content), content)
+	pw.endObj()
+}
+
+// newObj allocates the next object number.
+func (pw *Writer) newObj() int {
+	pw.offsets = append(pw.offsets, 0)
+	return len(pw.offsets)
+}
+
+func (pw *Writer) startObj(id int) {
+	pw.offsets[id-1] = pw.w.n
+	fmt.Fprintf(pw.w, "%d 0 obj\n", id)
+}
+
+func (pw *Writer) endObj() {
+	fmt.Fprint(pw.w, "endobj\n")
+}
+
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
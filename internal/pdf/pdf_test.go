@@ -0,0 +1,272 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// encodeJPEG encodes img as a JPEG with the given EXIF orientation.
+func encodeJPEG(t *testing.T, img image.Image, orientation uint16) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	data := buf.Bytes()
+	exif := exifSegment(tiffHeader(binary.LittleEndian, [2]uint16{0x0112, orientation}))
+	return append(append(data[:2:2], exif...), data[2:]...)
+}
+
+func solidImage(w, h int, c color.Color) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+var (
+	objRe      = regexp.MustCompile(`(?m)^(\d+) 0 obj\n`)
+	mediaBoxRe = regexp.MustCompile(`/MediaBox \[0 0 ([\d.]+) ([\d.]+)\]`)
+	imageRe    = regexp.MustCompile(`/Subtype /Image /Width (\d+) /Height (\d+) .* /Length (\d+) >>\nstream\n`)
+)
+
+// checkStructure verifies the cross-reference table of a PDF points at each of
+// its objects and returns the number of pages.
+func checkStructure(t *testing.T, data []byte) int {
+	t.Helper()
+
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4\n")) {
+		t.Fatalf("missing PDF header: %q", data[:min(len(data), 16)])
+	}
+	if !bytes.HasSuffix(data, []byte("%%EOF\n")) {
+		t.Fatal("missing end of file marker")
+	}
+
+	s := string(data)
+	i := strings.LastIndex(s, "startxref\n")
+	if i < 0 {
+		t.Fatal("missing startxref")
+	}
+	xref, err := strconv.Atoi(strings.Fields(s[i+len("startxref\n"):])[0])
+	if err != nil || !strings.HasPrefix(s[xref:], "xref\n") {
+		t.Fatalf("startxref %d does not point at the xref table", xref)
+	}
+
+	lines := strings.Split(s[xref:], "\n")
+	var first, size int
+	if _, err := fmt.Sscanf(lines[1], "%d %d", &first, &size); err != nil || first != 0 {
+		t.Fatalf("malformed xref subsection %q", lines[1])
+	}
+	if objs := len(objRe.FindAllString(s, -1)); size != objs+1 {
+		t.Fatalf("xref has %d entries, want %d", size, objs+1)
+	}
+
+	for id := 1; id < size; id++ {
+		var off int
+		if _, err := fmt.Sscanf(lines[2+id], "%010d 00000 n", &off); err != nil {
+			t.Fatalf("malformed xref entry %q", lines[2+id])
+		}
+		if want := fmt.Sprintf("%d 0 obj\n", id); !strings.HasPrefix(s[off:], want) {
+			t.Errorf("xref entry of object %d points at %q", id, s[off:min(off+16, len(s))])
+		}
+	}
+
+	if !strings.Contains(s, fmt.Sprintf("trailer\n<< /Size %d /Root 1 0 R >>", size)) {
+		t.Error("malformed trailer")
+	}
+
+	m := regexp.MustCompile(`/Type /Pages /Kids \[[\d R]*\] /Count (\d+)`).FindStringSubmatch(s)
+	if m == nil {
+		t.Fatal("missing page tree")
+	}
+	pages, _ := strconv.Atoi(m[1])
+	return pages
+}
+
+// pdfImages decodes the JPEG of each page.
+func pdfImages(t *testing.T, data []byte) []image.Image {
+	t.Helper()
+
+	var out []image.Image
+	s := string(data)
+	for _, loc := range imageRe.FindAllStringSubmatchIndex(s, -1) {
+		length, _ := strconv.Atoi(s[loc[6]:loc[7]])
+		img, err := jpeg.Decode(bytes.NewReader(data[loc[1] : loc[1]+length]))
+		if err != nil {
+			t.Fatalf("page %d: %v", len(out)+1, err)
+		}
+		out = append(out, img)
+	}
+	return out
+}
+
+func TestFromImages(t *testing.T) {
+	images := [][]byte{
+		encodePNG(t, solidImage(200, 100, color.NRGBA{R: 255, A: 255})),
+		encodeJPEG(t, solidImage(40, 80, color.NRGBA{B: 255, A: 255}), 1),
+		encodePNG(t, solidImage(50, 50, color.NRGBA{G: 255, A: 255})),
+	}
+
+	var buf bytes.Buffer
+	if err := FromImages(&buf, images, Options{}); err != nil {
+		t.Fatalf("FromImages() error = %v", err)
+	}
+
+	if pages := checkStructure(t, buf.Bytes()); pages != 3 {
+		t.Errorf("PDF has %d pages, want 3", pages)
+	}
+
+	boxes := mediaBoxRe.FindAllStringSubmatch(buf.String(), -1)
+	want := [][2]string{{"595.00", "297.50"}, {"595.00", "1190.00"}, {"595.00", "595.00"}}
+	if len(boxes) != len(want) {
+		t.Fatalf("PDF has %d media boxes, want %d", len(boxes), len(want))
+	}
+	for i, box := range boxes {
+		if box[1] != want[i][0] || box[2] != want[i][1] {
+			t.Errorf("page %d is %sx%s, want %sx%s", i+1, box[1], box[2], want[i][0], want[i][1])
+		}
+	}
+
+	colors := []color.RGBA{{R: 255}, {B: 255}, {G: 255}}
+	for i, img := range pdfImages(t, buf.Bytes()) {
+		r, g, b, _ := img.At(img.Bounds().Dx()/2, img.Bounds().Dy()/2).RGBA()
+		want := colors[i]
+		if !near(r, want.R) || !near(g, want.G) || !near(b, want.B) {
+			t.Errorf("page %d has color %d,%d,%d, want %v", i+1, r>>8, g>>8, b>>8, want)
+		}
+	}
+}
+
+// near reports whether a 16-bit color channel is within JPEG loss of an 8-bit one.
+func near(got uint32, want uint8) bool {
+	d := int(got>>8) - int(want)
+	return d > -16 && d < 16
+}
+
+func TestFromImagesPages(t *testing.T) {
+	tests := []struct {
+		name         string
+		image        []byte
+		opts         Options
+		wantW, wantH int
+	}{
+		{"png", encodePNG(t, solidImage(30, 20, color.White)), Options{}, 30, 20},
+		{"downscaled", encodePNG(t, solidImage(400, 100, color.White)), Options{MaxDimension: 100}, 100, 25},
+		{"upright jpeg", encodeJPEG(t, solidImage(40, 20, color.White), 1), Options{}, 40, 20},
+		{"rotated jpeg", encodeJPEG(t, solidImage(40, 20, color.White), 6), Options{}, 20, 40},
+		{"mirrored jpeg", encodeJPEG(t, solidImage(40, 20, color.White), 2), Options{}, 40, 20},
+		{"rotated and downscaled jpeg", encodeJPEG(t, solidImage(400, 200, color.White), 8), Options{MaxDimension: 100}, 50, 100},
+		{"invalid orientation", encodeJPEG(t, solidImage(40, 20, color.White), 42), Options{}, 40, 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := FromImages(&buf, [][]byte{tt.image}, tt.opts); err != nil {
+				t.Fatalf("FromImages() error = %v", err)
+			}
+			checkStructure(t, buf.Bytes())
+
+			m := imageRe.FindStringSubmatch(buf.String())
+			if m == nil {
+				t.Fatal("missing image object")
+			}
+			if m[1] != strconv.Itoa(tt.wantW) || m[2] != strconv.Itoa(tt.wantH) {
+				t.Errorf("image is %sx%s, want %dx%d", m[1], m[2], tt.wantW, tt.wantH)
+			}
+		})
+	}
+}
+
+func TestFromImagesFlattensTransparency(t *testing.T) {
+	var buf bytes.Buffer
+	if err := FromImages(&buf, [][]byte{encodePNG(t, solidImage(10, 10, color.Transparent))}, Options{}); err != nil {
+		t.Fatalf("FromImages() error = %v", err)
+	}
+
+	img := pdfImages(t, buf.Bytes())[0]
+	if r, g, b, _ := img.At(5, 5).RGBA(); !near(r, 255) || !near(g, 255) || !near(b, 255) {
+		t.Errorf("transparent pixel is %d,%d,%d, want white", r>>8, g>>8, b>>8)
+	}
+}
+
+func TestFromImagesErrors(t *testing.T) {
+	valid := encodePNG(t, solidImage(20, 20, color.White))
+
+	tests := []struct {
+		name    string
+		images  [][]byte
+		opts    Options
+		is      error
+		message string
+	}{
+		{"no images", nil, Options{}, ErrNoImages, ""},
+		{"too many pixels", [][]byte{valid}, Options{MaxPixels: 399}, ErrImageTooLarge, "image 1"},
+		{"not an image", [][]byte{valid, []byte("not an image")}, Options{}, image.ErrFormat, "image 2"},
+		{"truncated png", [][]byte{valid, valid[:len(valid)/2]}, Options{}, nil, "image 2"},
+		{"empty image", [][]byte{{}}, Options{}, nil, "image 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := FromImages(&bytes.Buffer{}, tt.images, tt.opts)
+			if err == nil {
+				t.Fatal("FromImages() succeeded")
+			}
+			if tt.is != nil && !errors.Is(err, tt.is) {
+				t.Errorf("FromImages() error = %v, want %v", err, tt.is)
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("FromImages() error = %v, want it to name %q", err, tt.message)
+			}
+		})
+	}
+}
+
+func TestWriterWithoutImages(t *testing.T) {
+	if err := NewWriter(&bytes.Buffer{}, Options{}).Close(); !errors.Is(err, ErrNoImages) {
+		t.Errorf("Close() error = %v, want %v", err, ErrNoImages)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestWriterWriteError(t *testing.T) {
+	pw := NewWriter(failingWriter{}, Options{})
+	err := pw.AddImage(encodePNG(t, solidImage(20, 20, color.White)))
+	if err == nil {
+		err = pw.Close()
+	}
+	if err == nil {
+		t.Error("writing to a failing writer succeeded")
+	}
+}
@@ -0,0 +1,83 @@
+package pdf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+)
+
+// Renderer rasterizes pages of a PDF document.
+type Renderer interface {
+	// RenderPages renders pages first..last (1-based, inclusive) of the PDF at
+	// input as JPEG images width pixels wide. Pages past the end of the document
+	// are skipped.
+	RenderPages(ctx context.Context, input string, first, last, width int) ([][]byte, error)
+}
+
+// ErrRendererUnavailable is returned when the poppler tooling is not installed.
+var ErrRendererUnavailable = errors.New("pdf renderer is not installed")
+
+// PopplerRenderer renders pages with poppler's pdftoppm binary.
+type PopplerRenderer struct {
+	// path to the pdftoppm binary, looked up in PATH if not absolute
+	Path string
+}
+
+func (r *PopplerRenderer) RenderPages(ctx context.Context, input string, first, last, width int) ([][]byte, error) {
+	bin, err := exec.LookPath(r.Path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRendererUnavailable, err)
+	}
+
+	dir, err := os.MkdirTemp("", "pdfrender-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	outDir := filepath.Join(dir, "out")
+	if err := os.Mkdir(outDir, 0o700); err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, bin,
+		"-jpeg",
+		"-f", strconv.Itoa(first),
+		"-l", strconv.Itoa(last),
+		"-scale-to-x", strconv.Itoa(width),
+		"-scale-to-y", "-1",
+		input,
+		filepath.Join(outDir, "page"),
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("pdftoppm failed: %v: %s", err, out)
+	}
+
+	// pdftoppm zero-pads page numbers, so names sort in page order
+	entries, err := os.ReadDir(outDir)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+	}
+	slices.Sort(names)
+
+	pages := make([][]byte, 0, len(names))
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(outDir, name))
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, data)
+	}
+
+	return pages, nil
+}
@@ -0,0 +1,96 @@
+package pdf
+
+import (
+	"image"
+	"testing"
+)
+
+func TestDownscaleSize(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		maxDim        int
+		wantW, wantH  int
+	}{
+		{"landscape", 400, 200, 100, 100, 50},
+		{"portrait", 200, 400, 100, 50, 100},
+		{"square", 300, 300, 100, 100, 100},
+		{"rounds down", 333, 100, 100, 100, 30},
+		{"thin line", 1000, 1, 100, 100, 1},
+		{"tall line", 1, 1000, 100, 1, 100},
+		{"fits", 100, 50, 100, 100, 50},
+		{"no limit", 400, 200, 0, 400, 200},
+		{"negative limit", 400, 200, -1, 400, 200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := downscale(image.NewGray(image.Rect(0, 0, tt.width, tt.height)), tt.maxDim).Bounds()
+			if b.Dx() != tt.wantW || b.Dy() != tt.wantH {
+				t.Errorf("downscale(%dx%d, %d) = %dx%d, want %dx%d", tt.width, tt.height, tt.maxDim, b.Dx(), b.Dy(), tt.wantW, tt.wantH)
+			}
+		})
+	}
+}
+
+func TestDownscaleKeepsSmallImages(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 10, 10))
+	if got := downscale(img, 10); got != image.Image(img) {
+		t.Error("downscale() copied an image that fits")
+	}
+}
+
+func TestDownscaleAverages(t *testing.T) {
+	tests := []struct {
+		name   string
+		src    *image.Gray
+		maxDim int
+		want   [][]uint8
+	}{
+		{
+			"halves",
+			grayImage(
+				[]uint8{0, 0, 200, 200},
+				[]uint8{0, 0, 200, 200},
+			),
+			2,
+			[][]uint8{{0, 200}},
+		},
+		{
+			"single pixel",
+			grayImage(
+				[]uint8{0, 100},
+				[]uint8{100, 200},
+			),
+			1,
+			[][]uint8{{100}},
+		},
+		{
+			"uneven blocks",
+			grayImage(
+				[]uint8{30, 60, 90},
+			),
+			2,
+			[][]uint8{{30, 75}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := grayRows(downscale(tt.src, tt.maxDim)); !equalRows(got, tt.want) {
+				t.Errorf("downscale() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDownscaleSubImage(t *testing.T) {
+	src := grayImage(
+		[]uint8{255, 255, 255},
+		[]uint8{255, 0, 100},
+	).SubImage(image.Rect(1, 1, 3, 2))
+
+	if got, want := grayRows(downscale(src, 1)), [][]uint8{{50}}; !equalRows(got, want) {
+		t.Errorf("downscale() = %v, want %v", got, want)
+	}
+}
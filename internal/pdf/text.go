@@ -0,0 +1,68 @@
+package pdf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+)
+
+// ErrExtractorUnavailable is returned when pdftotext is not installed.
+var ErrExtractorUnavailable = errors.New("pdf text extractor is not installed")
+
+// TextExtractor extracts the plain text of a PDF document.
+type TextExtractor interface {
+	// ExtractText returns the text of the PDF at input.
+	ExtractText(ctx context.Context, input string) (string, error)
+}
+
+// PopplerTextExtractor extracts text with poppler's pdftotext binary.
+type PopplerTextExtractor struct {
+	// path to the pdftotext binary, looked up in PATH if not absolute
+	Path string
+	// at most MaxBytes of text are read, pdftotext is killed once it writes
+	// more, unlimited if 0
+	MaxBytes int64
+}
+
+func (e *PopplerTextExtractor) ExtractText(ctx context.Context, input string) (string, error) {
+	bin, err := exec.LookPath(e.Path)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrExtractorUnavailable, err)
+	}
+
+	stderr := bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, bin, "-enc", "UTF-8", "-nopgbrk", input, "-")
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", err
+	}
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("pdftotext failed: %v", err)
+	}
+
+	var r io.Reader = stdout
+	if e.MaxBytes > 0 {
+		r = io.LimitReader(stdout, e.MaxBytes+1)
+	}
+	text, readErr := io.ReadAll(r)
+
+	// the rest of the text is not needed, so pdftotext is stopped early
+	if e.MaxBytes > 0 && int64(len(text)) > e.MaxBytes {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return string(text[:e.MaxBytes]), nil
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return "", fmt.Errorf("pdftotext failed: %v: %s", err, stderr.Bytes())
+	}
+	if readErr != nil {
+		return "", fmt.Errorf("pdftotext failed: %v", readErr)
+	}
+
+	return string(text), nil
+}
@@ -0,0 +1,76 @@
+package pdf
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakePdftotext writes a script standing in for pdftotext.
+func fakePdftotext(t *testing.T, script string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "pdftotext")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPopplerExtractText(t *testing.T) {
+	tests := []struct {
+		name     string
+		script   string
+		maxBytes int64
+		want     string
+		err      bool
+	}{
+		{"text", "printf 'hello world'", 0, "hello world", false},
+		{"within the limit", "printf 'hello'", 5, "hello", false},
+		{"truncated", "printf 'hello world'", 5, "hello", false},
+		{"endless output", "exec yes", 8, "y\ny\ny\ny\n", false},
+		{"failure", "echo 'Syntax Error' >&2; exit 1", 0, "", true},
+		{"failure within the limit", "printf 'he'; exit 1", 5, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &PopplerTextExtractor{Path: fakePdftotext(t, tt.script), MaxBytes: tt.maxBytes}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			got, err := e.ExtractText(ctx, "input.pdf")
+			if tt.err {
+				if err == nil {
+					t.Fatal("ExtractText() succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExtractText() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ExtractText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPopplerExtractTextUnavailable(t *testing.T) {
+	e := &PopplerTextExtractor{Path: filepath.Join(t.TempDir(), "pdftotext")}
+	if _, err := e.ExtractText(context.Background(), "input.pdf"); !errors.Is(err, ErrExtractorUnavailable) {
+		t.Errorf("ExtractText() error = %v, want %v", err, ErrExtractorUnavailable)
+	}
+}
+
+func TestPopplerExtractTextFailureMessage(t *testing.T) {
+	e := &PopplerTextExtractor{Path: fakePdftotext(t, "echo 'Syntax Error' >&2; exit 1")}
+	if _, err := e.ExtractText(context.Background(), "input.pdf"); err == nil || !strings.Contains(err.Error(), "Syntax Error") {
+		t.Errorf("ExtractText() error = %v, want pdftotext's stderr", err)
+	}
+}
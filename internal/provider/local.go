@@ -0,0 +1,530 @@
+package provider
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/skndash96/lastnight-backend/internal/config"
+)
+
+// signed URLs of the local driver, served by the storage handler
+const (
+	localUploadPath = "/api/storage/uploads"
+	localPartPath   = "/api/storage/parts"
+	localObjectPath = "/api/storage/objects"
+)
+
+// subdirectories of the storage directory
+const (
+	localObjectsDir   = "objects"
+	localMultipartDir = "multipart"
+	localTmpDir       = "tmp"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid or expired signature")
+	ErrObjectNotFound   = errors.New("object not found")
+	ErrSizeMismatch     = errors.New("size does not match the presigned size")
+)
+
+// LocalProvider keeps objects in a directory and signs URLs pointing at the API
+// itself, so it runs without any external services, e.g. during development.
+type LocalProvider struct {
+	cfg        *config.StorageConfig
+	dir        string
+	publicURL  *url.URL
+	signingKey []byte
+}
+
+func NewLocalProvider(cfg config.StorageConfig) (*LocalProvider, error) {
+	dir, err := filepath.Abs(cfg.Local.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sub := range []string{localObjectsDir, localMultipartDir, localTmpDir} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	publicURL, err := url.Parse(cfg.Local.PublicURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid storage public URL: %w", err)
+	}
+
+	signingKey := []byte(cfg.Local.SigningKey)
+	if len(signingKey) == 0 {
+		// URLs signed before a restart stop working, which is fine for development
+		signingKey = make([]byte, 32)
+		if _, err := rand.Read(signingKey); err != nil {
+			return nil, err
+		}
+	}
+
+	return &LocalProvider{
+		cfg:        &cfg,
+		dir:        dir,
+		publicURL:  publicURL,
+		signingKey: signingKey,
+	}, nil
+}
+
+// PresignUpload returns the fields to POST with a file of exactly size bytes to
+// the storage handler, see ReceiveUpload.
+func (p *LocalProvider) PresignUpload(ctx context.Context, key, name, mime string, size, maxSize int64) (*PresignedUpload, error) {
+	if size > p.maxSize(maxSize) {
+		return nil, ErrFileTooLarge
+	}
+
+	expiresAt := time.Now().Add(p.cfg.Expiration)
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	sizeStr := strconv.FormatInt(size, 10)
+
+	return &PresignedUpload{
+		Url: p.publicURL.JoinPath(localUploadPath),
+		Fields: map[string]string{
+			"key":          key,
+			"Content-Type": mime,
+			"size":         sizeStr,
+			"expires":      expires,
+			"signature":    p.sign(expires, "upload", key, mime, sizeStr),
+		},
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// ReceiveUpload stores a file POSTed with the fields of PresignUpload.
+func (p *LocalProvider) ReceiveUpload(ctx context.Context, fields map[string]string, r io.Reader) error {
+	key, mime, sizeStr := fields["key"], fields["Content-Type"], fields["size"]
+
+	if err := p.verify(fields["expires"], fields["signature"], "upload", key, mime, sizeStr); err != nil {
+		return err
+	}
+
+	size, err := strconv.ParseInt(sizeStr, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	_, err = p.writeObject(key, r, size, p.cfg.MaxSize)
+	return err
+}
+
+func (p *LocalProvider) MoveObject(ctx context.Context, dst, src string) error {
+	dstPath := p.objectPath(dst)
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0o755); err != nil {
+		return err
+	}
+
+	return os.Rename(p.objectPath(src), dstPath)
+}
+
+// DeleteObject removes an object, deleting a missing object is not an error like with S3.
+func (p *LocalProvider) DeleteObject(ctx context.Context, objectKey string) error {
+	err := os.Remove(p.objectPath(objectKey))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (p *LocalProvider) GetUploadInfo(ctx context.Context, objectKey string) (*UploadInfo, error) {
+	f, err := p.openObject(objectKey)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UploadInfo{
+		SHA256: EncodeChecksum(h.Sum(nil)),
+		Size:   n,
+	}, nil
+}
+
+// StatObject returns the size of an object without reading it.
+func (p *LocalProvider) StatObject(ctx context.Context, objectKey string) (int64, error) {
+	info, err := os.Stat(p.objectPath(objectKey))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, fmt.Errorf("%w: %s", ErrObjectNotFound, objectKey)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+func (p *LocalProvider) GetObject(ctx context.Context, objectKey string) (io.ReadCloser, error) {
+	return p.openObject(objectKey)
+}
+
+// GetObjectRange reads length bytes of an object starting at offset.
+func (p *LocalProvider) GetObjectRange(ctx context.Context, objectKey string, offset, length int64) ([]byte, error) {
+	if length <= 0 {
+		return []byte{}, nil
+	}
+
+	f, err := p.openObject(objectKey)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return io.ReadAll(io.NewSectionReader(f, offset, length))
+}
+
+func (p *LocalProvider) PutObject(ctx context.Context, objectKey string, r io.Reader, size int64, mime string) error {
+	_, err := p.writeObject(objectKey, r, size, size)
+	return err
+}
+
+// PresignGetObject returns a URL the storage handler serves the object at, see OpenSignedObject.
+func (p *LocalProvider) PresignGetObject(ctx context.Context, objectKey string) (*url.URL, error) {
+	expires := strconv.FormatInt(time.Now().Add(p.cfg.Expiration).Unix(), 10)
+
+	u := p.publicURL.JoinPath(localObjectPath, objectKey)
+	u.RawQuery = url.Values{
+		"expires":   {expires},
+		"signature": {p.sign(expires, "get", objectKey)},
+	}.Encode()
+
+	return u, nil
+}
+
+// OpenSignedObject opens the object of a URL returned by PresignGetObject.
+func (p *LocalProvider) OpenSignedObject(objectKey, expires, signature string) (*os.File, error) {
+	if err := p.verify(expires, signature, "get", objectKey); err != nil {
+		return nil, err
+	}
+
+	return p.openObject(objectKey)
+}
+
+// PutObjectStream stores an object of unknown size, computing its size and SHA-256
+// on the way. Objects larger than maxSize, or MaxSize if it is 0, fail with ErrFileTooLarge.
+func (p *LocalProvider) PutObjectStream(ctx context.Context, objectKey string, r io.Reader, mime string, maxSize int64) (*UploadInfo, error) {
+	return p.writeObject(objectKey, r, -1, p.maxSize(maxSize))
+}
+
+// CreateMultipartUpload starts a multipart upload, its parts are kept in a
+// directory named after the returned upload ID until it is completed or aborted.
+func (p *LocalProvider) CreateMultipartUpload(ctx context.Context, key, mime string, size int64) (string, error) {
+	if size > p.cfg.MaxSize {
+		return "", ErrFileTooLarge
+	}
+
+	uploadID := uuid.NewString()
+	if err := os.Mkdir(filepath.Join(p.dir, localMultipartDir, uploadID), 0o755); err != nil {
+		return "", err
+	}
+
+	return uploadID, nil
+}
+
+// PresignUploadPart returns a URL the client PUTs one part to, see ReceiveUploadPart.
+// The URL only accepts a part of exactly partSize bytes, of a session of partCount parts.
+func (p *LocalProvider) PresignUploadPart(ctx context.Context, key, uploadID string, partNumber, partCount int, partSize int64) (*url.URL, error) {
+	if partNumber < 1 || partNumber > partCount || partCount > MaxUploadParts {
+		return nil, fmt.Errorf("part number %d is out of range 1-%d", partNumber, partCount)
+	}
+	if partSize > p.cfg.MaxSize {
+		return nil, ErrFileTooLarge
+	}
+
+	expires := strconv.FormatInt(time.Now().Add(p.cfg.Expiration).Unix(), 10)
+	part := strconv.Itoa(partNumber)
+	parts := strconv.Itoa(partCount)
+	size := strconv.FormatInt(partSize, 10)
+
+	u := p.publicURL.JoinPath(localPartPath, uploadID, part)
+	u.RawQuery = url.Values{
+		"key":       {key},
+		"parts":     {parts},
+		"size":      {size},
+		"expires":   {expires},
+		"signature": {p.sign(expires, "part", key, uploadID, part, parts, size)},
+	}.Encode()
+
+	return u, nil
+}
+
+// ReceiveUploadPart stores a part PUT to a URL of PresignUploadPart, with its
+// query, and returns its ETag.
+func (p *LocalProvider) ReceiveUploadPart(ctx context.Context, uploadID, part string, query url.Values, r io.Reader) (string, error) {
+	key, parts, sizeStr := query.Get("key"), query.Get("parts"), query.Get("size")
+
+	if err := p.verify(query.Get("expires"), query.Get("signature"), "part", key, uploadID, part, parts, sizeStr); err != nil {
+		return "", err
+	}
+
+	partNumber, err := strconv.Atoi(part)
+	if err != nil {
+		return "", ErrInvalidSignature
+	}
+	partCount, err := strconv.Atoi(parts)
+	if err != nil || partNumber < 1 || partNumber > partCount || partCount > MaxUploadParts {
+		return "", ErrInvalidSignature
+	}
+	size, err := strconv.ParseInt(sizeStr, 10, 64)
+	if err != nil {
+		return "", ErrInvalidSignature
+	}
+
+	dir, err := p.multipartDir(uploadID)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.CreateTemp(filepath.Join(p.dir, localTmpDir), "part-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	h := md5.New()
+	n, err := io.Copy(io.MultiWriter(f, h), io.LimitReader(r, size+1))
+	if err != nil {
+		return "", err
+	}
+	if n != size {
+		return "", ErrSizeMismatch
+	}
+
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	// a part uploaded again replaces the previous one
+	previous, err := filepath.Glob(filepath.Join(dir, part+".*"))
+	if err != nil {
+		return "", err
+	}
+	for _, name := range previous {
+		if err := os.Remove(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+	}
+
+	etag := hex.EncodeToString(h.Sum(nil))
+	if err := os.Rename(f.Name(), filepath.Join(dir, part+"."+etag)); err != nil {
+		return "", err
+	}
+
+	return etag, nil
+}
+
+// ListUploadParts lists the parts uploaded so far, ordered by part number.
+// Parts are stored as <part number>.<etag>.
+func (p *LocalProvider) ListUploadParts(ctx context.Context, key, uploadID string) ([]UploadPart, error) {
+	dir, err := p.multipartDir(uploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := []UploadPart{}
+	for _, entry := range entries {
+		number, etag, ok := strings.Cut(entry.Name(), ".")
+		partNumber, err := strconv.Atoi(number)
+		if !ok || err != nil {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		parts = append(parts, UploadPart{
+			PartNumber: partNumber,
+			ETag:       etag,
+			Size:       info.Size(),
+		})
+	}
+
+	slices.SortFunc(parts, func(a, b UploadPart) int {
+		return a.PartNumber - b.PartNumber
+	})
+
+	return parts, nil
+}
+
+// CompleteMultipartUpload concatenates the parts into the object at key.
+func (p *LocalProvider) CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []UploadPart) error {
+	dir, err := p.multipartDir(uploadID)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Join(p.dir, localTmpDir), "object-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	for _, part := range parts {
+		if err := appendFile(f, filepath.Join(dir, fmt.Sprintf("%d.%s", part.PartNumber, part.ETag))); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("part %d with etag %s not found", part.PartNumber, part.ETag)
+			}
+			return err
+		}
+	}
+
+	if err := p.commitObject(f, key); err != nil {
+		return err
+	}
+
+	return os.RemoveAll(dir)
+}
+
+func (p *LocalProvider) AbortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	dir, err := p.multipartDir(uploadID)
+	if err != nil {
+		return err
+	}
+
+	return os.RemoveAll(dir)
+}
+
+// writeObject stores r at key through a temporary file, so readers never see a
+// partial object. size is checked unless it is -1.
+func (p *LocalProvider) writeObject(objectKey string, r io.Reader, size, maxSize int64) (*UploadInfo, error) {
+	f, err := os.CreateTemp(filepath.Join(p.dir, localTmpDir), "object-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, h), io.LimitReader(r, min(maxSize, math.MaxInt64-1)+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if n > maxSize {
+		return nil, ErrFileTooLarge
+	}
+	if size >= 0 && n != size {
+		return nil, ErrSizeMismatch
+	}
+
+	if err := p.commitObject(f, objectKey); err != nil {
+		return nil, err
+	}
+
+	return &UploadInfo{
+		SHA256: EncodeChecksum(h.Sum(nil)),
+		Size:   n,
+	}, nil
+}
+
+// commitObject closes a temporary file and moves it to key.
+func (p *LocalProvider) commitObject(f *os.File, objectKey string) error {
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	dst := p.objectPath(objectKey)
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), dst)
+}
+
+func (p *LocalProvider) openObject(objectKey string) (*os.File, error) {
+	f, err := os.Open(p.objectPath(objectKey))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, objectKey)
+	}
+	return f, err
+}
+
+// objectPath maps a key to a file, keys cannot reach outside the objects directory.
+func (p *LocalProvider) objectPath(objectKey string) string {
+	return filepath.Join(p.dir, localObjectsDir, filepath.FromSlash(path.Clean("/"+objectKey)))
+}
+
+func (p *LocalProvider) multipartDir(uploadID string) (string, error) {
+	if _, err := uuid.Parse(uploadID); err != nil {
+		return "", fmt.Errorf("%w: multipart upload %s", ErrObjectNotFound, uploadID)
+	}
+
+	dir := filepath.Join(p.dir, localMultipartDir, uploadID)
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("%w: multipart upload %s", ErrObjectNotFound, uploadID)
+	}
+
+	return dir, nil
+}
+
+// sign returns a hex HMAC of a URL's expiry and the values it grants access to.
+func (p *LocalProvider) sign(expires string, values ...string) string {
+	mac := hmac.New(sha256.New, p.signingKey)
+	mac.Write([]byte(expires))
+	for _, v := range values {
+		// separated by NUL, so values cannot be shifted between each other
+		mac.Write([]byte{0})
+		mac.Write([]byte(v))
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (p *LocalProvider) verify(expires, signature string, values ...string) error {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return ErrInvalidSignature
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(p.sign(expires, values...))) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+func (p *LocalProvider) maxSize(limit int64) int64 {
+	return maxUploadSize(p.cfg.MaxSize, limit)
+}
+
+func appendFile(dst io.Writer, name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	_, err = io.Copy(dst, src)
+	return err
+}
@@ -0,0 +1,287 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skndash96/lastnight-backend/internal/config"
+)
+
+func newTestLocalProvider(t *testing.T) *LocalProvider {
+	t.Helper()
+
+	p, err := NewLocalProvider(config.StorageConfig{
+		Driver:     config.StorageDriverLocal,
+		Expiration: time.Hour,
+		MaxSize:    1024,
+		Local: config.LocalStorageConfig{
+			Dir:        t.TempDir(),
+			PublicURL:  "http://localhost:8080",
+			SigningKey: "test",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func readObject(t *testing.T, p *LocalProvider, key string) string {
+	t.Helper()
+
+	r, err := p.GetObject(context.Background(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestLocalUploadSignature(t *testing.T) {
+	ctx := context.Background()
+	p := newTestLocalProvider(t)
+
+	presigned, err := p.PresignUpload(ctx, "tmp/a.pdf", "a.pdf", "application/pdf", 5, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		fields func(map[string]string)
+		body   string
+		err    error
+	}{
+		{"valid", func(map[string]string) {}, "hello", nil},
+		{"other key", func(f map[string]string) { f["key"] = "tmp/b.pdf" }, "hello", ErrInvalidSignature},
+		{"other mime", func(f map[string]string) { f["Content-Type"] = "text/html" }, "hello", ErrInvalidSignature},
+		{"larger size", func(f map[string]string) { f["size"] = "6" }, "hello!", ErrInvalidSignature},
+		{"tampered signature", func(f map[string]string) { f["signature"] = strings.Repeat("0", 64) }, "hello", ErrInvalidSignature},
+		{"missing signature", func(f map[string]string) { delete(f, "signature") }, "hello", ErrInvalidSignature},
+		{"expired", func(f map[string]string) {
+			expires := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+			f["expires"] = expires
+			f["signature"] = p.sign(expires, "upload", f["key"], f["Content-Type"], f["size"])
+		}, "hello", ErrInvalidSignature},
+		{"shorter file", func(map[string]string) {}, "hell", ErrSizeMismatch},
+		{"longer file", func(map[string]string) {}, "hello!", ErrSizeMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := map[string]string{}
+			for k, v := range presigned.Fields {
+				fields[k] = v
+			}
+			tt.fields(fields)
+
+			err := p.ReceiveUpload(ctx, fields, strings.NewReader(tt.body))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ReceiveUpload() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+
+	if got := readObject(t, p, "tmp/a.pdf"); got != "hello" {
+		t.Errorf("object = %q, want %q", got, "hello")
+	}
+}
+
+func TestLocalUploadTooLarge(t *testing.T) {
+	ctx := context.Background()
+	p := newTestLocalProvider(t)
+
+	if _, err := p.PresignUpload(ctx, "tmp/a.pdf", "a.pdf", "application/pdf", 2048, 0); !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("PresignUpload() over the server limit error = %v, want %v", err, ErrFileTooLarge)
+	}
+	if _, err := p.PresignUpload(ctx, "tmp/a.pdf", "a.pdf", "application/pdf", 20, 10); !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("PresignUpload() over the upload limit error = %v, want %v", err, ErrFileTooLarge)
+	}
+
+	_, err := p.PutObjectStream(ctx, "tmp/b.pdf", strings.NewReader(strings.Repeat("a", 11)), "application/pdf", 10)
+	if !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("PutObjectStream() error = %v, want %v", err, ErrFileTooLarge)
+	}
+	if _, err := p.GetObject(ctx, "tmp/b.pdf"); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("GetObject() of a rejected object error = %v, want %v", err, ErrObjectNotFound)
+	}
+}
+
+func TestLocalSignedObject(t *testing.T) {
+	ctx := context.Background()
+	p := newTestLocalProvider(t)
+
+	if err := p.PutObject(ctx, "uploads/a.pdf", strings.NewReader("hello"), 5, "application/pdf"); err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := p.PresignGetObject(ctx, "uploads/a.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, ok := strings.CutPrefix(path.Join("/", u.Path), localObjectPath+"/")
+	if !ok {
+		t.Fatalf("presigned path = %q, want prefix %q", u.Path, localObjectPath)
+	}
+	query := u.Query()
+
+	f, err := p.OpenSignedObject(key, query.Get("expires"), query.Get("signature"))
+	if err != nil {
+		t.Fatalf("OpenSignedObject() error = %v", err)
+	}
+	f.Close()
+
+	if _, err := p.OpenSignedObject("uploads/b.pdf", query.Get("expires"), query.Get("signature")); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("OpenSignedObject() of another key error = %v, want %v", err, ErrInvalidSignature)
+	}
+
+	expires := strconv.FormatInt(time.Now().Add(-time.Second).Unix(), 10)
+	if _, err := p.OpenSignedObject(key, expires, p.sign(expires, "get", key)); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("OpenSignedObject() of an expired URL error = %v, want %v", err, ErrInvalidSignature)
+	}
+}
+
+func TestLocalKeyTraversal(t *testing.T) {
+	ctx := context.Background()
+	p := newTestLocalProvider(t)
+
+	for _, key := range []string{"../outside", "../../outside", "/outside", "a/../../outside"} {
+		if err := p.PutObject(ctx, key, strings.NewReader("x"), 1, "text/plain"); err != nil {
+			t.Fatalf("PutObject(%q) error = %v", key, err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(p.dir, localObjectsDir, "outside")); err != nil {
+		t.Errorf("object not stored inside the objects directory: %v", err)
+	}
+	for _, dir := range []string{p.dir, filepath.Dir(p.dir)} {
+		if _, err := os.Stat(filepath.Join(dir, "outside")); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("object stored outside the objects directory in %s", dir)
+		}
+	}
+
+	// upload IDs name directories, so only UUIDs are accepted
+	if _, err := p.ListUploadParts(ctx, "tmp/a.pdf", "../objects"); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("ListUploadParts() of a path error = %v, want %v", err, ErrObjectNotFound)
+	}
+}
+
+func TestLocalMultipartUpload(t *testing.T) {
+	ctx := context.Background()
+	p := newTestLocalProvider(t)
+
+	key := "tmp/big.pdf"
+	content := []string{"aaaa", "bbbb", "cc"}
+
+	uploadID, err := p.CreateMultipartUpload(ctx, key, "application/pdf", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	presign := func(t *testing.T, partNumber int, size int64) url.Values {
+		t.Helper()
+		u, err := p.PresignUploadPart(ctx, key, uploadID, partNumber, len(content), size)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := path.Join(localPartPath, uploadID, strconv.Itoa(partNumber)); path.Join("/", u.Path) != want {
+			t.Fatalf("presigned path = %q, want %q", u.Path, want)
+		}
+		return u.Query()
+	}
+
+	// parts are uploaded out of order, and part 1 twice
+	for _, i := range []int{2, 0, 1, 0} {
+		query := presign(t, i+1, int64(len(content[i])))
+		if _, err := p.ReceiveUploadPart(ctx, uploadID, strconv.Itoa(i+1), query, strings.NewReader(content[i])); err != nil {
+			t.Fatalf("ReceiveUploadPart(%d) error = %v", i+1, err)
+		}
+	}
+
+	query := presign(t, 1, 4)
+	if _, err := p.ReceiveUploadPart(ctx, uploadID, "1", query, strings.NewReader("aaaaa")); !errors.Is(err, ErrSizeMismatch) {
+		t.Errorf("ReceiveUploadPart() of a larger part error = %v, want %v", err, ErrSizeMismatch)
+	}
+	if _, err := p.ReceiveUploadPart(ctx, uploadID, "2", query, strings.NewReader("aaaa")); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("ReceiveUploadPart() of another part number error = %v, want %v", err, ErrInvalidSignature)
+	}
+
+	tampered := url.Values{}
+	for k, v := range query {
+		tampered[k] = v
+	}
+	tampered.Set("size", "1024")
+	if _, err := p.ReceiveUploadPart(ctx, uploadID, "1", tampered, strings.NewReader("aaaa")); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("ReceiveUploadPart() with a changed size error = %v, want %v", err, ErrInvalidSignature)
+	}
+	tampered.Set("size", "4")
+	tampered.Set("key", "uploads/other.pdf")
+	if _, err := p.ReceiveUploadPart(ctx, uploadID, "1", tampered, strings.NewReader("aaaa")); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("ReceiveUploadPart() with a changed key error = %v, want %v", err, ErrInvalidSignature)
+	}
+
+	if _, err := p.PresignUploadPart(ctx, key, uploadID, len(content)+1, len(content), 4); err == nil {
+		t.Error("PresignUploadPart() beyond the part count succeeded")
+	}
+
+	parts, err := p.ListUploadParts(ctx, key, uploadID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) != len(content) {
+		t.Fatalf("ListUploadParts() = %d parts, want %d", len(parts), len(content))
+	}
+	for i, part := range parts {
+		if part.PartNumber != i+1 || part.Size != int64(len(content[i])) {
+			t.Errorf("part %d = %+v", i+1, part)
+		}
+	}
+
+	if err := p.CompleteMultipartUpload(ctx, key, uploadID, parts); err != nil {
+		t.Fatal(err)
+	}
+	if got := readObject(t, p, key); got != strings.Join(content, "") {
+		t.Errorf("object = %q, want %q", got, strings.Join(content, ""))
+	}
+
+	if _, err := p.ListUploadParts(ctx, key, uploadID); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("ListUploadParts() after completion error = %v, want %v", err, ErrObjectNotFound)
+	}
+}
+
+func TestLocalAbortMultipartUpload(t *testing.T) {
+	ctx := context.Background()
+	p := newTestLocalProvider(t)
+
+	uploadID, err := p.CreateMultipartUpload(ctx, "tmp/a.pdf", "application/pdf", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := p.PresignUploadPart(ctx, "tmp/a.pdf", uploadID, 1, 1, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.AbortMultipartUpload(ctx, "tmp/a.pdf", uploadID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := p.ReceiveUploadPart(ctx, uploadID, "1", u.Query(), strings.NewReader("aaaa")); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("ReceiveUploadPart() after abort error = %v, want %v", err, ErrObjectNotFound)
+	}
+}
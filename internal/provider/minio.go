@@ -0,0 +1,232 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/skndash96/lastnight-backend/internal/config"
+)
+
+// minioProvider stores objects in a MinIO, or any S3 compatible, bucket.
+type minioProvider struct {
+	client *minio.Client
+	cfg    *config.StorageConfig
+}
+
+func newMinioProvider(cfg config.StorageConfig) (*minioProvider, error) {
+	client, err := minio.New(cfg.Minio.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.Minio.Username, cfg.Minio.Password, ""),
+		Secure: cfg.Minio.UseSSL,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	err = client.MakeBucket(ctx, cfg.Minio.BucketName, minio.MakeBucketOptions{})
+	if err != nil {
+		exists, errBucketExists := client.BucketExists(ctx, cfg.Minio.BucketName)
+		if errBucketExists != nil || !exists {
+			return nil, err
+		}
+	}
+
+	return &minioProvider{
+		client: client,
+		cfg:    &cfg,
+	}, nil
+}
+
+// PresignUpload creates a POST policy for uploading a file of exactly size bytes
+// to key. maxSize lowers the server's MaxSize for this upload, 0 keeps it.
+func (p *minioProvider) PresignUpload(ctx context.Context, key, name, mime string, size, maxSize int64) (*PresignedUpload, error) {
+	if size > p.maxSize(maxSize) {
+		return nil, ErrFileTooLarge
+	}
+
+	expiresAt := time.Now().Add(p.cfg.Expiration)
+
+	policy := minio.NewPostPolicy()
+	policy.SetExpires(expiresAt)
+	policy.SetBucket(p.cfg.Minio.BucketName)
+	policy.SetContentLengthRange(size, size)
+	policy.SetContentType(mime)
+	policy.SetKey(key)
+
+	url, fields, err := p.client.PresignedPostPolicy(ctx, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PresignedUpload{
+		Url:       url,
+		Fields:    fields,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+func (p *minioProvider) MoveObject(ctx context.Context, dst, src string) error {
+	_, err := p.client.CopyObject(ctx, minio.CopyDestOptions{
+		Bucket: p.cfg.Minio.BucketName,
+		Object: dst,
+	}, minio.CopySrcOptions{
+		Bucket: p.cfg.Minio.BucketName,
+		Object: src,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	err = p.client.RemoveObject(ctx, p.cfg.Minio.BucketName, src, minio.RemoveObjectOptions{})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (p *minioProvider) DeleteObject(ctx context.Context, objectKey string) error {
+	err := p.client.RemoveObject(ctx, p.cfg.Minio.BucketName, objectKey, minio.RemoveObjectOptions{})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetUploadInfo returns the size and SHA-256 of an object. The store only keeps a
+// checksum when the client sent one, and multipart objects get a checksum of their
+// part checksums, so otherwise the object is read to hash it.
+func (p *minioProvider) GetUploadInfo(ctx context.Context, objectKey string) (*UploadInfo, error) {
+	attr, err := p.client.GetObjectAttributes(ctx, p.cfg.Minio.BucketName, objectKey, minio.ObjectAttributesOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	info := &UploadInfo{
+		SHA256: attr.Checksum.ChecksumSHA256,
+		Size:   int64(attr.ObjectSize),
+	}
+
+	if isFullChecksum(info.SHA256) {
+		return info, nil
+	}
+
+	obj, err := p.GetObject(ctx, objectKey)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	info.SHA256 = EncodeChecksum(h.Sum(nil))
+	info.Size = n
+
+	return info, nil
+}
+
+// StatObject returns the size of an object without reading it.
+func (p *minioProvider) StatObject(ctx context.Context, objectKey string) (int64, error) {
+	info, err := p.client.StatObject(ctx, p.cfg.Minio.BucketName, objectKey, minio.StatObjectOptions{})
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return 0, fmt.Errorf("%w: %s", ErrObjectNotFound, objectKey)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return info.Size, nil
+}
+
+func (p *minioProvider) GetObject(ctx context.Context, objectKey string) (io.ReadCloser, error) {
+	obj, err := p.client.GetObject(ctx, p.cfg.Minio.BucketName, objectKey, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// GetObjectRange reads length bytes of an object starting at offset.
+func (p *minioProvider) GetObjectRange(ctx context.Context, objectKey string, offset, length int64) ([]byte, error) {
+	if length <= 0 {
+		return []byte{}, nil
+	}
+
+	opts := minio.GetObjectOptions{}
+	if err := opts.SetRange(offset, offset+length-1); err != nil {
+		return nil, err
+	}
+
+	obj, err := p.client.GetObject(ctx, p.cfg.Minio.BucketName, objectKey, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+
+	return io.ReadAll(io.LimitReader(obj, length))
+}
+
+func (p *minioProvider) PutObject(ctx context.Context, objectKey string, r io.Reader, size int64, mime string) error {
+	_, err := p.client.PutObject(ctx, p.cfg.Minio.BucketName, objectKey, r, size, minio.PutObjectOptions{
+		ContentType: mime,
+	})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (p *minioProvider) PresignGetObject(ctx context.Context, objectKey string) (*url.URL, error) {
+	return p.client.PresignedGetObject(ctx, p.cfg.Minio.BucketName, objectKey, p.cfg.Expiration, url.Values{})
+}
+
+// parts buffered in memory while streaming an object of unknown size
+const streamPartSize = 16 * 1024 * 1024
+
+// PutObjectStream stores an object of unknown size, computing its size and SHA-256
+// on the fly. Objects larger than maxSize, or MaxSize if it is 0, are removed and
+// fail with ErrFileTooLarge.
+func (p *minioProvider) PutObjectStream(ctx context.Context, objectKey string, r io.Reader, mime string, maxSize int64) (*UploadInfo, error) {
+	maxSize = p.maxSize(maxSize)
+
+	h := sha256.New()
+	counter := &byteCounter{}
+
+	body := io.TeeReader(io.LimitReader(r, maxSize+1), io.MultiWriter(h, counter))
+
+	_, err := p.client.PutObject(ctx, p.cfg.Minio.BucketName, objectKey, body, -1, minio.PutObjectOptions{
+		ContentType: mime,
+		PartSize:    streamPartSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if counter.n > maxSize {
+		if err := p.DeleteObject(ctx, objectKey); err != nil {
+			return nil, err
+		}
+		return nil, ErrFileTooLarge
+	}
+
+	return &UploadInfo{
+		SHA256: EncodeChecksum(h.Sum(nil)),
+		Size:   counter.n,
+	}, nil
+}
+
+// maxSize applies a per-upload limit, which can only lower the server's MaxSize.
+func (p *minioProvider) maxSize(limit int64) int64 {
+	return maxUploadSize(p.cfg.MaxSize, limit)
+}
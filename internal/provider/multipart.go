@@ -0,0 +1,94 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// S3 limits multipart uploads to 10000 parts of at least 5MiB, except the last one
+const (
+	MaxUploadParts    = 10000
+	MinUploadPartSize = 5 * 1024 * 1024
+)
+
+type UploadPart struct {
+	PartNumber int
+	ETag       string
+	Size       int64
+}
+
+func (p *minioProvider) core() minio.Core {
+	return minio.Core{Client: p.client}
+}
+
+// CreateMultipartUpload starts a multipart upload to key and returns its upload ID.
+func (p *minioProvider) CreateMultipartUpload(ctx context.Context, key, mime string, size int64) (string, error) {
+	if size > p.cfg.MaxSize {
+		return "", ErrFileTooLarge
+	}
+
+	return p.core().NewMultipartUpload(ctx, p.cfg.Minio.BucketName, key, minio.PutObjectOptions{
+		ContentType: mime,
+	})
+}
+
+// PresignUploadPart returns a URL the client PUTs one part to. S3 does not bind
+// the part size to the URL, CompleteMultipartUpload checks the parts instead.
+func (p *minioProvider) PresignUploadPart(ctx context.Context, key, uploadID string, partNumber, partCount int, partSize int64) (*url.URL, error) {
+	params := url.Values{}
+	params.Set("partNumber", strconv.Itoa(partNumber))
+	params.Set("uploadId", uploadID)
+
+	return p.client.Presign(ctx, http.MethodPut, p.cfg.Minio.BucketName, key, p.cfg.Expiration, params)
+}
+
+// ListUploadParts lists the parts uploaded so far, ordered by part number.
+func (p *minioProvider) ListUploadParts(ctx context.Context, key, uploadID string) ([]UploadPart, error) {
+	parts := []UploadPart{}
+
+	marker := 0
+	for {
+		result, err := p.core().ListObjectParts(ctx, p.cfg.Minio.BucketName, key, uploadID, marker, 1000)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, part := range result.ObjectParts {
+			parts = append(parts, UploadPart{
+				PartNumber: part.PartNumber,
+				ETag:       part.ETag,
+				Size:       part.Size,
+			})
+		}
+
+		if !result.IsTruncated {
+			return parts, nil
+		}
+		marker = result.NextPartNumberMarker
+	}
+}
+
+func (p *minioProvider) CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []UploadPart) error {
+	completeParts := make([]minio.CompletePart, len(parts))
+	for i, part := range parts {
+		completeParts[i] = minio.CompletePart{
+			PartNumber: part.PartNumber,
+			ETag:       part.ETag,
+		}
+	}
+
+	_, err := p.core().CompleteMultipartUpload(ctx, p.cfg.Minio.BucketName, key, uploadID, completeParts, minio.PutObjectOptions{})
+	return err
+}
+
+func (p *minioProvider) AbortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	err := p.core().AbortMultipartUpload(ctx, p.cfg.Minio.BucketName, key, uploadID)
+	if minio.ToErrorResponse(err).Code == "NoSuchUpload" {
+		return nil
+	}
+	return err
+}
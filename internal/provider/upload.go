@@ -2,25 +2,39 @@ package provider
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"io"
 	"net/url"
 	"time"
 
-	"github.com/minio/minio-go/v7"
-	"github.com/minio/minio-go/v7/pkg/credentials"
 	"github.com/skndash96/lastnight-backend/internal/config"
 )
 
+// UploadProvider stores objects, and presigns URLs for clients to upload and
+// download them directly. See config.StorageConfig for the available drivers.
 type UploadProvider interface {
-	PresignUpload(ctx context.Context, key, name, mime string, size int64) (*url.URL, map[string]string, error)
+	PresignUpload(ctx context.Context, key, name, mime string, size, maxSize int64) (*PresignedUpload, error)
 	MoveObject(ctx context.Context, dstKey, srcKey string) error
 	DeleteObject(ctx context.Context, key string) error
 	GetUploadInfo(ctx context.Context, key string) (*UploadInfo, error)
-}
-
-type uploadProvider struct {
-	client *minio.Client
-	cfg    *config.MinioConfig
+	// StatObject returns the size of an object, ErrObjectNotFound if there is none
+	StatObject(ctx context.Context, key string) (int64, error)
+	GetObject(ctx context.Context, key string) (io.ReadCloser, error)
+	GetObjectRange(ctx context.Context, key string, offset, length int64) ([]byte, error)
+	PutObject(ctx context.Context, key string, r io.Reader, size int64, mime string) error
+	PresignGetObject(ctx context.Context, key string) (*url.URL, error)
+	PutObjectStream(ctx context.Context, key string, r io.Reader, mime string, maxSize int64) (*UploadInfo, error)
+
+	CreateMultipartUpload(ctx context.Context, key, mime string, size int64) (string, error)
+	PresignUploadPart(ctx context.Context, key, uploadID string, partNumber, partCount int, partSize int64) (*url.URL, error)
+	ListUploadParts(ctx context.Context, key, uploadID string) ([]UploadPart, error)
+	CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []UploadPart) error
+	// AbortMultipartUpload discards the parts of an upload, uploads completed or
+	// aborted before are ignored
+	AbortMultipartUpload(ctx context.Context, key, uploadID string) error
 }
 
 var (
@@ -28,93 +42,55 @@ var (
 	ErrInvalidFileType = errors.New("invalid file type")
 )
 
-func NewUploadProvider(cfg config.MinioConfig) (UploadProvider, error) {
-	client, err := minio.New(cfg.Endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(cfg.Username, cfg.Password, ""),
-		Secure: cfg.UseSSL,
-	})
-
-	if err != nil {
-		return nil, err
+// NewUploadProvider creates the storage driver selected by cfg.Driver.
+func NewUploadProvider(cfg config.StorageConfig) (UploadProvider, error) {
+	switch cfg.Driver {
+	case config.StorageDriverMinio:
+		return newMinioProvider(cfg)
+	case config.StorageDriverLocal:
+		return NewLocalProvider(cfg)
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
 	}
-
-	ctx := context.Background()
-	err = client.MakeBucket(ctx, cfg.BucketName, minio.MakeBucketOptions{})
-	if err != nil {
-		exists, errBucketExists := client.BucketExists(ctx, cfg.BucketName)
-		if errBucketExists != nil || !exists {
-			return nil, err
-		}
-	}
-
-	return &uploadProvider{
-		client: client,
-		cfg:    &cfg,
-	}, nil
 }
 
-func (p *uploadProvider) PresignUpload(ctx context.Context, key, name, mime string, size int64) (*url.URL, map[string]string, error) {
-	if size > p.cfg.MaxSize {
-		return nil, nil, ErrFileTooLarge
-	}
-
-	policy := minio.NewPostPolicy()
-	policy.SetExpires(time.Now().Add(p.cfg.Expiration))
-	policy.SetBucket(p.cfg.BucketName)
-	policy.SetContentLengthRange(9*size/10, 11*size/10)
-	policy.SetContentType(mime)
-	policy.SetKey(key)
-
-	url, fields, err := p.client.PresignedPostPolicy(ctx, policy)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	return url, fields, nil
+type PresignedUpload struct {
+	Url       *url.URL
+	Fields    map[string]string
+	ExpiresAt time.Time
 }
 
-func (p *uploadProvider) MoveObject(ctx context.Context, dst, src string) error {
-	_, err := p.client.CopyObject(ctx, minio.CopyDestOptions{
-		Bucket: p.cfg.BucketName,
-		Object: dst,
-	}, minio.CopySrcOptions{
-		Bucket: p.cfg.BucketName,
-		Object: src,
-	})
-
-	if err != nil {
-		return err
-	}
+type UploadInfo struct {
+	// base64 encoded, as reported by S3 checksums
+	SHA256 string
+	Size   int64
+}
 
-	err = p.client.RemoveObject(ctx, p.cfg.BucketName, src, minio.RemoveObjectOptions{})
-	if err != nil {
-		return err
-	}
+// EncodeChecksum encodes a SHA-256 digest the way UploadInfo stores it.
+func EncodeChecksum(sum []byte) string {
+	return base64.StdEncoding.EncodeToString(sum)
+}
 
-	return nil
+// isFullChecksum reports whether checksum is the SHA-256 of a whole object, and
+// not missing or composite like "<base64>-<parts>".
+func isFullChecksum(checksum string) bool {
+	sum, err := base64.StdEncoding.DecodeString(checksum)
+	return err == nil && len(sum) == sha256.Size
 }
 
-func (p *uploadProvider) DeleteObject(ctx context.Context, objectKey string) error {
-	err := p.client.RemoveObject(ctx, p.cfg.BucketName, objectKey, minio.RemoveObjectOptions{})
-	if err != nil {
-		return err
+// maxUploadSize applies a per-upload limit, which can only lower the server's limit.
+func maxUploadSize(serverMax, limit int64) int64 {
+	if limit > 0 && limit < serverMax {
+		return limit
 	}
-	return nil
+	return serverMax
 }
 
-type UploadInfo struct {
-	SHA256 string
-	Size   int64
+type byteCounter struct {
+	n int64
 }
 
-func (p *uploadProvider) GetUploadInfo(ctx context.Context, objectKey string) (*UploadInfo, error) {
-	attr, err := p.client.GetObjectAttributes(ctx, p.cfg.BucketName, objectKey, minio.ObjectAttributesOptions{})
-	if err != nil {
-		return nil, err
-	}
-
-	return &UploadInfo{
-		SHA256: attr.Checksum.ChecksumSHA256,
-		Size:   int64(attr.ObjectSize),
-	}, nil
+func (c *byteCounter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
 }
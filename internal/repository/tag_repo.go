@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/skndash96/lastnight-backend/internal/db"
 )
 
@@ -65,11 +66,13 @@ func (r *TagRepo) DeleteAllFilters(ctx context.Context, membershipID int32) erro
 	return nil
 }
 
-func (r *TagRepo) CreateTagKey(ctx context.Context, teamID int32, tagName string, dataType db.TagDataType) (db.TagKey, error) {
+func (r *TagRepo) CreateTagKey(ctx context.Context, teamID int32, tagName string, dataType db.TagDataType, mode db.TagKeyMode, requiresApproval bool) (db.TagKey, error) {
 	tagKey, err := r.q.CreateTagKey(ctx, db.CreateTagKeyParams{
-		TeamID:   teamID,
-		Name:     tagName,
-		DataType: dataType,
+		TeamID:           teamID,
+		Name:             tagName,
+		DataType:         dataType,
+		Mode:             mode,
+		RequiresApproval: requiresApproval,
 	})
 	if err != nil {
 		return db.TagKey{}, NewRepoError(err, RepoErrInternal, "failed to create tag key")
@@ -77,25 +80,198 @@ func (r *TagRepo) CreateTagKey(ctx context.Context, teamID int32, tagName string
 	return tagKey, nil
 }
 
-func (r *TagRepo) CreateTagValue(ctx context.Context, tagID int32, value string) (db.TagValue, error) {
+func (r *TagRepo) GetTagKeyByID(ctx context.Context, teamID int32, tagID int32) (db.TagKey, error) {
+	tagKey, err := r.q.GetTagKeyByID(ctx, db.GetTagKeyByIDParams{
+		ID:     tagID,
+		TeamID: teamID,
+	})
+	if err != nil {
+		return db.TagKey{}, NewRepoError(err, RepoErrInternal, "failed to get tag key")
+	}
+	return tagKey, nil
+}
+
+func (r *TagRepo) GetTagKeyByName(ctx context.Context, teamID int32, name string) (db.TagKey, error) {
+	tagKey, err := r.q.GetTagKeyByName(ctx, db.GetTagKeyByNameParams{
+		TeamID: teamID,
+		Name:   name,
+	})
+	if err != nil {
+		return db.TagKey{}, NewRepoError(err, RepoErrInternal, "failed to get tag key")
+	}
+	return tagKey, nil
+}
+
+// numValue is only set for values of number-typed keys
+func (r *TagRepo) CreateTagValue(ctx context.Context, tagID int32, value string, numValue *float64) (db.TagValue, error) {
 	tagValue, err := r.q.CreateTagValue(ctx, db.CreateTagValueParams{
+		KeyID:    tagID,
+		Value:    value,
+		NumValue: numValue,
+	})
+	if err != nil {
+		return db.TagValue{}, NewRepoError(err, RepoErrInternal, "failed to create tag value")
+	}
+	return tagValue, nil
+}
+
+// GetOrCreateTagValue returns the existing value of the key, or creates it with the given approval state
+func (r *TagRepo) GetOrCreateTagValue(ctx context.Context, tagID int32, value string, numValue *float64, approved bool) (db.TagValue, error) {
+	tagValue, err := r.q.GetOrCreateTagValue(ctx, db.GetOrCreateTagValueParams{
+		KeyID:    tagID,
+		Value:    value,
+		NumValue: numValue,
+		Approved: approved,
+	})
+	if err != nil {
+		return db.TagValue{}, NewRepoError(err, RepoErrInternal, "failed to get or create tag value")
+	}
+	return tagValue, nil
+}
+
+func (r *TagRepo) ApproveTagValue(ctx context.Context, tagID int32, tagValueID int32) (db.TagValue, error) {
+	tagValue, err := r.q.ApproveTagValue(ctx, db.ApproveTagValueParams{
+		ID:    tagValueID,
+		KeyID: tagID,
+	})
+	if err != nil {
+		return db.TagValue{}, NewRepoError(err, RepoErrInternal, "failed to approve tag value")
+	}
+	return tagValue, nil
+}
+
+func (r *TagRepo) ListPendingTagValues(ctx context.Context, teamID int32) ([]db.TagValue, error) {
+	tagValues, err := r.q.ListPendingTagValues(ctx, teamID)
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "failed to list pending tag values")
+	}
+	if tagValues == nil {
+		tagValues = []db.TagValue{}
+	}
+	return tagValues, nil
+}
+
+func (r *TagRepo) GetTagValueByID(ctx context.Context, teamID int32, tagValueID int32) (db.TagValue, error) {
+	tagValue, err := r.q.GetTagValueByID(ctx, db.GetTagValueByIDParams{
+		ID:     tagValueID,
+		TeamID: teamID,
+	})
+	if err != nil {
+		return db.TagValue{}, NewRepoError(err, RepoErrInternal, "failed to get tag value")
+	}
+	return tagValue, nil
+}
+
+// GetTagValueByValue looks up a value of the key by its canonical text
+func (r *TagRepo) GetTagValueByValue(ctx context.Context, tagID int32, value string) (db.TagValue, error) {
+	tagValue, err := r.q.GetTagValueByValue(ctx, db.GetTagValueByValueParams{
 		KeyID: tagID,
 		Value: value,
 	})
 	if err != nil {
-		return db.TagValue{}, NewRepoError(err, RepoErrInternal, "failed to create tag value")
+		return db.TagValue{}, NewRepoError(err, RepoErrInternal, "failed to get tag value")
 	}
 	return tagValue, nil
 }
 
-func (r *TagRepo) DeleteTagKey(ctx context.Context, tagID int32) (db.TagKey, error) {
-	tagKey, err := r.q.DeleteTagKey(ctx, tagID)
+func (r *TagRepo) DeleteTagValueParents(ctx context.Context, tagValueID int32) error {
+	err := r.q.DeleteTagValueParents(ctx, tagValueID)
+	if err != nil {
+		return NewRepoError(err, RepoErrInternal, "failed to delete tag value parents")
+	}
+	return nil
+}
+
+func (r *TagRepo) CreateTagValueParent(ctx context.Context, tagValueID int32, parentValueID int32) error {
+	err := r.q.CreateTagValueParent(ctx, db.CreateTagValueParentParams{
+		ValueID:       tagValueID,
+		ParentValueID: parentValueID,
+	})
+	if err != nil {
+		return NewRepoError(err, RepoErrInternal, "failed to create tag value parent")
+	}
+	return nil
+}
+
+func (r *TagRepo) ListDependentOptions(ctx context.Context, tagID int32, valueIDs []int32) ([]db.TagValue, error) {
+	tagValues, err := r.q.ListDependentOptions(ctx, db.ListDependentOptionsParams{
+		KeyID:    tagID,
+		ValueIds: append([]int32{}, valueIDs...),
+	})
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "failed to list dependent options")
+	}
+	if tagValues == nil {
+		tagValues = []db.TagValue{}
+	}
+	return tagValues, nil
+}
+
+func (r *TagRepo) ListTagValueConflicts(ctx context.Context, valueIDs []int32) ([]db.ListTagValueConflictsRow, error) {
+	conflicts, err := r.q.ListTagValueConflicts(ctx, append([]int32{}, valueIDs...))
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "failed to check tag value conflicts")
+	}
+	return conflicts, nil
+}
+
+func (r *TagRepo) DeleteTagKey(ctx context.Context, teamID int32, tagID int32) (db.TagKey, error) {
+	tagKey, err := r.q.DeleteTagKey(ctx, db.DeleteTagKeyParams{
+		ID:     tagID,
+		TeamID: teamID,
+	})
 	if err != nil {
 		return db.TagKey{}, NewRepoError(err, RepoErrInternal, "failed to delete tag key")
 	}
 	return tagKey, nil
 }
 
+func (r *TagRepo) ArchiveTagKey(ctx context.Context, teamID int32, tagID int32) (db.TagKey, error) {
+	tagKey, err := r.q.ArchiveTagKey(ctx, db.ArchiveTagKeyParams{
+		ID:     tagID,
+		TeamID: teamID,
+	})
+	if err != nil {
+		return db.TagKey{}, NewRepoError(err, RepoErrInternal, "failed to archive tag key")
+	}
+	return tagKey, nil
+}
+
+func (r *TagRepo) RestoreTagKey(ctx context.Context, teamID int32, tagID int32) (db.TagKey, error) {
+	tagKey, err := r.q.RestoreTagKey(ctx, db.RestoreTagKeyParams{
+		ID:     tagID,
+		TeamID: teamID,
+	})
+	if err != nil {
+		return db.TagKey{}, NewRepoError(err, RepoErrInternal, "failed to restore tag key")
+	}
+	return tagKey, nil
+}
+
+func (r *TagRepo) GetTagKeyImpact(ctx context.Context, tagID int32) (db.GetTagKeyImpactRow, error) {
+	impact, err := r.q.GetTagKeyImpact(ctx, tagID)
+	if err != nil {
+		return db.GetTagKeyImpactRow{}, NewRepoError(err, RepoErrInternal, "failed to get tag key impact")
+	}
+	return impact, nil
+}
+
+func (r *TagRepo) DeleteUploadRefTagsByKey(ctx context.Context, tagID int32) error {
+	err := r.q.DeleteUploadRefTagsByKey(ctx, tagID)
+	if err != nil {
+		return NewRepoError(err, RepoErrInternal, "failed to delete upload tags of tag key")
+	}
+	return nil
+}
+
+func (r *TagRepo) DeleteUploadRefTagsByValue(ctx context.Context, tagValueID int32) error {
+	err := r.q.DeleteUploadRefTagsByValue(ctx, tagValueID)
+	if err != nil {
+		return NewRepoError(err, RepoErrInternal, "failed to delete upload tags of tag value")
+	}
+	return nil
+}
+
 func (r *TagRepo) DeleteTagValue(ctx context.Context, tagValueID int32) (db.TagValue, error) {
 	tagValue, err := r.q.DeleteTagValue(ctx, tagValueID)
 	if err != nil {
@@ -104,13 +280,133 @@ func (r *TagRepo) DeleteTagValue(ctx context.Context, tagValueID int32) (db.TagV
 	return tagValue, nil
 }
 
-func (r *TagRepo) UpdateTagKey(ctx context.Context, tagID int32, tagName string) (db.TagKey, error) {
-	tagKey, err := r.q.UpdateTagKey(ctx, db.UpdateTagKeyParams{
-		ID:   tagID,
-		Name: tagName,
-	})
+// mode and requiresApproval are left unchanged when nil
+func (r *TagRepo) UpdateTagKey(ctx context.Context, teamID, tagID int32, tagName string, mode *db.TagKeyMode, requiresApproval *bool) (db.TagKey, error) {
+	params := db.UpdateTagKeyParams{
+		ID:     tagID,
+		TeamID: teamID,
+		Name:   tagName,
+	}
+	if mode != nil {
+		params.Mode = db.NullTagKeyMode{TagKeyMode: *mode, Valid: true}
+	}
+	if requiresApproval != nil {
+		params.RequiresApproval = pgtype.Bool{Bool: *requiresApproval, Valid: true}
+	}
+
+	tagKey, err := r.q.UpdateTagKey(ctx, params)
 	if err != nil {
 		return db.TagKey{}, NewRepoError(err, RepoErrInternal, "failed to update tag key")
 	}
 	return tagKey, nil
 }
+
+func (r *TagRepo) UpdateTagValue(ctx context.Context, tagValueID int32, value string, numValue *float64) (db.TagValue, error) {
+	tagValue, err := r.q.UpdateTagValue(ctx, db.UpdateTagValueParams{
+		ID:       tagValueID,
+		Value:    value,
+		NumValue: numValue,
+	})
+	if err != nil {
+		return db.TagValue{}, NewRepoError(err, RepoErrInternal, "failed to update tag value")
+	}
+	return tagValue, nil
+}
+
+// ReorderTagValues sets the sort order of the key's values to their position in valueIDs
+func (r *TagRepo) ReorderTagValues(ctx context.Context, tagID int32, valueIDs []int32) error {
+	err := r.q.ReorderTagValues(ctx, db.ReorderTagValuesParams{
+		ValueIds: append([]int32{}, valueIDs...),
+		KeyID:    tagID,
+	})
+	if err != nil {
+		return NewRepoError(err, RepoErrInternal, "failed to reorder tag values")
+	}
+	return nil
+}
+
+func (r *TagRepo) RepointUploadRefTags(ctx context.Context, sourceID int32, targetID int32) error {
+	err := r.q.RepointUploadRefTags(ctx, db.RepointUploadRefTagsParams{
+		TargetID: targetID,
+		SourceID: sourceID,
+	})
+	if err != nil {
+		return NewRepoError(err, RepoErrInternal, "failed to repoint upload tags")
+	}
+	return nil
+}
+
+func (r *TagRepo) RepointMemberFilters(ctx context.Context, sourceID int32, targetID int32) error {
+	err := r.q.RepointMemberFilters(ctx, db.RepointMemberFiltersParams{
+		TargetID: targetID,
+		SourceID: sourceID,
+	})
+	if err != nil {
+		return NewRepoError(err, RepoErrInternal, "failed to repoint member filters")
+	}
+	return nil
+}
+
+func (r *TagRepo) ListTagValueParents(ctx context.Context, tagValueID int32) ([]db.TagValue, error) {
+	parents, err := r.q.ListTagValueParents(ctx, tagValueID)
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "failed to list tag value parents")
+	}
+	return parents, nil
+}
+
+func (r *TagRepo) CopyTagValueChildren(ctx context.Context, sourceID int32, targetID int32) error {
+	err := r.q.CopyTagValueChildren(ctx, db.CopyTagValueChildrenParams{
+		TargetID: targetID,
+		SourceID: sourceID,
+	})
+	if err != nil {
+		return NewRepoError(err, RepoErrInternal, "failed to copy tag value children")
+	}
+	return nil
+}
+
+// ListTeamTagKeys returns every tag key of the team, archived ones included
+func (r *TagRepo) ListTeamTagKeys(ctx context.Context, teamID int32) ([]db.TagKey, error) {
+	tagKeys, err := r.q.ListTeamTagKeys(ctx, teamID)
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "failed to list tag keys")
+	}
+	return tagKeys, nil
+}
+
+// ListTeamTagValues returns every value of the team's tag keys, pending ones included
+func (r *TagRepo) ListTeamTagValues(ctx context.Context, teamID int32) ([]db.TagValue, error) {
+	tagValues, err := r.q.ListTeamTagValues(ctx, teamID)
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "failed to list tag values")
+	}
+	return tagValues, nil
+}
+
+func (r *TagRepo) ListTeamTagValueParents(ctx context.Context, teamID int32) ([]db.ListTeamTagValueParentsRow, error) {
+	parents, err := r.q.ListTeamTagValueParents(ctx, teamID)
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "failed to list tag value parents")
+	}
+	return parents, nil
+}
+
+func (r *TagRepo) CountTeamTagValueUploads(ctx context.Context, teamID int32) ([]db.CountTeamTagValueUploadsRow, error) {
+	counts, err := r.q.CountTeamTagValueUploads(ctx, teamID)
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "failed to count tag value uploads")
+	}
+	return counts, nil
+}
+
+func (r *TagRepo) ListTagValueCooccurrences(ctx context.Context, teamID int32, valueIDs []int32) ([]db.ListTagValueCooccurrencesRow, error) {
+	counts, err := r.q.ListTagValueCooccurrences(ctx, db.ListTagValueCooccurrencesParams{
+		TeamID:   teamID,
+		ValueIds: append([]int32{}, valueIDs...),
+	})
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "failed to list tag value co-occurrences")
+	}
+	return counts, nil
+}
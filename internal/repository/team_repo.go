@@ -2,7 +2,9 @@ package repository
 
 import (
 	"context"
+	"time"
 
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/skndash96/lastnight-backend/internal/db"
 )
 
@@ -54,3 +56,203 @@ func (r *TeamRepository) CreateTeamMembership(ctx context.Context, user_id, team
 	}
 	return membership, nil
 }
+
+func (r *TeamRepository) GetTeamSettings(ctx context.Context, teamID int32) (db.TeamSetting, error) {
+	settings, err := r.q.GetTeamSettings(ctx, teamID)
+	if err != nil {
+		return db.TeamSetting{}, NewRepoError(err, RepoErrInternal, "failed to get team settings")
+	}
+	return settings, nil
+}
+
+func (r *TeamRepository) LockTeamSettings(ctx context.Context, teamID int32) (db.TeamSetting, error) {
+	settings, err := r.q.LockTeamSettings(ctx, teamID)
+	if err != nil {
+		return db.TeamSetting{}, NewRepoError(err, RepoErrInternal, "failed to lock team settings")
+	}
+	return settings, nil
+}
+
+func (r *TeamRepository) UpsertTeamSettings(ctx context.Context, teamID int32, allowedMimeTypes, allowedExtensions []string, maxFileSize *int64) (db.TeamSetting, error) {
+	settings, err := r.q.UpsertTeamSettings(ctx, db.UpsertTeamSettingsParams{
+		TeamID:            teamID,
+		AllowedMimeTypes:  allowedMimeTypes,
+		AllowedExtensions: allowedExtensions,
+		MaxFileSize:       maxFileSize,
+	})
+	if err != nil {
+		return db.TeamSetting{}, NewRepoError(err, RepoErrInternal, "failed to update team settings")
+	}
+	return settings, nil
+}
+
+// UpsertTeamQuotas sets the team's storage quotas in bytes, nil means unlimited.
+func (r *TeamRepository) UpsertTeamQuotas(ctx context.Context, teamID int32, teamQuota, uploaderQuota *int64) (db.TeamSetting, error) {
+	settings, err := r.q.UpsertTeamQuotas(ctx, db.UpsertTeamQuotasParams{
+		TeamID:        teamID,
+		TeamQuota:     teamQuota,
+		UploaderQuota: uploaderQuota,
+	})
+	if err != nil {
+		return db.TeamSetting{}, NewRepoError(err, RepoErrInternal, "failed to update team quotas")
+	}
+	return settings, nil
+}
+
+func (r *TeamRepository) GetUserMembership(ctx context.Context, userID int32) (db.TeamMembership, error) {
+	membership, err := r.q.GetUserMembership(ctx, userID)
+	if err != nil {
+		return db.TeamMembership{}, NewRepoError(err, RepoErrInternal, "failed to get team membership of user")
+	}
+	return membership, nil
+}
+
+func (r *TeamRepository) ListTeamMembers(ctx context.Context, teamID int32) ([]db.ListTeamMembersRow, error) {
+	members, err := r.q.ListTeamMembers(ctx, teamID)
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "failed to list team members")
+	}
+	return members, nil
+}
+
+func (r *TeamRepository) ListTeamMemberFilters(ctx context.Context, teamID int32) ([]db.ListTeamMemberFiltersRow, error) {
+	filters, err := r.q.ListTeamMemberFilters(ctx, teamID)
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "failed to list member filters")
+	}
+	return filters, nil
+}
+
+func (r *TeamRepository) CreateTeamJob(ctx context.Context, teamID, userID int32, kind db.TeamJobKind, includeObjects bool) (db.TeamJob, error) {
+	job, err := r.q.CreateTeamJob(ctx, db.CreateTeamJobParams{
+		TeamID:         teamID,
+		UserID:         userID,
+		Kind:           kind,
+		IncludeObjects: includeObjects,
+	})
+	if err != nil {
+		return db.TeamJob{}, NewRepoError(err, RepoErrInternal, "failed to create team job")
+	}
+	return job, nil
+}
+
+func (r *TeamRepository) GetTeamJob(ctx context.Context, teamID, jobID int32) (db.TeamJob, error) {
+	job, err := r.q.GetTeamJob(ctx, db.GetTeamJobParams{
+		ID:     jobID,
+		TeamID: teamID,
+	})
+	if err != nil {
+		return db.TeamJob{}, NewRepoError(err, RepoErrInternal, "failed to get team job")
+	}
+	return job, nil
+}
+
+// ListTeamJobs returns the team's latest jobs, newest first
+func (r *TeamRepository) ListTeamJobs(ctx context.Context, teamID, limit int32) ([]db.TeamJob, error) {
+	jobs, err := r.q.ListTeamJobs(ctx, db.ListTeamJobsParams{
+		TeamID: teamID,
+		Limit:  limit,
+	})
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "failed to list team jobs")
+	}
+	if jobs == nil {
+		jobs = []db.TeamJob{}
+	}
+	return jobs, nil
+}
+
+func (r *TeamRepository) StartTeamJob(ctx context.Context, jobID int32) error {
+	if err := r.q.StartTeamJob(ctx, jobID); err != nil {
+		return NewRepoError(err, RepoErrInternal, "failed to start team job")
+	}
+	return nil
+}
+
+// FinishTeamJob records the outcome of a job, report is JSON and may be nil
+func (r *TeamRepository) FinishTeamJob(ctx context.Context, jobID int32, status db.TeamJobStatus, storageKey string, report []byte, errMsg string) (db.TeamJob, error) {
+	job, err := r.q.FinishTeamJob(ctx, db.FinishTeamJobParams{
+		ID:         jobID,
+		Status:     status,
+		StorageKey: storageKey,
+		Report:     report,
+		Error:      errMsg,
+	})
+	if err != nil {
+		return db.TeamJob{}, NewRepoError(err, RepoErrInternal, "failed to finish team job")
+	}
+	return job, nil
+}
+
+func (r *TeamRepository) TouchTeamJob(ctx context.Context, jobID int32) error {
+	if err := r.q.TouchTeamJob(ctx, jobID); err != nil {
+		return NewRepoError(err, RepoErrInternal, "failed to touch team job")
+	}
+	return nil
+}
+
+// FailStaleTeamJobs fails the unfinished jobs without a heartbeat for staleAfter
+func (r *TeamRepository) FailStaleTeamJobs(ctx context.Context, staleAfter time.Duration) (int64, error) {
+	n, err := r.q.FailStaleTeamJobs(ctx, pgtype.Interval{Microseconds: staleAfter.Microseconds(), Valid: true})
+	if err != nil {
+		return 0, NewRepoError(err, RepoErrInternal, "failed to fail stale team jobs")
+	}
+	return n, nil
+}
+
+func (r *TeamRepository) ListExpiredTeamJobArchives(ctx context.Context, cutoff time.Time, limit int32) ([]db.TeamJob, error) {
+	jobs, err := r.q.ListExpiredTeamJobArchives(ctx, db.ListExpiredTeamJobArchivesParams{
+		Cutoff: cutoff,
+		Lim:    limit,
+	})
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "failed to list expired team job archives")
+	}
+	return jobs, nil
+}
+
+func (r *TeamRepository) ClearTeamJobArchive(ctx context.Context, jobID int32) error {
+	if err := r.q.ClearTeamJobArchive(ctx, jobID); err != nil {
+		return NewRepoError(err, RepoErrInternal, "failed to clear team job archive")
+	}
+	return nil
+}
+
+// CreateModNotifications notifies every mod of the team, returning how many were notified
+func (r *TeamRepository) CreateModNotifications(ctx context.Context, teamID int32, kind string, uploadRefID int32, message string) (int64, error) {
+	n, err := r.q.CreateModNotifications(ctx, db.CreateModNotificationsParams{
+		TeamID:      teamID,
+		Kind:        kind,
+		UploadRefID: uploadRefID,
+		Message:     message,
+	})
+	if err != nil {
+		return 0, NewRepoError(err, RepoErrInternal, "failed to create mod notifications")
+	}
+	return n, nil
+}
+
+func (r *TeamRepository) ListTeamNotifications(ctx context.Context, membershipID, limit int32) ([]db.TeamNotification, error) {
+	notifications, err := r.q.ListTeamNotifications(ctx, db.ListTeamNotificationsParams{
+		MembershipID: membershipID,
+		Limit:        limit,
+	})
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "failed to list team notifications")
+	}
+	if notifications == nil {
+		notifications = []db.TeamNotification{}
+	}
+	return notifications, nil
+}
+
+func (r *TeamRepository) MarkTeamNotificationRead(ctx context.Context, membershipID, notificationID int32) (int64, error) {
+	n, err := r.q.MarkTeamNotificationRead(ctx, db.MarkTeamNotificationReadParams{
+		ID:           notificationID,
+		MembershipID: membershipID,
+	})
+	if err != nil {
+		return 0, NewRepoError(err, RepoErrInternal, "failed to mark team notification read")
+	}
+	return n, nil
+}
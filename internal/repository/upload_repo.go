@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
+	"time"
 
 	"github.com/skndash96/lastnight-backend/internal/db"
 )
@@ -16,12 +18,15 @@ func NewUploadRepository(d db.DBTX) *uploadRepository {
 	}
 }
 
-func (r *uploadRepository) GetOrCreateUpload(ctx context.Context, key, mimeType string, size int64, sha256 string) (*db.GetOrCreateUploadRow, error) {
+func (r *uploadRepository) GetOrCreateUpload(ctx context.Context, key, mimeType string, size int64, sha256 string, scanStatus db.UploadScanStatus, scanSignature string, scannedAt *time.Time) (*db.GetOrCreateUploadRow, error) {
 	upload, err := r.q.GetOrCreateUpload(ctx, db.GetOrCreateUploadParams{
-		StorageKey:   key,
-		FileMimeType: mimeType,
-		FileSize:     size,
-		FileSha256:   sha256,
+		StorageKey:    key,
+		FileMimeType:  mimeType,
+		FileSize:      size,
+		FileSha256:    sha256,
+		ScanStatus:    scanStatus,
+		ScanSignature: scanSignature,
+		ScannedAt:     scannedAt,
 	})
 	if err != nil {
 		return nil, NewRepoError(err, RepoErrInternal, "Failed to create upload")
@@ -29,12 +34,13 @@ func (r *uploadRepository) GetOrCreateUpload(ctx context.Context, key, mimeType
 	return &upload, nil
 }
 
-func (r *uploadRepository) CreateUploadRef(ctx context.Context, uploadID int32, teamID int32, userID int32, name string) (*db.UploadRef, error) {
+func (r *uploadRepository) CreateUploadRef(ctx context.Context, uploadID int32, teamID int32, userID int32, name string, status db.UploadRefStatus) (*db.UploadRef, error) {
 	ref, err := r.q.CreateUploadRef(ctx, db.CreateUploadRefParams{
 		FileName:   name,
 		UploadID:   uploadID,
 		TeamID:     teamID,
 		UploaderID: userID,
+		Status:     status,
 	})
 	if err != nil {
 		return nil, NewRepoError(err, RepoErrInternal, "Failed to create upload reference")
@@ -42,6 +48,66 @@ func (r *uploadRepository) CreateUploadRef(ctx context.Context, uploadID int32,
 	return &ref, nil
 }
 
+func (r *uploadRepository) GetUploadByHash(ctx context.Context, sha256 string, size int64) (*db.Upload, error) {
+	upload, err := r.q.GetUploadByHash(ctx, db.GetUploadByHashParams{
+		FileSha256: sha256,
+		FileSize:   size,
+	})
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "Failed to get upload")
+	}
+	return &upload, nil
+}
+
+// ClaimUpload locks the upload with the given hash and size so it can get a new ref.
+func (r *uploadRepository) ClaimUpload(ctx context.Context, sha256 string, size int64) (*db.Upload, error) {
+	upload, err := r.q.ClaimUpload(ctx, db.ClaimUploadParams{
+		FileSha256: sha256,
+		FileSize:   size,
+	})
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "Failed to claim upload")
+	}
+	return &upload, nil
+}
+
+func (r *uploadRepository) GetTeamUploadRef(ctx context.Context, teamID, uploadID int32) (*db.UploadRef, error) {
+	ref, err := r.q.GetTeamUploadRef(ctx, db.GetTeamUploadRefParams{
+		UploadID: uploadID,
+		TeamID:   teamID,
+	})
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "Failed to get upload reference")
+	}
+	return &ref, nil
+}
+
+func (r *uploadRepository) ListRejectedUploads(ctx context.Context, teamID int32) ([]db.ListRejectedUploadsRow, error) {
+	uploads, err := r.q.ListRejectedUploads(ctx, teamID)
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "Failed to list rejected uploads")
+	}
+	if uploads == nil {
+		uploads = []db.ListRejectedUploadsRow{}
+	}
+	return uploads, nil
+}
+
+func (r *uploadRepository) QuarantineUpload(ctx context.Context, uploadID int32, key string) error {
+	if err := r.q.QuarantineUpload(ctx, db.QuarantineUploadParams{ID: uploadID, StorageKey: key}); err != nil {
+		return NewRepoError(err, RepoErrInternal, "Failed to quarantine upload")
+	}
+	return nil
+}
+
+func (r *uploadRepository) RejectUploadRefs(ctx context.Context, uploadID int32) ([]db.UploadRef, error) {
+	refs, err := r.q.RejectUploadRefs(ctx, uploadID)
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "Failed to reject upload references")
+	}
+	return refs, nil
+}
+
 func (r *uploadRepository) CreateUploadRefTag(ctx context.Context, uploadRefID int32, keyID int32, valueID int32) error {
 	err := r.q.CreateUploadRefTags(ctx, db.CreateUploadRefTagsParams{
 		UploadRefID: uploadRefID,
@@ -55,3 +121,449 @@ func (r *uploadRepository) CreateUploadRefTag(ctx context.Context, uploadRefID i
 
 	return nil
 }
+
+func (r *uploadRepository) GetUploadByRefID(ctx context.Context, teamID, refID int32) (*db.Upload, error) {
+	upload, err := r.q.GetUploadByRefID(ctx, db.GetUploadByRefIDParams{
+		ID:     refID,
+		TeamID: teamID,
+	})
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "Failed to get upload")
+	}
+	return &upload, nil
+}
+
+func (r *uploadRepository) UpsertUploadDerivative(ctx context.Context, arg db.UpsertUploadDerivativeParams) (*db.UploadDerivative, error) {
+	derivative, err := r.q.UpsertUploadDerivative(ctx, arg)
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "Failed to save upload derivative")
+	}
+	return &derivative, nil
+}
+
+func (r *uploadRepository) UpsertUploadText(ctx context.Context, uploadID int32, content string) error {
+	err := r.q.UpsertUploadText(ctx, db.UpsertUploadTextParams{
+		UploadID: uploadID,
+		Content:  content,
+	})
+	if err != nil {
+		return NewRepoError(err, RepoErrInternal, "Failed to save upload text")
+	}
+	return nil
+}
+
+func (r *uploadRepository) ListUploadDerivatives(ctx context.Context, teamID, refID int32) ([]db.UploadDerivative, error) {
+	derivatives, err := r.q.ListUploadDerivatives(ctx, db.ListUploadDerivativesParams{
+		ID:     refID,
+		TeamID: teamID,
+	})
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "Failed to list upload derivatives")
+	}
+	if derivatives == nil {
+		derivatives = []db.UploadDerivative{}
+	}
+	return derivatives, nil
+}
+
+func (r *uploadRepository) GetUploadRef(ctx context.Context, teamID, refID int32) (*db.UploadRef, error) {
+	ref, err := r.q.GetUploadRef(ctx, db.GetUploadRefParams{
+		ID:     refID,
+		TeamID: teamID,
+	})
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "Failed to get upload reference")
+	}
+	return &ref, nil
+}
+
+func (r *uploadRepository) RenameUploadRef(ctx context.Context, refID int32, name string) (*db.UploadRef, error) {
+	ref, err := r.q.RenameUploadRef(ctx, db.RenameUploadRefParams{
+		ID:       refID,
+		FileName: name,
+	})
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "Failed to rename upload reference")
+	}
+	return &ref, nil
+}
+
+func (r *uploadRepository) DeleteUploadRefTags(ctx context.Context, refID int32) error {
+	if err := r.q.DeleteUploadRefTags(ctx, refID); err != nil {
+		return NewRepoError(err, RepoErrInternal, "Failed to delete upload tags")
+	}
+	return nil
+}
+
+func (r *uploadRepository) DeleteUploadRef(ctx context.Context, refID int32) error {
+	if err := r.q.DeleteUploadRef(ctx, refID); err != nil {
+		return NewRepoError(err, RepoErrInternal, "Failed to delete upload reference")
+	}
+	return nil
+}
+
+// MarkUploadOrphaned reports whether the upload had no refs left and was marked.
+func (r *uploadRepository) MarkUploadOrphaned(ctx context.Context, uploadID int32) (bool, error) {
+	n, err := r.q.MarkUploadOrphaned(ctx, uploadID)
+	if err != nil {
+		return false, NewRepoError(err, RepoErrInternal, "Failed to mark upload orphaned")
+	}
+	return n > 0, nil
+}
+
+func (r *uploadRepository) ListOrphanedUploads(ctx context.Context, cutoff time.Time, limit int32) ([]db.Upload, error) {
+	uploads, err := r.q.ListOrphanedUploads(ctx, db.ListOrphanedUploadsParams{
+		Cutoff: cutoff,
+		Lim:    limit,
+	})
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "Failed to list orphaned uploads")
+	}
+	return uploads, nil
+}
+
+func (r *uploadRepository) LockOrphanedUpload(ctx context.Context, uploadID int32, cutoff time.Time) (*db.Upload, error) {
+	upload, err := r.q.LockOrphanedUpload(ctx, db.LockOrphanedUploadParams{
+		ID:     uploadID,
+		Cutoff: cutoff,
+	})
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "Failed to lock orphaned upload")
+	}
+	return &upload, nil
+}
+
+func (r *uploadRepository) ListUploadDerivativeKeys(ctx context.Context, uploadID int32) ([]string, error) {
+	keys, err := r.q.ListUploadDerivativeKeys(ctx, uploadID)
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "Failed to list upload derivatives")
+	}
+	return keys, nil
+}
+
+func (r *uploadRepository) DeleteUpload(ctx context.Context, uploadID int32) error {
+	if err := r.q.DeleteUpload(ctx, uploadID); err != nil {
+		return NewRepoError(err, RepoErrInternal, "Failed to delete upload")
+	}
+	return nil
+}
+
+func (r *uploadRepository) CreateMultipartUpload(ctx context.Context, arg db.CreateMultipartUploadParams) (*db.MultipartUpload, error) {
+	upload, err := r.q.CreateMultipartUpload(ctx, arg)
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "Failed to create multipart upload")
+	}
+	return &upload, nil
+}
+
+func (r *uploadRepository) ListMultipartUploads(ctx context.Context, teamID, userID int32) ([]db.MultipartUpload, error) {
+	uploads, err := r.q.ListMultipartUploads(ctx, db.ListMultipartUploadsParams{
+		TeamID:     teamID,
+		UploaderID: userID,
+	})
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "Failed to list multipart uploads")
+	}
+	return uploads, nil
+}
+
+func (r *uploadRepository) GetMultipartUpload(ctx context.Context, teamID, id int32) (*db.MultipartUpload, error) {
+	upload, err := r.q.GetMultipartUpload(ctx, db.GetMultipartUploadParams{
+		ID:     id,
+		TeamID: teamID,
+	})
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "Failed to get multipart upload")
+	}
+	return &upload, nil
+}
+
+func (r *uploadRepository) LockMultipartUpload(ctx context.Context, teamID, id int32) (*db.MultipartUpload, error) {
+	upload, err := r.q.LockMultipartUpload(ctx, db.LockMultipartUploadParams{
+		ID:     id,
+		TeamID: teamID,
+	})
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "Failed to lock multipart upload")
+	}
+	return &upload, nil
+}
+
+func (r *uploadRepository) DeleteMultipartUpload(ctx context.Context, id int32) error {
+	if err := r.q.DeleteMultipartUpload(ctx, id); err != nil {
+		return NewRepoError(err, RepoErrInternal, "Failed to delete multipart upload")
+	}
+	return nil
+}
+
+func (r *uploadRepository) ListExpiredMultipartUploads(ctx context.Context, limit int32) ([]db.MultipartUpload, error) {
+	uploads, err := r.q.ListExpiredMultipartUploads(ctx, limit)
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "Failed to list expired multipart uploads")
+	}
+	return uploads, nil
+}
+
+func (r *uploadRepository) LockExpiredMultipartUpload(ctx context.Context, id int32) (*db.MultipartUpload, error) {
+	upload, err := r.q.LockExpiredMultipartUpload(ctx, id)
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "Failed to lock expired multipart upload")
+	}
+	return &upload, nil
+}
+
+func (r *uploadRepository) GetTeamUsage(ctx context.Context, teamID int32) (*db.GetTeamUsageRow, error) {
+	usage, err := r.q.GetTeamUsage(ctx, teamID)
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "Failed to get team usage")
+	}
+	return &usage, nil
+}
+
+func (r *uploadRepository) GetUploaderUsage(ctx context.Context, teamID, userID int32) (*db.GetUploaderUsageRow, error) {
+	usage, err := r.q.GetUploaderUsage(ctx, db.GetUploaderUsageParams{
+		TeamID:     teamID,
+		UploaderID: userID,
+	})
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "Failed to get uploader usage")
+	}
+	return &usage, nil
+}
+
+func (r *uploadRepository) IsUploadCounted(ctx context.Context, teamID, userID, uploadID int32) (*db.IsUploadCountedRow, error) {
+	counted, err := r.q.IsUploadCounted(ctx, db.IsUploadCountedParams{
+		TeamID:     teamID,
+		UploadID:   uploadID,
+		UploaderID: userID,
+	})
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "Failed to check upload usage")
+	}
+	return &counted, nil
+}
+
+func (r *uploadRepository) ListUploaderUsage(ctx context.Context, teamID int32) ([]db.ListUploaderUsageRow, error) {
+	usage, err := r.q.ListUploaderUsage(ctx, teamID)
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "Failed to list uploader usage")
+	}
+	return usage, nil
+}
+
+func (r *uploadRepository) CreateUploadPresign(ctx context.Context, arg db.CreateUploadPresignParams) error {
+	if err := r.q.CreateUploadPresign(ctx, arg); err != nil {
+		return NewRepoError(err, RepoErrInternal, "Failed to record upload presign")
+	}
+	return nil
+}
+
+func (r *uploadRepository) GetUploadPresign(ctx context.Context, teamID, userID int32, key string) (*db.UploadPresign, error) {
+	presign, err := r.q.GetUploadPresign(ctx, db.GetUploadPresignParams{
+		StorageKey: key,
+		TeamID:     teamID,
+		UploaderID: userID,
+	})
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "Failed to get upload presign")
+	}
+	return &presign, nil
+}
+
+func (r *uploadRepository) ConsumeUploadPresign(ctx context.Context, teamID, userID int32, key string) (*db.UploadPresign, error) {
+	presign, err := r.q.ConsumeUploadPresign(ctx, db.ConsumeUploadPresignParams{
+		StorageKey: key,
+		TeamID:     teamID,
+		UploaderID: userID,
+	})
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "Failed to get upload presign")
+	}
+	return &presign, nil
+}
+
+func (r *uploadRepository) ListExpiredUploadPresigns(ctx context.Context, cutoff time.Time, limit int32) ([]db.UploadPresign, error) {
+	presigns, err := r.q.ListExpiredUploadPresigns(ctx, db.ListExpiredUploadPresignsParams{
+		Cutoff: cutoff,
+		Lim:    limit,
+	})
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "Failed to list expired upload presigns")
+	}
+	return presigns, nil
+}
+
+func (r *uploadRepository) DeleteExpiredUploadPresign(ctx context.Context, id int32, cutoff time.Time) (*db.UploadPresign, error) {
+	presign, err := r.q.DeleteExpiredUploadPresign(ctx, db.DeleteExpiredUploadPresignParams{
+		ID:     id,
+		Cutoff: cutoff,
+	})
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "Failed to delete expired upload presign")
+	}
+	return &presign, nil
+}
+
+func (r *uploadRepository) CreateUploadVersion(ctx context.Context, refID, uploadID, userID int32, name string) (*db.UploadVersion, error) {
+	version, err := r.q.CreateUploadVersion(ctx, db.CreateUploadVersionParams{
+		UploadRefID: refID,
+		UploadID:    uploadID,
+		UploaderID:  userID,
+		FileName:    name,
+	})
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "Failed to create upload version")
+	}
+	return &version, nil
+}
+
+func (r *uploadRepository) GetUploadVersion(ctx context.Context, refID, version int32) (*db.UploadVersion, error) {
+	v, err := r.q.GetUploadVersion(ctx, db.GetUploadVersionParams{
+		UploadRefID: refID,
+		Version:     version,
+	})
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "Failed to get upload version")
+	}
+	return &v, nil
+}
+
+func (r *uploadRepository) GetUploadVersionByUpload(ctx context.Context, refID, uploadID int32) (*db.UploadVersion, error) {
+	v, err := r.q.GetUploadVersionByUpload(ctx, db.GetUploadVersionByUploadParams{
+		UploadRefID: refID,
+		UploadID:    uploadID,
+	})
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "Failed to get upload version")
+	}
+	return &v, nil
+}
+
+// HasTeamUploadVersion reports whether the upload is a version of one of the team's active refs.
+func (r *uploadRepository) HasTeamUploadVersion(ctx context.Context, teamID, uploadID int32) (bool, error) {
+	exists, err := r.q.HasTeamUploadVersion(ctx, db.HasTeamUploadVersionParams{
+		UploadID: uploadID,
+		TeamID:   teamID,
+	})
+	if err != nil {
+		return false, NewRepoError(err, RepoErrInternal, "Failed to check upload versions")
+	}
+	return exists, nil
+}
+
+func (r *uploadRepository) ListUploadVersions(ctx context.Context, teamID, refID int32) ([]db.ListUploadVersionsRow, error) {
+	versions, err := r.q.ListUploadVersions(ctx, db.ListUploadVersionsParams{
+		ID:     refID,
+		TeamID: teamID,
+	})
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "Failed to list upload versions")
+	}
+	if versions == nil {
+		versions = []db.ListUploadVersionsRow{}
+	}
+	return versions, nil
+}
+
+// DeleteUploadVersions removes every version of a ref and returns their uploads.
+func (r *uploadRepository) DeleteUploadVersions(ctx context.Context, refID int32) ([]int32, error) {
+	uploadIDs, err := r.q.DeleteUploadVersions(ctx, refID)
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "Failed to delete upload versions")
+	}
+	return uploadIDs, nil
+}
+
+func (r *uploadRepository) SetCurrentUploadVersion(ctx context.Context, refID, uploadID int32) (*db.UploadRef, error) {
+	ref, err := r.q.SetCurrentUploadVersion(ctx, db.SetCurrentUploadVersionParams{
+		ID:       refID,
+		UploadID: uploadID,
+	})
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "Failed to set current upload version")
+	}
+	return &ref, nil
+}
+
+// ListTeamUploadRefs returns the team's active upload refs with their uploads
+func (r *uploadRepository) ListTeamUploadRefs(ctx context.Context, teamID int32) ([]db.ListTeamUploadRefsRow, error) {
+	refs, err := r.q.ListTeamUploadRefs(ctx, teamID)
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "Failed to list team uploads")
+	}
+	return refs, nil
+}
+
+func (r *uploadRepository) ListTeamUploadRefTags(ctx context.Context, teamID int32) ([]db.ListTeamUploadRefTagsRow, error) {
+	tags, err := r.q.ListTeamUploadRefTags(ctx, teamID)
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "Failed to list team upload tags")
+	}
+	return tags, nil
+}
+
+// GetUploadRefText returns the text extracted from the upload of a ref
+func (r *uploadRepository) GetUploadRefText(ctx context.Context, teamID, refID int32) (string, error) {
+	content, err := r.q.GetUploadRefText(ctx, db.GetUploadRefTextParams{
+		TeamID: teamID,
+		ID:     refID,
+	})
+	if err != nil {
+		return "", NewRepoError(err, RepoErrInternal, "Failed to get upload text")
+	}
+	return content, nil
+}
+
+type RangeFilter struct {
+	KeyID int32   `json:"key_id"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+}
+
+func (r *uploadRepository) ListUploads(ctx context.Context, teamID int32, q string, valueIDs []int32, ranges []RangeFilter, limit, offset int32) ([]db.UploadItem, error) {
+	// nil slices are sent as NULL, which would never match in the query
+	params := db.ListUploadsParams{
+		Q:           q,
+		TeamID:      teamID,
+		ValueIds:    append([]int32{}, valueIDs...),
+		RangeKeyIds: []int32{},
+		RangeMins:   []float64{},
+		RangeMaxs:   []float64{},
+		Lim:         limit,
+		Off:         offset,
+	}
+	for _, rg := range ranges {
+		params.RangeKeyIds = append(params.RangeKeyIds, rg.KeyID)
+		params.RangeMins = append(params.RangeMins, rg.Min)
+		params.RangeMaxs = append(params.RangeMaxs, rg.Max)
+	}
+
+	raw, err := r.q.ListUploads(ctx, params)
+	if err != nil {
+		return nil, NewRepoError(err, RepoErrInternal, "Failed to list uploads")
+	}
+
+	out := []db.UploadItem{}
+	for _, u := range raw {
+		item := db.UploadItem{
+			ID:           u.ID,
+			FileName:     u.FileName,
+			UploaderID:   u.UploaderID,
+			CreatedAt:    u.CreatedAt,
+			StorageKey:   u.StorageKey,
+			FileMimeType: u.FileMimeType,
+			FileSize:     u.FileSize,
+			Version:      u.Version,
+			VersionCount: u.VersionCount,
+			Rank:         u.Rank,
+			Snippet:      u.Snippet,
+		}
+
+		if err := json.Unmarshal(u.Tags, &item.Tags); err != nil {
+			return nil, NewRepoError(err, RepoErrInternal, "Failed to unmarshal upload tags")
+		}
+		out = append(out, item)
+	}
+
+	return out, nil
+}
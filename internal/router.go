@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"log"
 
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -13,18 +14,24 @@ import (
 	"github.com/skndash96/lastnight-backend/internal/service"
 )
 
-func RegisterRoutes(e *echo.Echo, cfg *config.AppConfig, pool *pgxpool.Pool) {
+// RegisterRoutes registers the API, background work started here stops when
+// ctx is cancelled.
+func RegisterRoutes(ctx context.Context, e *echo.Echo, cfg *config.AppConfig, pool *pgxpool.Pool) {
 	r := e.Group("/api")
 
 	authRepo := repository.NewAuthRepository(pool)
 	teamRepo := repository.NewTeamRepository(pool)
 
 	sessionProvider := provider.NewSessionProvider(cfg.Auth.Session, authRepo)
-	uploadProvider, err := provider.NewUploadProvider(cfg.Minio)
+	uploadProvider, err := provider.NewUploadProvider(cfg.Storage)
 	if err != nil {
 		log.Fatalf("failed to initialize upload provider: %v", err)
 	}
 
+	// uploads without refs are collected in the background, see also the gc command
+	gcSrv := service.NewGCService(cfg.GC, uploadProvider, pool)
+	go gcSrv.Run(ctx)
+
 	r.Use(auth.SessionMW(sessionProvider, cfg.Auth.Cookie))
 
 	{
@@ -33,6 +40,15 @@ func RegisterRoutes(e *echo.Echo, cfg *config.AppConfig, pool *pgxpool.Pool) {
 		g.GET("", h.HealthCheck)
 	}
 
+	// the local storage driver serves its signed URLs itself
+	if storage, ok := uploadProvider.(*provider.LocalProvider); ok {
+		h := handler.NewStorageHandler(storage)
+		g := r.Group("/storage")
+		g.POST("/uploads", h.Upload)
+		g.PUT("/parts/:uploadID/:partNumber", h.UploadPart)
+		g.GET("/objects/*", h.Download)
+	}
+
 	{
 		authSrv := service.NewAuthService(pool, sessionProvider)
 
@@ -58,23 +74,75 @@ func RegisterRoutes(e *echo.Echo, cfg *config.AppConfig, pool *pgxpool.Pool) {
 		teamG := teamsG.Group("/:teamID")
 		teamG.Use(auth.TeamMW(teamRepo))
 
+		teamG.GET("/notifications", team_h.ListNotifications)
+		teamG.POST("/notifications/:notificationID/read", team_h.MarkNotificationRead)
+
 		teamG.GET("/filters", tag_h.ListFilters)
 		teamG.PUT("/filters", tag_h.UpdateFilters)
 
 		teamG.POST("/tags", tag_h.CreateTagKey)
-		teamG.PUT("/tags/:tagID", tag_h.UpdateTagKey)
-		teamG.DELETE("/tags/:tagID", tag_h.DeleteTagKey)
+		teamG.PUT("/tags/:tagID", tag_h.UpdateTagKey, auth.ModMW())
+		teamG.DELETE("/tags/:tagID", tag_h.DeleteTagKey, auth.ModMW())
+		teamG.POST("/tags/suggestions", tag_h.SuggestTags)
+		teamG.GET("/tags/:tagID/impact", tag_h.GetTagKeyImpact, auth.ModMW())
+		teamG.POST("/tags/:tagID/restore", tag_h.RestoreTagKey, auth.ModMW())
 
+		teamG.GET("/tags/:tagID/options", tag_h.ListTagOptions)
 		teamG.POST("/tags/:tagID/values", tag_h.CreateTagValue)
 		teamG.DELETE("/tags/:tagID/values/:tagValueID", tag_h.DeleteTagValue)
 
+		teamG.GET("/tags/values/pending", tag_h.ListPendingTagValues, auth.ModMW())
+		teamG.POST("/tags/:tagID/values/:tagValueID/approve", tag_h.ApproveTagValue, auth.ModMW())
+		teamG.POST("/tags/:tagID/values/:tagValueID/reject", tag_h.RejectTagValue, auth.ModMW())
+		teamG.PUT("/tags/:tagID/values/:tagValueID/parents", tag_h.SetTagValueParents, auth.ModMW())
+		teamG.PATCH("/tags/:tagID/values/:tagValueID", tag_h.UpdateTagValue, auth.ModMW())
+		teamG.POST("/tags/:tagID/values/:tagValueID/merge", tag_h.MergeTagValue, auth.ModMW())
+		teamG.PUT("/tags/:tagID/values/order", tag_h.ReorderTagValues, auth.ModMW())
+
 		{
-			uploadSrv := service.NewUploadService(uploadProvider, pool)
+			uploadSrv := service.NewUploadService(ctx, cfg.Upload, uploadProvider, pool)
 			h := handler.NewUploadHandler(uploadSrv)
 
+			teamG.GET("/usage", h.GetUsage, auth.ModMW())
+			teamG.PUT("/usage/quotas", h.UpdateQuotas, auth.ModMW())
+
 			uploadsG := teamG.Group("/uploads")
+			uploadsG.GET("", h.ListUploads)
+			uploadsG.POST("", h.Upload)
+			uploadsG.GET("/rejected", h.ListRejectedUploads, auth.ModMW())
+			uploadsG.GET("/export", h.ExportUploads)
+			uploadsG.POST("/check", h.CheckUpload)
 			uploadsG.POST("/presign", h.PresignUpload)
 			uploadsG.POST("/complete", h.CompleteUpload)
+			uploadsG.POST("/images/presign", h.PresignImageUpload)
+			uploadsG.POST("/images/complete", h.CompleteImageUpload)
+			uploadsG.POST("/multipart", h.CreateMultipartUpload)
+			uploadsG.GET("/multipart", h.ListMultipartUploads)
+			uploadsG.GET("/multipart/:multipartID", h.GetMultipartUpload)
+			uploadsG.DELETE("/multipart/:multipartID", h.AbortMultipartUpload)
+			uploadsG.POST("/multipart/:multipartID/parts", h.PresignUploadParts)
+			uploadsG.POST("/multipart/:multipartID/complete", h.CompleteMultipartUpload)
+			uploadsG.GET("/settings", h.GetUploadSettings)
+			uploadsG.PUT("/settings", h.UpdateUploadSettings, auth.ModMW())
+			uploadsG.PATCH("/:uploadID", h.UpdateUpload)
+			uploadsG.DELETE("/:uploadID", h.DeleteUpload)
+			uploadsG.GET("/:uploadID/derivatives", h.ListUploadDerivatives)
+			uploadsG.POST("/:uploadID/derivatives", h.RegenerateUploadDerivatives, auth.ModMW())
+			uploadsG.GET("/:uploadID/versions", h.ListUploadVersions)
+			uploadsG.PUT("/:uploadID/versions/current", h.SetCurrentUploadVersion, auth.ModMW())
+
+			archiveSrv := service.NewTeamArchiveService(ctx, uploadSrv, pool)
+			// jobs run within a server process, those of one that stopped cannot finish
+			if _, err := archiveSrv.FailStaleTeamJobs(ctx); err != nil {
+				log.Printf("failed to fail stale team jobs: %v", err)
+			}
+
+			job_h := handler.NewTeamJobHandler(archiveSrv)
+
+			teamG.GET("/jobs", job_h.ListTeamJobs, auth.ModMW())
+			teamG.POST("/jobs/export", job_h.StartTeamExport, auth.ModMW())
+			teamG.POST("/jobs/import", job_h.StartTeamImport, auth.ModMW())
+			teamG.GET("/jobs/:jobID", job_h.GetTeamJob, auth.ModMW())
 		}
 	}
 }
@@ -0,0 +1,133 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+const clamdChunkSize = 64 * 1024
+
+var (
+	ErrClamd = errors.New("clamd error")
+	// the file is larger than clamd's StreamMaxLength
+	ErrStreamTooLarge = fmt.Errorf("%w: stream size limit exceeded", ErrClamd)
+)
+
+// Clamd scans files with a ClamAV daemon over its INSTREAM protocol.
+type Clamd struct {
+	// "tcp" or "unix"
+	Network string
+	Address string
+	Timeout time.Duration
+}
+
+func (c *Clamd) Scan(ctx context.Context, r io.Reader) (Result, error) {
+	dialer := net.Dialer{Timeout: c.Timeout}
+	conn, err := dialer.DialContext(ctx, c.Network, c.Address)
+	if err != nil {
+		return Result{}, err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else if c.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(c.Timeout))
+	}
+
+	// clamd replies early, and closes the connection, when the stream exceeds
+	// its StreamMaxLength
+	replies := make(chan clamdReply, 1)
+	go func() {
+		reply, err := bufio.NewReader(conn).ReadBytes(0)
+		replies <- clamdReply{reply: reply, err: err}
+	}()
+
+	// z-prefixed commands are NUL terminated, as are their replies
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return readClamdReply(replies, err)
+	}
+
+	// each chunk is prefixed with its length, a zero length ends the stream
+	buf := make([]byte, 4+clamdChunkSize)
+	for {
+		select {
+		case reply := <-replies:
+			return reply.result()
+		default:
+		}
+
+		n, err := io.ReadFull(r, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, err := conn.Write(buf[:4+n]); err != nil {
+				return readClamdReply(replies, err)
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return Result{}, err
+		}
+	}
+
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return readClamdReply(replies, err)
+	}
+
+	return (<-replies).result()
+}
+
+type clamdReply struct {
+	reply []byte
+	err   error
+}
+
+func (r clamdReply) result() (Result, error) {
+	if r.err != nil && !(r.err == io.EOF && len(r.reply) > 0) {
+		return Result{}, r.err
+	}
+
+	return parseClamdReply(string(bytes.TrimRight(r.reply, "\x00")))
+}
+
+// readClamdReply returns clamd's reply after a write failed, which is usually
+// why it failed, or the write error if clamd sent none.
+func readClamdReply(replies <-chan clamdReply, writeErr error) (Result, error) {
+	reply := <-replies
+	if len(reply.reply) == 0 {
+		return Result{}, writeErr
+	}
+
+	return reply.result()
+}
+
+// parseClamdReply parses "stream: OK", "stream: <signature> FOUND" or "<message> ERROR".
+func parseClamdReply(reply string) (Result, error) {
+	reply = strings.TrimSpace(reply)
+	body := strings.TrimPrefix(reply, "stream: ")
+
+	switch {
+	case strings.Contains(reply, "size limit exceeded"):
+		return Result{}, fmt.Errorf("%w: %s", ErrStreamTooLarge, reply)
+	case body == "OK":
+		return Result{Scanned: true}, nil
+	case strings.HasSuffix(body, " FOUND"):
+		return Result{
+			Scanned:   true,
+			Infected:  true,
+			Signature: strings.TrimSuffix(body, " FOUND"),
+		}, nil
+	default:
+		return Result{}, fmt.Errorf("%w: %s", ErrClamd, reply)
+	}
+}
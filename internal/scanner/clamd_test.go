@@ -0,0 +1,214 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClamd accepts a single INSTREAM connection, sends the streamed file on
+// received and answers with reply.
+func fakeClamd(t *testing.T, reply string) (string, <-chan []byte) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		data, err := readInstream(conn)
+		if err != nil {
+			t.Errorf("fake clamd: %v", err)
+			return
+		}
+		received <- data
+
+		conn.Write([]byte(reply))
+	}()
+
+	return l.Addr().String(), received
+}
+
+func readInstream(r io.Reader) ([]byte, error) {
+	cmd := make([]byte, len("zINSTREAM\x00"))
+	if _, err := io.ReadFull(r, cmd); err != nil {
+		return nil, err
+	}
+	if string(cmd) != "zINSTREAM\x00" {
+		return nil, errors.New("unexpected command " + string(cmd))
+	}
+
+	var data bytes.Buffer
+	for {
+		var size uint32
+		if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return data.Bytes(), nil
+		}
+		if size > clamdChunkSize {
+			return nil, errors.New("chunk larger than clamdChunkSize")
+		}
+		if _, err := io.CopyN(&data, r, int64(size)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func TestParseClamdReply(t *testing.T) {
+	tests := []struct {
+		reply string
+		want  Result
+		err   error
+	}{
+		{"stream: OK", Result{Scanned: true}, nil},
+		{"stream: OK\n", Result{Scanned: true}, nil},
+		{"stream: Eicar-Test-Signature FOUND", Result{Scanned: true, Infected: true, Signature: "Eicar-Test-Signature"}, nil},
+		{"stream: Win.Test.EICAR_HDB-1 FOUND\n", Result{Scanned: true, Infected: true, Signature: "Win.Test.EICAR_HDB-1"}, nil},
+		{"INSTREAM size limit exceeded. ERROR", Result{}, ErrStreamTooLarge},
+		{"stream: Can't allocate memory ERROR", Result{}, ErrClamd},
+		{"", Result{}, ErrClamd},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.reply, func(t *testing.T) {
+			got, err := parseClamdReply(tt.reply)
+			if !errors.Is(err, tt.err) || (tt.err == nil && err != nil) {
+				t.Fatalf("parseClamdReply() error = %v, want %v", err, tt.err)
+			}
+			if tt.err != ErrStreamTooLarge && errors.Is(err, ErrStreamTooLarge) {
+				t.Errorf("parseClamdReply() error = %v, want no size limit error", err)
+			}
+			if got != tt.want {
+				t.Errorf("parseClamdReply() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClamdScan(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		reply string
+		want  Result
+		err   error
+	}{
+		{"clean", "hello", "stream: OK\x00", Result{Scanned: true}, nil},
+		{"infected", "X5O!P%@AP", "stream: Eicar-Test-Signature FOUND\x00", Result{Scanned: true, Infected: true, Signature: "Eicar-Test-Signature"}, nil},
+		{"several chunks", strings.Repeat("a", 2*clamdChunkSize+1), "stream: OK\x00", Result{Scanned: true}, nil},
+		{"empty file", "", "stream: OK\x00", Result{Scanned: true}, nil},
+		{"reply without NUL", "hello", "stream: OK", Result{Scanned: true}, nil},
+		{"clamd error", "hello", "stream: Can't allocate memory ERROR\x00", Result{}, ErrClamd},
+		{"size limit", "hello", "INSTREAM size limit exceeded. ERROR\x00", Result{}, ErrStreamTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, received := fakeClamd(t, tt.reply)
+			c := &Clamd{Network: "tcp", Address: addr, Timeout: 5 * time.Second}
+
+			got, err := c.Scan(context.Background(), strings.NewReader(tt.file))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Scan() error = %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("Scan() = %+v, want %+v", got, tt.want)
+			}
+
+			if data := <-received; string(data) != tt.file {
+				t.Errorf("clamd received %d bytes, want %d", len(data), len(tt.file))
+			}
+		})
+	}
+}
+
+func TestClamdScanSizeLimit(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// like clamd, the fake replies once the stream passes its limit, then
+	// counts what is still sent
+	const limit = 4 * clamdChunkSize
+	received := make(chan int64, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		n, _ := io.CopyN(io.Discard, conn, int64(len("zINSTREAM\x00"))+limit)
+		conn.Write([]byte("INSTREAM size limit exceeded. ERROR\x00"))
+		conn.(*net.TCPConn).CloseWrite()
+
+		rest, _ := io.Copy(io.Discard, conn)
+		received <- n + rest
+	}()
+
+	file := strings.Repeat("a", 512*clamdChunkSize)
+	c := &Clamd{Network: "tcp", Address: l.Addr().String(), Timeout: 5 * time.Second}
+	if _, err := c.Scan(context.Background(), strings.NewReader(file)); !errors.Is(err, ErrStreamTooLarge) {
+		t.Fatalf("Scan() error = %v, want %v", err, ErrStreamTooLarge)
+	}
+
+	if n := <-received; n >= int64(len(file)) {
+		t.Errorf("Scan() sent all %d bytes after clamd replied", n)
+	}
+}
+
+func TestClamdScanTimeout(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// the connection is accepted but never answered
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.Copy(io.Discard, conn)
+	}()
+
+	c := &Clamd{Network: "tcp", Address: l.Addr().String(), Timeout: 100 * time.Millisecond}
+	if _, err := c.Scan(context.Background(), strings.NewReader("hello")); err == nil {
+		t.Error("Scan() without a reply succeeded")
+	}
+}
+
+func TestClamdScanUnavailable(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	c := &Clamd{Network: "tcp", Address: addr, Timeout: time.Second}
+	if _, err := c.Scan(context.Background(), strings.NewReader("hello")); err == nil {
+		t.Error("Scan() without clamd succeeded")
+	}
+}
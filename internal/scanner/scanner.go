@@ -0,0 +1,26 @@
+// Package scanner checks uploaded files for malware.
+package scanner
+
+import (
+	"context"
+	"io"
+)
+
+type Result struct {
+	// false when the scanner did not look at the file, e.g. the no-op scanner
+	Scanned   bool
+	Infected  bool
+	Signature string
+}
+
+// Scanner scans the contents of a file.
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) (Result, error)
+}
+
+// Noop accepts every file without scanning it.
+type Noop struct{}
+
+func (Noop) Scan(ctx context.Context, r io.Reader) (Result, error) {
+	return Result{}, nil
+}
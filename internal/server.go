@@ -2,7 +2,13 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
@@ -15,7 +21,9 @@ import (
 func Server() error {
 	_ = godotenv.Load()
 
-	ctx := context.Background()
+	// cancels background work, like team jobs, and stops the server
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	appCfg := config.New()
 
@@ -42,10 +50,20 @@ func Server() error {
 
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
 
-	RegisterRoutes(e, appCfg, pool)
+	RegisterRoutes(ctx, e, appCfg, pool)
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := e.Shutdown(shutdownCtx); err != nil {
+			e.Logger.Error(err)
+		}
+	}()
 
 	err = e.Start(fmt.Sprintf("localhost:%d", appCfg.Port))
-	if err != nil {
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		return err
 	}
 
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image/jpeg"
+	"net/url"
+	"os"
+	"path"
+
+	"github.com/skndash96/lastnight-backend/internal/db"
+	"github.com/skndash96/lastnight-backend/internal/filetype"
+	"github.com/skndash96/lastnight-backend/internal/pdf"
+	"github.com/skndash96/lastnight-backend/internal/repository"
+)
+
+const (
+	DerivativeThumbnail = "thumbnail"
+	DerivativePreview   = "preview"
+)
+
+type UploadDerivative struct {
+	db.UploadDerivative
+	Url *url.URL
+}
+
+// ListUploadDerivatives returns the rendered previews of an upload with presigned GET URLs.
+func (s *UploadService) ListUploadDerivatives(ctx context.Context, teamID, uploadID int32) ([]UploadDerivative, error) {
+	uploadRepo := repository.NewUploadRepository(s.pool)
+
+	derivatives, err := uploadRepo.ListUploadDerivatives(ctx, teamID, uploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]UploadDerivative, len(derivatives))
+	for i, d := range derivatives {
+		url, err := s.uploadProvider.PresignGetObject(ctx, d.StorageKey)
+		if err != nil {
+			return nil, NewSrvError(err, SrvErrInternal, fmt.Sprintf("failed to presign %s", d.StorageKey))
+		}
+
+		out[i] = UploadDerivative{
+			UploadDerivative: d,
+			Url:              url,
+		}
+	}
+
+	return out, nil
+}
+
+// RegenerateDerivatives renders the previews of an upload again and extracts its
+// text, e.g. for uploads finalized before either existed or while the tools were
+// unavailable.
+func (s *UploadService) RegenerateDerivatives(ctx context.Context, teamID, uploadID int32) ([]UploadDerivative, error) {
+	uploadRepo := repository.NewUploadRepository(s.pool)
+
+	upload, err := uploadRepo.GetUploadByRefID(ctx, teamID, uploadID)
+	if err != nil {
+		return nil, NewSrvError(err, SrvErrNotFound, "upload not found")
+	}
+
+	if upload.FileMimeType != filetype.PDF {
+		return nil, NewSrvError(nil, SrvErrInvalidInput, "previews can only be generated for PDFs")
+	}
+
+	release, err := s.acquireProcessing(ctx)
+	if err != nil {
+		return nil, NewSrvError(err, SrvErrInternal, "failed to generate previews")
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.ProcessingTimeout)
+	defer cancel()
+
+	input, err := s.downloadObject(ctx, upload.StorageKey, upload.FileSize)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(input)
+
+	if err := s.generateDerivatives(ctx, upload.ID, upload.StorageKey, input); err != nil {
+		if errors.Is(err, pdf.ErrRendererUnavailable) {
+			return nil, NewSrvError(err, SrvErrInternal, "preview rendering is not available on this server")
+		}
+		return nil, err
+	}
+
+	// uploads processed before text extraction existed, or when it failed, get their text too
+	if err := s.extractText(ctx, upload.ID, input); err != nil {
+		if errors.Is(err, pdf.ErrExtractorUnavailable) {
+			return nil, NewSrvError(err, SrvErrInternal, "text extraction is not available on this server")
+		}
+		return nil, err
+	}
+
+	return s.ListUploadDerivatives(ctx, teamID, uploadID)
+}
+
+// generateDerivatives renders the first page of a PDF as a thumbnail, and
+// optionally every page as a low resolution preview.
+func (s *UploadService) generateDerivatives(ctx context.Context, uploadID int32, storageKey, input string) error {
+	thumbnails, err := s.renderer.RenderPages(ctx, input, 1, 1, s.cfg.ThumbnailWidth)
+	if err != nil {
+		return err
+	}
+
+	if err := s.storeDerivatives(ctx, uploadID, storageKey, DerivativeThumbnail, thumbnails); err != nil {
+		return err
+	}
+
+	if !s.cfg.PreviewAllPages {
+		return nil
+	}
+
+	previews, err := s.renderer.RenderPages(ctx, input, 1, s.cfg.MaxPreviewPages, s.cfg.PreviewWidth)
+	if err != nil {
+		return err
+	}
+
+	return s.storeDerivatives(ctx, uploadID, storageKey, DerivativePreview, previews)
+}
+
+func (s *UploadService) storeDerivatives(ctx context.Context, uploadID int32, storageKey, kind string, pages [][]byte) error {
+	uploadRepo := repository.NewUploadRepository(s.pool)
+
+	for i, data := range pages {
+		page := int32(i + 1)
+
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("rendered %s of page %d is not a JPEG: %w", kind, page, err)
+		}
+
+		key := derivativeKey(storageKey, kind, page)
+		if err := s.uploadProvider.PutObject(ctx, key, bytes.NewReader(data), int64(len(data)), "image/jpeg"); err != nil {
+			return NewSrvError(err, SrvErrInternal, fmt.Sprintf("failed to store %s", key))
+		}
+
+		_, err = uploadRepo.UpsertUploadDerivative(ctx, db.UpsertUploadDerivativeParams{
+			UploadID:   uploadID,
+			Kind:       kind,
+			Page:       page,
+			StorageKey: key,
+			MimeType:   "image/jpeg",
+			Width:      int32(cfg.Width),
+			Height:     int32(cfg.Height),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// derivativeKey places derivatives under a prefix next to the upload,
+// e.g. team_1/uploads/derivatives/<name>.pdf/thumbnail_1.jpg
+func derivativeKey(storageKey, kind string, page int32) string {
+	return fmt.Sprintf("%s/derivatives/%s/%s_%d.jpg", path.Dir(storageKey), path.Base(storageKey), kind, page)
+}
@@ -15,6 +15,7 @@ const (
 	SrvErrForbidden    SrvErrKind = "forbidden"     // auth ok, but access denied
 	SrvErrNotFound     SrvErrKind = "not_found"     // lookup failed
 	SrvErrConflict     SrvErrKind = "conflict"      // duplicate resource / violation (email exists)
+	SrvErrRateLimited  SrvErrKind = "rate_limited"  // too many requests, retry later
 
 	// Non-client, unexpected runtime/system errors
 	SrvErrInternal SrvErrKind = "internal_error" // unexpected application failure
@@ -0,0 +1,287 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/skndash96/lastnight-backend/internal/config"
+	"github.com/skndash96/lastnight-backend/internal/db"
+	"github.com/skndash96/lastnight-backend/internal/provider"
+	"github.com/skndash96/lastnight-backend/internal/repository"
+)
+
+// GCService deletes uploads, and their storage objects, once no upload refs
+// point at them anymore. It also aborts expired multipart uploads, removes
+// presigned uploads that were never completed and deletes old team export
+// archives.
+type GCService struct {
+	cfg            config.GCConfig
+	pool           *pgxpool.Pool
+	uploadProvider provider.UploadProvider
+}
+
+func NewGCService(cfg config.GCConfig, uploadProvider provider.UploadProvider, pool *pgxpool.Pool) *GCService {
+	return &GCService{
+		cfg:            cfg,
+		pool:           pool,
+		uploadProvider: uploadProvider,
+	}
+}
+
+type GCResult struct {
+	// uploads deleted, or that would be deleted on a dry run
+	Uploads []db.Upload
+	Bytes   int64
+	// uploads skipped because they were locked, got a new ref or failed to delete
+	Skipped int
+	// expired multipart uploads aborted, or that would be aborted on a dry run
+	MultipartUploads []db.MultipartUpload
+	// expired presigned uploads deleted, or that would be deleted on a dry run
+	Presigns []db.UploadPresign
+	// team jobs whose export archive was deleted, or would be on a dry run
+	JobArchives []db.TeamJob
+}
+
+// Run collects orphaned uploads every cfg.Interval until ctx is done.
+func (s *GCService) Run(ctx context.Context) {
+	if s.cfg.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			result, err := s.Collect(ctx, s.cfg.GracePeriod, false)
+			if err != nil {
+				fmt.Printf("upload gc failed: %v\n", err)
+				continue
+			}
+			if len(result.Uploads) > 0 || result.Skipped > 0 {
+				fmt.Printf("upload gc deleted %d uploads (%d bytes), skipped %d\n", len(result.Uploads), result.Bytes, result.Skipped)
+			}
+			if len(result.MultipartUploads) > 0 {
+				fmt.Printf("upload gc aborted %d expired multipart uploads\n", len(result.MultipartUploads))
+			}
+			if len(result.Presigns) > 0 {
+				fmt.Printf("upload gc deleted %d expired presigned uploads\n", len(result.Presigns))
+			}
+			if len(result.JobArchives) > 0 {
+				fmt.Printf("upload gc deleted %d team export archives\n", len(result.JobArchives))
+			}
+		}
+	}
+}
+
+// Collect deletes up to cfg.BatchSize uploads that have had no refs for longer
+// than grace, aborts expired multipart uploads, deletes presigned uploads
+// expired for longer than grace and deletes team export archives older than
+// cfg.ArchiveRetention. With dryRun, they are only listed.
+func (s *GCService) Collect(ctx context.Context, grace time.Duration, dryRun bool) (*GCResult, error) {
+	cutoff := time.Now().Add(-grace)
+
+	uploadRepo := repository.NewUploadRepository(s.pool)
+
+	candidates, err := uploadRepo.ListOrphanedUploads(ctx, cutoff, s.cfg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &GCResult{
+		Uploads:          []db.Upload{},
+		MultipartUploads: []db.MultipartUpload{},
+		Presigns:         []db.UploadPresign{},
+		JobArchives:      []db.TeamJob{},
+	}
+
+	for _, upload := range candidates {
+		if dryRun {
+			result.Uploads = append(result.Uploads, upload)
+			result.Bytes += upload.FileSize
+			continue
+		}
+
+		deleted, err := s.collectUpload(ctx, upload.ID, cutoff)
+		if err != nil {
+			fmt.Printf("upload gc failed to delete upload %d: %v\n", upload.ID, err)
+		}
+		if !deleted {
+			result.Skipped++
+			continue
+		}
+
+		result.Uploads = append(result.Uploads, upload)
+		result.Bytes += upload.FileSize
+	}
+
+	expired, err := uploadRepo.ListExpiredMultipartUploads(ctx, s.cfg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, upload := range expired {
+		if dryRun {
+			result.MultipartUploads = append(result.MultipartUploads, upload)
+			continue
+		}
+
+		aborted, err := s.collectMultipartUpload(ctx, upload.ID)
+		if err != nil {
+			fmt.Printf("upload gc failed to abort multipart upload %d: %v\n", upload.ID, err)
+		}
+		if aborted {
+			result.MultipartUploads = append(result.MultipartUploads, upload)
+		}
+	}
+
+	presigns, err := uploadRepo.ListExpiredUploadPresigns(ctx, cutoff, s.cfg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, presign := range presigns {
+		if dryRun {
+			result.Presigns = append(result.Presigns, presign)
+			continue
+		}
+
+		deleted, err := s.collectUploadPresign(ctx, presign.ID, cutoff)
+		if err != nil {
+			fmt.Printf("upload gc failed to delete presigned upload %d: %v\n", presign.ID, err)
+		}
+		if deleted {
+			result.Presigns = append(result.Presigns, presign)
+		}
+	}
+
+	teamRepo := repository.NewTeamRepository(s.pool)
+
+	jobs, err := teamRepo.ListExpiredTeamJobArchives(ctx, time.Now().Add(-s.cfg.ArchiveRetention), s.cfg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, job := range jobs {
+		if !dryRun {
+			if err := s.uploadProvider.DeleteObject(ctx, job.StorageKey); err != nil {
+				fmt.Printf("upload gc failed to delete the archive of team job %d: %v\n", job.ID, err)
+				continue
+			}
+			if err := teamRepo.ClearTeamJobArchive(ctx, job.ID); err != nil {
+				fmt.Printf("upload gc failed to clear the archive of team job %d: %v\n", job.ID, err)
+				continue
+			}
+		}
+		result.JobArchives = append(result.JobArchives, job)
+	}
+
+	return result, nil
+}
+
+// collectUploadPresign deletes an expired presign and whatever was uploaded to
+// its key, unless it is being completed concurrently.
+func (s *GCService) collectUploadPresign(ctx context.Context, id int32, cutoff time.Time) (bool, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	presign, err := repository.NewUploadRepository(tx).DeleteExpiredUploadPresign(ctx, id, cutoff)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err := s.uploadProvider.DeleteObject(ctx, presign.StorageKey); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// collectMultipartUpload aborts an expired multipart upload, unless it is being
+// completed concurrently.
+func (s *GCService) collectMultipartUpload(ctx context.Context, id int32) (bool, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	upload, err := repository.NewUploadRepository(tx).LockExpiredMultipartUpload(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err := abortMultipartUpload(ctx, s.uploadProvider, tx, upload); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// collectUpload deletes the row of an orphaned upload, then its objects. The row
+// stays locked until it is deleted, so a concurrent upload of the same file
+// either gets the lock first and keeps the upload, or waits and creates a new
+// one. Objects are only deleted once no row points at them anymore, one left
+// behind by a failure is harmless.
+func (s *GCService) collectUpload(ctx context.Context, uploadID int32, cutoff time.Time) (bool, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	uploadRepo := repository.NewUploadRepository(tx)
+
+	upload, err := uploadRepo.LockOrphanedUpload(ctx, uploadID, cutoff)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	derivativeKeys, err := uploadRepo.ListUploadDerivativeKeys(ctx, upload.ID)
+	if err != nil {
+		return false, err
+	}
+
+	if err := uploadRepo.DeleteUpload(ctx, upload.ID); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+
+	for _, key := range append(derivativeKeys, upload.StorageKey) {
+		if err := s.uploadProvider.DeleteObject(ctx, key); err != nil {
+			fmt.Printf("upload gc failed to delete %s of upload %d: %v\n", key, upload.ID, err)
+		}
+	}
+
+	return true, nil
+}
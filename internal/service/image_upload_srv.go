@@ -0,0 +1,262 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/skndash96/lastnight-backend/internal/db"
+	"github.com/skndash96/lastnight-backend/internal/filetype"
+	"github.com/skndash96/lastnight-backend/internal/pdf"
+	"github.com/skndash96/lastnight-backend/internal/provider"
+)
+
+var imageExts = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+type ImagePartInput struct {
+	MimeType string
+	Size     int64
+}
+
+type PresignImageUploadResult struct {
+	SetID string
+	Parts []PresignImagePart
+}
+
+type PresignImagePart struct {
+	Key string
+	PresignUploadResult
+}
+
+// PresignImageUpload presigns one upload per image of an image set. The parts
+// are combined into a single PDF by CompleteImageUpload.
+func (s *UploadService) PresignImageUpload(ctx context.Context, teamID, userID int32, images []ImagePartInput) (*PresignImageUploadResult, error) {
+	if len(images) == 0 {
+		return nil, NewSrvError(nil, SrvErrInvalidInput, "Upload presign failed: at least one image is required.")
+	}
+
+	if len(images) > s.cfg.MaxImages {
+		return nil, NewSrvError(nil, SrvErrInvalidInput, fmt.Sprintf("Upload presign failed: at most %d images are allowed.", s.cfg.MaxImages))
+	}
+
+	setID := uuid.NewString()
+	parts := make([]PresignImagePart, len(images))
+
+	for i, img := range images {
+		ext, ok := imageExts[img.MimeType]
+		if !ok {
+			return nil, NewSrvError(nil, SrvErrInvalidInput, "Upload presign failed: Only image/jpeg, image/png and image/gif are allowed.")
+		}
+
+		if img.Size > s.cfg.MaxImageSize {
+			return nil, NewSrvError(provider.ErrFileTooLarge, SrvErrInvalidInput, fmt.Sprintf("Upload presign failed: image %d is too large.", i))
+		}
+
+		key := fmt.Sprintf("%s%d%s", imageSetPrefix(teamID, setID), i, ext)
+		presigned, err := s.uploadProvider.PresignUpload(ctx, key, path.Base(key), img.MimeType, img.Size, s.cfg.MaxImageSize)
+		if err != nil {
+			return nil, NewSrvError(err, SrvErrInternal, fmt.Sprintf("failed to presign upload for image %d", i))
+		}
+
+		if err := s.recordUploadPresign(ctx, teamID, userID, key, path.Base(key), img.MimeType, img.Size, presigned); err != nil {
+			return nil, err
+		}
+
+		parts[i] = PresignImagePart{
+			Key: key,
+			PresignUploadResult: PresignUploadResult{
+				Url:    presigned.Url,
+				Fields: presigned.Fields,
+			},
+		}
+	}
+
+	return &PresignImageUploadResult{
+		SetID: setID,
+		Parts: parts,
+	}, nil
+}
+
+// CompleteImageUpload assembles the uploaded images of a set, in the order of keys,
+// into a PDF which is then stored like any other upload.
+func (s *UploadService) CompleteImageUpload(ctx context.Context, teamID, userID int32, setID string, keys []string, name string, versionOf int32, tags []UploadTagInput) error {
+	if len(keys) == 0 {
+		return NewSrvError(nil, SrvErrInvalidInput, "Upload completion failed: at least one image is required")
+	}
+
+	if len(keys) > s.cfg.MaxImages {
+		return NewSrvError(nil, SrvErrInvalidInput, fmt.Sprintf("Upload completion failed: at most %d images are allowed", s.cfg.MaxImages))
+	}
+
+	if uuid.Validate(setID) != nil {
+		return NewSrvError(nil, SrvErrInvalidInput, "Upload completion failed: invalid image set")
+	}
+
+	prefix := imageSetPrefix(teamID, setID)
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) || strings.Contains(strings.TrimPrefix(key, prefix), "/") || seen[key] {
+			return NewSrvError(nil, SrvErrInvalidInput, "Upload completion failed: invalid key")
+		}
+		seen[key] = true
+	}
+
+	settings, err := s.GetUploadSettings(ctx, teamID)
+	if err != nil {
+		return err
+	}
+
+	if err := settings.checkMimeType(filetype.PDF); err != nil {
+		return err
+	}
+
+	presigns := make([]*db.UploadPresign, len(keys))
+	for i, key := range keys {
+		presigns[i], err = getUploadPresign(ctx, s.pool, teamID, userID, key)
+		if err != nil {
+			return err
+		}
+	}
+
+	name = strings.TrimSuffix(name, path.Ext(name)) + ".pdf"
+	tmpKey := generateTmpObjectKey(teamID, name)
+
+	info, err := s.combineImages(ctx, presigns, tmpKey)
+	if err != nil {
+		return err
+	}
+
+	// the presigns are consumed once the PDF is finalized, no transaction is
+	// held open while it is assembled
+	err = s.finalizeUpload(ctx, settings, teamID, userID, tmpKey, name, filetype.PDF, info, versionOf, tags, func(tx db.DBTX) error {
+		for _, key := range keys {
+			if _, err := consumeUploadPresign(ctx, tx, teamID, userID, key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	// the presigns are kept for a retry, the garbage collector removes the
+	// images once they expire
+	if err != nil {
+		// the PDF is only this request's, e.g. when the images were completed concurrently
+		if isClientError(err) {
+			s.deleteInvalidUpload(ctx, tmpKey)
+		}
+		return err
+	}
+
+	for _, key := range keys {
+		if s.cfg.KeepImageOriginals {
+			err = s.uploadProvider.MoveObject(ctx, "originals/"+convertTmpKey(key), key)
+		} else {
+			err = s.uploadProvider.DeleteObject(ctx, key)
+		}
+		if err != nil {
+			// it's not a fatal error, so we can continue
+			fmt.Printf("failed to clean up image %s: %v\n", key, err)
+		}
+	}
+
+	return nil
+}
+
+// combineImages assembles the images of presigns into a PDF stored at key. The
+// images are read and added one at a time and the PDF is written to a temporary
+// file, so a set of images is never held in memory at once.
+func (s *UploadService) combineImages(ctx context.Context, presigns []*db.UploadPresign, key string) (*provider.UploadInfo, error) {
+	f, err := os.CreateTemp("", "images-*.pdf")
+	if err != nil {
+		return nil, NewSrvError(err, SrvErrInternal, "failed to combine images")
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	hash := sha256.New()
+	pw := pdf.NewWriter(io.MultiWriter(f, hash), pdf.Options{
+		MaxDimension: s.cfg.ImageMaxDimension,
+	})
+
+	for i, presign := range presigns {
+		data, err := s.readObject(ctx, presign.StorageKey, s.cfg.MaxImageSize)
+		if err != nil {
+			return nil, err
+		}
+
+		if int64(len(data)) != presign.FileSize {
+			return nil, NewSrvError(nil, SrvErrInvalidInput, fmt.Sprintf("Upload completion failed: image %d has %d bytes, expected %d", i, len(data), presign.FileSize))
+		}
+
+		if err := pw.AddImage(data); err != nil {
+			return nil, combineImagesError(err)
+		}
+	}
+
+	if err := pw.Close(); err != nil {
+		return nil, combineImagesError(err)
+	}
+
+	size, err := f.Seek(0, io.SeekCurrent)
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		return nil, NewSrvError(err, SrvErrInternal, "failed to combine images")
+	}
+
+	if err := s.uploadProvider.PutObject(ctx, key, f, size, filetype.PDF); err != nil {
+		return nil, NewSrvError(err, SrvErrInternal, fmt.Sprintf("failed to store combined PDF %s", key))
+	}
+
+	return &provider.UploadInfo{
+		SHA256: provider.EncodeChecksum(hash.Sum(nil)),
+		Size:   size,
+	}, nil
+}
+
+// combineImagesError blames images the PDF writer could not add on the client.
+func combineImagesError(err error) error {
+	if errors.Is(err, pdf.ErrImageTooLarge) {
+		return NewSrvError(err, SrvErrInvalidInput, fmt.Sprintf("Upload completion failed: %v", err))
+	}
+	// writing the temporary file failed
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return NewSrvError(err, SrvErrInternal, "failed to combine images")
+	}
+	return NewSrvError(err, SrvErrInvalidInput, "Upload completion failed: could not combine images")
+}
+
+func (s *UploadService) readObject(ctx context.Context, key string, maxSize int64) ([]byte, error) {
+	r, err := s.uploadProvider.GetObject(ctx, key)
+	if err != nil {
+		return nil, NewSrvError(err, SrvErrInternal, fmt.Sprintf("failed to read %s", key))
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, NewSrvError(err, SrvErrInternal, fmt.Sprintf("failed to read %s", key))
+	}
+
+	if int64(len(data)) > maxSize {
+		return nil, NewSrvError(provider.ErrFileTooLarge, SrvErrInvalidInput, fmt.Sprintf("%s is too large", key))
+	}
+
+	return data, nil
+}
+
+func imageSetPrefix(teamID int32, setID string) string {
+	return fmt.Sprintf("tmp/team_%d/images/%s/", teamID, setID)
+}
@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/skndash96/lastnight-backend/internal/db"
+	"github.com/skndash96/lastnight-backend/internal/repository"
+)
+
+// ImportService adds files from disk to a team in bulk, going through the same
+// checks and deduplication as uploads from clients.
+type ImportService struct {
+	pool      *pgxpool.Pool
+	uploadSrv *UploadService
+}
+
+func NewImportService(uploadSrv *UploadService, pool *pgxpool.Pool) *ImportService {
+	return &ImportService{
+		pool:      pool,
+		uploadSrv: uploadSrv,
+	}
+}
+
+// ImportEntry is a file to import, with tags given by key and value names.
+type ImportEntry struct {
+	// path on disk, and the name to import it under
+	Path string
+	Name string
+	Tags []ImportTag
+}
+
+type ImportTag struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type ImportOptions struct {
+	// create values missing on closed keys, open keys accept new values anyway
+	CreateValues bool
+	DryRun       bool
+}
+
+type ImportStatus string
+
+const (
+	// the file was uploaded, or on a dry run would be
+	ImportStatusImported ImportStatus = "imported"
+	// the team had the file as a version of another upload, it was only added
+	ImportStatusLinked ImportStatus = "linked"
+	// the team has the file already, nothing was done
+	ImportStatusInTeam ImportStatus = "in_team"
+	ImportStatusFailed ImportStatus = "failed"
+)
+
+type ImportResult struct {
+	Path   string       `json:"path"`
+	Name   string       `json:"name"`
+	Status ImportStatus `json:"status"`
+	// upload ref in the team, unset on failures and dry runs
+	UploadID int32  `json:"upload_id,omitempty"`
+	Size     int64  `json:"size"`
+	Error    string `json:"error,omitempty"`
+	// "key=value" of tag values created for this file
+	CreatedValues []string `json:"created_values,omitempty"`
+}
+
+// Import imports entries one by one as userID, calling onResult after each.
+// Failed entries do not stop the import.
+func (s *ImportService) Import(ctx context.Context, teamID, userID int32, entries []ImportEntry, opts ImportOptions, onResult func(ImportResult)) error {
+	teamRepo := repository.NewTeamRepository(s.pool)
+
+	if _, err := teamRepo.GetTeamMembershipByUserID(ctx, userID, teamID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return NewSrvError(err, SrvErrInvalidInput, fmt.Sprintf("user %d is not a member of team %d", userID, teamID))
+		}
+		return err
+	}
+
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		result := s.importFile(ctx, teamID, userID, entry, opts)
+		onResult(result)
+	}
+
+	return nil
+}
+
+func (s *ImportService) importFile(ctx context.Context, teamID, userID int32, entry ImportEntry, opts ImportOptions) ImportResult {
+	result := ImportResult{
+		Path: entry.Path,
+		Name: entry.Name,
+	}
+
+	fail := func(err error) ImportResult {
+		result.Status = ImportStatusFailed
+		result.Error = err.Error()
+		return result
+	}
+
+	f, err := os.Open(entry.Path)
+	if err != nil {
+		return fail(err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return fail(err)
+	}
+	result.Size = size
+
+	checksum := hex.EncodeToString(h.Sum(nil))
+
+	check, err := s.uploadSrv.CheckUpload(ctx, teamID, userID, checksum, size, "", 0, nil)
+	if err != nil {
+		return fail(err)
+	}
+	if check.InTeam {
+		result.Status = ImportStatusInTeam
+		result.UploadID = check.Ref.ID
+		return result
+	}
+
+	tags, created, err := s.resolveImportTags(ctx, teamID, entry.Tags, opts)
+	if err != nil {
+		return fail(err)
+	}
+	result.CreatedValues = created
+
+	if opts.DryRun {
+		result.Status = ImportStatusImported
+		if check.Exists {
+			result.Status = ImportStatusLinked
+		}
+		return result
+	}
+
+	status, ref, err := s.uploadSrv.addFile(ctx, teamID, userID, checksum, size, entry.Name, tags, func() (io.ReadCloser, error) {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		return io.NopCloser(f), nil
+	})
+	if err != nil {
+		return fail(err)
+	}
+
+	result.Status = status
+	result.UploadID = ref.ID
+	return result
+}
+
+// addFile adds a file with the given hex SHA-256 and size to the team, the way
+// client uploads are: nothing is done when the team has it, it is linked when it
+// is stored already, and only uploaded from open otherwise. open may be nil when
+// the contents are not available. The checksum must have been computed from the
+// contents, an upload that does not match it is refused.
+func (s *UploadService) addFile(ctx context.Context, teamID, userID int32, checksum string, size int64, name string, tags []UploadTagInput, open func() (io.ReadCloser, error)) (ImportStatus, *db.UploadRef, error) {
+	check, err := s.CheckUpload(ctx, teamID, userID, checksum, size, name, 0, tags)
+	if err != nil {
+		return "", nil, err
+	}
+	if check.InTeam {
+		return ImportStatusInTeam, check.Ref, nil
+	}
+	// a stored object that turned out unusable is uploaded again below
+	if check.Ref != nil {
+		return ImportStatusLinked, check.Ref, nil
+	}
+
+	if open == nil {
+		return "", nil, NewSrvError(nil, SrvErrNotFound, "the file is not stored and its contents were not provided")
+	}
+
+	r, err := open()
+	if err != nil {
+		return "", nil, err
+	}
+	defer r.Close()
+
+	if err := s.upload(ctx, teamID, userID, name, r, 0, tags, checksum); err != nil {
+		return "", nil, err
+	}
+
+	// the upload is deduplicated by content, look up the ref it ended up in
+	check, err = s.CheckUpload(ctx, teamID, userID, checksum, size, "", 0, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	if check.Ref == nil {
+		return "", nil, NewSrvError(nil, SrvErrInternal, fmt.Sprintf("uploaded %s but found no upload for it", name))
+	}
+
+	return ImportStatusImported, check.Ref, nil
+}
+
+// resolveImportTags maps tag names to the inputs of an upload. Values missing on
+// open keys are left for the upload to create, values missing on closed keys
+// are created up front when opts allow it. On a dry run nothing is created, the
+// values that would be are still reported.
+func (s *ImportService) resolveImportTags(ctx context.Context, teamID int32, tags []ImportTag, opts ImportOptions) ([]UploadTagInput, []string, error) {
+	tagRepo := repository.NewTagRepo(s.pool)
+
+	out := make([]UploadTagInput, 0, len(tags))
+	created := []string{}
+
+	for _, tag := range tags {
+		tagKey, err := tagRepo.GetTagKeyByName(ctx, teamID, strings.TrimSpace(tag.Key))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil, NewSrvError(err, SrvErrInvalidInput, fmt.Sprintf("tag key %s not found", tag.Key))
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if tagKey.ArchivedAt != nil {
+			return nil, nil, NewSrvError(nil, SrvErrInvalidInput, fmt.Sprintf("tag key %s is archived", tagKey.Name))
+		}
+
+		value, numValue, err := canonicalizeTagValue(tagKey.DataType, tag.Value)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		tagValue, err := tagRepo.GetTagValueByValue(ctx, tagKey.ID, value)
+		if err == nil {
+			switch {
+			case tagValue.Approved:
+				out = append(out, UploadTagInput{KeyID: tagKey.ID, ValueID: tagValue.ID})
+			case tagKey.Mode == db.TagKeyModeOpen:
+				// attached as typed, the way uploads add to a pending value
+				out = append(out, UploadTagInput{KeyID: tagKey.ID, Value: value})
+			default:
+				return nil, nil, NewSrvError(nil, SrvErrInvalidInput, fmt.Sprintf("tag value %s of %s is pending approval", value, tagKey.Name))
+			}
+			continue
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil, err
+		}
+
+		if tagKey.Mode != db.TagKeyModeOpen && !opts.CreateValues {
+			return nil, nil, NewSrvError(nil, SrvErrInvalidInput, fmt.Sprintf("tag key %s has no value %s, allow creating values to add it", tagKey.Name, value))
+		}
+
+		created = append(created, fmt.Sprintf("%s=%s", tagKey.Name, value))
+
+		switch {
+		case opts.DryRun:
+			out = append(out, UploadTagInput{KeyID: tagKey.ID, Value: value})
+		case tagKey.Mode == db.TagKeyModeOpen:
+			// created along with the upload, pending approval if the key requires it
+			out = append(out, UploadTagInput{KeyID: tagKey.ID, Value: value})
+		default:
+			tagValue, err := tagRepo.GetOrCreateTagValue(ctx, tagKey.ID, value, numValue, true)
+			if err != nil {
+				return nil, nil, err
+			}
+			out = append(out, UploadTagInput{KeyID: tagKey.ID, ValueID: tagValue.ID})
+		}
+	}
+
+	return out, created, nil
+}
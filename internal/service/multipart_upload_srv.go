@@ -0,0 +1,328 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/skndash96/lastnight-backend/internal/db"
+	"github.com/skndash96/lastnight-backend/internal/provider"
+	"github.com/skndash96/lastnight-backend/internal/repository"
+)
+
+type MultipartUploadStatus struct {
+	db.MultipartUpload
+	// parts uploaded so far, the client resumes with the missing ones
+	Parts []provider.UploadPart
+}
+
+type PresignedUploadPart struct {
+	PartNumber int32
+	Url        *url.URL
+}
+
+// CreateMultipartUpload starts a resumable upload of a large file. The client
+// uploads PartCount parts of PartSize bytes to presigned URLs, then completes it.
+func (s *UploadService) CreateMultipartUpload(ctx context.Context, teamID, userID int32, name, mimeType string, size int64) (*db.MultipartUpload, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, NewSrvError(nil, SrvErrInvalidInput, "Multipart upload failed: file name is required")
+	}
+
+	if size <= 0 {
+		return nil, NewSrvError(nil, SrvErrInvalidInput, "Multipart upload failed: size must be positive")
+	}
+
+	settings, err := s.checkUploadPolicy(ctx, teamID, name, mimeType, size)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkQuota(ctx, settings, teamID, userID, 0, size); err != nil {
+		return nil, err
+	}
+
+	partSize := max(s.cfg.MultipartPartSize, provider.MinUploadPartSize, ceilDiv(size, provider.MaxUploadParts))
+	partCount := ceilDiv(size, partSize)
+
+	key := generateTmpObjectKey(teamID, name)
+	s3UploadID, err := s.uploadProvider.CreateMultipartUpload(ctx, key, mimeType, size)
+	if errors.Is(err, provider.ErrFileTooLarge) {
+		return nil, NewSrvError(err, SrvErrInvalidInput, "Multipart upload failed: file is too large")
+	}
+	if err != nil {
+		return nil, NewSrvError(err, SrvErrInternal, fmt.Sprintf("failed to start multipart upload for file %s", name))
+	}
+
+	uploadRepo := repository.NewUploadRepository(s.pool)
+
+	upload, err := uploadRepo.CreateMultipartUpload(ctx, db.CreateMultipartUploadParams{
+		TeamID:       teamID,
+		UploaderID:   userID,
+		StorageKey:   key,
+		S3UploadID:   s3UploadID,
+		FileName:     name,
+		FileMimeType: mimeType,
+		FileSize:     size,
+		PartSize:     partSize,
+		PartCount:    int32(partCount),
+		ExpiresAt:    time.Now().Add(s.cfg.MultipartExpiration),
+	})
+	if err != nil {
+		if err := s.uploadProvider.AbortMultipartUpload(ctx, key, s3UploadID); err != nil {
+			fmt.Printf("failed to abort multipart upload %s: %v\n", key, err)
+		}
+		return nil, err
+	}
+
+	return upload, nil
+}
+
+// ListMultipartUploads returns the unfinished multipart uploads of a user.
+func (s *UploadService) ListMultipartUploads(ctx context.Context, teamID, userID int32) ([]db.MultipartUpload, error) {
+	uploadRepo := repository.NewUploadRepository(s.pool)
+
+	return uploadRepo.ListMultipartUploads(ctx, teamID, userID)
+}
+
+// GetMultipartUpload returns a multipart upload with the parts uploaded so far.
+func (s *UploadService) GetMultipartUpload(ctx context.Context, teamID, userID, id int32) (*MultipartUploadStatus, error) {
+	upload, err := getMultipartUpload(ctx, s.pool, teamID, userID, id, false)
+	if err != nil {
+		return nil, err
+	}
+
+	parts, err := s.uploadProvider.ListUploadParts(ctx, upload.StorageKey, upload.S3UploadID)
+	if err != nil {
+		return nil, NewSrvError(err, SrvErrInternal, fmt.Sprintf("failed to list parts of %s", upload.StorageKey))
+	}
+
+	return &MultipartUploadStatus{
+		MultipartUpload: *upload,
+		Parts:           parts,
+	}, nil
+}
+
+// PresignUploadParts creates upload URLs for the given part numbers.
+func (s *UploadService) PresignUploadParts(ctx context.Context, teamID, userID, id int32, partNumbers []int32) ([]PresignedUploadPart, error) {
+	upload, err := getMultipartUpload(ctx, s.pool, teamID, userID, id, false)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]PresignedUploadPart, len(partNumbers))
+	for i, n := range partNumbers {
+		if n < 1 || n > upload.PartCount {
+			return nil, NewSrvError(nil, SrvErrInvalidInput, fmt.Sprintf("part number %d is out of range 1-%d", n, upload.PartCount))
+		}
+
+		url, err := s.uploadProvider.PresignUploadPart(ctx, upload.StorageKey, upload.S3UploadID, int(n), int(upload.PartCount), uploadPartSize(upload, n))
+		if err != nil {
+			return nil, NewSrvError(err, SrvErrInternal, fmt.Sprintf("failed to presign part %d of %s", n, upload.StorageKey))
+		}
+
+		out[i] = PresignedUploadPart{
+			PartNumber: n,
+			Url:        url,
+		}
+	}
+
+	return out, nil
+}
+
+// CompleteMultipartUpload assembles the uploaded parts once all of them are
+// present, then processes the file like CompleteUpload. The multipart upload is
+// kept until the file is finalized, so a failed completion can be retried and
+// an abandoned file is collected when it expires.
+func (s *UploadService) CompleteMultipartUpload(ctx context.Context, teamID, userID, id, versionOf int32, tags []UploadTagInput) error {
+	settings, err := s.GetUploadSettings(ctx, teamID)
+	if err != nil {
+		return err
+	}
+
+	upload, err := getMultipartUpload(ctx, s.pool, teamID, userID, id, false)
+	if err != nil {
+		return err
+	}
+
+	// a retry finds the parts assembled already
+	_, err = s.uploadProvider.StatObject(ctx, upload.StorageKey)
+	if errors.Is(err, provider.ErrObjectNotFound) {
+		if err := s.assembleMultipartUpload(ctx, upload); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return NewSrvError(err, SrvErrInternal, fmt.Sprintf("failed to check %s", upload.StorageKey))
+	}
+
+	// the multipart upload is deleted once the file is finalized, no transaction
+	// is held open while it is validated
+	err = s.completeUpload(ctx, settings, teamID, userID, upload.StorageKey, upload.FileName, upload.FileMimeType, upload.FileSize, versionOf, tags, func(tx db.DBTX) error {
+		if _, err := getMultipartUpload(ctx, tx, teamID, userID, id, true); err != nil {
+			return err
+		}
+		return repository.NewUploadRepository(tx).DeleteMultipartUpload(ctx, id)
+	})
+	// the multipart upload is kept for a retry, unless the upload itself was refused
+	if isClientError(err) {
+		s.discardMultipartUpload(ctx, teamID, userID, id)
+	}
+
+	return err
+}
+
+// discardMultipartUpload aborts a multipart upload whose file was refused,
+// unless a concurrent completion has finalized it.
+func (s *UploadService) discardMultipartUpload(ctx context.Context, teamID, userID, id int32) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		fmt.Printf("failed to discard multipart upload %d: %v\n", id, err)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	upload, err := getMultipartUpload(ctx, tx, teamID, userID, id, true)
+	if err != nil {
+		// finalized concurrently, or expired and left to the garbage collector
+		return
+	}
+
+	if err := abortMultipartUpload(ctx, s.uploadProvider, tx, upload); err != nil {
+		fmt.Printf("failed to discard multipart upload %d: %v\n", id, err)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		fmt.Printf("failed to discard multipart upload %d: %v\n", id, err)
+	}
+}
+
+// assembleMultipartUpload combines the parts of a multipart upload into the
+// object at its storage key.
+func (s *UploadService) assembleMultipartUpload(ctx context.Context, upload *db.MultipartUpload) error {
+	parts, err := s.uploadProvider.ListUploadParts(ctx, upload.StorageKey, upload.S3UploadID)
+	if err != nil {
+		return NewSrvError(err, SrvErrInternal, fmt.Sprintf("failed to list parts of %s", upload.StorageKey))
+	}
+
+	if err := checkUploadParts(upload, parts); err != nil {
+		return err
+	}
+
+	if err := s.uploadProvider.CompleteMultipartUpload(ctx, upload.StorageKey, upload.S3UploadID, parts); err != nil {
+		return NewSrvError(err, SrvErrInternal, fmt.Sprintf("failed to complete multipart upload %s", upload.StorageKey))
+	}
+
+	return nil
+}
+
+// AbortMultipartUpload cancels a multipart upload and discards its parts.
+func (s *UploadService) AbortMultipartUpload(ctx context.Context, teamID, userID, id int32) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return NewSrvError(err, SrvErrInternal, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	upload, err := getMultipartUpload(ctx, tx, teamID, userID, id, true)
+	if err != nil {
+		return err
+	}
+
+	if err := abortMultipartUpload(ctx, s.uploadProvider, tx, upload); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return NewSrvError(err, SrvErrInternal, "failed to abort multipart upload")
+	}
+
+	return nil
+}
+
+// getMultipartUpload loads an unexpired multipart upload of the user, locking
+// it for completion or abort when lock is set.
+func getMultipartUpload(ctx context.Context, tx db.DBTX, teamID, userID, id int32, lock bool) (*db.MultipartUpload, error) {
+	uploadRepo := repository.NewUploadRepository(tx)
+
+	var (
+		upload *db.MultipartUpload
+		err    error
+	)
+	if lock {
+		upload, err = uploadRepo.LockMultipartUpload(ctx, teamID, id)
+	} else {
+		upload, err = uploadRepo.GetMultipartUpload(ctx, teamID, id)
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, NewSrvError(err, SrvErrNotFound, "multipart upload not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// sessions are private to the uploader
+	if upload.UploaderID != userID {
+		return nil, NewSrvError(nil, SrvErrNotFound, "multipart upload not found")
+	}
+
+	if upload.ExpiresAt.Before(time.Now()) {
+		return nil, NewSrvError(nil, SrvErrInvalidInput, "multipart upload has expired")
+	}
+
+	return upload, nil
+}
+
+// checkUploadParts verifies every part is present and the parts add up to the declared size.
+func checkUploadParts(upload *db.MultipartUpload, parts []provider.UploadPart) error {
+	if len(parts) != int(upload.PartCount) {
+		return NewSrvError(nil, SrvErrInvalidInput, fmt.Sprintf("Multipart upload incomplete: %d of %d parts uploaded", len(parts), upload.PartCount))
+	}
+
+	var total int64
+	for i, part := range parts {
+		if part.PartNumber != i+1 {
+			return NewSrvError(nil, SrvErrInvalidInput, fmt.Sprintf("Multipart upload incomplete: part %d is missing", i+1))
+		}
+		if part.Size > upload.PartSize {
+			return NewSrvError(nil, SrvErrInvalidInput, fmt.Sprintf("part %d is larger than the part size", part.PartNumber))
+		}
+		total += part.Size
+	}
+
+	if total != upload.FileSize {
+		return NewSrvError(nil, SrvErrInvalidInput, fmt.Sprintf("Multipart upload failed: uploaded %d bytes, expected %d", total, upload.FileSize))
+	}
+
+	return nil
+}
+
+// abortMultipartUpload discards the parts, or the assembled file, of a locked
+// multipart upload and deletes its row.
+func abortMultipartUpload(ctx context.Context, uploadProvider provider.UploadProvider, tx db.DBTX, upload *db.MultipartUpload) error {
+	if err := uploadProvider.AbortMultipartUpload(ctx, upload.StorageKey, upload.S3UploadID); err != nil {
+		return NewSrvError(err, SrvErrInternal, fmt.Sprintf("failed to abort multipart upload %s", upload.StorageKey))
+	}
+
+	// the parts were assembled if finalizing the file failed
+	if err := uploadProvider.DeleteObject(ctx, upload.StorageKey); err != nil {
+		return NewSrvError(err, SrvErrInternal, fmt.Sprintf("failed to delete %s", upload.StorageKey))
+	}
+
+	return repository.NewUploadRepository(tx).DeleteMultipartUpload(ctx, upload.ID)
+}
+
+// uploadPartSize returns the size of a part, the last one holds the remainder.
+func uploadPartSize(upload *db.MultipartUpload, partNumber int32) int64 {
+	if partNumber == upload.PartCount {
+		return upload.FileSize - int64(upload.PartCount-1)*upload.PartSize
+	}
+	return upload.PartSize
+}
+
+func ceilDiv(a, b int64) int64 {
+	return (a + b - 1) / b
+}
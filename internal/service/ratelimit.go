@@ -0,0 +1,92 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter allows up to limit events per key within a sliding window. It is
+// kept in memory, so limits apply per server instance.
+type rateLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	events map[int32][]time.Time
+	// keys are pruned once their window passes, swept at most once per window
+	lastSweep time.Time
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{
+		limit:  limit,
+		window: window,
+		events: map[int32][]time.Time{},
+	}
+}
+
+// Allow records an event for key and reports whether it is within the limit.
+// When it is not, it also returns how long until the next event is allowed.
+// A limit of 0 or less allows everything.
+func (l *rateLimiter) Allow(key int32) (bool, time.Duration) {
+	return l.allow(key, true)
+}
+
+// Check reports whether an event for key would be allowed, like Allow, without
+// recording one. Callers that may still fail record it with Allow once they
+// are sure to proceed.
+func (l *rateLimiter) Check(key int32) (bool, time.Duration) {
+	return l.allow(key, false)
+}
+
+func (l *rateLimiter) allow(key int32, record bool) (bool, time.Duration) {
+	if l.limit <= 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	cutoff := now.Add(-l.window)
+
+	if now.Sub(l.lastSweep) >= l.window {
+		l.sweep(cutoff)
+		l.lastSweep = now
+	}
+
+	events := inWindow(l.events[key], cutoff)
+
+	if len(events) >= l.limit {
+		l.events[key] = events
+		return false, events[0].Sub(cutoff)
+	}
+
+	if record {
+		events = append(events, now)
+	}
+	if len(events) == 0 {
+		delete(l.events, key)
+	} else {
+		l.events[key] = events
+	}
+	return true, 0
+}
+
+// sweep drops the keys without events since cutoff, which are otherwise only
+// pruned when seen again.
+func (l *rateLimiter) sweep(cutoff time.Time) {
+	for key, events := range l.events {
+		if len(events) == 0 || !events[len(events)-1].After(cutoff) {
+			delete(l.events, key)
+		}
+	}
+}
+
+// inWindow drops the events that left the window, events are appended in order.
+func inWindow(events []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(events) && !events[i].After(cutoff) {
+		i++
+	}
+	return events[i:]
+}
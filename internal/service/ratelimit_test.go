@@ -0,0 +1,57 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(2, time.Hour)
+
+	if ok, _ := l.Check(1); !ok {
+		t.Fatal("Check() refused the first event")
+	}
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Allow(1); !ok {
+			t.Fatalf("Allow() refused event %d", i+1)
+		}
+	}
+
+	ok, retryIn := l.Allow(1)
+	if ok {
+		t.Fatal("Allow() allowed an event over the limit")
+	}
+	if retryIn <= 0 || retryIn > time.Hour {
+		t.Errorf("Allow() retry in %v, want within the window", retryIn)
+	}
+	if ok, _ := l.Check(1); ok {
+		t.Error("Check() allowed an event over the limit")
+	}
+
+	if ok, _ := l.Allow(2); !ok {
+		t.Error("Allow() refused another key")
+	}
+}
+
+func TestRateLimiterPrunesExpiredKeys(t *testing.T) {
+	l := newRateLimiter(1, 20*time.Millisecond)
+
+	l.Check(1)
+	if _, ok := l.events[1]; ok {
+		t.Error("Check() kept a key without events")
+	}
+
+	l.Allow(2)
+	l.Allow(3)
+	time.Sleep(30 * time.Millisecond)
+
+	if ok, _ := l.Allow(2); !ok {
+		t.Error("Allow() refused an event once the window passed")
+	}
+	if _, ok := l.events[3]; ok {
+		t.Error("a key whose window passed was not pruned")
+	}
+	if len(l.events) != 1 {
+		t.Errorf("limiter keeps %d keys, want 1", len(l.events))
+	}
+}
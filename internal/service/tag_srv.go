@@ -2,7 +2,13 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/skndash96/lastnight-backend/internal/db"
 	"github.com/skndash96/lastnight-backend/internal/repository"
@@ -27,7 +33,7 @@ func (s *TagService) ListFilters(ctx context.Context, membershipID int32) ([]db.
 	return tags, nil
 }
 
-func (s *TagService) UpdateFilters(ctx context.Context, membershipID int32, filters *[][]int32) error {
+func (s *TagService) UpdateFilters(ctx context.Context, teamID, membershipID int32, filters *[][]int32) error {
 	tx, err := s.db.Begin(ctx)
 	if err != nil {
 		return NewSrvError(err, SrvErrInternal, "Failed to begin transaction")
@@ -36,6 +42,28 @@ func (s *TagService) UpdateFilters(ctx context.Context, membershipID int32, filt
 
 	tagRepo := repository.NewTagRepo(tx)
 
+	valueIDs := make([]int32, len(*filters))
+	for i, filter := range *filters {
+		tagKey, err := tagRepo.GetTagKeyByID(ctx, teamID, filter[0])
+		if err != nil {
+			return NewSrvError(err, SrvErrInvalidInput, fmt.Sprintf("tag key %d not found", filter[0]))
+		}
+		if tagKey.ArchivedAt != nil {
+			return NewSrvError(nil, SrvErrInvalidInput, fmt.Sprintf("tag key %s is archived", tagKey.Name))
+		}
+
+		tagValue, err := tagRepo.GetTagValueByID(ctx, teamID, filter[1])
+		if err != nil || tagValue.KeyID != tagKey.ID {
+			return NewSrvError(err, SrvErrInvalidInput, fmt.Sprintf("tag value %d not found for tag key %s", filter[1], tagKey.Name))
+		}
+
+		valueIDs[i] = filter[1]
+	}
+
+	if err := checkTagValueConflicts(ctx, tagRepo, valueIDs); err != nil {
+		return err
+	}
+
 	if err := tagRepo.DeleteAllFilters(ctx, membershipID); err != nil {
 		return err
 	}
@@ -54,42 +82,370 @@ func (s *TagService) UpdateFilters(ctx context.Context, membershipID int32, filt
 }
 
 
-func (s *TagService) CreateTagKey(ctx context.Context, teamID int32, name string, dataType db.TagDataType) (*db.TagKey, error) {
+func (s *TagService) CreateTagKey(ctx context.Context, teamID int32, name string, dataType db.TagDataType, mode db.TagKeyMode, requiresApproval bool) (*db.TagKey, error) {
+	if mode == "" {
+		mode = db.TagKeyModeClosed
+	}
+
 	tagRepo := repository.NewTagRepo(s.db)
-	tag, err := tagRepo.CreateTagKey(ctx, teamID, name, dataType)
+	tag, err := tagRepo.CreateTagKey(ctx, teamID, name, dataType, mode, requiresApproval)
 	if err != nil {
 		return nil, err
 	}
 	return &tag, nil
 }
 
-func (s *TagService) UpdateTagKey(ctx context.Context, tagID int32, name string) (*db.TagKey, error) {
+func (s *TagService) UpdateTagKey(ctx context.Context, teamID, tagID int32, name string, mode *db.TagKeyMode, requiresApproval *bool) (*db.TagKey, error) {
 	tagRepo := repository.NewTagRepo(s.db)
-	tag, err := tagRepo.UpdateTagKey(ctx, tagID, name)
+	tag, err := tagRepo.UpdateTagKey(ctx, teamID, tagID, name, mode, requiresApproval)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, NewSrvError(err, SrvErrNotFound, "tag key not found")
+	}
 	if err != nil {
 		return nil, err
 	}
 	return &tag, nil
 }
 
-func (s *TagService) DeleteTagKey(ctx context.Context, tagID int32) (*db.TagKey, error) {
-	tagRepo := repository.NewTagRepo(s.db)
-	tag, err := tagRepo.DeleteTagKey(ctx, tagID)
+// DeleteTagKey archives the key, or with force permanently deletes it along
+// with its values, filters and upload tags.
+func (s *TagService) DeleteTagKey(ctx context.Context, teamID, tagID int32, force bool) (*db.TagKey, error) {
+	if !force {
+		tagRepo := repository.NewTagRepo(s.db)
+		tag, err := tagRepo.ArchiveTagKey(ctx, teamID, tagID)
+		if err != nil {
+			return nil, NewSrvError(err, SrvErrNotFound, "tag key not found")
+		}
+		return &tag, nil
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, NewSrvError(err, SrvErrInternal, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	tagRepo := repository.NewTagRepo(tx)
+
+	if _, err := tagRepo.GetTagKeyByID(ctx, teamID, tagID); err != nil {
+		return nil, NewSrvError(err, SrvErrNotFound, "tag key not found")
+	}
+
+	// upload tags don't cascade, values, filters and parents do
+	if err := tagRepo.DeleteUploadRefTagsByKey(ctx, tagID); err != nil {
+		return nil, err
+	}
+
+	tag, err := tagRepo.DeleteTagKey(ctx, teamID, tagID)
 	if err != nil {
 		return nil, NewSrvError(err, SrvErrInternal, "failed to delete tag key")
 	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, NewSrvError(err, SrvErrInternal, "failed to delete tag key")
+	}
+
 	return &tag, nil
 }
 
-func (s *TagService) CreateTagValue(ctx context.Context, tagID int32, value string) (*db.TagValue, error) {
+func (s *TagService) RestoreTagKey(ctx context.Context, teamID, tagID int32) (*db.TagKey, error) {
+	tagRepo := repository.NewTagRepo(s.db)
+	tag, err := tagRepo.RestoreTagKey(ctx, teamID, tagID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, NewSrvError(err, SrvErrNotFound, "tag key not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// GetTagKeyImpact counts what deleting the key would affect, without changing anything
+func (s *TagService) GetTagKeyImpact(ctx context.Context, teamID, tagID int32) (*db.GetTagKeyImpactRow, error) {
+	tagRepo := repository.NewTagRepo(s.db)
+
+	if _, err := tagRepo.GetTagKeyByID(ctx, teamID, tagID); err != nil {
+		return nil, NewSrvError(err, SrvErrNotFound, "tag key not found")
+	}
+
+	impact, err := tagRepo.GetTagKeyImpact(ctx, tagID)
+	if err != nil {
+		return nil, err
+	}
+	return &impact, nil
+}
+
+func (s *TagService) CreateTagValue(ctx context.Context, teamID, tagID int32, value string) (*db.TagValue, error) {
 	tagValueRepo := repository.NewTagRepo(s.db)
-	tagValue, err := tagValueRepo.CreateTagValue(ctx, tagID, value)
+
+	tagKey, err := tagValueRepo.GetTagKeyByID(ctx, teamID, tagID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, NewSrvError(err, SrvErrNotFound, "tag key not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if tagKey.ArchivedAt != nil {
+		return nil, NewSrvError(nil, SrvErrInvalidInput, "tag key is archived")
+	}
+
+	value, numValue, err := canonicalizeTagValue(tagKey.DataType, value)
+	if err != nil {
+		return nil, err
+	}
+
+	tagValue, err := tagValueRepo.CreateTagValue(ctx, tagID, value, numValue)
+	if err != nil {
+		return nil, err
+	}
+	return &tagValue, nil
+}
+
+func (s *TagService) ListPendingTagValues(ctx context.Context, teamID int32) ([]db.TagValue, error) {
+	tagRepo := repository.NewTagRepo(s.db)
+	tagValues, err := tagRepo.ListPendingTagValues(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
+	return tagValues, nil
+}
+
+func (s *TagService) ApproveTagValue(ctx context.Context, teamID, tagID, tagValueID int32) (*db.TagValue, error) {
+	tagRepo := repository.NewTagRepo(s.db)
+
+	_, err := tagRepo.GetTagKeyByID(ctx, teamID, tagID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, NewSrvError(err, SrvErrNotFound, "tag key not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tagValue, err := tagRepo.ApproveTagValue(ctx, tagID, tagValueID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, NewSrvError(err, SrvErrNotFound, "tag value not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tagValue, nil
+}
+
+// RejectTagValue deletes a pending value, untagging the uploads it was added to.
+func (s *TagService) RejectTagValue(ctx context.Context, teamID, tagID, tagValueID int32) (*db.TagValue, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, NewSrvError(err, SrvErrInternal, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	tagRepo := repository.NewTagRepo(tx)
+
+	tagValue, err := tagRepo.GetTagValueByID(ctx, teamID, tagValueID)
+	if err != nil || tagValue.KeyID != tagID {
+		return nil, NewSrvError(err, SrvErrNotFound, "tag value not found")
+	}
+
+	if tagValue.Approved {
+		return nil, NewSrvError(nil, SrvErrInvalidInput, "only pending tag values can be rejected")
+	}
+
+	if err := tagRepo.DeleteUploadRefTagsByValue(ctx, tagValueID); err != nil {
+		return nil, err
+	}
+
+	// parents and member filters of the value are deleted with it
+	if _, err := tagRepo.DeleteTagValue(ctx, tagValueID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, NewSrvError(err, SrvErrInternal, "failed to reject tag value")
+	}
+
+	return &tagValue, nil
+}
+
+func (s *TagService) ListDependentOptions(ctx context.Context, teamID, tagID int32, valueIDs []int32) ([]db.TagValue, error) {
+	tagRepo := repository.NewTagRepo(s.db)
+
+	if _, err := tagRepo.GetTagKeyByID(ctx, teamID, tagID); err != nil {
+		return nil, NewSrvError(err, SrvErrNotFound, "tag key not found")
+	}
+
+	options, err := tagRepo.ListDependentOptions(ctx, tagID, valueIDs)
+	if err != nil {
+		return nil, err
+	}
+	return options, nil
+}
+
+// SetTagValueParents replaces the values of other keys the given value is valid under.
+func (s *TagService) SetTagValueParents(ctx context.Context, teamID, tagID, tagValueID int32, parentIDs []int32) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return NewSrvError(err, SrvErrInternal, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	tagRepo := repository.NewTagRepo(tx)
+
+	tagValue, err := tagRepo.GetTagValueByID(ctx, teamID, tagValueID)
+	if err != nil || tagValue.KeyID != tagID {
+		return NewSrvError(err, SrvErrNotFound, "tag value not found")
+	}
+
+	if err := tagRepo.DeleteTagValueParents(ctx, tagValueID); err != nil {
+		return err
+	}
+
+	for _, parentID := range uniqueIDs(parentIDs) {
+		parent, err := tagRepo.GetTagValueByID(ctx, teamID, parentID)
+		if err != nil {
+			return NewSrvError(err, SrvErrInvalidInput, fmt.Sprintf("parent value %d not found", parentID))
+		}
+
+		if parent.KeyID == tagValue.KeyID {
+			return NewSrvError(nil, SrvErrInvalidInput, "parent value must belong to a different tag key")
+		}
+
+		if err := tagRepo.CreateTagValueParent(ctx, tagValueID, parentID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return NewSrvError(err, SrvErrInternal, "failed to set tag value parents")
+	}
+
+	return nil
+}
+
+func (s *TagService) RenameTagValue(ctx context.Context, teamID, tagID, tagValueID int32, value string) (*db.TagValue, error) {
+	tagRepo := repository.NewTagRepo(s.db)
+
+	tagKey, err := tagRepo.GetTagKeyByID(ctx, teamID, tagID)
+	if err != nil {
+		return nil, NewSrvError(err, SrvErrNotFound, "tag key not found")
+	}
+
+	tagValue, err := tagRepo.GetTagValueByID(ctx, teamID, tagValueID)
+	if err != nil || tagValue.KeyID != tagID {
+		return nil, NewSrvError(err, SrvErrNotFound, "tag value not found")
+	}
+
+	value, numValue, err := canonicalizeTagValue(tagKey.DataType, value)
+	if err != nil {
+		return nil, err
+	}
+
+	tagValue, err = tagRepo.UpdateTagValue(ctx, tagValueID, value, numValue)
 	if err != nil {
 		return nil, err
 	}
 	return &tagValue, nil
 }
 
+// MergeTagValue moves all uploads, filters and child values of the source value
+// onto the target value of the same key, then deletes the source. The source's
+// parents are only added on keys the target has parents on already, on other
+// keys the target stays valid under any value.
+func (s *TagService) MergeTagValue(ctx context.Context, teamID, tagID, sourceID, targetID int32) (*db.TagValue, error) {
+	if sourceID == targetID {
+		return nil, NewSrvError(nil, SrvErrInvalidInput, "cannot merge a tag value into itself")
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, NewSrvError(err, SrvErrInternal, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	tagRepo := repository.NewTagRepo(tx)
+
+	source, err := tagRepo.GetTagValueByID(ctx, teamID, sourceID)
+	if err != nil || source.KeyID != tagID {
+		return nil, NewSrvError(err, SrvErrNotFound, "tag value not found")
+	}
+
+	target, err := tagRepo.GetTagValueByID(ctx, teamID, targetID)
+	if err != nil || target.KeyID != tagID {
+		return nil, NewSrvError(err, SrvErrInvalidInput, "target tag value must belong to the same tag key")
+	}
+
+	sourceParents, err := tagRepo.ListTagValueParents(ctx, sourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	targetParents, err := tagRepo.ListTagValueParents(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, parentID := range mergedTagValueParents(sourceParents, targetParents) {
+		if err := tagRepo.CreateTagValueParent(ctx, targetID, parentID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tagRepo.CopyTagValueChildren(ctx, sourceID, targetID); err != nil {
+		return nil, err
+	}
+
+	if err := tagRepo.RepointUploadRefTags(ctx, sourceID, targetID); err != nil {
+		return nil, err
+	}
+
+	if err := tagRepo.RepointMemberFilters(ctx, sourceID, targetID); err != nil {
+		return nil, err
+	}
+
+	if _, err := tagRepo.DeleteTagValue(ctx, sourceID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, NewSrvError(err, SrvErrInternal, "failed to merge tag values")
+	}
+
+	return &target, nil
+}
+
+// mergedTagValueParents returns the parents of a merged value to add to the
+// value it is merged into, those on keys the target is restricted on already.
+func mergedTagValueParents(source, target []db.TagValue) []int32 {
+	restricted := make(map[int32]bool, len(target))
+	present := make(map[int32]bool, len(target))
+	for _, parent := range target {
+		restricted[parent.KeyID] = true
+		present[parent.ID] = true
+	}
+
+	out := []int32{}
+	for _, parent := range source {
+		if restricted[parent.KeyID] && !present[parent.ID] {
+			out = append(out, parent.ID)
+		}
+	}
+	return out
+}
+
+// ReorderTagValues sets the option order of a key, values not listed are placed last.
+func (s *TagService) ReorderTagValues(ctx context.Context, teamID, tagID int32, valueIDs []int32) error {
+	tagRepo := repository.NewTagRepo(s.db)
+
+	if _, err := tagRepo.GetTagKeyByID(ctx, teamID, tagID); err != nil {
+		return NewSrvError(err, SrvErrNotFound, "tag key not found")
+	}
+
+	if err := tagRepo.ReorderTagValues(ctx, tagID, uniqueIDs(valueIDs)); err != nil {
+		return err
+	}
+	return nil
+}
+
 func (s *TagService) DeleteTagValue(ctx context.Context, tagValueID int32) (*db.TagValue, error) {
 	tagValueRepo := repository.NewTagRepo(s.db)
 	tagValue, err := tagValueRepo.DeleteTagValue(ctx, tagValueID)
@@ -98,3 +454,48 @@ func (s *TagService) DeleteTagValue(ctx context.Context, tagValueID int32) (*db.
 	}
 	return &tagValue, nil
 }
+
+// checkTagValueConflicts ensures chosen values are valid under the chosen values of their parent keys
+func checkTagValueConflicts(ctx context.Context, tagRepo *repository.TagRepo, valueIDs []int32) error {
+	conflicts, err := tagRepo.ListTagValueConflicts(ctx, valueIDs)
+	if err != nil {
+		return err
+	}
+
+	if len(conflicts) > 0 {
+		return NewSrvError(nil, SrvErrInvalidInput, fmt.Sprintf("tag value %s is not valid with the chosen values", conflicts[0].Value))
+	}
+
+	return nil
+}
+
+// canonicalizeTagValue validates value against the key's data type and returns
+// its canonical text form, along with the numeric form for number-typed keys.
+func canonicalizeTagValue(dataType db.TagDataType, value string) (string, *float64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil, NewSrvError(nil, SrvErrInvalidInput, "tag value cannot be empty")
+	}
+
+	switch dataType {
+	case db.TagDataTypeNumber:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+			return "", nil, NewSrvError(err, SrvErrInvalidInput, "tag value must be a number")
+		}
+		return strconv.FormatFloat(n, 'f', -1, 64), &n, nil
+	case db.TagDataTypeBoolean:
+		// strconv.ParseBool would also take 1, t, F and the like
+		switch {
+		case strings.EqualFold(value, "true"):
+			return "true", nil, nil
+		case strings.EqualFold(value, "false"):
+			return "false", nil, nil
+		}
+		return "", nil, NewSrvError(nil, SrvErrInvalidInput, "tag value must be true or false")
+	case db.TagDataTypeString:
+		return value, nil, nil
+	default:
+		return "", nil, NewSrvError(nil, SrvErrInternal, "unknown tag data type")
+	}
+}
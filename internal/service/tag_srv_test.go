@@ -0,0 +1,75 @@
+package service
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/skndash96/lastnight-backend/internal/db"
+)
+
+func TestMergedTagValueParents(t *testing.T) {
+	// values 1-3 of key 10 (semester), 4-5 of key 20 (branch)
+	parent := func(id, keyID int32) db.TagValue { return db.TagValue{ID: id, KeyID: keyID} }
+
+	tests := []struct {
+		name   string
+		source []db.TagValue
+		target []db.TagValue
+		want   []int32
+	}{
+		{"unrestricted target stays unrestricted", []db.TagValue{parent(1, 10), parent(4, 20)}, nil, []int32{}},
+		{"union on a restricted key", []db.TagValue{parent(2, 10)}, []db.TagValue{parent(1, 10)}, []int32{2}},
+		{"only restricted keys", []db.TagValue{parent(2, 10), parent(4, 20)}, []db.TagValue{parent(1, 10)}, []int32{2}},
+		{"several keys", []db.TagValue{parent(2, 10), parent(3, 10), parent(5, 20)}, []db.TagValue{parent(1, 10), parent(4, 20)}, []int32{2, 3, 5}},
+		{"shared parents are not added again", []db.TagValue{parent(1, 10), parent(2, 10)}, []db.TagValue{parent(1, 10)}, []int32{2}},
+		{"unrestricted source", nil, []db.TagValue{parent(1, 10)}, []int32{}},
+		{"neither restricted", nil, nil, []int32{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergedTagValueParents(tt.source, tt.target); !slices.Equal(got, tt.want) {
+				t.Errorf("mergedTagValueParents() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonicalizeTagValue(t *testing.T) {
+	tests := []struct {
+		dataType db.TagDataType
+		in       string
+		want     string
+		err      bool
+	}{
+		{db.TagDataTypeBoolean, "true", "true", false},
+		{db.TagDataTypeBoolean, "False", "false", false},
+		{db.TagDataTypeBoolean, " TRUE ", "true", false},
+		{db.TagDataTypeBoolean, "1", "", true},
+		{db.TagDataTypeBoolean, "t", "", true},
+		{db.TagDataTypeBoolean, "F", "", true},
+		{db.TagDataTypeBoolean, "yes", "", true},
+		{db.TagDataTypeNumber, "3.50", "3.5", false},
+		{db.TagDataTypeNumber, "NaN", "", true},
+		{db.TagDataTypeString, " Circuit Theory ", "Circuit Theory", false},
+		{db.TagDataTypeString, "  ", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.dataType)+"/"+tt.in, func(t *testing.T) {
+			got, _, err := canonicalizeTagValue(tt.dataType, tt.in)
+			if tt.err {
+				if !isClientError(err) {
+					t.Fatalf("canonicalizeTagValue(%q) error = %v, want an invalid input error", tt.in, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("canonicalizeTagValue(%q) error = %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("canonicalizeTagValue(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
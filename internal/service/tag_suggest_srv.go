@@ -0,0 +1,322 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/skndash96/lastnight-backend/internal/db"
+	"github.com/skndash96/lastnight-backend/internal/repository"
+)
+
+const (
+	// weight of a value whose words all appear in the file name, or the text
+	suggestNameWeight = 1.0
+	suggestTextWeight = 0.5
+	// weight of a value always tagged along with a matched one
+	suggestRelatedWeight = 0.6
+	// weight of how often a value is used among those of its key, breaks ties
+	suggestPriorWeight = 0.05
+
+	// values matched at least this well are used to find related values
+	suggestEvidenceScore = 0.5
+	suggestMinScore      = 0.1
+	suggestPerKey        = 3
+
+	// only the start of long documents is looked at
+	suggestMaxTextLen = 64 * 1024
+)
+
+// words that say nothing about which value is meant
+var suggestStopWords = map[string]bool{
+	"an": true, "and": true, "the": true, "of": true, "in": true, "on": true,
+	"to": true, "for": true, "with": true, "by": true, "at": true, "or": true,
+}
+
+type TagSuggestion struct {
+	ValueID int32   `json:"value_id"`
+	Value   string  `json:"value"`
+	Score   float64 `json:"score"`
+	// what the value was suggested for: "name", "text" and "related" when it is
+	// often tagged along with another suggested value
+	Reasons []string `json:"reasons"`
+}
+
+type TagKeySuggestions struct {
+	KeyID      int32           `json:"key_id"`
+	KeyName    string          `json:"key_name"`
+	Candidates []TagSuggestion `json:"candidates"`
+}
+
+// SuggestTags proposes values of each of the team's tag keys for a file, by
+// matching their words against the file name and, with an uploadID, the text
+// extracted from that upload, and by how values were tagged together on the
+// team's uploads so far. Every active key is listed, with its best candidates
+// first and none when nothing fits.
+func (s *TagService) SuggestTags(ctx context.Context, teamID int32, name string, uploadID int32) ([]TagKeySuggestions, error) {
+	text := ""
+	if uploadID != 0 {
+		uploadRepo := repository.NewUploadRepository(s.db)
+
+		ref, err := uploadRepo.GetUploadRef(ctx, teamID, uploadID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, NewSrvError(err, SrvErrNotFound, "upload not found")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if name == "" {
+			name = ref.FileName
+		}
+
+		// text is extracted in the background and missing for some files
+		text, err = uploadRepo.GetUploadRefText(ctx, teamID, uploadID)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		if len(text) > suggestMaxTextLen {
+			text = text[:suggestMaxTextLen]
+		}
+	}
+
+	if strings.TrimSpace(name) == "" {
+		return nil, NewSrvError(nil, SrvErrInvalidInput, "a file name or an upload is required")
+	}
+
+	tagRepo := repository.NewTagRepo(s.db)
+
+	tagKeys, err := tagRepo.ListTeamTagKeys(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
+	tagValues, err := tagRepo.ListTeamTagValues(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := tagRepo.CountTeamTagValueUploads(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	uploads := map[int32]int32{}
+	for _, c := range counts {
+		uploads[c.ValueID] = c.Uploads
+	}
+
+	nameTokens := suggestTokenSet(strings.TrimSuffix(name, path.Ext(name)))
+	textTokens := suggestTokenSet(text)
+
+	active := map[int32]bool{}
+	boolean := map[int32]bool{}
+	for _, k := range tagKeys {
+		active[k.ID] = k.ArchivedAt == nil
+		boolean[k.ID] = k.DataType == db.TagDataTypeBoolean
+	}
+
+	candidates := map[int32]*tagCandidate{}
+	keyUploads := map[int32]int32{}
+	evidence := []int32{}
+
+	for _, v := range tagValues {
+		// pending values are not offered until a mod approves them
+		if !active[v.KeyID] || !v.Approved {
+			continue
+		}
+
+		c := newTagCandidate(v, boolean[v.KeyID], nameTokens, textTokens)
+		candidates[v.ID] = c
+		keyUploads[v.KeyID] += uploads[v.ID]
+
+		if c.match >= suggestEvidenceScore {
+			evidence = append(evidence, v.ID)
+		}
+	}
+
+	if len(evidence) > 0 {
+		cooccurrences, err := tagRepo.ListTagValueCooccurrences(ctx, teamID, evidence)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, co := range cooccurrences {
+			c, ok := candidates[co.OtherValueID]
+			if !ok || c.value.KeyID == candidates[co.ValueID].value.KeyID {
+				continue
+			}
+			// a single upload tagged with both is weaker evidence than many
+			related := candidates[co.ValueID].match * float64(co.Uploads) / float64(uploads[co.ValueID]+1)
+			c.related = math.Max(c.related, related)
+		}
+	}
+
+	byKey := map[int32][]TagSuggestion{}
+	for _, c := range candidates {
+		if keyUploads[c.value.KeyID] > 0 {
+			c.prior = float64(uploads[c.value.ID]) / float64(keyUploads[c.value.KeyID])
+		}
+
+		score := c.score()
+		if score < suggestMinScore {
+			continue
+		}
+
+		byKey[c.value.KeyID] = append(byKey[c.value.KeyID], TagSuggestion{
+			ValueID: c.value.ID,
+			Value:   c.value.Value,
+			Score:   math.Round(score*1000) / 1000,
+			Reasons: c.reasons(),
+		})
+	}
+
+	out := []TagKeySuggestions{}
+	for _, k := range tagKeys {
+		if k.ArchivedAt != nil {
+			continue
+		}
+
+		suggestions := byKey[k.ID]
+		sort.Slice(suggestions, func(i, j int) bool {
+			if suggestions[i].Score != suggestions[j].Score {
+				return suggestions[i].Score > suggestions[j].Score
+			}
+			return suggestions[i].ValueID < suggestions[j].ValueID
+		})
+		if len(suggestions) > suggestPerKey {
+			suggestions = suggestions[:suggestPerKey]
+		}
+		if suggestions == nil {
+			suggestions = []TagSuggestion{}
+		}
+
+		out = append(out, TagKeySuggestions{
+			KeyID:      k.ID,
+			KeyName:    k.Name,
+			Candidates: suggestions,
+		})
+	}
+
+	return out, nil
+}
+
+type tagCandidate struct {
+	value db.TagValue
+	// fractions of the value's words found in the file name and text
+	name float64
+	text float64
+	// combined name and text match
+	match   float64
+	related float64
+	prior   float64
+}
+
+// newTagCandidate matches a value of a key against the words of a file name and text.
+func newTagCandidate(v db.TagValue, boolean bool, nameTokens, textTokens map[string]bool) *tagCandidate {
+	c := &tagCandidate{value: v}
+	// "true" or "false" in a name does not say what it is true of
+	if !boolean {
+		c.matchTokens(suggestTokens(v.Value), nameTokens, textTokens)
+	}
+	return c
+}
+
+func (c *tagCandidate) matchTokens(tokens []string, nameTokens, textTokens map[string]bool) {
+	if len(tokens) == 0 {
+		return
+	}
+
+	nameHits, textHits := 0, 0
+	for _, t := range tokens {
+		if nameTokens[t] {
+			nameHits++
+		}
+		if textTokens[t] {
+			textHits++
+		}
+	}
+
+	c.name = float64(nameHits) / float64(len(tokens))
+	c.text = float64(textHits) / float64(len(tokens))
+	c.match = 1 - (1-suggestNameWeight*c.name)*(1-suggestTextWeight*c.text)
+}
+
+// score combines the evidence for the value, each source adding to the others
+// without exceeding 1.
+func (c *tagCandidate) score() float64 {
+	score := 1 - (1-c.match)*(1-suggestRelatedWeight*c.related)
+	return math.Min(1, score+suggestPriorWeight*c.prior)
+}
+
+func (c *tagCandidate) reasons() []string {
+	reasons := []string{}
+	if c.name > 0 {
+		reasons = append(reasons, "name")
+	}
+	if c.text > 0 {
+		reasons = append(reasons, "text")
+	}
+	if c.related > 0 {
+		reasons = append(reasons, "related")
+	}
+	return reasons
+}
+
+// suggestTokens splits s into lowercase words and numbers, also where letters and
+// digits meet so "sem3" matches "3", and drops leading zeros of numbers.
+func suggestTokens(s string) []string {
+	tokens := []string{}
+	seen := map[string]bool{}
+
+	add := func(t string) {
+		if t == "" {
+			return
+		}
+		if unicode.IsDigit(rune(t[0])) {
+			if trimmed := strings.TrimLeft(t, "0"); trimmed != "" {
+				t = trimmed
+			} else {
+				t = "0"
+			}
+		} else if len([]rune(t)) < 2 || suggestStopWords[t] {
+			return
+		}
+		if !seen[t] {
+			seen[t] = true
+			tokens = append(tokens, t)
+		}
+	}
+
+	var b strings.Builder
+	var last rune
+	for _, r := range s {
+		isDigit := unicode.IsDigit(r)
+		if !isDigit && !unicode.IsLetter(r) {
+			add(b.String())
+			b.Reset()
+			last = 0
+			continue
+		}
+		// camel case as in "CircuitTheory" separates words too
+		if b.Len() > 0 && (isDigit != unicode.IsDigit(last) || unicode.IsLower(last) && unicode.IsUpper(r)) {
+			add(b.String())
+			b.Reset()
+		}
+		b.WriteRune(unicode.ToLower(r))
+		last = r
+	}
+	add(b.String())
+
+	return tokens
+}
+
+func suggestTokenSet(s string) map[string]bool {
+	set := map[string]bool{}
+	for _, t := range suggestTokens(s) {
+		set[t] = true
+	}
+	return set
+}
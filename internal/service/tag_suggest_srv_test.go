@@ -0,0 +1,86 @@
+package service
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/skndash96/lastnight-backend/internal/db"
+)
+
+func TestSuggestTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{"separators", "circuit_theory-notes.v2", []string{"circuit", "theory", "notes", "2"}},
+		{"camel case", "CircuitTheory", []string{"circuit", "theory"}},
+		{"acronyms stay together", "PDFNotes", []string{"pdfnotes"}},
+		{"letters and digits", "sem3", []string{"sem", "3"}},
+		{"digits and letters", "2nd", []string{"2", "nd"}},
+		{"leading zeros", "Unit 03", []string{"unit", "3"}},
+		{"only zeros", "000", []string{"0"}},
+		{"single letters", "a b c", []string{}},
+		{"single digits", "a 1", []string{"1"}},
+		{"stop words", "Theory of the Circuits and Systems", []string{"theory", "circuits", "systems"}},
+		{"duplicates", "Exam exam EXAM", []string{"exam"}},
+		{"years", "2023-24_midsem", []string{"2023", "24", "midsem"}},
+		{"unicode", "Über Straße", []string{"über", "straße"}},
+		{"empty", "", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := suggestTokens(tt.in); !slices.Equal(got, tt.want) {
+				t.Errorf("suggestTokens(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTagCandidateScore(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		boolean bool
+		file    string
+		text    string
+		related float64
+		prior   float64
+		match   float64
+		score   float64
+		reasons []string
+	}{
+		{"every word in the name", "Circuit Theory", false, "CircuitTheory_sem3", "", 0, 0, 1, 1, []string{"name"}},
+		{"some words in the name", "Signals and Systems", false, "signals_notes", "", 0, 0, 0.5, 0.5, []string{"name"}},
+		{"every word in the text", "Circuit Theory", false, "notes", "an intro to circuit theory", 0, 0, 0.5, 0.5, []string{"text"}},
+		{"name and text add up", "Signals Systems", false, "signals", "signals and systems", 0, 0, 0.75, 0.75, []string{"name", "text"}},
+		{"number without leading zeros", "Unit 3", false, "unit03", "", 0, 0, 1, 1, []string{"name"}},
+		{"nothing matches", "Circuit Theory", false, "calculus", "limits", 0, 0, 0, 0, []string{}},
+		{"stop words only", "The", false, "the notes", "", 0, 0, 0, 0, []string{}},
+		{"boolean keys", "true", true, "true", "true", 0, 0, 0, 0, []string{}},
+		{"related only", "Semester 3", false, "notes", "", 1, 0, 0, 0.6, []string{"related"}},
+		{"related adds to a match", "Signals Systems", false, "signals", "", 0.5, 0, 0.5, 0.65, []string{"name", "related"}},
+		{"prior breaks ties", "Circuit Theory", false, "circuit", "", 0, 1, 0.5, 0.55, []string{"name"}},
+		{"capped at 1", "Circuit Theory", false, "circuit theory", "", 1, 1, 1, 1, []string{"name", "related"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTagCandidate(db.TagValue{Value: tt.value}, tt.boolean, suggestTokenSet(tt.file), suggestTokenSet(tt.text))
+			c.related = tt.related
+			c.prior = tt.prior
+
+			if math.Abs(c.match-tt.match) > 1e-9 {
+				t.Errorf("match = %v, want %v", c.match, tt.match)
+			}
+			if score := c.score(); math.Abs(score-tt.score) > 1e-9 {
+				t.Errorf("score() = %v, want %v", score, tt.score)
+			}
+			if reasons := c.reasons(); !slices.Equal(reasons, tt.reasons) {
+				t.Errorf("reasons() = %q, want %q", reasons, tt.reasons)
+			}
+		})
+	}
+}
@@ -0,0 +1,682 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/skndash96/lastnight-backend/internal/db"
+	"github.com/skndash96/lastnight-backend/internal/repository"
+)
+
+const (
+	teamArchiveFormat = "lastnight-team"
+	// bumped on changes older importers cannot read
+	teamArchiveVersion = 1
+	// name of the description of the team at the root of every archive, objects
+	// are stored next to it as objects/<hex sha256>
+	teamArchiveManifestName = "team.json"
+)
+
+// TeamArchiveService exports a team's tags, members and uploads to a ZIP archive,
+// and imports such archives into a team of this or another instance. IDs in an
+// archive are those of the source team, and are remapped on import.
+type TeamArchiveService struct {
+	pool      *pgxpool.Pool
+	uploadSrv *UploadService
+	// background jobs are cancelled with it, e.g. on shutdown
+	ctx context.Context
+}
+
+func NewTeamArchiveService(ctx context.Context, uploadSrv *UploadService, pool *pgxpool.Pool) *TeamArchiveService {
+	return &TeamArchiveService{
+		pool:      pool,
+		uploadSrv: uploadSrv,
+		ctx:       ctx,
+	}
+}
+
+type teamArchive struct {
+	Format       string    `json:"format"`
+	Version      int       `json:"version"`
+	ExportedAt   time.Time `json:"exported_at"`
+	SourceTeamID int32     `json:"source_team_id"`
+
+	TagKeys []teamArchiveTagKey `json:"tag_keys"`
+	Members []teamArchiveMember `json:"members"`
+	Uploads []teamArchiveUpload `json:"uploads"`
+}
+
+type teamArchiveTagKey struct {
+	ID               int32              `json:"id"`
+	Name             string             `json:"name"`
+	DataType         db.TagDataType     `json:"data_type"`
+	Mode             db.TagKeyMode      `json:"mode"`
+	RequiresApproval bool               `json:"requires_approval"`
+	Archived         bool               `json:"archived"`
+	Values           []teamArchiveValue `json:"values"`
+}
+
+type teamArchiveValue struct {
+	ID        int32   `json:"id"`
+	Value     string  `json:"value"`
+	Approved  bool    `json:"approved"`
+	ParentIDs []int32 `json:"parent_ids"`
+}
+
+// members are matched to users by email, who must have registered on the
+// instance the archive is imported to
+type teamArchiveMember struct {
+	Email   string           `json:"email"`
+	Name    string           `json:"name"`
+	Role    db.TeamUserRole  `json:"role"`
+	Filters []teamArchiveTag `json:"filters"`
+}
+
+type teamArchiveTag struct {
+	KeyID   int32 `json:"key_id"`
+	ValueID int32 `json:"value_id"`
+}
+
+type teamArchiveUpload struct {
+	ID            int32     `json:"id"`
+	FileName      string    `json:"file_name"`
+	UploaderEmail string    `json:"uploader_email"`
+	CreatedAt     time.Time `json:"created_at"`
+	// hex encoded, empty for objects stored without a checksum
+	SHA256   string `json:"sha256"`
+	Size     int64  `json:"size"`
+	MimeType string `json:"mime_type"`
+	// path of the object within the archive, empty when exported without objects
+	Object string           `json:"object"`
+	Tags   []teamArchiveTag `json:"tags"`
+}
+
+type TeamExportReport struct {
+	TagKeys   int `json:"tag_keys"`
+	TagValues int `json:"tag_values"`
+	Members   int `json:"members"`
+	Uploads   int `json:"uploads"`
+	// distinct objects written to the archive, and their size
+	Objects int   `json:"objects"`
+	Bytes   int64 `json:"bytes"`
+}
+
+type TeamImportReport struct {
+	CreatedTagKeys   int `json:"created_tag_keys"`
+	ReusedTagKeys    int `json:"reused_tag_keys"`
+	CreatedTagValues int `json:"created_tag_values"`
+	AddedMembers     int `json:"added_members"`
+	ExistingMembers  int `json:"existing_members"`
+	// members of the archive not matched to a member of the team, only counted
+	// so the report does not tell who is registered on the instance
+	SkippedMembers int `json:"skipped_members"`
+	// uploads by how they were added, see ImportStatus
+	Uploads       map[ImportStatus]int `json:"uploads"`
+	FailedUploads []TeamImportSkip     `json:"failed_uploads"`
+}
+
+type TeamImportSkip struct {
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+// WriteTeamArchive writes the team's tags, members and uploads to w as a ZIP,
+// with the upload objects when includeObjects is set.
+func (s *TeamArchiveService) WriteTeamArchive(ctx context.Context, w io.Writer, teamID int32, includeObjects bool) (*TeamExportReport, error) {
+	archive, keys, err := s.readTeamArchive(ctx, teamID, includeObjects)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &TeamExportReport{
+		TagKeys: len(archive.TagKeys),
+		Members: len(archive.Members),
+		Uploads: len(archive.Uploads),
+	}
+	for _, key := range archive.TagKeys {
+		report.TagValues += len(key.Values)
+	}
+
+	zw := zip.NewWriter(w)
+
+	mw, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     teamArchiveManifestName,
+		Method:   zip.Deflate,
+		Modified: archive.ExportedAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	enc := json.NewEncoder(mw)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(archive); err != nil {
+		return nil, err
+	}
+
+	// refs of different names may share an object, it is written once
+	written := map[string]bool{}
+	for _, upload := range archive.Uploads {
+		if upload.Object == "" || written[upload.Object] {
+			continue
+		}
+		written[upload.Object] = true
+
+		if err := s.writeTeamArchiveObject(ctx, zw, upload.Object, keys[upload.Object], upload.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		report.Objects++
+		report.Bytes += upload.Size
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+func (s *TeamArchiveService) writeTeamArchiveObject(ctx context.Context, zw *zip.Writer, name, storageKey string, modified time.Time) error {
+	obj, err := s.uploadSrv.uploadProvider.GetObject(ctx, storageKey)
+	if err != nil {
+		return fmt.Errorf("failed to get %s: %w", storageKey, err)
+	}
+	defer obj.Close()
+
+	fw, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Store,
+		Modified: modified,
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(fw, obj); err != nil {
+		return fmt.Errorf("failed to export %s: %w", storageKey, err)
+	}
+
+	return nil
+}
+
+// readTeamArchive reads the team from a single snapshot. With objects, it also
+// returns the storage key of every object path in the archive.
+func (s *TeamArchiveService) readTeamArchive(ctx context.Context, teamID int32, includeObjects bool) (*teamArchive, map[string]string, error) {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{
+		IsoLevel:   pgx.RepeatableRead,
+		AccessMode: pgx.ReadOnly,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	tagRepo := repository.NewTagRepo(tx)
+	teamRepo := repository.NewTeamRepository(tx)
+	uploadRepo := repository.NewUploadRepository(tx)
+
+	archive := &teamArchive{
+		Format:       teamArchiveFormat,
+		Version:      teamArchiveVersion,
+		ExportedAt:   time.Now(),
+		SourceTeamID: teamID,
+		TagKeys:      []teamArchiveTagKey{},
+		Members:      []teamArchiveMember{},
+		Uploads:      []teamArchiveUpload{},
+	}
+
+	tagKeys, err := tagRepo.ListTeamTagKeys(ctx, teamID)
+	if err != nil {
+		return nil, nil, err
+	}
+	tagValues, err := tagRepo.ListTeamTagValues(ctx, teamID)
+	if err != nil {
+		return nil, nil, err
+	}
+	valueParents, err := tagRepo.ListTeamTagValueParents(ctx, teamID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	parents := map[int32][]int32{}
+	for _, p := range valueParents {
+		parents[p.ValueID] = append(parents[p.ValueID], p.ParentValueID)
+	}
+
+	// values are listed in their display order
+	values := map[int32][]teamArchiveValue{}
+	for _, v := range tagValues {
+		parentIDs := parents[v.ID]
+		if parentIDs == nil {
+			parentIDs = []int32{}
+		}
+		values[v.KeyID] = append(values[v.KeyID], teamArchiveValue{
+			ID:        v.ID,
+			Value:     v.Value,
+			Approved:  v.Approved,
+			ParentIDs: parentIDs,
+		})
+	}
+
+	for _, k := range tagKeys {
+		keyValues := values[k.ID]
+		if keyValues == nil {
+			keyValues = []teamArchiveValue{}
+		}
+		archive.TagKeys = append(archive.TagKeys, teamArchiveTagKey{
+			ID:               k.ID,
+			Name:             k.Name,
+			DataType:         k.DataType,
+			Mode:             k.Mode,
+			RequiresApproval: k.RequiresApproval,
+			Archived:         k.ArchivedAt != nil,
+			Values:           keyValues,
+		})
+	}
+
+	members, err := teamRepo.ListTeamMembers(ctx, teamID)
+	if err != nil {
+		return nil, nil, err
+	}
+	memberFilters, err := teamRepo.ListTeamMemberFilters(ctx, teamID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	filters := map[int32][]teamArchiveTag{}
+	for _, f := range memberFilters {
+		filters[f.MembershipID] = append(filters[f.MembershipID], teamArchiveTag{KeyID: f.KeyID, ValueID: f.ValueID})
+	}
+
+	for _, m := range members {
+		memberFilters := filters[m.ID]
+		if memberFilters == nil {
+			memberFilters = []teamArchiveTag{}
+		}
+		archive.Members = append(archive.Members, teamArchiveMember{
+			Email:   m.Email,
+			Name:    m.Name,
+			Role:    m.Role,
+			Filters: memberFilters,
+		})
+	}
+
+	refs, err := uploadRepo.ListTeamUploadRefs(ctx, teamID)
+	if err != nil {
+		return nil, nil, err
+	}
+	refTags, err := uploadRepo.ListTeamUploadRefTags(ctx, teamID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tags := map[int32][]teamArchiveTag{}
+	for _, t := range refTags {
+		tags[t.UploadRefID] = append(tags[t.UploadRefID], teamArchiveTag{KeyID: t.KeyID, ValueID: t.ValueID})
+	}
+
+	keys := map[string]string{}
+	for _, ref := range refs {
+		refTags := tags[ref.ID]
+		if refTags == nil {
+			refTags = []teamArchiveTag{}
+		}
+
+		upload := teamArchiveUpload{
+			ID:            ref.ID,
+			FileName:      ref.FileName,
+			UploaderEmail: ref.UploaderEmail,
+			CreatedAt:     ref.CreatedAt,
+			Size:          ref.FileSize,
+			MimeType:      ref.FileMimeType,
+			Tags:          refTags,
+		}
+
+		// without a checksum the object cannot be deduplicated on import
+		if sum, err := base64.StdEncoding.DecodeString(ref.FileSha256); err == nil && len(sum) == 32 {
+			upload.SHA256 = hex.EncodeToString(sum)
+			if includeObjects {
+				upload.Object = "objects/" + upload.SHA256
+				keys[upload.Object] = ref.StorageKey
+			}
+		}
+
+		archive.Uploads = append(archive.Uploads, upload)
+	}
+
+	return archive, keys, nil
+}
+
+// ImportTeamArchive imports an archive of size bytes into the team. Tag keys
+// are matched by name and values by value, members by email. Users who are not
+// members yet are only added to the team with addMembers, which is left to the
+// command line as it joins users to a team without their consent, and always
+// as plain members. Uploads are added as userID when their uploader is not a
+// member, and only when the team has the file already or the archive includes it.
+func (s *TeamArchiveService) ImportTeamArchive(ctx context.Context, teamID, userID int32, r io.ReaderAt, size int64, addMembers bool) (*TeamImportReport, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, NewSrvError(err, SrvErrInvalidInput, "the archive is not a valid ZIP file")
+	}
+
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	archive, err := readTeamArchiveManifest(files[teamArchiveManifestName])
+	if err != nil {
+		return nil, err
+	}
+
+	teamRepo := repository.NewTeamRepository(s.pool)
+	if _, err := teamRepo.GetTeamMembershipByUserID(ctx, userID, teamID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, NewSrvError(err, SrvErrInvalidInput, fmt.Sprintf("user %d is not a member of team %d", userID, teamID))
+		}
+		return nil, err
+	}
+
+	report := &TeamImportReport{
+		Uploads:       map[ImportStatus]int{},
+		FailedUploads: []TeamImportSkip{},
+	}
+
+	ids, err := s.importTeamStructure(ctx, teamID, archive, addMembers, report)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, upload := range archive.Uploads {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		status, err := s.importTeamUpload(ctx, teamID, userID, upload, ids, files)
+		if err != nil {
+			report.FailedUploads = append(report.FailedUploads, TeamImportSkip{Name: upload.FileName, Error: err.Error()})
+			status = ImportStatusFailed
+		}
+		report.Uploads[status]++
+	}
+
+	return report, nil
+}
+
+func readTeamArchiveManifest(f *zip.File) (*teamArchive, error) {
+	if f == nil {
+		return nil, NewSrvError(nil, SrvErrInvalidInput, fmt.Sprintf("the archive has no %s", teamArchiveManifestName))
+	}
+
+	r, err := f.Open()
+	if err != nil {
+		return nil, NewSrvError(err, SrvErrInvalidInput, fmt.Sprintf("failed to read %s", teamArchiveManifestName))
+	}
+	defer r.Close()
+
+	archive := &teamArchive{}
+	if err := json.NewDecoder(r).Decode(archive); err != nil {
+		return nil, NewSrvError(err, SrvErrInvalidInput, fmt.Sprintf("invalid %s: %v", teamArchiveManifestName, err))
+	}
+
+	if archive.Format != teamArchiveFormat {
+		return nil, NewSrvError(nil, SrvErrInvalidInput, "the archive is not a team export")
+	}
+	if archive.Version < 1 || archive.Version > teamArchiveVersion {
+		return nil, NewSrvError(nil, SrvErrInvalidInput, fmt.Sprintf("unsupported archive version %d, this server reads up to version %d", archive.Version, teamArchiveVersion))
+	}
+
+	return archive, nil
+}
+
+// teamArchiveIDs maps the IDs of an archive to those in the team imported to.
+type teamArchiveIDs struct {
+	keys   map[int32]int32
+	values map[int32]int32
+	// values pending approval in the team, not attached to uploads
+	pending map[int32]bool
+	// users by email
+	members map[string]int32
+}
+
+func (ids *teamArchiveIDs) tag(t teamArchiveTag) (int32, int32, bool) {
+	keyID, ok := ids.keys[t.KeyID]
+	if !ok {
+		return 0, 0, false
+	}
+	valueID, ok := ids.values[t.ValueID]
+	return keyID, valueID, ok
+}
+
+// importTeamStructure creates the tag keys, values and, with addMembers, the
+// memberships of an archive in one transaction, so a failed import does not
+// leave half of them.
+func (s *TeamArchiveService) importTeamStructure(ctx context.Context, teamID int32, archive *teamArchive, addMembers bool, report *TeamImportReport) (*teamArchiveIDs, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	tagRepo := repository.NewTagRepo(tx)
+	teamRepo := repository.NewTeamRepository(tx)
+	authRepo := repository.NewAuthRepository(tx)
+
+	ids := &teamArchiveIDs{
+		keys:    map[int32]int32{},
+		values:  map[int32]int32{},
+		pending: map[int32]bool{},
+		members: map[string]int32{},
+	}
+
+	// parents are only set on values created here, existing values keep theirs
+	created := []teamArchiveValue{}
+
+	for _, k := range archive.TagKeys {
+		tagKey, err := tagRepo.GetTagKeyByName(ctx, teamID, k.Name)
+		isNew := errors.Is(err, pgx.ErrNoRows)
+		switch {
+		case isNew:
+			mode := k.Mode
+			if mode == "" {
+				mode = db.TagKeyModeClosed
+			}
+			tagKey, err = tagRepo.CreateTagKey(ctx, teamID, k.Name, k.DataType, mode, k.RequiresApproval)
+			if err != nil {
+				return nil, err
+			}
+			if k.Archived {
+				if _, err := tagRepo.ArchiveTagKey(ctx, teamID, tagKey.ID); err != nil {
+					return nil, err
+				}
+			}
+			report.CreatedTagKeys++
+		case err != nil:
+			return nil, err
+		case tagKey.DataType != k.DataType:
+			return nil, NewSrvError(nil, SrvErrInvalidInput, fmt.Sprintf("tag key %s exists with data type %s, the archive has %s", k.Name, tagKey.DataType, k.DataType))
+		default:
+			report.ReusedTagKeys++
+		}
+		ids.keys[k.ID] = tagKey.ID
+
+		order := make([]int32, 0, len(k.Values))
+		for _, v := range k.Values {
+			value, numValue, err := canonicalizeTagValue(tagKey.DataType, v.Value)
+			if err != nil {
+				return nil, err
+			}
+
+			tagValue, err := tagRepo.GetTagValueByValue(ctx, tagKey.ID, value)
+			if errors.Is(err, pgx.ErrNoRows) {
+				tagValue, err = tagRepo.GetOrCreateTagValue(ctx, tagKey.ID, value, numValue, v.Approved)
+				if err != nil {
+					return nil, err
+				}
+				report.CreatedTagValues++
+				created = append(created, v)
+			} else if err != nil {
+				return nil, err
+			}
+
+			ids.values[v.ID] = tagValue.ID
+			ids.pending[v.ID] = !tagValue.Approved
+			order = append(order, tagValue.ID)
+		}
+
+		// existing keys keep the order set in this team
+		if isNew && len(order) > 0 {
+			if err := tagRepo.ReorderTagValues(ctx, tagKey.ID, order); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, v := range created {
+		for _, parentID := range v.ParentIDs {
+			parent, ok := ids.values[parentID]
+			if !ok {
+				continue
+			}
+			if err := tagRepo.CreateTagValueParent(ctx, ids.values[v.ID], parent); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, m := range archive.Members {
+		user, err := authRepo.GetUserByEmail(ctx, m.Email)
+		if errors.Is(err, pgx.ErrNoRows) {
+			report.SkippedMembers++
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		membership, err := teamRepo.GetUserMembership(ctx, user.ID)
+		if err == nil {
+			if membership.TeamID != teamID {
+				report.SkippedMembers++
+				continue
+			}
+			// their role and filters in this team are left as they are
+			ids.members[m.Email] = user.ID
+			report.ExistingMembers++
+			continue
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+
+		if !addMembers {
+			report.SkippedMembers++
+			continue
+		}
+
+		// mods are appointed in the team, never by an archive
+		membership, err = teamRepo.CreateTeamMembership(ctx, user.ID, teamID, db.TeamUserRoleMember)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, f := range m.Filters {
+			keyID, valueID, ok := ids.tag(f)
+			if !ok {
+				continue
+			}
+			if err := tagRepo.CreateFilter(ctx, membership.ID, keyID, valueID); err != nil {
+				return nil, err
+			}
+		}
+
+		ids.members[m.Email] = user.ID
+		report.AddedMembers++
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (s *TeamArchiveService) importTeamUpload(ctx context.Context, teamID, userID int32, upload teamArchiveUpload, ids *teamArchiveIDs, files map[string]*zip.File) (ImportStatus, error) {
+	if upload.SHA256 == "" {
+		return "", NewSrvError(nil, SrvErrInvalidInput, "the upload has no checksum and cannot be imported")
+	}
+
+	uploaderID, ok := ids.members[upload.UploaderEmail]
+	if !ok {
+		uploaderID = userID
+	}
+
+	tags := make([]UploadTagInput, 0, len(upload.Tags))
+	for _, t := range upload.Tags {
+		keyID, valueID, ok := ids.tag(t)
+		// a mod approves pending values before they are attached
+		if !ok || ids.pending[t.ValueID] {
+			continue
+		}
+		tags = append(tags, UploadTagInput{KeyID: keyID, ValueID: valueID})
+	}
+
+	f, ok := files[upload.Object]
+	if !ok || upload.Object == "" {
+		// a checksum alone proves nothing, without the object only files the
+		// team has already are matched
+		check, err := s.uploadSrv.CheckUpload(ctx, teamID, uploaderID, upload.SHA256, upload.Size, "", 0, nil)
+		if err != nil {
+			return "", err
+		}
+		if !check.InTeam {
+			return "", NewSrvError(nil, SrvErrInvalidInput, "the file is not in the archive")
+		}
+		return ImportStatusInTeam, nil
+	}
+
+	// the declared checksum would otherwise link any file stored under it
+	checksum, size, err := zipEntrySHA256(f)
+	if err != nil {
+		return "", NewSrvError(err, SrvErrInvalidInput, fmt.Sprintf("failed to read %s", upload.Object))
+	}
+	if !strings.EqualFold(checksum, upload.SHA256) || size != upload.Size {
+		return "", NewSrvError(nil, SrvErrInvalidInput, "the file in the archive does not match its checksum")
+	}
+
+	status, _, err := s.uploadSrv.addFile(ctx, teamID, uploaderID, checksum, size, upload.FileName, tags, f.Open)
+	if err != nil {
+		return "", err
+	}
+
+	return status, nil
+}
+
+// zipEntrySHA256 returns the hex SHA-256 and size of the contents of f.
+func zipEntrySHA256(f *zip.File) (string, int64, error) {
+	r, err := f.Open()
+	if err != nil {
+		return "", 0, err
+	}
+	defer r.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, r)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/skndash96/lastnight-backend/internal/db"
+	"github.com/skndash96/lastnight-backend/internal/repository"
+)
+
+const (
+	// number of jobs ListTeamJobs returns
+	teamJobsListLimit = 20
+
+	// running jobs record a heartbeat this often, jobs without one for
+	// teamJobStaleAfter were interrupted
+	teamJobHeartbeat  = 30 * time.Second
+	teamJobStaleAfter = 3 * teamJobHeartbeat
+)
+
+type TeamJobResult struct {
+	db.TeamJob
+	// download URL of an export's archive, until it is collected
+	Url *url.URL
+}
+
+// StartTeamExport starts exporting the team in the background. The archive is
+// stored and can be downloaded from the job once it is done.
+func (s *TeamArchiveService) StartTeamExport(ctx context.Context, teamID, userID int32, includeObjects bool) (*db.TeamJob, error) {
+	job, err := s.createTeamJob(ctx, teamID, userID, db.TeamJobKindExport, includeObjects)
+	if err != nil {
+		return nil, err
+	}
+
+	go s.runTeamJob(job, func(ctx context.Context) (string, any, error) {
+		return s.exportTeamJob(ctx, job)
+	})
+
+	return job, nil
+}
+
+func (s *TeamArchiveService) exportTeamJob(ctx context.Context, job *db.TeamJob) (string, any, error) {
+	// the size of the archive must be known to store it
+	tmp, err := os.CreateTemp("", "team-export-*.zip")
+	if err != nil {
+		return "", nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	report, err := s.WriteTeamArchive(ctx, tmp, job.TeamID, job.IncludeObjects)
+	if err != nil {
+		return "", nil, err
+	}
+
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", nil, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return "", nil, err
+	}
+
+	key := fmt.Sprintf("exports/team_%d/%s.zip", job.TeamID, uuid.NewString())
+	if err := s.uploadSrv.uploadProvider.PutObject(ctx, key, tmp, size, "application/zip"); err != nil {
+		return "", nil, err
+	}
+
+	return key, report, nil
+}
+
+// StartTeamImport reads an archive from r and starts importing it into the team
+// in the background, as userID. The archive is kept on disk until then.
+func (s *TeamArchiveService) StartTeamImport(ctx context.Context, teamID, userID int32, r io.Reader) (*db.TeamJob, error) {
+	tmp, err := os.CreateTemp("", "team-import-*.zip")
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := s.spoolTeamArchive(tmp, r)
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+
+	job, err := s.createTeamJob(ctx, teamID, userID, db.TeamJobKindImport, false)
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+
+	go s.runTeamJob(job, func(ctx context.Context) (string, any, error) {
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+
+		// members are not added from the API, see ImportTeamArchive
+		report, err := s.ImportTeamArchive(ctx, job.TeamID, job.UserID, tmp, size, false)
+		return "", report, err
+	})
+
+	return job, nil
+}
+
+func (s *TeamArchiveService) spoolTeamArchive(tmp *os.File, r io.Reader) (int64, error) {
+	maxSize := s.uploadSrv.cfg.ArchiveMaxSize
+
+	size, err := io.Copy(tmp, io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return 0, NewSrvError(err, SrvErrInvalidInput, "failed to read the archive")
+	}
+	if size > maxSize {
+		return 0, NewSrvError(nil, SrvErrInvalidInput, fmt.Sprintf("the archive exceeds the limit of %d bytes", maxSize))
+	}
+	if size == 0 {
+		return 0, NewSrvError(nil, SrvErrInvalidInput, "the archive is empty")
+	}
+
+	return size, nil
+}
+
+// createTeamJob records a pending job, a team runs one job at a time.
+func (s *TeamArchiveService) createTeamJob(ctx context.Context, teamID, userID int32, kind db.TeamJobKind, includeObjects bool) (*db.TeamJob, error) {
+	// a job of an instance that went away must not block the team
+	if _, err := s.FailStaleTeamJobs(ctx); err != nil {
+		return nil, err
+	}
+
+	teamRepo := repository.NewTeamRepository(s.pool)
+
+	job, err := teamRepo.CreateTeamJob(ctx, teamID, userID, kind, includeObjects)
+	var repoErr *repository.RepoError
+	if errors.As(err, &repoErr) && repoErr.Kind == repository.RepoErrConflict {
+		return nil, NewSrvError(err, SrvErrConflict, "another export or import of the team is in progress")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// runTeamJob runs a job to completion and records its outcome. Jobs outlive
+// the request that started them, they are cancelled with the service's context.
+func (s *TeamArchiveService) runTeamJob(job *db.TeamJob, run func(ctx context.Context) (string, any, error)) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+
+	teamRepo := repository.NewTeamRepository(s.pool)
+
+	if err := teamRepo.StartTeamJob(ctx, job.ID); err != nil {
+		fmt.Printf("failed to start team job %d: %v\n", job.ID, err)
+		return
+	}
+
+	go s.heartbeatTeamJob(ctx, job.ID)
+
+	status := db.TeamJobStatusDone
+	errMsg := ""
+
+	storageKey, report, err := run(ctx)
+	if err != nil {
+		fmt.Printf("team job %d failed: %v\n", job.ID, err)
+		status = db.TeamJobStatusFailed
+		errMsg = err.Error()
+	}
+
+	var reportJSON []byte
+	if report != nil {
+		reportJSON, err = json.Marshal(report)
+		if err != nil {
+			fmt.Printf("failed to encode the report of team job %d: %v\n", job.ID, err)
+		}
+	}
+
+	// the outcome of a cancelled job is still recorded
+	if _, err := teamRepo.FinishTeamJob(context.WithoutCancel(ctx), job.ID, status, storageKey, reportJSON, errMsg); err != nil {
+		fmt.Printf("failed to finish team job %d: %v\n", job.ID, err)
+	}
+}
+
+// heartbeatTeamJob touches a running job until ctx is done, so other instances
+// can tell it from one whose server stopped.
+func (s *TeamArchiveService) heartbeatTeamJob(ctx context.Context, jobID int32) {
+	teamRepo := repository.NewTeamRepository(s.pool)
+
+	ticker := time.NewTicker(teamJobHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := teamRepo.TouchTeamJob(ctx, jobID); err != nil && ctx.Err() == nil {
+				fmt.Printf("failed to touch team job %d: %v\n", jobID, err)
+			}
+		}
+	}
+}
+
+// GetTeamJob returns a job of the team, with the download URL of a finished
+// export's archive.
+func (s *TeamArchiveService) GetTeamJob(ctx context.Context, teamID, jobID int32) (*TeamJobResult, error) {
+	teamRepo := repository.NewTeamRepository(s.pool)
+
+	job, err := teamRepo.GetTeamJob(ctx, teamID, jobID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, NewSrvError(err, SrvErrNotFound, "job not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := &TeamJobResult{TeamJob: job}
+
+	if job.StorageKey != "" {
+		result.Url, err = s.uploadSrv.uploadProvider.PresignGetObject(ctx, job.StorageKey)
+		if err != nil {
+			return nil, NewSrvError(err, SrvErrInternal, "failed to presign the archive")
+		}
+	}
+
+	return result, nil
+}
+
+func (s *TeamArchiveService) ListTeamJobs(ctx context.Context, teamID int32) ([]db.TeamJob, error) {
+	teamRepo := repository.NewTeamRepository(s.pool)
+	return teamRepo.ListTeamJobs(ctx, teamID, teamJobsListLimit)
+}
+
+// FailStaleTeamJobs marks jobs left pending or running by a server process that
+// stopped as failed. Jobs of running instances keep their heartbeat fresh.
+func (s *TeamArchiveService) FailStaleTeamJobs(ctx context.Context) (int64, error) {
+	teamRepo := repository.NewTeamRepository(s.pool)
+	return teamRepo.FailStaleTeamJobs(ctx, teamJobStaleAfter)
+}
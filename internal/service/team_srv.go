@@ -66,3 +66,27 @@ func (s *TeamService) JoinDefaultTeam(ctx context.Context, userID int32, userEma
 
 	return &team, nil
 }
+
+// number of notifications ListNotifications returns
+const teamNotificationsListLimit = 50
+
+// ListNotifications lists the member's latest notifications, newest first.
+func (s *TeamService) ListNotifications(ctx context.Context, membershipID int32) ([]db.TeamNotification, error) {
+	teamRepo := repository.NewTeamRepository(s.db)
+	return teamRepo.ListTeamNotifications(ctx, membershipID, teamNotificationsListLimit)
+}
+
+// MarkNotificationRead marks one of the member's notifications as read.
+func (s *TeamService) MarkNotificationRead(ctx context.Context, membershipID, notificationID int32) error {
+	teamRepo := repository.NewTeamRepository(s.db)
+
+	n, err := teamRepo.MarkTeamNotificationRead(ctx, membershipID, notificationID)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return NewSrvError(nil, SrvErrNotFound, "notification not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/skndash96/lastnight-backend/internal/db"
+	"github.com/skndash96/lastnight-backend/internal/provider"
+	"github.com/skndash96/lastnight-backend/internal/repository"
+)
+
+type UploadCheckResult struct {
+	// the team can download the file already, as a version of one of its uploads,
+	// so it need not be uploaded again
+	Exists bool
+	// the team already has the file
+	InTeam bool
+	// the team's existing ref, or the ref created for the check
+	Ref *db.UploadRef
+}
+
+// CheckUpload looks up an upload by its client-computed hash and size before
+// the file is uploaded. A hash and size are no proof the caller has the file, so
+// only files the team can download already are found, and with a name given
+// added to the team directly, like a completed upload. Files of other teams are
+// reported as missing.
+func (s *UploadService) CheckUpload(ctx context.Context, teamID, userID int32, sha256 string, size int64, name string, versionOf int32, tags []UploadTagInput) (*UploadCheckResult, error) {
+	checksum, err := normalizeChecksum(sha256)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkNewVersion(versionOf, tags); err != nil {
+		return nil, err
+	}
+
+	uploadRepo := repository.NewUploadRepository(s.pool)
+
+	upload, err := uploadRepo.GetUploadByHash(ctx, checksum, size)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &UploadCheckResult{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ref, err := uploadRepo.GetTeamUploadRef(ctx, teamID, upload.ID)
+	if err == nil {
+		return &UploadCheckResult{
+			Exists: true,
+			InTeam: true,
+			Ref:    ref,
+		}, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	reachable, err := uploadRepo.HasTeamUploadVersion(ctx, teamID, upload.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !reachable {
+		return &UploadCheckResult{}, nil
+	}
+
+	if upload.ScanStatus == db.UploadScanStatusInfected {
+		return nil, NewSrvError(nil, SrvErrInvalidInput, "Upload rejected: the file failed the malware scan")
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return &UploadCheckResult{Exists: true}, nil
+	}
+
+	settings, err := s.checkUploadPolicy(ctx, teamID, name, upload.FileMimeType, upload.FileSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkQuota(ctx, settings, teamID, userID, upload.ID, upload.FileSize); err != nil {
+		return nil, err
+	}
+
+	// the row alone is not proof the object is intact, e.g. after a failed move
+	if !s.verifyStoredUpload(ctx, upload) {
+		return &UploadCheckResult{}, nil
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, NewSrvError(err, SrvErrInternal, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	// keeps the garbage collector off the upload until the ref exists
+	upload, err = repository.NewUploadRepository(tx).ClaimUpload(ctx, checksum, size)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &UploadCheckResult{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lockQuota(ctx, tx, teamID, userID, upload.ID, upload.FileSize); err != nil {
+		return nil, err
+	}
+
+	if versionOf != 0 {
+		ref, err = addUploadVersion(ctx, tx, teamID, userID, versionOf, upload.ID, name)
+	} else {
+		ref, err = createUploadRef(ctx, tx, teamID, userID, upload.ID, name, db.UploadRefStatusActive, tags)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, NewSrvError(err, SrvErrInternal, "failed to add upload")
+	}
+
+	return &UploadCheckResult{
+		Exists: true,
+		Ref:    ref,
+	}, nil
+}
+
+// verifyStoredUpload checks the object of an upload is present with its recorded
+// size. Its hash was verified when it was stored, reading the object again on
+// every check would let any member make the server hash large files on demand.
+func (s *UploadService) verifyStoredUpload(ctx context.Context, upload *db.Upload) bool {
+	size, err := s.uploadProvider.StatObject(ctx, upload.StorageKey)
+	if err != nil {
+		fmt.Printf("failed to verify upload %d at %s: %v\n", upload.ID, upload.StorageKey, err)
+		return false
+	}
+
+	if size != upload.FileSize {
+		fmt.Printf("upload %d at %s does not match its recorded size\n", upload.ID, upload.StorageKey)
+		return false
+	}
+
+	return true
+}
+
+// normalizeChecksum accepts a SHA-256 digest as hex or base64 and returns it
+// base64 encoded, the way uploads store it.
+func normalizeChecksum(checksum string) (string, error) {
+	checksum = strings.TrimSpace(checksum)
+
+	if len(checksum) == 64 {
+		if sum, err := hex.DecodeString(checksum); err == nil {
+			return provider.EncodeChecksum(sum), nil
+		}
+	}
+
+	sum, err := base64.StdEncoding.DecodeString(checksum)
+	if err != nil || len(sum) != 32 {
+		return "", NewSrvError(err, SrvErrInvalidInput, "sha256 must be a hex or base64 encoded SHA-256 digest")
+	}
+
+	return provider.EncodeChecksum(sum), nil
+}
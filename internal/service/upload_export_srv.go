@@ -0,0 +1,229 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/skndash96/lastnight-backend/internal/db"
+)
+
+// name of the manifest written at the root of every export
+const exportManifestName = "manifest.json"
+
+// uploads are listed for an export in pages of this size
+const exportPageSize = 100
+
+// UploadExport is a set of uploads about to be streamed as a ZIP.
+type UploadExport struct {
+	TeamID  int32
+	Filters ExportFilters
+	Uploads []db.UploadItem
+	Size    int64
+}
+
+type ExportFilters struct {
+	Q        string        `json:"q,omitempty"`
+	ValueIDs []int32       `json:"value_ids,omitempty"`
+	Ranges   []RangeFilter `json:"ranges,omitempty"`
+}
+
+type exportManifest struct {
+	TeamID     int32                `json:"team_id"`
+	ExportedAt time.Time            `json:"exported_at"`
+	Filters    ExportFilters        `json:"filters"`
+	Files      []exportManifestFile `json:"files"`
+}
+
+type exportManifestFile struct {
+	// path of the file within the ZIP
+	Path         string         `json:"path"`
+	UploadID     int32          `json:"upload_id"`
+	FileName     string         `json:"file_name"`
+	FileMimeType string         `json:"file_mime_type"`
+	FileSize     int64          `json:"file_size"`
+	Version      int32          `json:"version"`
+	UploaderID   int32          `json:"uploader_id"`
+	CreatedAt    time.Time      `json:"created_at"`
+	Tags         []db.UploadTag `json:"tags"`
+}
+
+// PrepareExport lists every upload matching the same filters as ListUploads, to
+// be written with WriteExport. It fails before anything is streamed when the
+// user exports too often or the uploads exceed the export size cap.
+func (s *UploadService) PrepareExport(ctx context.Context, teamID, userID int32, filters ExportFilters) (*UploadExport, error) {
+	// exports refused below, e.g. for matching too many uploads, do not count
+	if ok, retryIn := s.exportLimiter.Check(userID); !ok {
+		return nil, exportRateLimitedError(retryIn)
+	}
+
+	export := &UploadExport{
+		TeamID:  teamID,
+		Filters: filters,
+		Uploads: []db.UploadItem{},
+	}
+
+	// pages are read from a single snapshot, uploads added or deleted meanwhile
+	// would otherwise shift them and skip or repeat uploads
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{
+		IsoLevel:   pgx.RepeatableRead,
+		AccessMode: pgx.ReadOnly,
+	})
+	if err != nil {
+		return nil, NewSrvError(err, SrvErrInternal, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	for offset := int32(0); ; offset += exportPageSize {
+		uploads, err := listUploads(ctx, tx, teamID, filters.Q, filters.ValueIDs, filters.Ranges, exportPageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, upload := range uploads {
+			export.Size += upload.FileSize
+			if export.Size > s.cfg.ExportMaxSize {
+				return nil, NewSrvError(nil, SrvErrInvalidInput, fmt.Sprintf("the matching uploads exceed the export limit of %d bytes, narrow down the filters", s.cfg.ExportMaxSize))
+			}
+		}
+		export.Uploads = append(export.Uploads, uploads...)
+
+		if len(uploads) < exportPageSize {
+			break
+		}
+	}
+
+	if len(export.Uploads) == 0 {
+		return nil, NewSrvError(nil, SrvErrNotFound, "no uploads match the filters")
+	}
+
+	// checked again, concurrent exports may have used up the limit meanwhile
+	if ok, retryIn := s.exportLimiter.Allow(userID); !ok {
+		return nil, exportRateLimitedError(retryIn)
+	}
+
+	return export, nil
+}
+
+func exportRateLimitedError(retryIn time.Duration) error {
+	return NewSrvError(nil, SrvErrRateLimited, fmt.Sprintf("too many exports, try again in %s", retryIn.Round(time.Minute)))
+}
+
+// WriteExport streams the objects of an export to w as a ZIP, named by their file
+// names, followed by a manifest with their tags. Objects are copied straight
+// from storage, nothing is buffered to disk.
+func (s *UploadService) WriteExport(ctx context.Context, w io.Writer, export *UploadExport) error {
+	zw := zip.NewWriter(w)
+
+	manifest := exportManifest{
+		TeamID:     export.TeamID,
+		ExportedAt: time.Now(),
+		Filters:    export.Filters,
+		Files:      make([]exportManifestFile, 0, len(export.Uploads)),
+	}
+
+	names := newExportNames()
+	for _, upload := range export.Uploads {
+		name := names.add(upload.FileName, upload.ID)
+
+		if err := s.writeExportFile(ctx, zw, name, upload); err != nil {
+			return err
+		}
+
+		manifest.Files = append(manifest.Files, exportManifestFile{
+			Path:         name,
+			UploadID:     upload.ID,
+			FileName:     upload.FileName,
+			FileMimeType: upload.FileMimeType,
+			FileSize:     upload.FileSize,
+			Version:      upload.Version,
+			UploaderID:   upload.UploaderID,
+			CreatedAt:    upload.CreatedAt,
+			Tags:         upload.Tags,
+		})
+	}
+
+	mw, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     exportManifestName,
+		Method:   zip.Deflate,
+		Modified: manifest.ExportedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(mw)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(manifest); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+func (s *UploadService) writeExportFile(ctx context.Context, zw *zip.Writer, name string, upload db.UploadItem) error {
+	obj, err := s.uploadProvider.GetObject(ctx, upload.StorageKey)
+	if err != nil {
+		return fmt.Errorf("failed to get %s: %w", upload.StorageKey, err)
+	}
+	defer obj.Close()
+
+	// PDFs and office documents are compressed already
+	fw, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Store,
+		Modified: upload.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(fw, obj); err != nil {
+		return fmt.Errorf("failed to export %s: %w", upload.StorageKey, err)
+	}
+
+	return nil
+}
+
+// exportNames hands out unique file names within an export, compared case
+// insensitively so they do not collide when extracted on Windows or macOS.
+type exportNames struct {
+	used map[string]bool
+}
+
+func newExportNames() *exportNames {
+	return &exportNames{
+		used: map[string]bool{exportManifestName: true},
+	}
+}
+
+// add returns name, made safe to extract, or "name (2).ext" and so on if it is
+// taken already.
+func (n *exportNames) add(name string, uploadID int32) string {
+	name = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r < 0x20 || r == 0x7f {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if name == "" || name == "." || name == ".." {
+		name = fmt.Sprintf("upload-%d", uploadID)
+	}
+
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+
+	candidate := name
+	for i := 2; n.used[strings.ToLower(candidate)]; i++ {
+		candidate = fmt.Sprintf("%s (%d)%s", base, i, ext)
+	}
+
+	n.used[strings.ToLower(candidate)] = true
+	return candidate
+}
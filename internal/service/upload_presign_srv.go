@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/skndash96/lastnight-backend/internal/db"
+	"github.com/skndash96/lastnight-backend/internal/provider"
+	"github.com/skndash96/lastnight-backend/internal/repository"
+)
+
+// recordUploadPresign remembers who a key was presigned for and what they declared
+// to upload, to be checked on completion.
+func (s *UploadService) recordUploadPresign(ctx context.Context, teamID, userID int32, key, name, mime string, size int64, presigned *provider.PresignedUpload) error {
+	uploadRepo := repository.NewUploadRepository(s.pool)
+
+	return uploadRepo.CreateUploadPresign(ctx, db.CreateUploadPresignParams{
+		StorageKey:   key,
+		TeamID:       teamID,
+		UploaderID:   userID,
+		FileName:     name,
+		FileMimeType: mime,
+		FileSize:     size,
+		ExpiresAt:    presigned.ExpiresAt,
+	})
+}
+
+// getUploadPresign returns the presign of a key issued to the user in the team.
+func getUploadPresign(ctx context.Context, d db.DBTX, teamID, userID int32, key string) (*db.UploadPresign, error) {
+	presign, err := repository.NewUploadRepository(d).GetUploadPresign(ctx, teamID, userID, key)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, NewSrvError(err, SrvErrInvalidInput, "Upload completion failed: invalid key")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return presign, nil
+}
+
+// consumeUploadPresign removes the presign of a key issued to the user in the
+// team. It is restored if tx is rolled back.
+func consumeUploadPresign(ctx context.Context, tx db.DBTX, teamID, userID int32, key string) (*db.UploadPresign, error) {
+	uploadRepo := repository.NewUploadRepository(tx)
+
+	presign, err := uploadRepo.ConsumeUploadPresign(ctx, teamID, userID, key)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, NewSrvError(err, SrvErrInvalidInput, "Upload completion failed: invalid key")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return presign, nil
+}
+
+// dropUploadPresign removes the presign of an upload that was refused, whose
+// object is deleted already.
+func (s *UploadService) dropUploadPresign(ctx context.Context, teamID, userID int32, key string) {
+	_, err := repository.NewUploadRepository(s.pool).ConsumeUploadPresign(ctx, teamID, userID, key)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		// it's not a fatal error, the garbage collector removes it once expired
+		fmt.Printf("failed to delete presign of refused upload %s: %v\n", key, err)
+	}
+}
+
+// deleteInvalidUpload removes an uploaded object that failed validation.
+func (s *UploadService) deleteInvalidUpload(ctx context.Context, key string) {
+	if err := s.uploadProvider.DeleteObject(ctx, key); err != nil {
+		// it's not a fatal error, so we can continue
+		fmt.Printf("failed to delete invalid upload %s: %v\n", key, err)
+	}
+}
+
+// isClientError reports whether err was caused by the request rather than the server.
+func isClientError(err error) bool {
+	var srvErr *SrvError
+	return errors.As(err, &srvErr) && srvErr.Kind != SrvErrInternal
+}
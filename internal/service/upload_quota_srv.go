@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/skndash96/lastnight-backend/internal/db"
+	"github.com/skndash96/lastnight-backend/internal/repository"
+)
+
+type TeamUsage struct {
+	Bytes   int64
+	Uploads int64
+	// nil when unlimited
+	TeamQuota     *int64
+	UploaderQuota *int64
+	Uploaders     []db.ListUploaderUsageRow
+}
+
+// GetUsage returns the bytes stored by a team and each of its uploaders. An
+// upload counts once per team, however often it was uploaded.
+func (s *UploadService) GetUsage(ctx context.Context, teamID int32) (*TeamUsage, error) {
+	settings, err := getTeamSettings(ctx, repository.NewTeamRepository(s.pool), teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	uploadRepo := repository.NewUploadRepository(s.pool)
+
+	usage, err := uploadRepo.GetTeamUsage(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	uploaders, err := uploadRepo.ListUploaderUsage(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TeamUsage{
+		Bytes:         usage.Bytes,
+		Uploads:       usage.Uploads,
+		TeamQuota:     settings.TeamQuota,
+		UploaderQuota: settings.UploaderQuota,
+		Uploaders:     uploaders,
+	}, nil
+}
+
+// UpdateQuotas sets the bytes a team and each uploader may store, nil means unlimited.
+// Lowering a quota below the current usage only blocks new uploads.
+func (s *UploadService) UpdateQuotas(ctx context.Context, teamID int32, teamQuota, uploaderQuota *int64) (*TeamUsage, error) {
+	if (teamQuota != nil && *teamQuota <= 0) || (uploaderQuota != nil && *uploaderQuota <= 0) {
+		return nil, NewSrvError(nil, SrvErrInvalidInput, "quotas must be positive, or null for unlimited")
+	}
+
+	teamRepo := repository.NewTeamRepository(s.pool)
+
+	if _, err := teamRepo.UpsertTeamQuotas(ctx, teamID, teamQuota, uploaderQuota); err != nil {
+		return nil, err
+	}
+
+	return s.GetUsage(ctx, teamID)
+}
+
+// checkQuota fails if storing size more bytes would exceed the team's or the
+// uploader's quota. uploadID is the stored upload of the same file, if any, which
+// adds nothing where it is counted already, or 0. It only rejects early, lockQuota
+// checks again when the ref is created.
+func (s *UploadService) checkQuota(ctx context.Context, settings *UploadSettings, teamID, userID, uploadID int32, size int64) error {
+	return checkQuotaUsage(ctx, s.pool, settings.team, teamID, userID, uploadID, size)
+}
+
+// lockQuota checks the quotas like checkQuota within the transaction that adds
+// the upload to the team. The team's settings stay locked until it ends, so
+// concurrent uploads are counted one after another.
+func lockQuota(ctx context.Context, tx db.DBTX, teamID, userID, uploadID int32, size int64) error {
+	settings, err := repository.NewTeamRepository(tx).LockTeamSettings(ctx, teamID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// teams without settings have no quotas
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return checkQuotaUsage(ctx, tx, settings, teamID, userID, uploadID, size)
+}
+
+func checkQuotaUsage(ctx context.Context, d db.DBTX, settings db.TeamSetting, teamID, userID, uploadID int32, size int64) error {
+	if settings.TeamQuota == nil && settings.UploaderQuota == nil {
+		return nil
+	}
+
+	uploadRepo := repository.NewUploadRepository(d)
+
+	// a file stored already counts once, however often it is uploaded
+	var counted db.IsUploadCountedRow
+	if uploadID != 0 {
+		c, err := uploadRepo.IsUploadCounted(ctx, teamID, userID, uploadID)
+		if err != nil {
+			return err
+		}
+		counted = *c
+	}
+
+	if settings.TeamQuota != nil && !counted.ByTeam {
+		usage, err := uploadRepo.GetTeamUsage(ctx, teamID)
+		if err != nil {
+			return err
+		}
+
+		if usage.Bytes+size > *settings.TeamQuota {
+			return NewSrvError(nil, SrvErrForbidden, fmt.Sprintf("Upload failed: the team's storage quota of %s would be exceeded", formatBytes(*settings.TeamQuota)))
+		}
+	}
+
+	if settings.UploaderQuota != nil && !counted.ByUploader {
+		usage, err := uploadRepo.GetUploaderUsage(ctx, teamID, userID)
+		if err != nil {
+			return err
+		}
+
+		if usage.Bytes+size > *settings.UploaderQuota {
+			return NewSrvError(nil, SrvErrForbidden, fmt.Sprintf("Upload failed: your storage quota of %s would be exceeded", formatBytes(*settings.UploaderQuota)))
+		}
+	}
+
+	return nil
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/skndash96/lastnight-backend/internal/db"
+	"github.com/skndash96/lastnight-backend/internal/repository"
+)
+
+// UpdateUploadRef renames an upload ref and replaces its tags. A nil name or nil
+// tags leaves them unchanged, an empty tags slice removes every tag.
+func (s *UploadService) UpdateUploadRef(ctx context.Context, teamID, userID int32, role db.TeamUserRole, refID int32, name *string, tags []UploadTagInput) (*db.UploadRef, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, NewSrvError(err, SrvErrInternal, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	uploadRepo := repository.NewUploadRepository(tx)
+
+	ref, err := getEditableUploadRef(ctx, tx, teamID, userID, role, refID)
+	if err != nil {
+		return nil, err
+	}
+
+	if name != nil {
+		trimmed := strings.TrimSpace(*name)
+		if trimmed == "" {
+			return nil, NewSrvError(nil, SrvErrInvalidInput, "file name cannot be empty")
+		}
+
+		ref, err = uploadRepo.RenameUploadRef(ctx, ref.ID, trimmed)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if tags != nil {
+		tagRepo := repository.NewTagRepo(tx)
+
+		resolved, err := resolveUploadTags(ctx, tagRepo, teamID, tags)
+		if err != nil {
+			return nil, err
+		}
+
+		valueIDs := make([]int32, len(resolved))
+		for i, tag := range resolved {
+			valueIDs[i] = tag[1]
+		}
+
+		if err := checkTagValueConflicts(ctx, tagRepo, valueIDs); err != nil {
+			return nil, err
+		}
+
+		if err := uploadRepo.DeleteUploadRefTags(ctx, ref.ID); err != nil {
+			return nil, err
+		}
+
+		for _, tag := range resolved {
+			if err := uploadRepo.CreateUploadRefTag(ctx, ref.ID, tag[0], tag[1]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, NewSrvError(err, SrvErrInternal, "failed to update upload")
+	}
+
+	return ref, nil
+}
+
+// DeleteUploadRef removes an upload ref and its versions from the team. Uploads
+// no other ref or version points at are left for garbage collection.
+func (s *UploadService) DeleteUploadRef(ctx context.Context, teamID, userID int32, role db.TeamUserRole, refID int32) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return NewSrvError(err, SrvErrInternal, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	uploadRepo := repository.NewUploadRepository(tx)
+
+	ref, err := getEditableUploadRef(ctx, tx, teamID, userID, role, refID)
+	if err != nil {
+		return err
+	}
+
+	if err := uploadRepo.DeleteUploadRefTags(ctx, ref.ID); err != nil {
+		return err
+	}
+
+	uploadIDs, err := uploadRepo.DeleteUploadVersions(ctx, ref.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := uploadRepo.DeleteUploadRef(ctx, ref.ID); err != nil {
+		return err
+	}
+
+	if !slices.Contains(uploadIDs, ref.UploadID) {
+		uploadIDs = append(uploadIDs, ref.UploadID)
+	}
+
+	orphans := []int32{}
+	for _, uploadID := range uploadIDs {
+		orphaned, err := uploadRepo.MarkUploadOrphaned(ctx, uploadID)
+		if err != nil {
+			return err
+		}
+		if orphaned {
+			orphans = append(orphans, uploadID)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return NewSrvError(err, SrvErrInternal, "failed to delete upload")
+	}
+
+	for _, uploadID := range orphans {
+		fmt.Printf("upload %d has no refs left, scheduled for garbage collection\n", uploadID)
+	}
+
+	return nil
+}
+
+// getEditableUploadRef locks an upload ref of the team for editing. Uploaders
+// may edit their own active refs, mods any ref.
+func getEditableUploadRef(ctx context.Context, tx db.DBTX, teamID, userID int32, role db.TeamUserRole, refID int32) (*db.UploadRef, error) {
+	uploadRepo := repository.NewUploadRepository(tx)
+
+	ref, err := uploadRepo.GetUploadRef(ctx, teamID, refID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, NewSrvError(err, SrvErrNotFound, "upload not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if role == db.TeamUserRoleMod {
+		return ref, nil
+	}
+
+	if ref.UploaderID != userID {
+		return nil, NewSrvError(nil, SrvErrForbidden, "only the uploader or a mod can edit this upload")
+	}
+
+	// rejected uploads stay for mods to review
+	if ref.Status != db.UploadRefStatusActive {
+		return nil, NewSrvError(nil, SrvErrForbidden, "only a mod can edit a rejected upload")
+	}
+
+	return ref, nil
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/skndash96/lastnight-backend/internal/config"
+	"github.com/skndash96/lastnight-backend/internal/db"
+	"github.com/skndash96/lastnight-backend/internal/provider"
+	"github.com/skndash96/lastnight-backend/internal/repository"
+	"github.com/skndash96/lastnight-backend/internal/scanner"
+)
+
+const quarantinePrefix = "quarantine/"
+
+type scanResult struct {
+	Status    db.UploadScanStatus
+	Signature string
+	ScannedAt *time.Time
+}
+
+func newScanner(cfg config.UploadConfig) scanner.Scanner {
+	if cfg.ClamdAddress == "" {
+		return scanner.Noop{}
+	}
+
+	return &scanner.Clamd{
+		Network: cfg.ClamdNetwork,
+		Address: cfg.ClamdAddress,
+		Timeout: cfg.ScanTimeout,
+	}
+}
+
+// scanUpload scans an uploaded object, reusing the result of an identical
+// upload if it was scanned before.
+func (s *UploadService) scanUpload(ctx context.Context, key string, info *provider.UploadInfo) (*scanResult, error) {
+	uploadRepo := repository.NewUploadRepository(s.pool)
+
+	existing, err := uploadRepo.GetUploadByHash(ctx, info.SHA256, info.Size)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	if err == nil && existing.ScanStatus != db.UploadScanStatusUnscanned {
+		return &scanResult{
+			Status:    existing.ScanStatus,
+			Signature: existing.ScanSignature,
+			ScannedAt: existing.ScannedAt,
+		}, nil
+	}
+
+	r, err := s.uploadProvider.GetObject(ctx, key)
+	if err != nil {
+		return nil, NewSrvError(err, SrvErrInternal, fmt.Sprintf("failed to read %s", key))
+	}
+	defer r.Close()
+
+	result, err := s.scanner.Scan(ctx, r)
+	if errors.Is(err, scanner.ErrStreamTooLarge) {
+		return nil, NewSrvError(err, SrvErrInvalidInput, "Upload failed: the file is too large to be scanned for malware")
+	}
+	if err != nil {
+		// the tmp object is kept so the client can retry
+		return nil, NewSrvError(err, SrvErrInternal, "failed to scan upload")
+	}
+
+	if !result.Scanned {
+		return &scanResult{Status: db.UploadScanStatusUnscanned}, nil
+	}
+
+	now := time.Now()
+	status := db.UploadScanStatusClean
+	if result.Infected {
+		status = db.UploadScanStatusInfected
+	}
+
+	return &scanResult{
+		Status:    status,
+		Signature: result.Signature,
+		ScannedAt: &now,
+	}, nil
+}
+
+// ListRejectedUploads lists the team's uploads that failed the malware scan.
+func (s *UploadService) ListRejectedUploads(ctx context.Context, teamID int32) ([]db.ListRejectedUploadsRow, error) {
+	uploadRepo := repository.NewUploadRepository(s.pool)
+
+	return uploadRepo.ListRejectedUploads(ctx, teamID)
+}
+
+// NotificationUploadRejected notifies mods of an upload rejected by the malware scan
+const NotificationUploadRejected = "upload_rejected"
+
+// notifyRejectedUpload tells the team's mods about a rejected upload.
+func notifyRejectedUpload(ctx context.Context, tx db.DBTX, ref *db.UploadRef, signature string) error {
+	teamRepo := repository.NewTeamRepository(tx)
+
+	message := fmt.Sprintf("%s was rejected by the malware scan: %s", ref.FileName, signature)
+	if _, err := teamRepo.CreateModNotifications(ctx, ref.TeamID, NotificationUploadRejected, ref.ID, message); err != nil {
+		return NewSrvError(err, SrvErrInternal, "failed to notify mods of a rejected upload")
+	}
+	return nil
+}
+
+// quarantineStoredUpload records that an upload stored before it was scanned is
+// infected: its refs are rejected in every team, and its object is to be moved
+// to the quarantined key by moveToQuarantine once the transaction committed.
+func quarantineStoredUpload(ctx context.Context, tx db.DBTX, upload *db.GetOrCreateUploadRow) error {
+	uploadRepo := repository.NewUploadRepository(tx)
+
+	upload.StorageKey = quarantineKey(upload.StorageKey)
+	if err := uploadRepo.QuarantineUpload(ctx, upload.ID, upload.StorageKey); err != nil {
+		return err
+	}
+
+	refs, err := uploadRepo.RejectUploadRefs(ctx, upload.ID)
+	if err != nil {
+		return err
+	}
+
+	for i := range refs {
+		if err := notifyRejectedUpload(ctx, tx, &refs[i], upload.ScanSignature); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// moveToQuarantine moves the object of an upload found infected after it was
+// stored at key.
+func (s *UploadService) moveToQuarantine(ctx context.Context, key string) {
+	// TODO: Retry worker
+	if err := s.uploadProvider.MoveObject(ctx, quarantineKey(key), key); err != nil {
+		fmt.Printf("FATAL: failed to move upload from %s to quarantine: %v\n", key, err)
+	}
+}
+
+func quarantineKey(key string) string {
+	return quarantinePrefix + key
+}
+
+func isQuarantined(key string) bool {
+	return strings.HasPrefix(key, quarantinePrefix)
+}
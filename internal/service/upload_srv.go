@@ -1,27 +1,57 @@
 package service
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/url"
+	"os"
 	"path"
 	"strings"
+	"sync"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/skndash96/lastnight-backend/internal/config"
+	"github.com/skndash96/lastnight-backend/internal/db"
+	"github.com/skndash96/lastnight-backend/internal/filetype"
+	"github.com/skndash96/lastnight-backend/internal/pdf"
 	"github.com/skndash96/lastnight-backend/internal/provider"
 	"github.com/skndash96/lastnight-backend/internal/repository"
+	"github.com/skndash96/lastnight-backend/internal/scanner"
 )
 
 type UploadService struct {
+	// uploads are processed in the background until ctx is cancelled
+	ctx            context.Context
+	cfg            config.UploadConfig
 	pool           *pgxpool.Pool
 	uploadProvider provider.UploadProvider
+	renderer       pdf.Renderer
+	textExtractor  pdf.TextExtractor
+	scanner        scanner.Scanner
+	exportLimiter  *rateLimiter
+	// uploads being processed in the background
+	processing sync.WaitGroup
+	// one slot per PDF being processed, bounding the renderers running at once
+	processingSlots chan struct{}
 }
 
-func NewUploadService(uploadProvider provider.UploadProvider, pool *pgxpool.Pool) *UploadService {
+func NewUploadService(ctx context.Context, cfg config.UploadConfig, uploadProvider provider.UploadProvider, pool *pgxpool.Pool) *UploadService {
 	return &UploadService{
+		ctx:            ctx,
+		cfg:            cfg,
 		pool:           pool,
 		uploadProvider: uploadProvider,
+		renderer:       &pdf.PopplerRenderer{Path: cfg.PdftoppmPath},
+		textExtractor:  &pdf.PopplerTextExtractor{Path: cfg.PdftotextPath, MaxBytes: maxExtractedText},
+		scanner:        newScanner(cfg),
+		exportLimiter:  newRateLimiter(cfg.ExportRateLimit, cfg.ExportRateWindow),
+
+		processingSlots: make(chan struct{}, max(cfg.ProcessingWorkers, 1)),
 	}
 }
 
@@ -30,59 +60,292 @@ type PresignUploadResult struct {
 	Fields map[string]string
 }
 
-func (s *UploadService) PresignUpload(ctx context.Context, teamID int32, name, mimeType string, size int64) (*PresignUploadResult, error) {
-	// Allow only application/* mime types
-	// TODO: Support image/* mime types while combining images to PDF if done so
-	if !strings.HasPrefix(mimeType, "application/") {
-		return nil, NewSrvError(nil, SrvErrInvalidInput, "Upload presign failed: Only application/* mime types are allowed.")
+func (s *UploadService) PresignUpload(ctx context.Context, teamID, userID int32, name, mimeType string, size int64) (*PresignUploadResult, error) {
+	// image/* uploads go through PresignImageUpload and are combined into a PDF
+	settings, err := s.checkUploadPolicy(ctx, teamID, name, mimeType, size)
+	if err != nil {
+		return nil, err
+	}
+
+	// checked again on completion, with the actual size
+	if err := s.checkQuota(ctx, settings, teamID, userID, 0, size); err != nil {
+		return nil, err
 	}
 
 	key := generateTmpObjectKey(teamID, name)
-	url, fields, err := s.uploadProvider.PresignUpload(ctx, key, name, mimeType, size)
+	presigned, err := s.uploadProvider.PresignUpload(ctx, key, name, mimeType, size, settings.maxFileSize())
+	if errors.Is(err, provider.ErrFileTooLarge) {
+		return nil, NewSrvError(err, SrvErrInvalidInput, "Upload presign failed: file is too large")
+	}
 	if err != nil {
 		// TODO: Handle error properly
 		return nil, NewSrvError(err, SrvErrInternal, fmt.Sprintf("failed to presign upload for file %s", name))
 	}
 
+	if err := s.recordUploadPresign(ctx, teamID, userID, key, name, mimeType, size, presigned); err != nil {
+		return nil, err
+	}
+
 	return &PresignUploadResult{
-		Url:    url,
-		Fields: fields,
+		Url:    presigned.Url,
+		Fields: presigned.Fields,
 	}, nil
 }
 
-func (s *UploadService) CompleteUpload(ctx context.Context, teamID, userID int32, tmpKey, name, mime string, tags [][]int32) error {
+// uploadClaim removes what an upload is completed from, e.g. its presign, in
+// the transaction that finalizes it, so a concurrent completion fails.
+type uploadClaim func(tx db.DBTX) error
+
+// UploadTagInput references either an existing value by ValueID, or a free-form
+// Value which is created on the fly for open keys.
+type UploadTagInput struct {
+	KeyID   int32
+	ValueID int32
+	Value   string
+}
+
+// CompleteUpload processes a file uploaded with a presigned POST. Only the user
+// the upload was presigned for can complete it, once. With versionOf, the file
+// is added as the next version of that upload ref instead of a new one.
+func (s *UploadService) CompleteUpload(ctx context.Context, teamID, userID int32, tmpKey, name string, versionOf int32, tags []UploadTagInput) error {
+	settings, err := s.GetUploadSettings(ctx, teamID)
+	if err != nil {
+		return err
+	}
+
+	// the name may differ from the one the upload was presigned with
+	if err := settings.checkExtension(name); err != nil {
+		return err
+	}
+
+	presign, err := getUploadPresign(ctx, s.pool, teamID, userID, tmpKey)
+	if err != nil {
+		return err
+	}
+
+	// the presign is consumed once the upload is finalized, no transaction is
+	// held open while the file is validated
+	err = s.completeUpload(ctx, settings, teamID, userID, tmpKey, name, presign.FileMimeType, presign.FileSize, versionOf, tags, func(tx db.DBTX) error {
+		_, err := consumeUploadPresign(ctx, tx, teamID, userID, tmpKey)
+		return err
+	})
+	// the presign is kept for a retry, unless the upload itself was refused
+	if isClientError(err) {
+		s.dropUploadPresign(ctx, teamID, userID, tmpKey)
+	}
+
+	return err
+}
+
+// completeUpload checks an uploaded object against the size and content type it
+// was declared with, then validates and finalizes it.
+func (s *UploadService) completeUpload(ctx context.Context, settings *UploadSettings, teamID, userID int32, tmpKey, name, declaredMime string, declaredSize int64, versionOf int32, tags []UploadTagInput, claim uploadClaim) error {
 	info, err := s.uploadProvider.GetUploadInfo(ctx, tmpKey)
 	if err != nil {
 		return NewSrvError(err, SrvErrInternal, fmt.Sprintf("failed to get upload info for %s", tmpKey))
 	}
 
+	if info.Size != declaredSize {
+		s.deleteInvalidUpload(ctx, tmpKey)
+		return NewSrvError(nil, SrvErrInvalidInput, fmt.Sprintf("Upload completion failed: uploaded %d bytes, expected %d", info.Size, declaredSize))
+	}
+
+	// the client's mime type is only trusted for the POST policy
+	mime, err := s.validateUpload(ctx, settings, tmpKey, info)
+	if err != nil {
+		return err
+	}
+
+	if !filetype.Matches(mime, declaredMime) {
+		s.deleteInvalidUpload(ctx, tmpKey)
+		return NewSrvError(nil, SrvErrInvalidInput, fmt.Sprintf("Upload completion failed: file is %s, not %s", mime, declaredMime))
+	}
+
+	return s.finalizeUpload(ctx, settings, teamID, userID, tmpKey, name, mime, info, versionOf, tags, claim)
+}
+
+// Upload streams a file through the server instead of a presigned POST, computing
+// its size and hash on the way, then completes it like CompleteUpload.
+func (s *UploadService) Upload(ctx context.Context, teamID, userID int32, name string, r io.Reader, versionOf int32, tags []UploadTagInput) error {
+	return s.upload(ctx, teamID, userID, name, r, versionOf, tags, "")
+}
+
+// upload is Upload, refusing the file before it is finalized when checksum is
+// set and the hex SHA-256 computed on the way differs.
+func (s *UploadService) upload(ctx context.Context, teamID, userID int32, name string, r io.Reader, versionOf int32, tags []UploadTagInput, checksum string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return NewSrvError(nil, SrvErrInvalidInput, "Upload failed: file name is required")
+	}
+
+	settings, err := s.GetUploadSettings(ctx, teamID)
+	if err != nil {
+		return err
+	}
+
+	if err := settings.checkExtension(name); err != nil {
+		return err
+	}
+
+	// the content type stored with the object, validateUpload sniffs it again
+	br := bufio.NewReaderSize(r, sniffLength)
+	head, err := br.Peek(sniffLength)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return NewSrvError(err, SrvErrInvalidInput, "Upload failed: could not read file")
+	}
+
+	tmpKey := generateTmpObjectKey(teamID, name)
+	info, err := s.uploadProvider.PutObjectStream(ctx, tmpKey, br, filetype.Detect(head), settings.maxFileSize())
+	if errors.Is(err, provider.ErrFileTooLarge) {
+		return NewSrvError(err, SrvErrInvalidInput, "Upload failed: file is too large")
+	}
+	if err != nil {
+		return NewSrvError(err, SrvErrInternal, fmt.Sprintf("failed to store upload %s", name))
+	}
+
+	if info.Size == 0 {
+		if err := s.uploadProvider.DeleteObject(ctx, tmpKey); err != nil {
+			fmt.Printf("failed to delete empty upload %s: %v\n", tmpKey, err)
+		}
+		return NewSrvError(nil, SrvErrInvalidInput, "Upload failed: file is empty")
+	}
+
+	if checksum != "" && !strings.EqualFold(info.SHA256, checksum) {
+		s.deleteInvalidUpload(ctx, tmpKey)
+		return NewSrvError(nil, SrvErrInvalidInput, "Upload failed: the file does not match its checksum")
+	}
+
+	mime, err := s.validateUpload(ctx, settings, tmpKey, info)
+	if err != nil {
+		return err
+	}
+
+	return s.finalizeUpload(ctx, settings, teamID, userID, tmpKey, name, mime, info, versionOf, tags, nil)
+}
+
+// finalizeUpload records an object uploaded to tmpKey, deduplicating by (hash, size),
+// and moves it to its permanent key. It is added to the team as a new upload ref,
+// or as the next version of the ref versionOf. A claim, if any, runs first in the
+// same transaction.
+func (s *UploadService) finalizeUpload(ctx context.Context, settings *UploadSettings, teamID, userID int32, tmpKey, name, mime string, info *provider.UploadInfo, versionOf int32, tags []UploadTagInput, claim uploadClaim) error {
 	newKey := convertTmpKey(tmpKey)
 	if newKey == "" {
 		return NewSrvError(nil, SrvErrInvalidInput, "Upload completion failed: invalid key")
 	}
 
+	if err := checkNewVersion(versionOf, tags); err != nil {
+		s.deleteInvalidUpload(ctx, tmpKey)
+		return err
+	}
+
+	// an empty hash would make every upload of the same size a duplicate
+	if info.SHA256 == "" {
+		return NewSrvError(nil, SrvErrInternal, fmt.Sprintf("Upload completion failed: no checksum for %s", tmpKey))
+	}
+
+	// a duplicate of a file the team stores already adds nothing to its usage
+	var existingID int32
+	existing, err := repository.NewUploadRepository(s.pool).GetUploadByHash(ctx, info.SHA256, info.Size)
+	if err == nil {
+		existingID = existing.ID
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+
+	if err := s.checkQuota(ctx, settings, teamID, userID, existingID, info.Size); err != nil {
+		if err := s.uploadProvider.DeleteObject(ctx, tmpKey); err != nil {
+			// it's not a fatal error, so we can continue
+			fmt.Printf("failed to delete upload over quota %s: %v\n", tmpKey, err)
+		}
+		return err
+	}
+
+	scan, err := s.scanUpload(ctx, tmpKey, info)
+	if err != nil {
+		if isClientError(err) {
+			s.deleteInvalidUpload(ctx, tmpKey)
+		}
+		return err
+	}
+
+	// infected files never reach the permanent key
+	if scan.Status == db.UploadScanStatusInfected {
+		newKey = quarantineKey(newKey)
+	}
+
 	tx, err := s.pool.Begin(ctx)
 	if err != nil {
 		return NewSrvError(err, SrvErrInternal, "failed to begin transaction")
 	}
 	defer tx.Rollback(ctx)
 
+	// fails if a concurrent completion finalized the object already, which is
+	// left alone then
+	if claim != nil {
+		if err := claim(tx); err != nil {
+			return err
+		}
+	}
+
 	uploadRepo := repository.NewUploadRepository(tx)
 
 	// (hash, size) duplication check happens here
-	upload, err := uploadRepo.GetOrCreateUpload(ctx, newKey, mime, info.Size, info.SHA256)
+	upload, err := uploadRepo.GetOrCreateUpload(ctx, newKey, mime, info.Size, info.SHA256, scan.Status, scan.Signature, scan.ScannedAt)
 	if err != nil {
 		return NewSrvError(err, SrvErrInternal, fmt.Sprintf("failed to create upload for %s", newKey))
 	}
 
-	uploadRef, err := uploadRepo.CreateUploadRef(ctx, upload.ID, teamID, userID, name)
+	// concurrent uploads may have used up the quota since it was checked
+	if err := lockQuota(ctx, tx, teamID, userID, upload.ID, info.Size); err != nil {
+		if isClientError(err) {
+			s.deleteInvalidUpload(ctx, tmpKey)
+		}
+		return err
+	}
+
+	// an upload stored before it was ever scanned is quarantined now, in every team
+	storedKey := ""
+	if !upload.Created && upload.ScanStatus == db.UploadScanStatusInfected && !isQuarantined(upload.StorageKey) {
+		storedKey = upload.StorageKey
+		if err := quarantineStoredUpload(ctx, tx, upload); err != nil {
+			return err
+		}
+	}
+
+	refStatus := db.UploadRefStatusActive
+	if upload.ScanStatus == db.UploadScanStatusInfected {
+		refStatus = db.UploadRefStatusRejected
+
+		// only new upload refs are kept as rejected for mods to review
+		if versionOf != 0 {
+			if storedKey != "" {
+				if err := tx.Commit(ctx); err != nil {
+					return NewSrvError(err, SrvErrInternal, "failed to complete upload")
+				}
+				s.moveToQuarantine(ctx, storedKey)
+			}
+			s.deleteInvalidUpload(ctx, tmpKey)
+			return NewSrvError(nil, SrvErrInvalidInput, "Upload rejected: the file failed the malware scan")
+		}
+	}
+
+	var uploadRef *db.UploadRef
+	if versionOf != 0 {
+		uploadRef, err = addUploadVersion(ctx, tx, teamID, userID, versionOf, upload.ID, name)
+	} else {
+		uploadRef, err = createUploadRef(ctx, tx, teamID, userID, upload.ID, name, refStatus, tags)
+	}
 	if err != nil {
-		return NewSrvError(err, SrvErrInternal, fmt.Sprintf("failed to create upload reference for %s", newKey))
+		// the upload row is rolled back, its object is left at tmpKey
+		if isClientError(err) {
+			s.deleteInvalidUpload(ctx, tmpKey)
+		}
+		return err
 	}
 
-	for _, tag := range tags {
-		if err := uploadRepo.CreateUploadRefTag(ctx, uploadRef.ID, tag[0], tag[1]); err != nil {
-			return NewSrvError(err, SrvErrInternal, fmt.Sprintf("failed to create upload tag for %s", newKey))
+	if refStatus == db.UploadRefStatusRejected {
+		if err := notifyRejectedUpload(ctx, tx, uploadRef, upload.ScanSignature); err != nil {
+			return err
 		}
 	}
 
@@ -90,6 +353,10 @@ func (s *UploadService) CompleteUpload(ctx context.Context, teamID, userID int32
 		return NewSrvError(err, SrvErrInternal, "failed to complete upload")
 	}
 
+	if storedKey != "" {
+		s.moveToQuarantine(ctx, storedKey)
+	}
+
 	if upload.Created == false {
 		fmt.Printf("Upload already exists, using duplicate: %s\n", upload.StorageKey)
 		if err := s.uploadProvider.DeleteObject(ctx, tmpKey); err != nil {
@@ -101,14 +368,241 @@ func (s *UploadService) CompleteUpload(ctx context.Context, teamID, userID int32
 		err = s.uploadProvider.MoveObject(ctx, newKey, tmpKey)
 		if err != nil {
 			fmt.Printf("FATAL: failed to move upload from %s to %s: %v\n", tmpKey, newKey, err)
+		} else if mime == filetype.PDF && refStatus == db.UploadRefStatusActive {
+			// TODO: push to queue
+			s.processing.Add(1)
+			go func() {
+				defer s.processing.Done()
+				s.processUpload(s.ctx, upload.ID, newKey, info.Size)
+			}()
 		}
 	}
 
-	// TODO: push to queue
+	if refStatus == db.UploadRefStatusRejected {
+		return NewSrvError(nil, SrvErrInvalidInput, "Upload rejected: the file failed the malware scan")
+	}
 
 	return nil
 }
 
+// createUploadRef adds an upload to the team under name, with the given tags,
+// as version 1 of the new ref.
+func createUploadRef(ctx context.Context, tx db.DBTX, teamID, userID, uploadID int32, name string, status db.UploadRefStatus, tags []UploadTagInput) (*db.UploadRef, error) {
+	uploadRepo := repository.NewUploadRepository(tx)
+
+	uploadRef, err := uploadRepo.CreateUploadRef(ctx, uploadID, teamID, userID, name, status)
+	if err != nil {
+		return nil, NewSrvError(err, SrvErrInternal, fmt.Sprintf("failed to create upload reference for %s", name))
+	}
+
+	if _, err := uploadRepo.CreateUploadVersion(ctx, uploadRef.ID, uploadID, userID, name); err != nil {
+		return nil, err
+	}
+
+	tagRepo := repository.NewTagRepo(tx)
+
+	resolved, err := resolveUploadTags(ctx, tagRepo, teamID, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	valueIDs := make([]int32, len(resolved))
+	for i, tag := range resolved {
+		valueIDs[i] = tag[1]
+	}
+
+	if err := checkTagValueConflicts(ctx, tagRepo, valueIDs); err != nil {
+		return nil, err
+	}
+
+	for _, tag := range resolved {
+		if err := uploadRepo.CreateUploadRefTag(ctx, uploadRef.ID, tag[0], tag[1]); err != nil {
+			return nil, NewSrvError(err, SrvErrInternal, fmt.Sprintf("failed to create upload tag for %s", name))
+		}
+	}
+
+	return uploadRef, nil
+}
+
+// WaitProcessing waits for the previews and text of finished uploads, for
+// commands that would otherwise exit before they are done.
+func (s *UploadService) WaitProcessing() {
+	s.processing.Wait()
+}
+
+// processUpload renders previews of a finalized PDF and extracts its text for search.
+// Failures are logged, the upload itself stays valid.
+func (s *UploadService) processUpload(ctx context.Context, uploadID int32, storageKey string, size int64) {
+	release, err := s.acquireProcessing(ctx)
+	if err != nil {
+		fmt.Printf("skipped processing %s: %v\n", storageKey, err)
+		return
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.ProcessingTimeout)
+	defer cancel()
+
+	input, err := s.downloadObject(ctx, storageKey, size)
+	if err != nil {
+		fmt.Printf("failed to read %s for processing: %v\n", storageKey, err)
+		return
+	}
+	defer os.Remove(input)
+
+	if err := s.generateDerivatives(ctx, uploadID, storageKey, input); err != nil {
+		fmt.Printf("failed to generate previews for %s: %v\n", storageKey, err)
+	}
+
+	if err := s.extractText(ctx, uploadID, input); err != nil {
+		fmt.Printf("failed to extract text of %s: %v\n", storageKey, err)
+	}
+}
+
+// acquireProcessing waits for a free processing slot, the returned function
+// frees it again.
+func (s *UploadService) acquireProcessing(ctx context.Context) (func(), error) {
+	select {
+	case s.processingSlots <- struct{}{}:
+		return func() { <-s.processingSlots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// downloadObject copies an object of at most maxSize bytes to a temporary file
+// for the PDF tools, the caller removes it.
+func (s *UploadService) downloadObject(ctx context.Context, key string, maxSize int64) (string, error) {
+	r, err := s.uploadProvider.GetObject(ctx, key)
+	if err != nil {
+		return "", NewSrvError(err, SrvErrInternal, fmt.Sprintf("failed to read %s", key))
+	}
+	defer r.Close()
+
+	f, err := os.CreateTemp("", "upload-*.pdf")
+	if err != nil {
+		return "", NewSrvError(err, SrvErrInternal, fmt.Sprintf("failed to read %s", key))
+	}
+	defer f.Close()
+
+	n, err := io.Copy(f, io.LimitReader(r, maxSize+1))
+	if err == nil {
+		err = f.Close()
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", NewSrvError(err, SrvErrInternal, fmt.Sprintf("failed to read %s", key))
+	}
+
+	if n > maxSize {
+		os.Remove(f.Name())
+		return "", NewSrvError(provider.ErrFileTooLarge, SrvErrInvalidInput, fmt.Sprintf("%s is too large", key))
+	}
+
+	return f.Name(), nil
+}
+
+// resolveUploadTags maps tag inputs to (keyID, valueID) pairs, creating
+// free-form values of open keys as needed. Values chosen by ID must be approved
+// values of the given key.
+func resolveUploadTags(ctx context.Context, tagRepo *repository.TagRepo, teamID int32, tags []UploadTagInput) ([][]int32, error) {
+	out := make([][]int32, 0, len(tags))
+
+	for _, tag := range tags {
+		tagKey, err := tagRepo.GetTagKeyByID(ctx, teamID, tag.KeyID)
+		if err != nil {
+			return nil, NewSrvError(err, SrvErrInvalidInput, fmt.Sprintf("tag key %d not found", tag.KeyID))
+		}
+
+		if tagKey.ArchivedAt != nil {
+			return nil, NewSrvError(nil, SrvErrInvalidInput, fmt.Sprintf("tag key %s is archived", tagKey.Name))
+		}
+
+		if tag.ValueID != 0 {
+			tagValue, err := tagRepo.GetTagValueByID(ctx, teamID, tag.ValueID)
+			if err != nil || tagValue.KeyID != tagKey.ID {
+				return nil, NewSrvError(err, SrvErrInvalidInput, fmt.Sprintf("tag value %d not found for tag key %s", tag.ValueID, tagKey.Name))
+			}
+
+			// pending values are only attached by typing them on open keys
+			if !tagValue.Approved {
+				return nil, NewSrvError(nil, SrvErrInvalidInput, fmt.Sprintf("tag value %s is pending approval", tagValue.Value))
+			}
+
+			out = append(out, []int32{tagKey.ID, tagValue.ID})
+			continue
+		}
+
+		if tagKey.Mode != db.TagKeyModeOpen {
+			return nil, NewSrvError(nil, SrvErrInvalidInput, fmt.Sprintf("tag key %s only accepts predefined values", tagKey.Name))
+		}
+
+		value, numValue, err := canonicalizeTagValue(tagKey.DataType, tag.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		tagValue, err := tagRepo.GetOrCreateTagValue(ctx, tagKey.ID, value, numValue, !tagKey.RequiresApproval)
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, []int32{tagKey.ID, tagValue.ID})
+	}
+
+	return out, nil
+}
+
+type RangeFilter = repository.RangeFilter
+
+func (s *UploadService) ListUploads(ctx context.Context, teamID int32, q string, valueIDs []int32, ranges []RangeFilter, limit, offset int32) ([]db.UploadItem, error) {
+	return listUploads(ctx, s.pool, teamID, q, valueIDs, ranges, limit, offset)
+}
+
+func listUploads(ctx context.Context, d db.DBTX, teamID int32, q string, valueIDs []int32, ranges []RangeFilter, limit, offset int32) ([]db.UploadItem, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+
+	tagRepo := repository.NewTagRepo(d)
+
+	// ranges are only meaningful on number-typed keys of this team
+	for _, rg := range ranges {
+		if rg.Min > rg.Max {
+			return nil, NewSrvError(nil, SrvErrInvalidInput, "range minimum cannot exceed maximum")
+		}
+
+		tagKey, err := tagRepo.GetTagKeyByID(ctx, teamID, rg.KeyID)
+		if err != nil {
+			return nil, NewSrvError(err, SrvErrInvalidInput, fmt.Sprintf("tag key %d not found", rg.KeyID))
+		}
+
+		if tagKey.DataType != db.TagDataTypeNumber {
+			return nil, NewSrvError(nil, SrvErrInvalidInput, fmt.Sprintf("tag key %s is not a number", tagKey.Name))
+		}
+	}
+
+	uploadRepo := repository.NewUploadRepository(d)
+	uploads, err := uploadRepo.ListUploads(ctx, teamID, strings.TrimSpace(q), uniqueIDs(valueIDs), ranges, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return uploads, nil
+}
+
+func uniqueIDs(ids []int32) []int32 {
+	seen := make(map[int32]bool, len(ids))
+	out := []int32{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
 func generateTmpObjectKey(teamID int32, originalName string) string {
 	ext := path.Ext(originalName)
 	if len(ext) > 10 {
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/skndash96/lastnight-backend/internal/repository"
+)
+
+// Postgres refuses tsvectors over 1MB, so extracted text is capped well below it
+const maxExtractedText = 512 * 1024
+
+// extractText stores the text of a PDF for full-text search. The text belongs
+// to the upload, so every team referencing a deduplicated upload shares it.
+func (s *UploadService) extractText(ctx context.Context, uploadID int32, input string) error {
+	text, err := s.textExtractor.ExtractText(ctx, input)
+	if err != nil {
+		return err
+	}
+
+	uploadRepo := repository.NewUploadRepository(s.pool)
+
+	return uploadRepo.UpsertUploadText(ctx, uploadID, cleanExtractedText(text))
+}
+
+// cleanExtractedText makes text storable in Postgres: valid UTF-8 without NUL
+// bytes, truncated on a rune boundary.
+func cleanExtractedText(text string) string {
+	text = strings.ToValidUTF8(text, "")
+	text = strings.ReplaceAll(text, "\x00", "")
+
+	if len(text) > maxExtractedText {
+		text = text[:maxExtractedText]
+		for !utf8.ValidString(text) {
+			text = text[:len(text)-1]
+		}
+	}
+
+	return text
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/skndash96/lastnight-backend/internal/db"
+	"github.com/skndash96/lastnight-backend/internal/filetype"
+	"github.com/skndash96/lastnight-backend/internal/provider"
+	"github.com/skndash96/lastnight-backend/internal/repository"
+)
+
+// bytes read from each end of an uploaded object for sniffing
+const sniffLength = 64 * 1024
+
+type UploadSettings struct {
+	AllowedMimeTypes []string
+	// whether AllowedMimeTypes is the server default rather than the team's own
+	IsDefault bool
+	// lowercase with a leading dot, nil accepts any extension
+	AllowedExtensions []string
+	// bytes per file, nil falls back to the server limit
+	MaxFileSize *int64
+	// the settings row the policy was read from, for checking quotas
+	team db.TeamSetting
+}
+
+func (s *UploadService) GetUploadSettings(ctx context.Context, teamID int32) (*UploadSettings, error) {
+	settings, err := getTeamSettings(ctx, repository.NewTeamRepository(s.pool), teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.uploadSettings(settings), nil
+}
+
+// UpdateUploadSettings sets the team's upload policy. A nil allowlist restores the
+// server default, nil extensions accept any and a nil size falls back to the server limit.
+func (s *UploadService) UpdateUploadSettings(ctx context.Context, teamID int32, allowedMimeTypes, allowedExtensions []string, maxFileSize *int64) (*UploadSettings, error) {
+	if allowedMimeTypes != nil && len(allowedMimeTypes) == 0 {
+		return nil, NewSrvError(nil, SrvErrInvalidInput, "at least one mime type must be allowed")
+	}
+
+	if allowedExtensions != nil && len(allowedExtensions) == 0 {
+		return nil, NewSrvError(nil, SrvErrInvalidInput, "at least one extension must be allowed")
+	}
+
+	if maxFileSize != nil && *maxFileSize <= 0 {
+		return nil, NewSrvError(nil, SrvErrInvalidInput, "max file size must be positive")
+	}
+
+	var mimeTypes []string
+	for _, mime := range allowedMimeTypes {
+		mime = strings.ToLower(strings.TrimSpace(mime))
+		if err := filetype.CheckAllowable(mime); err != nil {
+			return nil, NewSrvError(err, SrvErrInvalidInput, err.Error())
+		}
+		if !slices.Contains(mimeTypes, mime) {
+			mimeTypes = append(mimeTypes, mime)
+		}
+	}
+
+	var extensions []string
+	for _, ext := range allowedExtensions {
+		ext = normalizeExtension(ext)
+		if ext == "." {
+			return nil, NewSrvError(nil, SrvErrInvalidInput, "extensions cannot be empty")
+		}
+		if !slices.Contains(extensions, ext) {
+			extensions = append(extensions, ext)
+		}
+	}
+
+	teamRepo := repository.NewTeamRepository(s.pool)
+
+	settings, err := teamRepo.UpsertTeamSettings(ctx, teamID, mimeTypes, extensions, maxFileSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.uploadSettings(settings), nil
+}
+
+func (s *UploadService) uploadSettings(settings db.TeamSetting) *UploadSettings {
+	out := &UploadSettings{
+		AllowedMimeTypes:  settings.AllowedMimeTypes,
+		AllowedExtensions: settings.AllowedExtensions,
+		MaxFileSize:       settings.MaxFileSize,
+		team:              settings,
+	}
+
+	if settings.AllowedMimeTypes == nil {
+		out.AllowedMimeTypes = s.cfg.AllowedMimeTypes
+		out.IsDefault = true
+	}
+
+	return out
+}
+
+// maxFileSize is the team's limit in bytes, 0 if only the server limit applies.
+func (settings *UploadSettings) maxFileSize() int64 {
+	if settings.MaxFileSize == nil {
+		return 0
+	}
+	return *settings.MaxFileSize
+}
+
+// checkUploadPolicy checks a file's name, declared content type and size against
+// the team's policy before it is uploaded. The content type is verified again,
+// by sniffing, once the file is uploaded.
+func (s *UploadService) checkUploadPolicy(ctx context.Context, teamID int32, name, mime string, size int64) (*UploadSettings, error) {
+	settings, err := s.GetUploadSettings(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := settings.checkMimeType(mime); err != nil {
+		return nil, err
+	}
+
+	if err := settings.checkExtension(name); err != nil {
+		return nil, err
+	}
+
+	if max := settings.maxFileSize(); max > 0 && size > max {
+		return nil, NewSrvError(provider.ErrFileTooLarge, SrvErrInvalidInput, fmt.Sprintf("Upload failed: files may be at most %s", formatBytes(max)))
+	}
+
+	return settings, nil
+}
+
+// checkMimeType fails unless a content type, detected or declared by a
+// client, is on the team's allowlist.
+func (settings *UploadSettings) checkMimeType(mime string) error {
+	if !slices.ContainsFunc(settings.AllowedMimeTypes, func(allowed string) bool {
+		return filetype.Matches(allowed, mime)
+	}) {
+		return NewSrvError(nil, SrvErrInvalidInput, fmt.Sprintf("Upload failed: %s files are not allowed", mime))
+	}
+
+	return nil
+}
+
+// checkExtension fails unless the extension of name is on the team's allowlist.
+func (settings *UploadSettings) checkExtension(name string) error {
+	if settings.AllowedExtensions == nil {
+		return nil
+	}
+
+	ext := normalizeExtension(path.Ext(name))
+	if !slices.Contains(settings.AllowedExtensions, ext) {
+		return NewSrvError(nil, SrvErrInvalidInput, fmt.Sprintf("Upload failed: only %s files are allowed", strings.Join(settings.AllowedExtensions, ", ")))
+	}
+
+	return nil
+}
+
+func normalizeExtension(ext string) string {
+	return "." + strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+}
+
+// getTeamSettings returns the team's settings, or empty settings if the team has none.
+func getTeamSettings(ctx context.Context, teamRepo *repository.TeamRepository, teamID int32) (db.TeamSetting, error) {
+	settings, err := teamRepo.GetTeamSettings(ctx, teamID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.TeamSetting{TeamID: teamID}, nil
+	}
+	if err != nil {
+		return db.TeamSetting{}, err
+	}
+
+	return settings, nil
+}
+
+// validateUpload sniffs the content type of an uploaded object from its magic
+// bytes and checks it against the team's rules. Invalid objects are deleted.
+func (s *UploadService) validateUpload(ctx context.Context, settings *UploadSettings, key string, info *provider.UploadInfo) (string, error) {
+	mime, err := s.sniffUpload(ctx, settings, key, info)
+	if err != nil {
+		var srvErr *SrvError
+		if errors.As(err, &srvErr) && srvErr.Kind == SrvErrInvalidInput {
+			if err := s.uploadProvider.DeleteObject(ctx, key); err != nil {
+				// it's not a fatal error, so we can continue
+				fmt.Printf("failed to delete invalid upload %s: %v\n", key, err)
+			}
+		}
+		return "", err
+	}
+
+	return mime, nil
+}
+
+func (s *UploadService) sniffUpload(ctx context.Context, settings *UploadSettings, key string, info *provider.UploadInfo) (string, error) {
+	if info.Size == 0 {
+		return "", NewSrvError(nil, SrvErrInvalidInput, "Upload completion failed: file is empty")
+	}
+
+	head, err := s.uploadProvider.GetObjectRange(ctx, key, 0, min(info.Size, sniffLength))
+	if err != nil {
+		return "", NewSrvError(err, SrvErrInternal, fmt.Sprintf("failed to read %s", key))
+	}
+
+	tail := head
+	if info.Size > sniffLength {
+		tail, err = s.uploadProvider.GetObjectRange(ctx, key, info.Size-sniffLength, sniffLength)
+		if err != nil {
+			return "", NewSrvError(err, SrvErrInternal, fmt.Sprintf("failed to read %s", key))
+		}
+	}
+
+	mime := filetype.Detect(head)
+
+	if err := settings.checkMimeType(mime); err != nil {
+		return "", err
+	}
+
+	if max := settings.maxFileSize(); max > 0 && info.Size > max {
+		return "", NewSrvError(provider.ErrFileTooLarge, SrvErrInvalidInput, fmt.Sprintf("Upload completion failed: files may be at most %s", formatBytes(max)))
+	}
+
+	if s.cfg.RejectPolyglots && filetype.IsPolyglot(mime, head, tail) {
+		return "", NewSrvError(nil, SrvErrInvalidInput, "Upload completion failed: file matches more than one file type")
+	}
+
+	if s.cfg.RejectEncryptedPDFs && mime == filetype.PDF && filetype.IsEncryptedPDF(head, tail) {
+		return "", NewSrvError(nil, SrvErrInvalidInput, "Upload completion failed: encrypted PDFs are not allowed")
+	}
+
+	return mime, nil
+}
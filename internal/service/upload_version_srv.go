@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/skndash96/lastnight-backend/internal/db"
+	"github.com/skndash96/lastnight-backend/internal/repository"
+)
+
+type UploadVersion struct {
+	db.ListUploadVersionsRow
+	Url *url.URL
+}
+
+// ListUploadVersions returns every version of an upload, newest first, with
+// presigned GET URLs so older versions stay downloadable.
+func (s *UploadService) ListUploadVersions(ctx context.Context, teamID, refID int32) ([]UploadVersion, error) {
+	uploadRepo := repository.NewUploadRepository(s.pool)
+
+	versions, err := uploadRepo.ListUploadVersions(ctx, teamID, refID)
+	if err != nil {
+		return nil, err
+	}
+
+	// every active ref has at least one version
+	if len(versions) == 0 {
+		return nil, NewSrvError(nil, SrvErrNotFound, "upload not found")
+	}
+
+	out := make([]UploadVersion, len(versions))
+	for i, v := range versions {
+		url, err := s.uploadProvider.PresignGetObject(ctx, v.StorageKey)
+		if err != nil {
+			return nil, NewSrvError(err, SrvErrInternal, fmt.Sprintf("failed to presign %s", v.StorageKey))
+		}
+
+		out[i] = UploadVersion{
+			ListUploadVersionsRow: v,
+			Url:                   url,
+		}
+	}
+
+	return out, nil
+}
+
+// SetCurrentUploadVersion makes an earlier, or later, version the one the upload
+// is listed, searched and previewed with.
+func (s *UploadService) SetCurrentUploadVersion(ctx context.Context, teamID, refID, version int32) (*db.UploadRef, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, NewSrvError(err, SrvErrInternal, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	uploadRepo := repository.NewUploadRepository(tx)
+
+	ref, err := uploadRepo.GetUploadRef(ctx, teamID, refID)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && ref.Status != db.UploadRefStatusActive) {
+		return nil, NewSrvError(err, SrvErrNotFound, "upload not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := uploadRepo.GetUploadVersion(ctx, ref.ID, version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, NewSrvError(err, SrvErrNotFound, fmt.Sprintf("version %d not found", version))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if v.UploadID == ref.UploadID {
+		return ref, nil
+	}
+
+	// refs are unique per (upload, team)
+	other, err := uploadRepo.GetTeamUploadRef(ctx, teamID, v.UploadID)
+	if err == nil {
+		return nil, NewSrvError(nil, SrvErrConflict, fmt.Sprintf("version %d is also uploaded to the team as upload %d", version, other.ID))
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	ref, err = uploadRepo.SetCurrentUploadVersion(ctx, ref.ID, v.UploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, NewSrvError(err, SrvErrInternal, "failed to set current version")
+	}
+
+	return ref, nil
+}
+
+// addUploadVersion attaches an upload to a ref of the team as its next version,
+// which becomes the current one. The ref's name and tags stay as they are. Like
+// other edits, only its uploader or a mod may replace the file.
+func addUploadVersion(ctx context.Context, tx db.DBTX, teamID, userID, refID, uploadID int32, name string) (*db.UploadRef, error) {
+	uploadRepo := repository.NewUploadRepository(tx)
+	teamRepo := repository.NewTeamRepository(tx)
+
+	// the upload paths only pass the user, their role is looked up
+	membership, err := teamRepo.GetTeamMembershipByUserID(ctx, userID, teamID)
+	if err != nil {
+		return nil, NewSrvError(err, SrvErrForbidden, "not a member of the team")
+	}
+
+	ref, err := getEditableUploadRef(ctx, tx, teamID, userID, membership.Role, refID)
+	if err != nil {
+		return nil, err
+	}
+
+	if ref.Status != db.UploadRefStatusActive {
+		return nil, NewSrvError(nil, SrvErrInvalidInput, "rejected uploads cannot get new versions")
+	}
+
+	existing, err := uploadRepo.GetUploadVersionByUpload(ctx, ref.ID, uploadID)
+	if err == nil {
+		return nil, NewSrvError(nil, SrvErrConflict, fmt.Sprintf("the file is already version %d of this upload", existing.Version))
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	other, err := uploadRepo.GetTeamUploadRef(ctx, teamID, uploadID)
+	if err == nil {
+		return nil, NewSrvError(nil, SrvErrConflict, fmt.Sprintf("the file is already uploaded to the team as upload %d", other.ID))
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	if _, err := uploadRepo.CreateUploadVersion(ctx, ref.ID, uploadID, userID, name); err != nil {
+		return nil, err
+	}
+
+	return uploadRepo.SetCurrentUploadVersion(ctx, ref.ID, uploadID)
+}
+
+// checkNewVersion rejects tags sent with a new version, they belong to the upload
+// and are changed with UpdateUploadRef.
+func checkNewVersion(versionOf int32, tags []UploadTagInput) error {
+	if versionOf != 0 && len(tags) > 0 {
+		return NewSrvError(nil, SrvErrInvalidInput, "tags cannot be set on a new version, update the upload instead")
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/joho/godotenv"
+	"github.com/skndash96/lastnight-backend/internal/config"
+	"github.com/skndash96/lastnight-backend/internal/provider"
+	"github.com/skndash96/lastnight-backend/internal/service"
+)
+
+// TeamExport writes a team's tags, members and uploads to a ZIP archive, from
+// the command line.
+func TeamExport(args []string) error {
+	_ = godotenv.Load()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	appCfg := config.New()
+
+	fs := flag.NewFlagSet("team-export", flag.ContinueOnError)
+	teamID := fs.Int("team", 0, "team to export")
+	outPath := fs.String("out", "", "path of the archive to write")
+	objects := fs.Bool("objects", false, "include the files of uploads, not only their metadata")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *teamID <= 0 || *outPath == "" {
+		fs.Usage()
+		return errors.New("-team and -out are required")
+	}
+
+	archiveSrv, closeSrv, err := newTeamArchiveService(ctx, appCfg)
+	if err != nil {
+		return err
+	}
+	defer closeSrv()
+
+	f, err := os.Create(*outPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	report, err := archiveSrv.WriteTeamArchive(ctx, f, int32(*teamID), *objects)
+	if err != nil {
+		os.Remove(*outPath)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	fmt.Printf("exported %d tag keys with %d values, %d members and %d uploads with %d files (%d bytes) to %s\n",
+		report.TagKeys, report.TagValues, report.Members, report.Uploads, report.Objects, report.Bytes, *outPath)
+
+	return nil
+}
+
+// TeamImport imports an archive written by TeamExport into a team, from the
+// command line.
+func TeamImport(args []string) error {
+	_ = godotenv.Load()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	appCfg := config.New()
+
+	fs := flag.NewFlagSet("team-import", flag.ContinueOnError)
+	teamID := fs.Int("team", 0, "team to import into")
+	userID := fs.Int("user", 0, "member of the team uploads are added as when their uploader is not a member")
+	inPath := fs.String("in", "", "path of the archive to import")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *teamID <= 0 || *userID <= 0 || *inPath == "" {
+		fs.Usage()
+		return errors.New("-team, -user and -in are required")
+	}
+
+	f, err := os.Open(*inPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	archiveSrv, closeSrv, err := newTeamArchiveService(ctx, appCfg)
+	if err != nil {
+		return err
+	}
+	defer closeSrv()
+
+	report, err := archiveSrv.ImportTeamArchive(ctx, int32(*teamID), int32(*userID), f, info.Size(), true)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("created %d tag keys, reused %d, created %d tag values\n", report.CreatedTagKeys, report.ReusedTagKeys, report.CreatedTagValues)
+
+	fmt.Printf("added %d members, %d were members already, skipped %d not registered or in another team\n",
+		report.AddedMembers, report.ExistingMembers, report.SkippedMembers)
+
+	fmt.Printf("imported %d uploads, linked %d older versions, skipped %d already in the team, %d failed\n",
+		report.Uploads[service.ImportStatusImported],
+		report.Uploads[service.ImportStatusLinked],
+		report.Uploads[service.ImportStatusInTeam],
+		report.Uploads[service.ImportStatusFailed],
+	)
+	for _, skip := range report.FailedUploads {
+		fmt.Printf("  failed %s: %s\n", skip.Name, skip.Error)
+	}
+
+	if n := len(report.FailedUploads); n > 0 {
+		return fmt.Errorf("%d uploads failed to import, run again to retry them", n)
+	}
+
+	return nil
+}
+
+// newTeamArchiveService connects to the database and storage, the returned
+// function waits for uploads being processed and closes the connections.
+func newTeamArchiveService(ctx context.Context, appCfg *config.AppConfig) (*service.TeamArchiveService, func(), error) {
+	pool, err := pgxpool.New(ctx, appCfg.DbURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	uploadProvider, err := provider.NewUploadProvider(appCfg.Storage)
+	if err != nil {
+		pool.Close()
+		return nil, nil, err
+	}
+
+	uploadSrv := service.NewUploadService(ctx, appCfg.Upload, uploadProvider, pool)
+
+	return service.NewTeamArchiveService(ctx, uploadSrv, pool), func() {
+		// previews and text of the last uploads are still being generated
+		uploadSrv.WaitProcessing()
+		pool.Close()
+	}, nil
+}